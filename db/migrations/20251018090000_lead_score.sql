@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.scoring_policy
+(
+    category_id integer NOT NULL,
+    gemini_weight double precision NOT NULL DEFAULT 0.5,
+    model_weight double precision NOT NULL DEFAULT 0.4,
+    completeness_weight double precision NOT NULL DEFAULT 0.1,
+    min_completeness integer NOT NULL DEFAULT 0,
+    decay_half_life_days double precision NOT NULL DEFAULT 0,
+    calibration_method character varying(16) COLLATE pg_catalog."default" NOT NULL DEFAULT 'none',
+    calibration jsonb NOT NULL DEFAULT '{}'::jsonb,
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT scoring_policy_pkey PRIMARY KEY (category_id),
+    CONSTRAINT scoring_policy_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+ALTER TABLE public.user_profile_category
+  ADD COLUMN IF NOT EXISTS final_score double precision,
+  ADD COLUMN IF NOT EXISTS final_score_at timestamp without time zone,
+  ADD COLUMN IF NOT EXISTS label smallint;
+
+CREATE INDEX IF NOT EXISTS idx_user_profile_category_final_score
+  ON public.user_profile_category (category_id, final_score DESC NULLS LAST);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_user_profile_category_final_score;
+
+ALTER TABLE public.user_profile_category
+  DROP COLUMN IF EXISTS final_score,
+  DROP COLUMN IF EXISTS final_score_at,
+  DROP COLUMN IF EXISTS label;
+
+DROP TABLE IF EXISTS public.scoring_policy;
+-- +goose StatementEnd
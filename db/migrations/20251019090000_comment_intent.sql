@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.comment
+  ADD COLUMN IF NOT EXISTS intent character varying(32),
+  ADD COLUMN IF NOT EXISTS intent_confidence double precision,
+  ADD COLUMN IF NOT EXISTS intents jsonb NOT NULL DEFAULT '{}'::jsonb,
+  ADD COLUMN IF NOT EXISTS analyzed_at timestamp without time zone;
+
+CREATE INDEX IF NOT EXISTS idx_comment_not_analyzed
+  ON public.comment (inserted_at)
+  WHERE is_analyzed = false;
+
+ALTER TABLE public.user_profile_category
+  ADD COLUMN IF NOT EXISTS intent_score double precision;
+
+ALTER TABLE public.scoring_policy
+  ADD COLUMN IF NOT EXISTS intent_weight double precision NOT NULL DEFAULT 0.3;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.scoring_policy
+  DROP COLUMN IF EXISTS intent_weight;
+
+ALTER TABLE public.user_profile_category
+  DROP COLUMN IF EXISTS intent_score;
+
+DROP INDEX IF EXISTS public.idx_comment_not_analyzed;
+
+ALTER TABLE public.comment
+  DROP COLUMN IF EXISTS intent,
+  DROP COLUMN IF EXISTS intent_confidence,
+  DROP COLUMN IF EXISTS intents,
+  DROP COLUMN IF EXISTS analyzed_at;
+-- +goose StatementEnd
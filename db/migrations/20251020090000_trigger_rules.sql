@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.trigger_rule
+(
+    id SERIAL,
+    category_id integer NOT NULL,
+    name character varying(128) COLLATE pg_catalog."default" NOT NULL,
+    keywords text[] NOT NULL DEFAULT '{}'::text[],
+    patterns text[] NOT NULL DEFAULT '{}'::text[],
+    negative_keywords text[] NOT NULL DEFAULT '{}'::text[],
+    language character varying(8) COLLATE pg_catalog."default",
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT trigger_rule_pkey PRIMARY KEY (id),
+    CONSTRAINT trigger_rule_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS public.trigger_rule_hit
+(
+    id SERIAL,
+    rule_id integer NOT NULL,
+    category_id integer NOT NULL,
+    user_profile_id integer NOT NULL,
+    source_type character varying(16) COLLATE pg_catalog."default" NOT NULL,
+    source_id integer NOT NULL,
+    matched text COLLATE pg_catalog."default" NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT trigger_rule_hit_pkey PRIMARY KEY (id),
+    CONSTRAINT uq_trigger_rule_hit_source UNIQUE (rule_id, user_profile_id, source_type, source_id),
+    CONSTRAINT trigger_rule_hit_rule_id_fkey FOREIGN KEY (rule_id)
+        REFERENCES public.trigger_rule (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT trigger_rule_hit_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT trigger_rule_hit_user_profile_id_fkey FOREIGN KEY (user_profile_id)
+        REFERENCES public.user_profile (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trigger_rule_category_id ON public.trigger_rule(category_id);
+CREATE INDEX IF NOT EXISTS idx_trigger_rule_hit_user_profile_id ON public.trigger_rule_hit(user_profile_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_trigger_rule_hit_user_profile_id;
+DROP INDEX IF EXISTS public.idx_trigger_rule_category_id;
+DROP TABLE IF EXISTS public.trigger_rule_hit;
+DROP TABLE IF EXISTS public.trigger_rule;
+-- +goose StatementEnd
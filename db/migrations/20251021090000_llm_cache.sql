@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.llm_cache
+(
+    prompt_hash character(64) COLLATE pg_catalog."default" NOT NULL,
+    model character varying(64) COLLATE pg_catalog."default" NOT NULL,
+    response text COLLATE pg_catalog."default" NOT NULL,
+    hit_count integer NOT NULL DEFAULT 0,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    expires_at timestamp without time zone NOT NULL,
+    CONSTRAINT llm_cache_pkey PRIMARY KEY (prompt_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_cache_expires_at ON public.llm_cache(expires_at);
+
+CREATE TABLE IF NOT EXISTS public.llm_cache_stat
+(
+    date date NOT NULL,
+    hits bigint NOT NULL DEFAULT 0,
+    misses bigint NOT NULL DEFAULT 0,
+    CONSTRAINT llm_cache_stat_pkey PRIMARY KEY (date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.llm_cache_stat;
+DROP INDEX IF EXISTS public.idx_llm_cache_expires_at;
+DROP TABLE IF EXISTS public.llm_cache;
+-- +goose StatementEnd
@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.llm_price
+(
+    model character varying(64) COLLATE pg_catalog."default" NOT NULL,
+    provider character varying(32) COLLATE pg_catalog."default" NOT NULL DEFAULT 'gemini',
+    input_price double precision NOT NULL DEFAULT 0,
+    output_price double precision NOT NULL DEFAULT 0,
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT llm_price_pkey PRIMARY KEY (model)
+);
+
+COMMENT ON COLUMN public.llm_price.input_price IS 'USD per 1M prompt tokens';
+COMMENT ON COLUMN public.llm_price.output_price IS 'USD per 1M output tokens, thinking included';
+
+INSERT INTO public.llm_price (model, provider, input_price, output_price)
+VALUES
+    ('gemini-2.5-flash-lite', 'gemini', 0.10, 0.40),
+    ('gemini-2.5-flash', 'gemini', 0.30, 2.50),
+    ('gemini-2.5-pro', 'gemini', 1.25, 10.00)
+ON CONFLICT (model) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS public.llm_usage
+(
+    id SERIAL,
+    key_id integer,
+    provider character varying(32) COLLATE pg_catalog."default" NOT NULL,
+    model character varying(64) COLLATE pg_catalog."default" NOT NULL,
+    category_id integer,
+    job character varying(64) COLLATE pg_catalog."default" NOT NULL,
+    prompt_tokens bigint NOT NULL DEFAULT 0,
+    output_tokens bigint NOT NULL DEFAULT 0,
+    cost double precision NOT NULL DEFAULT 0,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT llm_usage_pkey PRIMARY KEY (id),
+    CONSTRAINT llm_usage_key_id_fkey FOREIGN KEY (key_id)
+        REFERENCES public.gemini_key (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL,
+    CONSTRAINT llm_usage_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON public.llm_usage(created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_category_id_created_at ON public.llm_usage(category_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_llm_usage_category_id_created_at;
+DROP INDEX IF EXISTS public.idx_llm_usage_created_at;
+DROP TABLE IF EXISTS public.llm_usage;
+DROP TABLE IF EXISTS public.llm_price;
+-- +goose StatementEnd
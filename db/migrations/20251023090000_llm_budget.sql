@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.llm_budget
+(
+    id SERIAL,
+    category_id integer,
+    period character varying(16) COLLATE pg_catalog."default" NOT NULL,
+    max_tokens bigint,
+    max_cost double precision,
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT llm_budget_pkey PRIMARY KEY (id),
+    CONSTRAINT llm_budget_period_check CHECK (period IN ('daily', 'monthly')),
+    CONSTRAINT llm_budget_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+COMMENT ON COLUMN public.llm_budget.category_id IS 'NULL for the global budget';
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_llm_budget_category_period ON public.llm_budget((COALESCE(category_id, 0)), period);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.uq_llm_budget_category_period;
+DROP TABLE IF EXISTS public.llm_budget;
+-- +goose StatementEnd
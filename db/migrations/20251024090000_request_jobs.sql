@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.request_status
+(
+    code smallint NOT NULL,
+    name character varying(16) COLLATE pg_catalog."default" NOT NULL,
+    is_final boolean NOT NULL DEFAULT false,
+    CONSTRAINT request_status_pkey PRIMARY KEY (code),
+    CONSTRAINT request_status_name_key UNIQUE (name)
+);
+
+INSERT INTO public.request_status (code, name, is_final) VALUES
+    (0, 'queued', false),
+    (1, 'running', false),
+    (2, 'done', true),
+    (3, 'failed', true),
+    (4, 'cancelled', true),
+    (5, 'interrupted', true)
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE public.request
+    ALTER COLUMN description TYPE character varying(255),
+    ADD COLUMN IF NOT EXISTS kind character varying(32) NOT NULL DEFAULT 'unknown',
+    ADD COLUMN IF NOT EXISTS result jsonb,
+    ADD COLUMN IF NOT EXISTS started_at timestamp without time zone,
+    ADD COLUMN IF NOT EXISTS finished_at timestamp without time zone;
+
+-- Training was the only producer of requests so far
+UPDATE public.request SET kind = 'ml.train' WHERE kind = 'unknown';
+UPDATE public.request SET finished_at = updated_at WHERE status IN (2, 3) AND finished_at IS NULL;
+
+ALTER TABLE public.request
+    ADD CONSTRAINT request_status_fkey FOREIGN KEY (status)
+        REFERENCES public.request_status (code) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION;
+
+COMMENT ON COLUMN public.request.kind IS 'Job kind, e.g. ml.train or profile.import';
+COMMENT ON COLUMN public.request.result IS 'Job result, e.g. counts or the name of an exported file';
+
+CREATE INDEX IF NOT EXISTS idx_request_status ON public.request(status);
+CREATE INDEX IF NOT EXISTS idx_request_finished_at ON public.request(finished_at) WHERE finished_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_request_finished_at;
+DROP INDEX IF EXISTS public.idx_request_status;
+ALTER TABLE public.request DROP CONSTRAINT IF EXISTS request_status_fkey;
+UPDATE public.request SET status = 3 WHERE status > 3;
+UPDATE public.request SET description = LEFT(description, 50);
+ALTER TABLE public.request
+    DROP COLUMN IF EXISTS finished_at,
+    DROP COLUMN IF EXISTS started_at,
+    DROP COLUMN IF EXISTS result,
+    DROP COLUMN IF EXISTS kind,
+    ALTER COLUMN description TYPE character varying(50);
+DROP TABLE IF EXISTS public.request_status;
+-- +goose StatementEnd
@@ -0,0 +1,106 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.model_version
+(
+    id SERIAL,
+    model_id integer NOT NULL,
+    version integer NOT NULL,
+    category_id integer,
+    path character varying COLLATE pg_catalog."default" NOT NULL,
+    metrics jsonb NOT NULL DEFAULT '{}'::jsonb,
+    dataset_snapshot_id character varying(64) COLLATE pg_catalog."default",
+    dataset_size integer,
+    prompt_service character varying COLLATE pg_catalog."default",
+    prompt_version integer,
+    parent_version_id integer,
+    request_id integer,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT model_version_pkey PRIMARY KEY (id),
+    CONSTRAINT model_version_model_id_version_key UNIQUE (model_id, version),
+    CONSTRAINT model_version_path_key UNIQUE (path),
+    CONSTRAINT model_version_model_id_fkey FOREIGN KEY (model_id)
+        REFERENCES public.model (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT model_version_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL,
+    CONSTRAINT model_version_parent_version_id_fkey FOREIGN KEY (parent_version_id)
+        REFERENCES public.model_version (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL
+);
+
+COMMENT ON COLUMN public.model_version.path IS 'Folder of the version, relative to python/resources/models';
+COMMENT ON COLUMN public.model_version.dataset_snapshot_id IS 'Hash of the (profile, gemini score) pairs the version was trained on';
+COMMENT ON COLUMN public.model_version.parent_version_id IS 'Champion of the category when the version was trained';
+
+CREATE TABLE IF NOT EXISTS public.model_deployment
+(
+    category_id integer NOT NULL,
+    champion_version_id integer,
+    challenger_version_id integer,
+    challenger_sample_rate double precision NOT NULL DEFAULT 0.1,
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT model_deployment_pkey PRIMARY KEY (category_id),
+    CONSTRAINT model_deployment_sample_rate_check CHECK (challenger_sample_rate >= 0 AND challenger_sample_rate <= 1),
+    CONSTRAINT model_deployment_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT model_deployment_champion_version_id_fkey FOREIGN KEY (champion_version_id)
+        REFERENCES public.model_version (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL,
+    CONSTRAINT model_deployment_challenger_version_id_fkey FOREIGN KEY (challenger_version_id)
+        REFERENCES public.model_version (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS public.model_shadow_score
+(
+    version_id integer NOT NULL,
+    user_profile_id integer NOT NULL,
+    category_id integer NOT NULL,
+    score double precision NOT NULL,
+    champion_score double precision,
+    scored_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT model_shadow_score_pkey PRIMARY KEY (version_id, user_profile_id, category_id),
+    CONSTRAINT model_shadow_score_version_id_fkey FOREIGN KEY (version_id)
+        REFERENCES public.model_version (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT model_shadow_score_user_profile_id_fkey FOREIGN KEY (user_profile_id)
+        REFERENCES public.user_profile (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT model_shadow_score_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_shadow_score_category_id ON public.model_shadow_score(category_id);
+
+-- Existing models become their own first version, assigned ones champions
+INSERT INTO public.model_version (model_id, version, category_id, path, created_at)
+SELECT id, 1, category_id, name, created_at FROM public.model
+ON CONFLICT DO NOTHING;
+
+INSERT INTO public.model_deployment (category_id, champion_version_id, updated_at)
+SELECT m.category_id, mv.id, NOW()
+FROM public.model m
+JOIN public.model_version mv ON mv.model_id = m.id AND mv.version = 1
+WHERE m.category_id IS NOT NULL
+ON CONFLICT (category_id) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_model_shadow_score_category_id;
+DROP TABLE IF EXISTS public.model_shadow_score;
+DROP TABLE IF EXISTS public.model_deployment;
+DROP TABLE IF EXISTS public.model_version;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.retrain_policy
+(
+    category_id integer NOT NULL,
+    model_name character varying(128) COLLATE pg_catalog."default" NOT NULL,
+    is_enabled boolean NOT NULL DEFAULT true,
+    interval_hours integer NOT NULL DEFAULT 0,
+    min_new_profiles integer NOT NULL DEFAULT 0,
+    rmse_margin double precision NOT NULL DEFAULT 0,
+    r2_margin double precision NOT NULL DEFAULT 0,
+    auto_tune boolean NOT NULL DEFAULT false,
+    trials integer,
+    last_run_at timestamp without time zone,
+    profiles_at_last_run integer NOT NULL DEFAULT 0,
+    last_request_id integer,
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT retrain_policy_pkey PRIMARY KEY (category_id),
+    CONSTRAINT retrain_policy_interval_hours_check CHECK (interval_hours >= 0),
+    CONSTRAINT retrain_policy_min_new_profiles_check CHECK (min_new_profiles >= 0),
+    CONSTRAINT retrain_policy_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+COMMENT ON COLUMN public.retrain_policy.interval_hours IS 'Retrain every N hours, 0 disables the schedule';
+COMMENT ON COLUMN public.retrain_policy.min_new_profiles IS 'Retrain once N profiles were labelled or gemini-scored since the last run, 0 disables it';
+COMMENT ON COLUMN public.retrain_policy.profiles_at_last_run IS 'Labelled or gemini-scored profiles of the category when the last run started';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.retrain_policy;
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.user_profile_category ADD COLUMN IF NOT EXISTS model_scored_at timestamp without time zone;
+
+CREATE INDEX IF NOT EXISTS idx_user_profile_category_model_scored_at ON public.user_profile_category(category_id, model_scored_at)
+    WHERE model_scored_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.model_drift
+(
+    category_id integer NOT NULL,
+    day date NOT NULL,
+    version_id integer,
+    sample_size integer NOT NULL,
+    score_mean double precision NOT NULL,
+    score_std double precision NOT NULL,
+    psi double precision NOT NULL,
+    ks double precision NOT NULL,
+    completeness jsonb NOT NULL DEFAULT '{}'::jsonb,
+    completeness_drift double precision NOT NULL DEFAULT 0,
+    alerts jsonb NOT NULL DEFAULT '[]'::jsonb,
+    is_alert boolean NOT NULL DEFAULT false,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT model_drift_pkey PRIMARY KEY (category_id, day),
+    CONSTRAINT model_drift_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT model_drift_version_id_fkey FOREIGN KEY (version_id)
+        REFERENCES public.model_version (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL
+);
+
+COMMENT ON COLUMN public.model_drift.completeness IS 'Share of recently scored profiles with each input feature filled in';
+COMMENT ON COLUMN public.model_drift.completeness_drift IS 'Largest difference with the completeness of the training set';
+
+CREATE INDEX IF NOT EXISTS idx_model_drift_is_alert ON public.model_drift(day) WHERE is_alert;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_model_drift_is_alert;
+DROP TABLE IF EXISTS public.model_drift;
+DROP INDEX IF EXISTS public.idx_user_profile_category_model_scored_at;
+ALTER TABLE public.user_profile_category DROP COLUMN IF EXISTS model_scored_at;
+-- +goose StatementEnd
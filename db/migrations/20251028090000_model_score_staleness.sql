@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.user_profile_category
+    ADD COLUMN IF NOT EXISTS model_version_id integer,
+    ADD COLUMN IF NOT EXISTS model_input_hash character varying(32);
+
+COMMENT ON COLUMN public.user_profile_category.model_version_id IS 'Model version that produced model_score, NULL for unversioned models';
+COMMENT ON COLUMN public.user_profile_category.model_input_hash IS 'Hash of the profile inputs model_score was computed from';
+
+ALTER TABLE ONLY public.user_profile_category
+    ADD CONSTRAINT user_profile_category_model_version_id_fkey FOREIGN KEY (model_version_id) REFERENCES public.model_version(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.user_profile_category DROP CONSTRAINT IF EXISTS user_profile_category_model_version_id_fkey;
+ALTER TABLE public.user_profile_category
+    DROP COLUMN IF EXISTS model_input_hash,
+    DROP COLUMN IF EXISTS model_version_id;
+-- +goose StatementEnd
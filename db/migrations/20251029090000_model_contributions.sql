@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.user_profile_category ADD COLUMN IF NOT EXISTS model_contributions jsonb;
+
+COMMENT ON COLUMN public.user_profile_category.model_contributions IS 'SHAP contribution of each model input to model_score, with the embedding summed into one';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.user_profile_category DROP COLUMN IF EXISTS model_contributions;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.embedded_profile_embedding_idx;
+
+DO $$
+DECLARE
+  c record;
+BEGIN
+  FOR c IN SELECT id FROM public.category LOOP
+    EXECUTE format(
+      'CREATE INDEX IF NOT EXISTS %I ON public.embedded_profile USING hnsw (embedding vector_cosine_ops) WITH (m = 16, ef_construction = 64) WHERE cid = %s',
+      'idx_embedded_profile_embedding_cat_' || c.id, c.id
+    );
+  END LOOP;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_embedded_profile_cid ON public.embedded_profile USING btree (cid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+DECLARE
+  i record;
+BEGIN
+  FOR i IN SELECT indexname FROM pg_indexes
+    WHERE schemaname = 'public' AND indexname LIKE 'idx\_embedded\_profile\_embedding\_cat\_%' LOOP
+    EXECUTE format('DROP INDEX IF EXISTS public.%I', i.indexname);
+  END LOOP;
+END
+$$;
+
+DROP INDEX IF EXISTS public.idx_embedded_profile_cid;
+
+CREATE INDEX IF NOT EXISTS embedded_profile_embedding_idx ON public.embedded_profile USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100);
+-- +goose StatementEnd
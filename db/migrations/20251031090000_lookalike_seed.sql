@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.lookalike_seed
+(
+    id serial NOT NULL,
+    category_id integer NOT NULL,
+    name character varying(255) NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT lookalike_seed_pkey PRIMARY KEY (id),
+    CONSTRAINT uq_lookalike_seed_category_name UNIQUE (category_id, name),
+    CONSTRAINT lookalike_seed_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS public.lookalike_seed_profile
+(
+    seed_id integer NOT NULL,
+    user_profile_id integer NOT NULL,
+    added_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT lookalike_seed_profile_pkey PRIMARY KEY (seed_id, user_profile_id),
+    CONSTRAINT lookalike_seed_profile_seed_id_fkey FOREIGN KEY (seed_id)
+        REFERENCES public.lookalike_seed (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT lookalike_seed_profile_user_profile_id_fkey FOREIGN KEY (user_profile_id)
+        REFERENCES public.user_profile (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+COMMENT ON TABLE public.lookalike_seed IS 'Named list of converted customers of a category, the source of lookalike audiences';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.lookalike_seed_profile;
+DROP TABLE IF EXISTS public.lookalike_seed;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.persona
+(
+    id serial NOT NULL,
+    category_id integer NOT NULL,
+    cluster integer NOT NULL,
+    centroid vector(1024) NOT NULL,
+    label character varying(255),
+    description text,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT persona_pkey PRIMARY KEY (id),
+    CONSTRAINT uq_persona_category_cluster UNIQUE (category_id, cluster),
+    CONSTRAINT persona_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+COMMENT ON COLUMN public.persona.centroid IS 'Normalized mean of the embeddings of the cluster';
+
+ALTER TABLE public.embedded_profile ADD COLUMN IF NOT EXISTS persona_id integer;
+ALTER TABLE public.embedded_profile ADD CONSTRAINT embedded_profile_persona_id_fkey FOREIGN KEY (persona_id)
+    REFERENCES public.persona (id) MATCH SIMPLE
+    ON UPDATE NO ACTION
+    ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_embedded_profile_persona_id ON public.embedded_profile(persona_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_embedded_profile_persona_id;
+ALTER TABLE public.embedded_profile DROP CONSTRAINT IF EXISTS embedded_profile_persona_id_fkey;
+ALTER TABLE public.embedded_profile DROP COLUMN IF EXISTS persona_id;
+DROP TABLE IF EXISTS public.persona;
+-- +goose StatementEnd
@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.profile_duplicate
+(
+    id serial NOT NULL,
+    profile_id integer NOT NULL,
+    duplicate_id integer NOT NULL,
+    signals jsonb NOT NULL DEFAULT '[]'::jsonb,
+    similarity double precision,
+    confidence double precision NOT NULL,
+    status character varying(16) NOT NULL DEFAULT 'pending',
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT profile_duplicate_pkey PRIMARY KEY (id),
+    CONSTRAINT uq_profile_duplicate_pair UNIQUE (profile_id, duplicate_id),
+    CONSTRAINT profile_duplicate_order_check CHECK (profile_id < duplicate_id),
+    CONSTRAINT profile_duplicate_status_check CHECK (status IN ('pending', 'dismissed')),
+    CONSTRAINT profile_duplicate_profile_id_fkey FOREIGN KEY (profile_id)
+        REFERENCES public.user_profile (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT profile_duplicate_duplicate_id_fkey FOREIGN KEY (duplicate_id)
+        REFERENCES public.user_profile (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+COMMENT ON COLUMN public.profile_duplicate.signals IS 'Signals pointing to the same person: email, phone, profile_url, embedding';
+
+CREATE INDEX IF NOT EXISTS idx_profile_duplicate_status ON public.profile_duplicate(status, confidence);
+
+CREATE TABLE IF NOT EXISTS public.profile_merge
+(
+    id serial NOT NULL,
+    survivor_id integer,
+    merged_id integer NOT NULL,
+    merged_facebook_id character varying NOT NULL,
+    snapshot jsonb NOT NULL,
+    moved jsonb NOT NULL DEFAULT '{}'::jsonb,
+    signals jsonb NOT NULL DEFAULT '[]'::jsonb,
+    confidence double precision,
+    created_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT profile_merge_pkey PRIMARY KEY (id),
+    CONSTRAINT profile_merge_survivor_id_fkey FOREIGN KEY (survivor_id)
+        REFERENCES public.user_profile (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE SET NULL
+);
+
+COMMENT ON COLUMN public.profile_merge.snapshot IS 'Merged user_profile row and its categories before the merge';
+COMMENT ON COLUMN public.profile_merge.moved IS 'Number of rows re-pointed to the survivor, per table';
+
+CREATE INDEX IF NOT EXISTS idx_profile_merge_merged_facebook_id ON public.profile_merge(merged_facebook_id);
+CREATE INDEX IF NOT EXISTS idx_profile_merge_survivor_id ON public.profile_merge(survivor_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_profile_merge_survivor_id;
+DROP INDEX IF EXISTS public.idx_profile_merge_merged_facebook_id;
+DROP TABLE IF EXISTS public.profile_merge;
+DROP INDEX IF EXISTS public.idx_profile_duplicate_status;
+DROP TABLE IF EXISTS public.profile_duplicate;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.comment_intent
+(
+    comment_id integer NOT NULL,
+    category_id integer NOT NULL,
+    intent character varying(32),
+    intent_confidence double precision,
+    intents jsonb NOT NULL DEFAULT '{}'::jsonb,
+    attempts integer NOT NULL DEFAULT 0,
+    analyzed_at timestamp without time zone,
+    updated_at timestamp without time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT comment_intent_pkey PRIMARY KEY (comment_id, category_id),
+    CONSTRAINT comment_intent_comment_id_fkey FOREIGN KEY (comment_id)
+        REFERENCES public.comment (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE,
+    CONSTRAINT comment_intent_category_id_fkey FOREIGN KEY (category_id)
+        REFERENCES public.category (id) MATCH SIMPLE
+        ON UPDATE NO ACTION
+        ON DELETE CASCADE
+);
+
+COMMENT ON TABLE public.comment_intent IS 'Intent of a comment as classified with the business description of a category';
+COMMENT ON COLUMN public.comment_intent.attempts IS 'Classification attempts, answered or not. The comment is skipped after COMMENT_INTENT_MAX_ATTEMPTS';
+
+CREATE INDEX IF NOT EXISTS idx_comment_intent_category_id ON public.comment_intent(category_id);
+
+-- Comments classified so far keep their result in every category of their group
+INSERT INTO public.comment_intent (comment_id, category_id, intent, intent_confidence, intents, attempts, analyzed_at)
+SELECT c.id, gc.category_id, c.intent, c.intent_confidence, c.intents, 1, c.analyzed_at
+FROM public.comment c
+JOIN public.post p ON p.id = c.post_id
+JOIN public.group_category gc ON gc.group_id = p.group_id
+WHERE c.analyzed_at IS NOT NULL
+ON CONFLICT (comment_id, category_id) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.idx_comment_intent_category_id;
+DROP TABLE IF EXISTS public.comment_intent;
+-- +goose StatementEnd
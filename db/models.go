@@ -34,14 +34,31 @@ type Category struct {
 }
 
 type Comment struct {
-	ID         int32     `json:"id"`
-	Content    string    `json:"content"`
-	IsAnalyzed bool      `json:"is_analyzed"`
-	CreatedAt  time.Time `json:"created_at"`
-	InsertedAt time.Time `json:"inserted_at"`
-	PostID     int32     `json:"post_id"`
-	AuthorID   int32     `json:"author_id"`
-	CommentID  string    `json:"comment_id"`
+	ID               int32           `json:"id"`
+	Content          string          `json:"content"`
+	IsAnalyzed       bool            `json:"is_analyzed"`
+	CreatedAt        time.Time       `json:"created_at"`
+	InsertedAt       time.Time       `json:"inserted_at"`
+	PostID           int32           `json:"post_id"`
+	AuthorID         int32           `json:"author_id"`
+	CommentID        string          `json:"comment_id"`
+	Intent           sql.NullString  `json:"intent"`
+	IntentConfidence sql.NullFloat64 `json:"intent_confidence"`
+	Intents          NullableJSON    `json:"intents"`
+	AnalyzedAt       sql.NullTime    `json:"analyzed_at"`
+}
+
+// Intent of a comment as classified with the business description of a category
+type CommentIntent struct {
+	CommentID        int32           `json:"comment_id"`
+	CategoryID       int32           `json:"category_id"`
+	Intent           sql.NullString  `json:"intent"`
+	IntentConfidence sql.NullFloat64 `json:"intent_confidence"`
+	Intents          NullableJSON    `json:"intents"`
+	// Classification attempts, answered or not. The comment is skipped after COMMENT_INTENT_MAX_ATTEMPTS
+	Attempts   int32        `json:"attempts"`
+	AnalyzedAt sql.NullTime `json:"analyzed_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
 }
 
 type Config struct {
@@ -51,11 +68,12 @@ type Config struct {
 }
 
 type EmbeddedProfile struct {
-	ID        int32        `json:"id"`
-	Pid       int32        `json:"pid"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	Embedding interface{}  `json:"embedding"`
-	Cid       int32        `json:"cid"`
+	ID        int32         `json:"id"`
+	Pid       int32         `json:"pid"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+	Embedding interface{}   `json:"embedding"`
+	Cid       int32         `json:"cid"`
+	PersonaID sql.NullInt32 `json:"persona_id"`
 }
 
 type FinancialAnalysis struct {
@@ -103,6 +121,53 @@ type GroupCategory struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+type LlmBudget struct {
+	ID         int32           `json:"id"`
+	CategoryID sql.NullInt32   `json:"category_id"`
+	Period     string          `json:"period"`
+	MaxTokens  sql.NullInt64   `json:"max_tokens"`
+	MaxCost    sql.NullFloat64 `json:"max_cost"`
+	IsActive   bool            `json:"is_active"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+type LlmCache struct {
+	PromptHash string    `json:"prompt_hash"`
+	Model      string    `json:"model"`
+	Response   string    `json:"response"`
+	HitCount   int32     `json:"hit_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type LlmCacheStat struct {
+	Date   time.Time `json:"date"`
+	Hits   int64     `json:"hits"`
+	Misses int64     `json:"misses"`
+}
+
+type LlmPrice struct {
+	Model       string    `json:"model"`
+	Provider    string    `json:"provider"`
+	InputPrice  float64   `json:"input_price"`
+	OutputPrice float64   `json:"output_price"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type LlmUsage struct {
+	ID           int32         `json:"id"`
+	KeyID        sql.NullInt32 `json:"key_id"`
+	Provider     string        `json:"provider"`
+	Model        string        `json:"model"`
+	CategoryID   sql.NullInt32 `json:"category_id"`
+	Job          string        `json:"job"`
+	PromptTokens int64         `json:"prompt_tokens"`
+	OutputTokens int64         `json:"output_tokens"`
+	Cost         float64       `json:"cost"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
 type Log struct {
 	ID          int32          `json:"id"`
 	AccountID   sql.NullInt32  `json:"account_id"`
@@ -112,6 +177,21 @@ type Log struct {
 	CreatedAt   sql.NullTime   `json:"created_at"`
 }
 
+// Named list of converted customers of a category, the source of lookalike audiences
+type LookalikeSeed struct {
+	ID         int32     `json:"id"`
+	CategoryID int32     `json:"category_id"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type LookalikeSeedProfile struct {
+	SeedID        int32     `json:"seed_id"`
+	UserProfileID int32     `json:"user_profile_id"`
+	AddedAt       time.Time `json:"added_at"`
+}
+
 type Model struct {
 	ID          int32          `json:"id"`
 	Name        string         `json:"name"`
@@ -120,6 +200,71 @@ type Model struct {
 	CategoryID  sql.NullInt32  `json:"category_id"`
 }
 
+type ModelDeployment struct {
+	CategoryID           int32         `json:"category_id"`
+	ChampionVersionID    sql.NullInt32 `json:"champion_version_id"`
+	ChallengerVersionID  sql.NullInt32 `json:"challenger_version_id"`
+	ChallengerSampleRate float64       `json:"challenger_sample_rate"`
+	UpdatedAt            time.Time     `json:"updated_at"`
+}
+
+type ModelDrift struct {
+	CategoryID int32         `json:"category_id"`
+	Day        time.Time     `json:"day"`
+	VersionID  sql.NullInt32 `json:"version_id"`
+	SampleSize int32         `json:"sample_size"`
+	ScoreMean  float64       `json:"score_mean"`
+	ScoreStd   float64       `json:"score_std"`
+	Psi        float64       `json:"psi"`
+	Ks         float64       `json:"ks"`
+	// Share of recently scored profiles with each input feature filled in
+	Completeness NullableJSON `json:"completeness"`
+	// Largest difference with the completeness of the training set
+	CompletenessDrift float64      `json:"completeness_drift"`
+	Alerts            NullableJSON `json:"alerts"`
+	IsAlert           bool         `json:"is_alert"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+type ModelShadowScore struct {
+	VersionID     int32           `json:"version_id"`
+	UserProfileID int32           `json:"user_profile_id"`
+	CategoryID    int32           `json:"category_id"`
+	Score         float64         `json:"score"`
+	ChampionScore sql.NullFloat64 `json:"champion_score"`
+	ScoredAt      time.Time       `json:"scored_at"`
+}
+
+type ModelVersion struct {
+	ID         int32         `json:"id"`
+	ModelID    int32         `json:"model_id"`
+	Version    int32         `json:"version"`
+	CategoryID sql.NullInt32 `json:"category_id"`
+	// Folder of the version, relative to python/resources/models
+	Path    string       `json:"path"`
+	Metrics NullableJSON `json:"metrics"`
+	// Hash of the (profile, gemini score) pairs the version was trained on
+	DatasetSnapshotID sql.NullString `json:"dataset_snapshot_id"`
+	DatasetSize       sql.NullInt32  `json:"dataset_size"`
+	PromptService     sql.NullString `json:"prompt_service"`
+	PromptVersion     sql.NullInt32  `json:"prompt_version"`
+	// Champion of the category when the version was trained
+	ParentVersionID sql.NullInt32 `json:"parent_version_id"`
+	RequestID       sql.NullInt32 `json:"request_id"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+type Persona struct {
+	ID         int32 `json:"id"`
+	CategoryID int32 `json:"category_id"`
+	Cluster    int32 `json:"cluster"`
+	// Normalized mean of the embeddings of the cluster
+	Centroid    interface{}    `json:"centroid"`
+	Label       sql.NullString `json:"label"`
+	Description sql.NullString `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
 type Post struct {
 	ID         int32     `json:"id"`
 	PostID     string    `json:"post_id"`
@@ -130,6 +275,33 @@ type Post struct {
 	IsAnalyzed bool      `json:"is_analyzed"`
 }
 
+type ProfileDuplicate struct {
+	ID          int32 `json:"id"`
+	ProfileID   int32 `json:"profile_id"`
+	DuplicateID int32 `json:"duplicate_id"`
+	// Signals pointing to the same person: email, phone, profile_url, embedding
+	Signals    NullableJSON    `json:"signals"`
+	Similarity sql.NullFloat64 `json:"similarity"`
+	Confidence float64         `json:"confidence"`
+	Status     string          `json:"status"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+type ProfileMerge struct {
+	ID               int32         `json:"id"`
+	SurvivorID       sql.NullInt32 `json:"survivor_id"`
+	MergedID         int32         `json:"merged_id"`
+	MergedFacebookID string        `json:"merged_facebook_id"`
+	// Merged user_profile row and its categories before the merge
+	Snapshot NullableJSON `json:"snapshot"`
+	// Number of rows re-pointed to the survivor, per table
+	Moved      NullableJSON    `json:"moved"`
+	Signals    NullableJSON    `json:"signals"`
+	Confidence sql.NullFloat64 `json:"confidence"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
 type Prompt struct {
 	ID          int32     `json:"id"`
 	Content     string    `json:"content"`
@@ -159,6 +331,71 @@ type Request struct {
 	CreatedAt    sql.NullTime   `json:"created_at"`
 	UpdatedAt    sql.NullTime   `json:"updated_at"`
 	ErrorMessage sql.NullString `json:"error_message"`
+	Kind         string         `json:"kind"`
+	Result       NullableJSON   `json:"result"`
+	StartedAt    sql.NullTime   `json:"started_at"`
+	FinishedAt   sql.NullTime   `json:"finished_at"`
+}
+
+type RequestStatus struct {
+	Code    int16  `json:"code"`
+	Name    string `json:"name"`
+	IsFinal bool   `json:"is_final"`
+}
+
+type RetrainPolicy struct {
+	CategoryID int32  `json:"category_id"`
+	ModelName  string `json:"model_name"`
+	IsEnabled  bool   `json:"is_enabled"`
+	// Retrain every N hours, 0 disables the schedule
+	IntervalHours int32 `json:"interval_hours"`
+	// Retrain once N profiles were labelled or gemini-scored since the last run, 0 disables it
+	MinNewProfiles int32         `json:"min_new_profiles"`
+	RmseMargin     float64       `json:"rmse_margin"`
+	R2Margin       float64       `json:"r2_margin"`
+	AutoTune       bool          `json:"auto_tune"`
+	Trials         sql.NullInt32 `json:"trials"`
+	LastRunAt      sql.NullTime  `json:"last_run_at"`
+	// Labelled or gemini-scored profiles of the category when the last run started
+	ProfilesAtLastRun int32         `json:"profiles_at_last_run"`
+	LastRequestID     sql.NullInt32 `json:"last_request_id"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+}
+
+type ScoringPolicy struct {
+	CategoryID         int32        `json:"category_id"`
+	GeminiWeight       float64      `json:"gemini_weight"`
+	ModelWeight        float64      `json:"model_weight"`
+	CompletenessWeight float64      `json:"completeness_weight"`
+	MinCompleteness    int32        `json:"min_completeness"`
+	DecayHalfLifeDays  float64      `json:"decay_half_life_days"`
+	CalibrationMethod  string       `json:"calibration_method"`
+	Calibration        NullableJSON `json:"calibration"`
+	UpdatedAt          time.Time    `json:"updated_at"`
+	IntentWeight       float64      `json:"intent_weight"`
+}
+
+type TriggerRule struct {
+	ID               int32          `json:"id"`
+	CategoryID       int32          `json:"category_id"`
+	Name             string         `json:"name"`
+	Keywords         []string       `json:"keywords"`
+	Patterns         []string       `json:"patterns"`
+	NegativeKeywords []string       `json:"negative_keywords"`
+	Language         sql.NullString `json:"language"`
+	IsActive         bool           `json:"is_active"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+type TriggerRuleHit struct {
+	ID            int32     `json:"id"`
+	RuleID        int32     `json:"rule_id"`
+	CategoryID    int32     `json:"category_id"`
+	UserProfileID int32     `json:"user_profile_id"`
+	SourceType    string    `json:"source_type"`
+	SourceID      int32     `json:"source_id"`
+	Matched       string    `json:"matched"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type UserProfile struct {
@@ -185,9 +422,17 @@ type UserProfile struct {
 }
 
 type UserProfileCategory struct {
-	UserProfileID int32           `json:"user_profile_id"`
-	CategoryID    int32           `json:"category_id"`
-	CreatedAt     time.Time       `json:"created_at"`
-	ModelScore    sql.NullFloat64 `json:"model_score"`
-	GeminiScore   sql.NullFloat64 `json:"gemini_score"`
+	UserProfileID      int32           `json:"user_profile_id"`
+	CategoryID         int32           `json:"category_id"`
+	CreatedAt          time.Time       `json:"created_at"`
+	ModelScore         sql.NullFloat64 `json:"model_score"`
+	GeminiScore        sql.NullFloat64 `json:"gemini_score"`
+	FinalScore         sql.NullFloat64 `json:"final_score"`
+	FinalScoreAt       sql.NullTime    `json:"final_score_at"`
+	Label              sql.NullInt16   `json:"label"`
+	IntentScore        sql.NullFloat64 `json:"intent_score"`
+	ModelScoredAt      sql.NullTime    `json:"model_scored_at"`
+	ModelVersionID     sql.NullInt32   `json:"model_version_id"`
+	ModelInputHash     sql.NullString  `json:"model_input_hash"`
+	ModelContributions NullableJSON    `json:"model_contributions"`
 }
@@ -32,6 +32,26 @@ func (q *Queries) AddAllProfilesToCategory(ctx context.Context, categoryID int32
 	return result.RowsAffected()
 }
 
+const addCommentIntentAttempts = `-- name: AddCommentIntentAttempts :exec
+INSERT INTO public.comment_intent (comment_id, category_id, attempts)
+SELECT unnest($1::int[]), $2::int, 1
+ON CONFLICT (comment_id, category_id) DO UPDATE SET
+  attempts = comment_intent.attempts + 1,
+  updated_at = NOW()
+`
+
+type AddCommentIntentAttemptsParams struct {
+	CommentIds []int32 `json:"comment_ids"`
+	CategoryID int32   `json:"category_id"`
+}
+
+// Counts an attempt to classify the comments for a category, whether the
+// model answers for them or not.
+func (q *Queries) AddCommentIntentAttempts(ctx context.Context, arg AddCommentIntentAttemptsParams) error {
+	_, err := q.db.ExecContext(ctx, addCommentIntentAttempts, pq.Array(arg.CommentIds), arg.CategoryID)
+	return err
+}
+
 const addGroupCategory = `-- name: AddGroupCategory :exec
 INSERT INTO public.group_category (group_id, category_id)
 VALUES ($1, $2)
@@ -48,6 +68,61 @@ func (q *Queries) AddGroupCategory(ctx context.Context, arg AddGroupCategoryPara
 	return err
 }
 
+const addLlmCacheStat = `-- name: AddLlmCacheStat :exec
+INSERT INTO public.llm_cache_stat (date, hits, misses)
+VALUES (CURRENT_DATE, $1, $2)
+ON CONFLICT (date) DO UPDATE
+SET hits = llm_cache_stat.hits + EXCLUDED.hits,
+  misses = llm_cache_stat.misses + EXCLUDED.misses
+`
+
+type AddLlmCacheStatParams struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+func (q *Queries) AddLlmCacheStat(ctx context.Context, arg AddLlmCacheStatParams) error {
+	_, err := q.db.ExecContext(ctx, addLlmCacheStat, arg.Hits, arg.Misses)
+	return err
+}
+
+const addLookalikeSeedProfiles = `-- name: AddLookalikeSeedProfiles :execrows
+INSERT INTO public.lookalike_seed_profile (seed_id, user_profile_id)
+SELECT $1::int, unnest($2::int[])
+ON CONFLICT (seed_id, user_profile_id) DO NOTHING
+`
+
+type AddLookalikeSeedProfilesParams struct {
+	SeedID     int32   `json:"seed_id"`
+	ProfileIds []int32 `json:"profile_ids"`
+}
+
+func (q *Queries) AddLookalikeSeedProfiles(ctx context.Context, arg AddLookalikeSeedProfilesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addLookalikeSeedProfiles, arg.SeedID, pq.Array(arg.ProfileIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const addProfileToGroupCategories = `-- name: AddProfileToGroupCategories :exec
+INSERT INTO public.user_profile_category (user_profile_id, category_id)
+SELECT $1, gc.category_id
+FROM public.group_category gc
+WHERE gc.group_id = $2
+ON CONFLICT (user_profile_id, category_id) DO NOTHING
+`
+
+type AddProfileToGroupCategoriesParams struct {
+	UserProfileID int32 `json:"user_profile_id"`
+	GroupID       int32 `json:"group_id"`
+}
+
+func (q *Queries) AddProfileToGroupCategories(ctx context.Context, arg AddProfileToGroupCategoriesParams) error {
+	_, err := q.db.ExecContext(ctx, addProfileToGroupCategories, arg.UserProfileID, arg.GroupID)
+	return err
+}
+
 const addUserProfileCategory = `-- name: AddUserProfileCategory :exec
 INSERT INTO public.user_profile_category (user_profile_id, category_id)
 VALUES ($1, $2)
@@ -64,6 +139,68 @@ func (q *Queries) AddUserProfileCategory(ctx context.Context, arg AddUserProfile
 	return err
 }
 
+const assignModelCategory = `-- name: AssignModelCategory :exec
+UPDATE public.model
+SET category_id = CASE WHEN id = $1 THEN $2 ELSE NULL END
+WHERE id = $1 OR category_id = $2
+`
+
+type AssignModelCategoryParams struct {
+	ID         int32         `json:"id"`
+	CategoryID sql.NullInt32 `json:"category_id"`
+}
+
+func (q *Queries) AssignModelCategory(ctx context.Context, arg AssignModelCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, assignModelCategory, arg.ID, arg.CategoryID)
+	return err
+}
+
+const assignPersonas = `-- name: AssignPersonas :execrows
+UPDATE public.embedded_profile ep SET persona_id = (
+  SELECT p.id FROM public.persona p
+  WHERE p.category_id = ep.cid
+  ORDER BY p.centroid <=> ep.embedding
+  LIMIT 1
+)
+WHERE ep.cid = $1 AND ep.embedding IS NOT NULL
+`
+
+// Moves every embedding of a category to its closest persona.
+func (q *Queries) AssignPersonas(ctx context.Context, cid int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, assignPersonas, cid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const backfillProfileCategoriesFromComments = `-- name: BackfillProfileCategoriesFromComments :execrows
+INSERT INTO public.user_profile_category (user_profile_id, category_id, created_at)
+SELECT DISTINCT c.author_id, gc.category_id, NOW()
+FROM public.comment c
+JOIN public.post p ON p.id = c.post_id
+JOIN public.group_category gc ON gc.group_id = p.group_id
+WHERE ($1::int IS NULL OR gc.category_id = $1::int)
+ON CONFLICT (user_profile_id, category_id) DO NOTHING
+`
+
+func (q *Queries) BackfillProfileCategoriesFromComments(ctx context.Context, categoryID sql.NullInt32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, backfillProfileCategoriesFromComments, categoryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const clearLookalikeSeedProfiles = `-- name: ClearLookalikeSeedProfiles :exec
+DELETE FROM public.lookalike_seed_profile WHERE seed_id = $1
+`
+
+func (q *Queries) ClearLookalikeSeedProfiles(ctx context.Context, seedID int32) error {
+	_, err := q.db.ExecContext(ctx, clearLookalikeSeedProfiles, seedID)
+	return err
+}
+
 const countGeminiKeys = `-- name: CountGeminiKeys :one
 SELECT COUNT(*) as total_gemini_keys FROM public.gemini_key
 `
@@ -75,6 +212,37 @@ func (q *Queries) CountGeminiKeys(ctx context.Context) (int64, error) {
 	return total_gemini_keys, err
 }
 
+const countLabeledProfiles = `-- name: CountLabeledProfiles :one
+SELECT COUNT(*) FROM public.user_profile_category
+WHERE category_id = $1 AND (gemini_score IS NOT NULL OR label IS NOT NULL)
+`
+
+func (q *Queries) CountLabeledProfiles(ctx context.Context, categoryID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLabeledProfiles, categoryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countLeads = `-- name: CountLeads :one
+SELECT COUNT(*) FROM public.user_profile_category
+WHERE category_id = $1
+AND final_score IS NOT NULL
+AND final_score >= $2::float8
+`
+
+type CountLeadsParams struct {
+	CategoryID int32   `json:"category_id"`
+	MinScore   float64 `json:"min_score"`
+}
+
+func (q *Queries) CountLeads(ctx context.Context, arg CountLeadsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLeads, arg.CategoryID, arg.MinScore)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countLogs = `-- name: CountLogs :one
 SELECT COUNT(*) as total_logs FROM public.log
 `
@@ -86,6 +254,34 @@ func (q *Queries) CountLogs(ctx context.Context) (int64, error) {
 	return total_logs, err
 }
 
+const countProfileDuplicates = `-- name: CountProfileDuplicates :one
+SELECT COUNT(*) FROM public.profile_duplicate
+WHERE status = $1 AND confidence >= $2::float8
+`
+
+type CountProfileDuplicatesParams struct {
+	Status        string  `json:"status"`
+	MinConfidence float64 `json:"min_confidence"`
+}
+
+func (q *Queries) CountProfileDuplicates(ctx context.Context, arg CountProfileDuplicatesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countProfileDuplicates, arg.Status, arg.MinConfidence)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countProfileMerges = `-- name: CountProfileMerges :one
+SELECT COUNT(*) FROM public.profile_merge
+`
+
+func (q *Queries) CountProfileMerges(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countProfileMerges)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countProfiles = `-- name: CountProfiles :one
 SELECT COUNT(*) as total_profiles FROM public.user_profile WHERE is_scanned = true
 `
@@ -119,6 +315,17 @@ func (q *Queries) CountPrompts(ctx context.Context) (int64, error) {
 	return total_prompt, err
 }
 
+const countTriggerRuleHits = `-- name: CountTriggerRuleHits :one
+SELECT COUNT(*) FROM public.trigger_rule_hit WHERE category_id = $1
+`
+
+func (q *Queries) CountTriggerRuleHits(ctx context.Context, categoryID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTriggerRuleHits, categoryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO public.account (email, username, password, is_block, ua, created_at, updated_at, access_token, proxy_id)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
@@ -195,7 +402,7 @@ INSERT INTO public.comment (post_id, comment_id, content, created_at, author_id,
 VALUES ($1, $2, $3, $4, $5, false, NOW())
 ON CONFLICT (comment_id) DO UPDATE SET
     id = EXCLUDED.id
-RETURNING id, content, is_analyzed, created_at, inserted_at, post_id, author_id, comment_id
+RETURNING id, content, is_analyzed, created_at, inserted_at, post_id, author_id, comment_id, intent, intent_confidence, intents, analyzed_at
 `
 
 type CreateCommentParams struct {
@@ -224,6 +431,10 @@ func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (C
 		&i.PostID,
 		&i.AuthorID,
 		&i.CommentID,
+		&i.Intent,
+		&i.IntentConfidence,
+		&i.Intents,
+		&i.AnalyzedAt,
 	)
 	return i, err
 }
@@ -272,6 +483,49 @@ func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group
 	return i, err
 }
 
+const createLlmUsage = `-- name: CreateLlmUsage :exec
+INSERT INTO public.llm_usage (key_id, provider, model, category_id, job, prompt_tokens, output_tokens, cost, created_at)
+VALUES (
+  (SELECT id FROM public.gemini_key WHERE api_key = $1),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  COALESCE((
+    SELECT ($6 * p.input_price + $7 * p.output_price) / 1000000
+    FROM public.llm_price p
+    WHERE p.model = $3
+  ), 0),
+  NOW()
+)
+`
+
+type CreateLlmUsageParams struct {
+	ApiKey       string        `json:"api_key"`
+	Provider     string        `json:"provider"`
+	Model        string        `json:"model"`
+	CategoryID   sql.NullInt32 `json:"category_id"`
+	Job          string        `json:"job"`
+	PromptTokens int64         `json:"prompt_tokens"`
+	OutputTokens int64         `json:"output_tokens"`
+}
+
+// LLM usage ledger queries
+func (q *Queries) CreateLlmUsage(ctx context.Context, arg CreateLlmUsageParams) error {
+	_, err := q.db.ExecContext(ctx, createLlmUsage,
+		arg.ApiKey,
+		arg.Provider,
+		arg.Model,
+		arg.CategoryID,
+		arg.Job,
+		arg.PromptTokens,
+		arg.OutputTokens,
+	)
+	return err
+}
+
 const createModel = `-- name: CreateModel :one
 INSERT INTO public.model (name, description, category_id, created_at)
 VALUES ($1, $2, $3, NOW())
@@ -297,6 +551,87 @@ func (q *Queries) CreateModel(ctx context.Context, arg CreateModelParams) (Model
 	return i, err
 }
 
+const createModelVersion = `-- name: CreateModelVersion :one
+INSERT INTO public.model_version (model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+ON CONFLICT DO NOTHING
+RETURNING id, model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at
+`
+
+type CreateModelVersionParams struct {
+	ModelID           int32          `json:"model_id"`
+	Version           int32          `json:"version"`
+	CategoryID        sql.NullInt32  `json:"category_id"`
+	Path              string         `json:"path"`
+	Metrics           NullableJSON   `json:"metrics"`
+	DatasetSnapshotID sql.NullString `json:"dataset_snapshot_id"`
+	DatasetSize       sql.NullInt32  `json:"dataset_size"`
+	PromptService     sql.NullString `json:"prompt_service"`
+	PromptVersion     sql.NullInt32  `json:"prompt_version"`
+	ParentVersionID   sql.NullInt32  `json:"parent_version_id"`
+	RequestID         sql.NullInt32  `json:"request_id"`
+}
+
+func (q *Queries) CreateModelVersion(ctx context.Context, arg CreateModelVersionParams) (ModelVersion, error) {
+	row := q.db.QueryRowContext(ctx, createModelVersion,
+		arg.ModelID,
+		arg.Version,
+		arg.CategoryID,
+		arg.Path,
+		arg.Metrics,
+		arg.DatasetSnapshotID,
+		arg.DatasetSize,
+		arg.PromptService,
+		arg.PromptVersion,
+		arg.ParentVersionID,
+		arg.RequestID,
+	)
+	var i ModelVersion
+	err := row.Scan(
+		&i.ID,
+		&i.ModelID,
+		&i.Version,
+		&i.CategoryID,
+		&i.Path,
+		&i.Metrics,
+		&i.DatasetSnapshotID,
+		&i.DatasetSize,
+		&i.PromptService,
+		&i.PromptVersion,
+		&i.ParentVersionID,
+		&i.RequestID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPersona = `-- name: CreatePersona :one
+INSERT INTO public.persona (category_id, cluster, centroid)
+VALUES ($1, $2, $3)
+RETURNING id, category_id, cluster, centroid, label, description, created_at
+`
+
+type CreatePersonaParams struct {
+	CategoryID int32       `json:"category_id"`
+	Cluster    int32       `json:"cluster"`
+	Centroid   interface{} `json:"centroid"`
+}
+
+func (q *Queries) CreatePersona(ctx context.Context, arg CreatePersonaParams) (Persona, error) {
+	row := q.db.QueryRowContext(ctx, createPersona, arg.CategoryID, arg.Cluster, arg.Centroid)
+	var i Persona
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.Cluster,
+		&i.Centroid,
+		&i.Label,
+		&i.Description,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO public.post (post_id, content, created_at, inserted_at, group_id, is_analyzed)
 VALUES ($1, $2, $3, NOW(), $4, true)
@@ -374,6 +709,47 @@ func (q *Queries) CreateProfile(ctx context.Context, arg CreateProfileParams) (U
 	return i, err
 }
 
+const createProfileMerge = `-- name: CreateProfileMerge :one
+INSERT INTO public.profile_merge (survivor_id, merged_id, merged_facebook_id, snapshot, moved, signals, confidence)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, survivor_id, merged_id, merged_facebook_id, snapshot, moved, signals, confidence, created_at
+`
+
+type CreateProfileMergeParams struct {
+	SurvivorID       sql.NullInt32   `json:"survivor_id"`
+	MergedID         int32           `json:"merged_id"`
+	MergedFacebookID string          `json:"merged_facebook_id"`
+	Snapshot         NullableJSON    `json:"snapshot"`
+	Moved            NullableJSON    `json:"moved"`
+	Signals          NullableJSON    `json:"signals"`
+	Confidence       sql.NullFloat64 `json:"confidence"`
+}
+
+func (q *Queries) CreateProfileMerge(ctx context.Context, arg CreateProfileMergeParams) (ProfileMerge, error) {
+	row := q.db.QueryRowContext(ctx, createProfileMerge,
+		arg.SurvivorID,
+		arg.MergedID,
+		arg.MergedFacebookID,
+		arg.Snapshot,
+		arg.Moved,
+		arg.Signals,
+		arg.Confidence,
+	)
+	var i ProfileMerge
+	err := row.Scan(
+		&i.ID,
+		&i.SurvivorID,
+		&i.MergedID,
+		&i.MergedFacebookID,
+		&i.Snapshot,
+		&i.Moved,
+		&i.Signals,
+		&i.Confidence,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createPrompt = `-- name: CreatePrompt :one
 WITH next_version AS (
   SELECT COALESCE(MAX(version), 0) + 1 AS version
@@ -414,18 +790,94 @@ func (q *Queries) CreatePrompt(ctx context.Context, arg CreatePromptParams) (Pro
 }
 
 const createRequest = `-- name: CreateRequest :one
-INSERT INTO public.request(description)
-VALUES ($1)
+INSERT INTO public.request(kind, description)
+VALUES ($1, $2)
 RETURNING id
 `
 
-func (q *Queries) CreateRequest(ctx context.Context, description sql.NullString) (int32, error) {
-	row := q.db.QueryRowContext(ctx, createRequest, description)
+type CreateRequestParams struct {
+	Kind        string         `json:"kind"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) CreateRequest(ctx context.Context, arg CreateRequestParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, createRequest, arg.Kind, arg.Description)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
+const createTriggerRule = `-- name: CreateTriggerRule :one
+INSERT INTO public.trigger_rule (category_id, name, keywords, patterns, negative_keywords, language, is_active, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+RETURNING id, category_id, name, keywords, patterns, negative_keywords, language, is_active, created_at
+`
+
+type CreateTriggerRuleParams struct {
+	CategoryID       int32          `json:"category_id"`
+	Name             string         `json:"name"`
+	Keywords         []string       `json:"keywords"`
+	Patterns         []string       `json:"patterns"`
+	NegativeKeywords []string       `json:"negative_keywords"`
+	Language         sql.NullString `json:"language"`
+	IsActive         bool           `json:"is_active"`
+}
+
+func (q *Queries) CreateTriggerRule(ctx context.Context, arg CreateTriggerRuleParams) (TriggerRule, error) {
+	row := q.db.QueryRowContext(ctx, createTriggerRule,
+		arg.CategoryID,
+		arg.Name,
+		pq.Array(arg.Keywords),
+		pq.Array(arg.Patterns),
+		pq.Array(arg.NegativeKeywords),
+		arg.Language,
+		arg.IsActive,
+	)
+	var i TriggerRule
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.Name,
+		pq.Array(&i.Keywords),
+		pq.Array(&i.Patterns),
+		pq.Array(&i.NegativeKeywords),
+		&i.Language,
+		&i.IsActive,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createTriggerRuleHit = `-- name: CreateTriggerRuleHit :execrows
+INSERT INTO public.trigger_rule_hit (rule_id, category_id, user_profile_id, source_type, source_id, matched, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (rule_id, user_profile_id, source_type, source_id) DO NOTHING
+`
+
+type CreateTriggerRuleHitParams struct {
+	RuleID        int32  `json:"rule_id"`
+	CategoryID    int32  `json:"category_id"`
+	UserProfileID int32  `json:"user_profile_id"`
+	SourceType    string `json:"source_type"`
+	SourceID      int32  `json:"source_id"`
+	Matched       string `json:"matched"`
+}
+
+func (q *Queries) CreateTriggerRuleHit(ctx context.Context, arg CreateTriggerRuleHitParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createTriggerRuleHit,
+		arg.RuleID,
+		arg.CategoryID,
+		arg.UserProfileID,
+		arg.SourceType,
+		arg.SourceID,
+		arg.Matched,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteAccounts = `-- name: DeleteAccounts :exec
 DELETE FROM public.account WHERE id = ANY($1::int[])
 `
@@ -444,6 +896,47 @@ func (q *Queries) DeleteCategory(ctx context.Context, id int32) error {
 	return err
 }
 
+const deleteCategoryPersonas = `-- name: DeleteCategoryPersonas :execrows
+DELETE FROM public.persona WHERE category_id = $1
+`
+
+func (q *Queries) DeleteCategoryPersonas(ctx context.Context, categoryID int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCategoryPersonas, categoryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFinishedRequests = `-- name: DeleteFinishedRequests :many
+DELETE FROM public.request
+WHERE finished_at < $1::timestamp
+RETURNING id
+`
+
+func (q *Queries) DeleteFinishedRequests(ctx context.Context, finishedBefore time.Time) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, deleteFinishedRequests, finishedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteGeminiKey = `-- name: DeleteGeminiKey :exec
 DELETE FROM public.gemini_key WHERE id = $1
 `
@@ -532,6 +1025,49 @@ func (q *Queries) DeleteJunkProfiles(ctx context.Context) (int64, error) {
 	return deleted_count, err
 }
 
+const deleteLlmBudget = `-- name: DeleteLlmBudget :execrows
+DELETE FROM public.llm_budget WHERE id = $1
+`
+
+func (q *Queries) DeleteLlmBudget(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLlmBudget, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteLlmCache = `-- name: DeleteLlmCache :execrows
+DELETE FROM public.llm_cache
+WHERE ($1::varchar IS NULL OR model = $1::varchar)
+  AND (NOT $2::boolean OR expires_at <= NOW())
+`
+
+type DeleteLlmCacheParams struct {
+	Model       sql.NullString `json:"model"`
+	ExpiredOnly bool           `json:"expired_only"`
+}
+
+func (q *Queries) DeleteLlmCache(ctx context.Context, arg DeleteLlmCacheParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLlmCache, arg.Model, arg.ExpiredOnly)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteLookalikeSeed = `-- name: DeleteLookalikeSeed :execrows
+DELETE FROM public.lookalike_seed WHERE id = $1
+`
+
+func (q *Queries) DeleteLookalikeSeed(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLookalikeSeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteModel = `-- name: DeleteModel :exec
 DELETE FROM public.model WHERE id = $1
 `
@@ -541,6 +1077,27 @@ func (q *Queries) DeleteModel(ctx context.Context, id int32) error {
 	return err
 }
 
+const deleteModelVersion = `-- name: DeleteModelVersion :exec
+DELETE FROM public.model_version WHERE id = $1
+`
+
+func (q *Queries) DeleteModelVersion(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteModelVersion, id)
+	return err
+}
+
+const deleteProfileEmbeddings = `-- name: DeleteProfileEmbeddings :execrows
+DELETE FROM public.embedded_profile WHERE pid = $1
+`
+
+func (q *Queries) DeleteProfileEmbeddings(ctx context.Context, pid int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteProfileEmbeddings, pid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deletePrompt = `-- name: DeletePrompt :exec
 WITH prompt_to_delete AS (
   SELECT id, content, service_name, version, created_by, created_at, category_id FROM public.prompt d WHERE d.id = $1
@@ -553,8 +1110,323 @@ func (q *Queries) DeletePrompt(ctx context.Context, id int32) error {
 	return err
 }
 
-const findSimilarProfiles = `-- name: FindSimilarProfiles :many
-SELECT
+const deleteRetrainPolicy = `-- name: DeleteRetrainPolicy :exec
+DELETE FROM public.retrain_policy WHERE category_id = $1
+`
+
+func (q *Queries) DeleteRetrainPolicy(ctx context.Context, categoryID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteRetrainPolicy, categoryID)
+	return err
+}
+
+const deleteTriggerRule = `-- name: DeleteTriggerRule :exec
+DELETE FROM public.trigger_rule WHERE id = $1
+`
+
+func (q *Queries) DeleteTriggerRule(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteTriggerRule, id)
+	return err
+}
+
+const deleteUserProfile = `-- name: DeleteUserProfile :execrows
+DELETE FROM public.user_profile WHERE id = $1
+`
+
+func (q *Queries) DeleteUserProfile(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserProfile, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const fillMergedProfileFields = `-- name: FillMergedProfileFields :exec
+UPDATE public.user_profile s SET
+  name = COALESCE(s.name, m.name),
+  bio = COALESCE(s.bio, m.bio),
+  location = COALESCE(s.location, m.location),
+  work = COALESCE(s.work, m.work),
+  education = COALESCE(s.education, m.education),
+  relationship_status = COALESCE(s.relationship_status, m.relationship_status),
+  hometown = COALESCE(s.hometown, m.hometown),
+  gender = COALESCE(s.gender, m.gender),
+  birthday = COALESCE(s.birthday, m.birthday),
+  email = COALESCE(s.email, m.email),
+  phone = COALESCE(s.phone, m.phone),
+  locale = CASE WHEN s.locale = 'NOT_SPECIFIED' THEN m.locale ELSE s.locale END,
+  profile_url = CASE WHEN s.profile_url = 'NOT_SPECIFIED' THEN m.profile_url ELSE s.profile_url END,
+  is_scanned = s.is_scanned OR m.is_scanned,
+  updated_at = NOW()
+FROM public.user_profile m
+WHERE s.id = $1 AND m.id = $2
+`
+
+type FillMergedProfileFieldsParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+// Fills the fields the survivor lacks from the merged profile.
+func (q *Queries) FillMergedProfileFields(ctx context.Context, arg FillMergedProfileFieldsParams) error {
+	_, err := q.db.ExecContext(ctx, fillMergedProfileFields, arg.SurvivorID, arg.MergedID)
+	return err
+}
+
+const fillProfileCategoryScores = `-- name: FillProfileCategoryScores :execrows
+UPDATE public.user_profile_category s SET
+  model_score = CASE WHEN s.model_score IS NULL THEN m.model_score ELSE s.model_score END,
+  model_scored_at = CASE WHEN s.model_score IS NULL THEN m.model_scored_at ELSE s.model_scored_at END,
+  model_version_id = CASE WHEN s.model_score IS NULL THEN m.model_version_id ELSE s.model_version_id END,
+  model_input_hash = CASE WHEN s.model_score IS NULL THEN m.model_input_hash ELSE s.model_input_hash END,
+  model_contributions = CASE WHEN s.model_score IS NULL THEN m.model_contributions ELSE s.model_contributions END,
+  gemini_score = COALESCE(s.gemini_score, m.gemini_score),
+  final_score = CASE WHEN s.final_score IS NULL THEN m.final_score ELSE s.final_score END,
+  final_score_at = CASE WHEN s.final_score IS NULL THEN m.final_score_at ELSE s.final_score_at END,
+  intent_score = COALESCE(s.intent_score, m.intent_score),
+  label = COALESCE(s.label, m.label)
+FROM public.user_profile_category m
+WHERE s.user_profile_id = $1
+  AND m.user_profile_id = $2
+  AND m.category_id = s.category_id
+`
+
+type FillProfileCategoryScoresParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+// Fills the scores the survivor lacks in the categories both profiles are in.
+// Model fields are taken together so they stay consistent with each other.
+func (q *Queries) FillProfileCategoryScores(ctx context.Context, arg FillProfileCategoryScoresParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, fillProfileCategoryScores, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const findEmbeddingDuplicates = `-- name: FindEmbeddingDuplicates :many
+SELECT
+  ep.id AS embedding_id,
+  ep.pid AS profile_id,
+  p.name AS profile_name,
+  n.pid AS duplicate_id,
+  dp.name AS duplicate_name,
+  n.similarity
+FROM (
+  SELECT id, pid, embedding FROM public.embedded_profile
+  WHERE cid = $1 AND id > $2 AND embedding IS NOT NULL
+  ORDER BY id
+  LIMIT $3
+) ep
+JOIN public.user_profile p ON p.id = ep.pid
+LEFT JOIN LATERAL (
+  SELECT o.pid, CAST(1 - (o.embedding <=> ep.embedding) AS DOUBLE PRECISION) AS similarity
+  FROM public.embedded_profile o
+  WHERE o.cid = $1 AND o.pid <> ep.pid
+  ORDER BY o.embedding <=> ep.embedding
+  LIMIT $4::int
+) n ON n.similarity >= $5::float8
+LEFT JOIN public.user_profile dp ON dp.id = n.pid
+ORDER BY ep.id
+`
+
+type FindEmbeddingDuplicatesParams struct {
+	CategoryID    int32   `json:"category_id"`
+	AfterID       int32   `json:"after_id"`
+	PageLimit     int32   `json:"page_limit"`
+	Neighbors     int32   `json:"neighbors"`
+	MinSimilarity float64 `json:"min_similarity"`
+}
+
+type FindEmbeddingDuplicatesRow struct {
+	EmbeddingID   int32           `json:"embedding_id"`
+	ProfileID     int32           `json:"profile_id"`
+	ProfileName   sql.NullString  `json:"profile_name"`
+	DuplicateID   sql.NullInt32   `json:"duplicate_id"`
+	DuplicateName sql.NullString  `json:"duplicate_name"`
+	Similarity    sql.NullFloat64 `json:"similarity"`
+}
+
+// Nearest neighbours of a page of the embeddings of a category. Embeddings
+// without a neighbour above min_similarity come back once with a NULL
+// duplicate so the caller can keep paging by embedding_id.
+func (q *Queries) FindEmbeddingDuplicates(ctx context.Context, arg FindEmbeddingDuplicatesParams) ([]FindEmbeddingDuplicatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, findEmbeddingDuplicates,
+		arg.CategoryID,
+		arg.AfterID,
+		arg.PageLimit,
+		arg.Neighbors,
+		arg.MinSimilarity,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindEmbeddingDuplicatesRow
+	for rows.Next() {
+		var i FindEmbeddingDuplicatesRow
+		if err := rows.Scan(
+			&i.EmbeddingID,
+			&i.ProfileID,
+			&i.ProfileName,
+			&i.DuplicateID,
+			&i.DuplicateName,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findExactDuplicateProfiles = `-- name: FindExactDuplicateProfiles :many
+WITH profile_keys AS (
+  SELECT id, 'email' AS signal, lower(trim(email)) AS value
+  FROM public.user_profile
+  WHERE email IS NOT NULL AND trim(email) <> ''
+  UNION ALL
+  SELECT id, 'phone' AS signal, right(regexp_replace(phone, '\D', '', 'g'), 9) AS value
+  FROM public.user_profile
+  WHERE length(regexp_replace(COALESCE(phone, ''), '\D', '', 'g')) >= 9
+  UNION ALL
+  SELECT id, 'profile_url' AS signal, rtrim(regexp_replace(lower(profile_url), '^https?://(www\.|m\.)?', ''), '/') AS value
+  FROM public.user_profile
+  WHERE profile_url <> 'NOT_SPECIFIED' AND profile_url <> ''
+), shared AS (
+  SELECT signal, value FROM profile_keys
+  GROUP BY signal, value
+  HAVING COUNT(*) BETWEEN 2 AND $1::int
+)
+SELECT
+  a.id AS profile_id,
+  b.id AS duplicate_id,
+  CAST(array_agg(DISTINCT a.signal) AS TEXT[]) AS signals
+FROM shared s
+JOIN profile_keys a ON a.signal = s.signal AND a.value = s.value
+JOIN profile_keys b ON b.signal = s.signal AND b.value = s.value AND b.id > a.id
+GROUP BY a.id, b.id
+`
+
+type FindExactDuplicateProfilesRow struct {
+	ProfileID   int32    `json:"profile_id"`
+	DuplicateID int32    `json:"duplicate_id"`
+	Signals     []string `json:"signals"`
+}
+
+// Pairs of profiles sharing an email, a phone number or a profile url. Values
+// shared by more than max_group_size profiles are ignored as placeholders.
+func (q *Queries) FindExactDuplicateProfiles(ctx context.Context, maxGroupSize int32) ([]FindExactDuplicateProfilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, findExactDuplicateProfiles, maxGroupSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindExactDuplicateProfilesRow
+	for rows.Next() {
+		var i FindExactDuplicateProfilesRow
+		if err := rows.Scan(&i.ProfileID, &i.DuplicateID, pq.Array(&i.Signals)); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findLookalikeProfiles = `-- name: FindLookalikeProfiles :many
+SELECT
+  p.id AS profile_id,
+  p.facebook_id,
+  p.profile_url,
+  p.name AS profile_name,
+  p.location,
+  upc.model_score,
+  upc.final_score,
+  CAST(1 - (ep.embedding <=> $1::vector) AS DOUBLE PRECISION) AS similarity
+FROM public.embedded_profile ep
+JOIN public.user_profile p ON p.id = ep.pid
+LEFT JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = ep.cid
+WHERE ep.cid = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM public.lookalike_seed_profile sp
+    WHERE sp.seed_id = $3 AND sp.user_profile_id = ep.pid
+  )
+ORDER BY ep.embedding <=> $1::vector
+LIMIT $4
+`
+
+type FindLookalikeProfilesParams struct {
+	Query      interface{} `json:"query"`
+	CategoryID int32       `json:"category_id"`
+	SeedID     int32       `json:"seed_id"`
+	PageLimit  int32       `json:"page_limit"`
+}
+
+type FindLookalikeProfilesRow struct {
+	ProfileID   int32           `json:"profile_id"`
+	FacebookID  string          `json:"facebook_id"`
+	ProfileUrl  string          `json:"profile_url"`
+	ProfileName sql.NullString  `json:"profile_name"`
+	Location    sql.NullString  `json:"location"`
+	ModelScore  sql.NullFloat64 `json:"model_score"`
+	FinalScore  sql.NullFloat64 `json:"final_score"`
+	Similarity  float64         `json:"similarity"`
+}
+
+// Nearest profiles of a category to a query embedding, leaving out the
+// profiles of the seed.
+func (q *Queries) FindLookalikeProfiles(ctx context.Context, arg FindLookalikeProfilesParams) ([]FindLookalikeProfilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, findLookalikeProfiles,
+		arg.Query,
+		arg.CategoryID,
+		arg.SeedID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindLookalikeProfilesRow
+	for rows.Next() {
+		var i FindLookalikeProfilesRow
+		if err := rows.Scan(
+			&i.ProfileID,
+			&i.FacebookID,
+			&i.ProfileUrl,
+			&i.ProfileName,
+			&i.Location,
+			&i.ModelScore,
+			&i.FinalScore,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findSimilarProfiles = `-- name: FindSimilarProfiles :many
+SELECT
   p.id AS profile_id,
   p.profile_url as profile_url,
   p.name AS profile_name,
@@ -710,6 +1582,46 @@ func (q *Queries) GetAccounts(ctx context.Context, arg GetAccountsParams) ([]Get
 	return items, nil
 }
 
+const getActiveTriggerRules = `-- name: GetActiveTriggerRules :many
+SELECT id, category_id, name, keywords, patterns, negative_keywords, language, is_active, created_at FROM public.trigger_rule
+WHERE is_active = true
+ORDER BY category_id, id
+`
+
+// Trigger rule queries
+func (q *Queries) GetActiveTriggerRules(ctx context.Context) ([]TriggerRule, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveTriggerRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TriggerRule
+	for rows.Next() {
+		var i TriggerRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CategoryID,
+			&i.Name,
+			pq.Array(&i.Keywords),
+			pq.Array(&i.Patterns),
+			pq.Array(&i.NegativeKeywords),
+			&i.Language,
+			&i.IsActive,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllConfigs = `-- name: GetAllConfigs :many
 SELECT id, key, value FROM public.config
 `
@@ -737,6 +1649,47 @@ func (q *Queries) GetAllConfigs(ctx context.Context) ([]Config, error) {
 	return items, nil
 }
 
+const getAllModelVersions = `-- name: GetAllModelVersions :many
+SELECT id, model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at FROM public.model_version ORDER BY model_id, version
+`
+
+func (q *Queries) GetAllModelVersions(ctx context.Context) ([]ModelVersion, error) {
+	rows, err := q.db.QueryContext(ctx, getAllModelVersions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModelVersion
+	for rows.Next() {
+		var i ModelVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModelID,
+			&i.Version,
+			&i.CategoryID,
+			&i.Path,
+			&i.Metrics,
+			&i.DatasetSnapshotID,
+			&i.DatasetSize,
+			&i.PromptService,
+			&i.PromptVersion,
+			&i.ParentVersionID,
+			&i.RequestID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllPrompts = `-- name: GetAllPrompts :many
 SELECT id, content, service_name, version, created_by, created_at, category_id, rn
 FROM (
@@ -796,6 +1749,69 @@ func (q *Queries) GetAllPrompts(ctx context.Context, arg GetAllPromptsParams) ([
 	return items, nil
 }
 
+const getCalibrationSamples = `-- name: GetCalibrationSamples :many
+SELECT upc.gemini_score,
+  upc.model_score,
+  upc.intent_score,
+  up.updated_at,
+  ((COALESCE(up.bio, '') != '')::int +
+  (COALESCE(up.location, '') != '')::int +
+  (COALESCE(up.work, '') != '')::int +
+  (COALESCE(up.locale, '') != '')::int +
+  (COALESCE(up.education, '') != '')::int +
+  (COALESCE(up.relationship_status, '') != '')::int +
+  (COALESCE(up.hometown, '') != '')::int +
+  (COALESCE(up.gender, '') != '')::int +
+  (COALESCE(up.birthday, '') != '')::int +
+  (COALESCE(up.email, '') != '')::int +
+  (COALESCE(up.phone, '') != '')::int)::int AS non_null_count,
+  upc.label
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+WHERE upc.category_id = $1
+AND upc.label IS NOT NULL
+AND (upc.gemini_score IS NOT NULL OR upc.model_score IS NOT NULL OR upc.intent_score IS NOT NULL)
+`
+
+type GetCalibrationSamplesRow struct {
+	GeminiScore  sql.NullFloat64 `json:"gemini_score"`
+	ModelScore   sql.NullFloat64 `json:"model_score"`
+	IntentScore  sql.NullFloat64 `json:"intent_score"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	NonNullCount int32           `json:"non_null_count"`
+	Label        sql.NullInt16   `json:"label"`
+}
+
+func (q *Queries) GetCalibrationSamples(ctx context.Context, categoryID int32) ([]GetCalibrationSamplesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCalibrationSamples, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCalibrationSamplesRow
+	for rows.Next() {
+		var i GetCalibrationSamplesRow
+		if err := rows.Scan(
+			&i.GeminiScore,
+			&i.ModelScore,
+			&i.IntentScore,
+			&i.UpdatedAt,
+			&i.NonNullCount,
+			&i.Label,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCategories = `-- name: GetCategories :many
 SELECT id, name, description, created_at, updated_at FROM public.category ORDER BY name
 `
@@ -846,6 +1862,65 @@ func (q *Queries) GetCategoryByID(ctx context.Context, id int32) (Category, erro
 	return i, err
 }
 
+const getCommentsForIntentAnalysis = `-- name: GetCommentsForIntentAnalysis :many
+SELECT c.id, c.content, c.author_id, c.post_id, p.content AS post_content
+FROM public.comment c
+JOIN public.post p ON p.id = c.post_id
+JOIN public.group_category gc ON gc.group_id = p.group_id
+LEFT JOIN public.comment_intent ci ON ci.comment_id = c.id AND ci.category_id = gc.category_id
+WHERE c.is_analyzed = false
+  AND gc.category_id = $1
+  AND ci.analyzed_at IS NULL
+  AND COALESCE(ci.attempts, 0) < $2::int
+ORDER BY c.inserted_at ASC
+LIMIT $3
+`
+
+type GetCommentsForIntentAnalysisParams struct {
+	CategoryID  int32 `json:"category_id"`
+	MaxAttempts int32 `json:"max_attempts"`
+	PageLimit   int32 `json:"page_limit"`
+}
+
+type GetCommentsForIntentAnalysisRow struct {
+	ID          int32  `json:"id"`
+	Content     string `json:"content"`
+	AuthorID    int32  `json:"author_id"`
+	PostID      int32  `json:"post_id"`
+	PostContent string `json:"post_content"`
+}
+
+// Comments of the category's groups not classified for the category yet,
+// leaving out the ones that failed max_attempts times.
+func (q *Queries) GetCommentsForIntentAnalysis(ctx context.Context, arg GetCommentsForIntentAnalysisParams) ([]GetCommentsForIntentAnalysisRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentsForIntentAnalysis, arg.CategoryID, arg.MaxAttempts, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentsForIntentAnalysisRow
+	for rows.Next() {
+		var i GetCommentsForIntentAnalysisRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Content,
+			&i.AuthorID,
+			&i.PostID,
+			&i.PostContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getConfigByKey = `-- name: GetConfigByKey :one
 SELECT id, key, value FROM public.config WHERE "key" = $1
 `
@@ -857,11 +1932,59 @@ func (q *Queries) GetConfigByKey(ctx context.Context, key string) (Config, error
 	return i, err
 }
 
-const getDashboardStats = `-- name: GetDashboardStats :one
+const getDailySpend = `-- name: GetDailySpend :many
 SELECT
-  (SELECT COUNT(*) FROM public."group") AS total_groups,
-  (SELECT COUNT(*) FROM public.comment) AS total_comments,
-  (SELECT COUNT(*) FROM public.post) AS total_posts,
+  DATE_TRUNC('day', created_at)::date AS date,
+  SUM(prompt_tokens)::bigint AS prompt_tokens,
+  SUM(output_tokens)::bigint AS output_tokens,
+  SUM(cost)::float AS cost
+FROM public.llm_usage
+WHERE created_at >= NOW() - INTERVAL '6 months'
+  AND category_id = $1
+GROUP BY DATE_TRUNC('day', created_at)
+ORDER BY date
+`
+
+type GetDailySpendRow struct {
+	Date         time.Time `json:"date"`
+	PromptTokens int64     `json:"prompt_tokens"`
+	OutputTokens int64     `json:"output_tokens"`
+	Cost         float64   `json:"cost"`
+}
+
+func (q *Queries) GetDailySpend(ctx context.Context, categoryID sql.NullInt32) ([]GetDailySpendRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDailySpend, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDailySpendRow
+	for rows.Next() {
+		var i GetDailySpendRow
+		if err := rows.Scan(
+			&i.Date,
+			&i.PromptTokens,
+			&i.OutputTokens,
+			&i.Cost,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDashboardStats = `-- name: GetDashboardStats :one
+SELECT
+  (SELECT COUNT(*) FROM public."group") AS total_groups,
+  (SELECT COUNT(*) FROM public.comment) AS total_comments,
+  (SELECT COUNT(*) FROM public.post) AS total_posts,
   (SELECT COUNT(*) FROM public.user_profile) AS total_profiles,
   (SELECT COUNT(*) FROM public.embedded_profile WHERE cid = $1) AS embedded_count,
   (SELECT COUNT(*) FROM public.user_profile WHERE is_scanned = true) AS scanned_profiles,
@@ -869,7 +1992,10 @@ SELECT
   (SELECT COUNT(*) FROM public.user_profile WHERE is_analyzed = true) AS analyzed_profiles,
   (SELECT COUNT(*) FROM public.account) AS total_accounts,
   (SELECT COUNT(*) FROM public.account WHERE is_block = false and access_token IS NOT NULL) AS active_accounts,
-  (SELECT COUNT(*) FROM public.account WHERE is_block = true) AS blocked_accounts
+  (SELECT COUNT(*) FROM public.account WHERE is_block = true) AS blocked_accounts,
+  (SELECT COUNT(*) FROM public.llm_cache WHERE expires_at > NOW()) AS llm_cache_entries,
+  (SELECT COALESCE(SUM(hits), 0) FROM public.llm_cache_stat)::bigint AS llm_cache_hits,
+  (SELECT COALESCE(SUM(misses), 0) FROM public.llm_cache_stat)::bigint AS llm_cache_misses
 `
 
 type GetDashboardStatsRow struct {
@@ -884,6 +2010,9 @@ type GetDashboardStatsRow struct {
 	TotalAccounts    int64 `json:"total_accounts"`
 	ActiveAccounts   int64 `json:"active_accounts"`
 	BlockedAccounts  int64 `json:"blocked_accounts"`
+	LlmCacheEntries  int64 `json:"llm_cache_entries"`
+	LlmCacheHits     int64 `json:"llm_cache_hits"`
+	LlmCacheMisses   int64 `json:"llm_cache_misses"`
 }
 
 func (q *Queries) GetDashboardStats(ctx context.Context, cid int32) (GetDashboardStatsRow, error) {
@@ -901,10 +2030,64 @@ func (q *Queries) GetDashboardStats(ctx context.Context, cid int32) (GetDashboar
 		&i.TotalAccounts,
 		&i.ActiveAccounts,
 		&i.BlockedAccounts,
+		&i.LlmCacheEntries,
+		&i.LlmCacheHits,
+		&i.LlmCacheMisses,
 	)
 	return i, err
 }
 
+const getEmbeddingIndexes = `-- name: GetEmbeddingIndexes :many
+SELECT
+  i.indexname::text AS name,
+  i.indexdef::text AS definition,
+  pg_relation_size(c.oid)::bigint AS size_bytes,
+  x.indisvalid AS is_valid
+FROM pg_indexes i
+JOIN pg_class c ON c.relname = i.indexname AND c.relnamespace = 'public'::regnamespace
+JOIN pg_index x ON x.indexrelid = c.oid
+WHERE i.schemaname = 'public' AND i.tablename = 'embedded_profile'
+  AND i.indexdef LIKE '%vector_%_ops%'
+ORDER BY i.indexname
+`
+
+type GetEmbeddingIndexesRow struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+	SizeBytes  int64  `json:"size_bytes"`
+	IsValid    bool   `json:"is_valid"`
+}
+
+// Vector indexes of embedded_profile, invalid ones are left by failed
+// concurrent builds.
+func (q *Queries) GetEmbeddingIndexes(ctx context.Context) ([]GetEmbeddingIndexesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEmbeddingIndexes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEmbeddingIndexesRow
+	for rows.Next() {
+		var i GetEmbeddingIndexesRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Definition,
+			&i.SizeBytes,
+			&i.IsValid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getGeminiKeyForUse = `-- name: GetGeminiKeyForUse :one
 SELECT id, api_key, token_used, updated_at FROM public.gemini_key ORDER BY updated_at ASC NULLS FIRST LIMIT 1
 `
@@ -1096,45 +2279,96 @@ func (q *Queries) GetGroupsToScan(ctx context.Context, arg GetGroupsToScanParams
 	return items, nil
 }
 
-const getLogs = `-- name: GetLogs :many
-SELECT l.id, l.account_id, l.action, l.target_id, l.description, l.created_at, a.username FROM public.log l
-LEFT JOIN public.account a ON l.account_id = a.id
-ORDER BY l.created_at DESC
-LIMIT $1 OFFSET $2
+const getLatestModelVersion = `-- name: GetLatestModelVersion :one
+SELECT id, model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at FROM public.model_version WHERE model_id = $1 ORDER BY version DESC LIMIT 1
 `
 
-type GetLogsParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+func (q *Queries) GetLatestModelVersion(ctx context.Context, modelID int32) (ModelVersion, error) {
+	row := q.db.QueryRowContext(ctx, getLatestModelVersion, modelID)
+	var i ModelVersion
+	err := row.Scan(
+		&i.ID,
+		&i.ModelID,
+		&i.Version,
+		&i.CategoryID,
+		&i.Path,
+		&i.Metrics,
+		&i.DatasetSnapshotID,
+		&i.DatasetSize,
+		&i.PromptService,
+		&i.PromptVersion,
+		&i.ParentVersionID,
+		&i.RequestID,
+		&i.CreatedAt,
+	)
+	return i, err
 }
 
-type GetLogsRow struct {
-	ID          int32          `json:"id"`
-	AccountID   sql.NullInt32  `json:"account_id"`
-	Action      string         `json:"action"`
-	TargetID    sql.NullInt32  `json:"target_id"`
-	Description sql.NullString `json:"description"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
-	Username    sql.NullString `json:"username"`
+const getLeadScoreInputs = `-- name: GetLeadScoreInputs :many
+SELECT upc.user_profile_id,
+  upc.category_id,
+  upc.gemini_score,
+  upc.model_score,
+  upc.intent_score,
+  up.updated_at,
+  ((COALESCE(up.bio, '') != '')::int +
+  (COALESCE(up.location, '') != '')::int +
+  (COALESCE(up.work, '') != '')::int +
+  (COALESCE(up.locale, '') != '')::int +
+  (COALESCE(up.education, '') != '')::int +
+  (COALESCE(up.relationship_status, '') != '')::int +
+  (COALESCE(up.hometown, '') != '')::int +
+  (COALESCE(up.gender, '') != '')::int +
+  (COALESCE(up.birthday, '') != '')::int +
+  (COALESCE(up.email, '') != '')::int +
+  (COALESCE(up.phone, '') != '')::int)::int AS non_null_count
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+LEFT JOIN public.scoring_policy sp ON sp.category_id = upc.category_id
+WHERE upc.category_id = $1
+AND (upc.gemini_score IS NOT NULL OR upc.model_score IS NOT NULL OR upc.intent_score IS NOT NULL)
+AND (
+  upc.final_score_at IS NULL
+  OR upc.final_score_at < up.updated_at
+  OR upc.final_score_at < sp.updated_at
+  OR upc.final_score_at < NOW() - INTERVAL '1 day'
+)
+ORDER BY upc.final_score_at ASC NULLS FIRST
+LIMIT $2
+`
+
+type GetLeadScoreInputsParams struct {
+	CategoryID int32 `json:"category_id"`
+	Limit      int32 `json:"limit"`
 }
 
-func (q *Queries) GetLogs(ctx context.Context, arg GetLogsParams) ([]GetLogsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getLogs, arg.Limit, arg.Offset)
+type GetLeadScoreInputsRow struct {
+	UserProfileID int32           `json:"user_profile_id"`
+	CategoryID    int32           `json:"category_id"`
+	GeminiScore   sql.NullFloat64 `json:"gemini_score"`
+	ModelScore    sql.NullFloat64 `json:"model_score"`
+	IntentScore   sql.NullFloat64 `json:"intent_score"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	NonNullCount  int32           `json:"non_null_count"`
+}
+
+func (q *Queries) GetLeadScoreInputs(ctx context.Context, arg GetLeadScoreInputsParams) ([]GetLeadScoreInputsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLeadScoreInputs, arg.CategoryID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetLogsRow
+	var items []GetLeadScoreInputsRow
 	for rows.Next() {
-		var i GetLogsRow
+		var i GetLeadScoreInputsRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.AccountID,
-			&i.Action,
-			&i.TargetID,
-			&i.Description,
-			&i.CreatedAt,
-			&i.Username,
+			&i.UserProfileID,
+			&i.CategoryID,
+			&i.GeminiScore,
+			&i.ModelScore,
+			&i.IntentScore,
+			&i.UpdatedAt,
+			&i.NonNullCount,
 		); err != nil {
 			return nil, err
 		}
@@ -1149,61 +2383,82 @@ func (q *Queries) GetLogs(ctx context.Context, arg GetLogsParams) ([]GetLogsRow,
 	return items, nil
 }
 
-const getModelByCategory = `-- name: GetModelByCategory :one
-SELECT id, name, description, created_at, category_id FROM public.model WHERE category_id = $1
+const getLeads = `-- name: GetLeads :many
+SELECT up.id,
+  up.facebook_id,
+  up.name,
+  up.profile_url,
+  up.email,
+  up.phone,
+  up.location,
+  upc.gemini_score,
+  upc.model_score,
+  upc.final_score,
+  upc.final_score_at,
+  upc.label
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+WHERE upc.category_id = $1
+AND upc.final_score IS NOT NULL
+AND upc.final_score >= $2::float8
+ORDER BY
+  CASE WHEN $3::bool THEN upc.final_score END ASC,
+  CASE WHEN NOT $3::bool THEN upc.final_score END DESC,
+  up.id ASC
+LIMIT $4 OFFSET $5
 `
 
-func (q *Queries) GetModelByCategory(ctx context.Context, categoryID sql.NullInt32) (Model, error) {
-	row := q.db.QueryRowContext(ctx, getModelByCategory, categoryID)
-	var i Model
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.CreatedAt,
-		&i.CategoryID,
-	)
-	return i, err
+type GetLeadsParams struct {
+	CategoryID int32   `json:"category_id"`
+	MinScore   float64 `json:"min_score"`
+	Ascending  bool    `json:"ascending"`
+	PageLimit  int32   `json:"page_limit"`
+	PageOffset int32   `json:"page_offset"`
 }
 
-const getModelByID = `-- name: GetModelByID :one
-SELECT id, name, description, created_at, category_id FROM public.model WHERE id = $1
-`
-
-func (q *Queries) GetModelByID(ctx context.Context, id int32) (Model, error) {
-	row := q.db.QueryRowContext(ctx, getModelByID, id)
-	var i Model
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Description,
-		&i.CreatedAt,
-		&i.CategoryID,
-	)
-	return i, err
+type GetLeadsRow struct {
+	ID           int32           `json:"id"`
+	FacebookID   string          `json:"facebook_id"`
+	Name         sql.NullString  `json:"name"`
+	ProfileUrl   string          `json:"profile_url"`
+	Email        sql.NullString  `json:"email"`
+	Phone        sql.NullString  `json:"phone"`
+	Location     sql.NullString  `json:"location"`
+	GeminiScore  sql.NullFloat64 `json:"gemini_score"`
+	ModelScore   sql.NullFloat64 `json:"model_score"`
+	FinalScore   sql.NullFloat64 `json:"final_score"`
+	FinalScoreAt sql.NullTime    `json:"final_score_at"`
+	Label        sql.NullInt16   `json:"label"`
 }
 
-const getModels = `-- name: GetModels :many
-SELECT id, name, description, created_at, category_id FROM public.model
-ORDER BY created_at DESC
-`
-
-// Model Management queries
-func (q *Queries) GetModels(ctx context.Context) ([]Model, error) {
-	rows, err := q.db.QueryContext(ctx, getModels)
+func (q *Queries) GetLeads(ctx context.Context, arg GetLeadsParams) ([]GetLeadsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLeads,
+		arg.CategoryID,
+		arg.MinScore,
+		arg.Ascending,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Model
+	var items []GetLeadsRow
 	for rows.Next() {
-		var i Model
+		var i GetLeadsRow
 		if err := rows.Scan(
 			&i.ID,
+			&i.FacebookID,
 			&i.Name,
-			&i.Description,
-			&i.CreatedAt,
-			&i.CategoryID,
+			&i.ProfileUrl,
+			&i.Email,
+			&i.Phone,
+			&i.Location,
+			&i.GeminiScore,
+			&i.ModelScore,
+			&i.FinalScore,
+			&i.FinalScoreAt,
+			&i.Label,
 		); err != nil {
 			return nil, err
 		}
@@ -1218,25 +2473,53 @@ func (q *Queries) GetModels(ctx context.Context) ([]Model, error) {
 	return items, nil
 }
 
-const getModelsWithoutCategory = `-- name: GetModelsWithoutCategory :many
-SELECT id, name, description, created_at, category_id FROM public.model WHERE category_id IS NULL ORDER BY created_at DESC
+const getLlmBudgetUsage = `-- name: GetLlmBudgetUsage :many
+SELECT b.id,
+  b.category_id,
+  b.period,
+  b.max_tokens,
+  b.max_cost,
+  b.is_active,
+  COALESCE(SUM(u.prompt_tokens + u.output_tokens), 0)::bigint AS used_tokens,
+  COALESCE(SUM(u.cost), 0)::float AS used_cost
+FROM public.llm_budget b
+LEFT JOIN public.llm_usage u
+  ON (b.category_id IS NULL OR u.category_id = b.category_id)
+  AND u.created_at >= DATE_TRUNC(CASE WHEN b.period = 'monthly' THEN 'month' ELSE 'day' END, NOW()::timestamp)
+GROUP BY b.id
+ORDER BY b.id
 `
 
-func (q *Queries) GetModelsWithoutCategory(ctx context.Context) ([]Model, error) {
-	rows, err := q.db.QueryContext(ctx, getModelsWithoutCategory)
+type GetLlmBudgetUsageRow struct {
+	ID         int32           `json:"id"`
+	CategoryID sql.NullInt32   `json:"category_id"`
+	Period     string          `json:"period"`
+	MaxTokens  sql.NullInt64   `json:"max_tokens"`
+	MaxCost    sql.NullFloat64 `json:"max_cost"`
+	IsActive   bool            `json:"is_active"`
+	UsedTokens int64           `json:"used_tokens"`
+	UsedCost   float64         `json:"used_cost"`
+}
+
+// LLM budget queries
+func (q *Queries) GetLlmBudgetUsage(ctx context.Context) ([]GetLlmBudgetUsageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLlmBudgetUsage)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Model
+	var items []GetLlmBudgetUsageRow
 	for rows.Next() {
-		var i Model
+		var i GetLlmBudgetUsageRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
-			&i.Description,
-			&i.CreatedAt,
 			&i.CategoryID,
+			&i.Period,
+			&i.MaxTokens,
+			&i.MaxCost,
+			&i.IsActive,
+			&i.UsedTokens,
+			&i.UsedCost,
 		); err != nil {
 			return nil, err
 		}
@@ -1251,29 +2534,44 @@ func (q *Queries) GetModelsWithoutCategory(ctx context.Context) ([]Model, error)
 	return items, nil
 }
 
-const getOKAccountIds = `-- name: GetOKAccountIds :many
-SELECT t.id
-FROM (SELECT a.id,
-  (SELECT COUNT(*) FROM public."group" g WHERE g.account_id = a.id) AS group_count
-  FROM public.account a
-  WHERE a.is_block = false AND a.access_token IS NOT NULL
-) t
-WHERE t.group_count > 0
+const getLlmCacheResponse = `-- name: GetLlmCacheResponse :one
+UPDATE public.llm_cache
+SET hit_count = hit_count + 1
+WHERE prompt_hash = $1 AND expires_at > NOW()
+RETURNING response
 `
 
-func (q *Queries) GetOKAccountIds(ctx context.Context) ([]int32, error) {
-	rows, err := q.db.QueryContext(ctx, getOKAccountIds)
+// LLM response cache queries
+func (q *Queries) GetLlmCacheResponse(ctx context.Context, promptHash string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getLlmCacheResponse, promptHash)
+	var response string
+	err := row.Scan(&response)
+	return response, err
+}
+
+const getLlmPrices = `-- name: GetLlmPrices :many
+SELECT model, provider, input_price, output_price, updated_at FROM public.llm_price ORDER BY model
+`
+
+func (q *Queries) GetLlmPrices(ctx context.Context) ([]LlmPrice, error) {
+	rows, err := q.db.QueryContext(ctx, getLlmPrices)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []int32
+	var items []LlmPrice
 	for rows.Next() {
-		var id int32
-		if err := rows.Scan(&id); err != nil {
+		var i LlmPrice
+		if err := rows.Scan(
+			&i.Model,
+			&i.Provider,
+			&i.InputPrice,
+			&i.OutputPrice,
+			&i.UpdatedAt,
+		); err != nil {
 			return nil, err
 		}
-		items = append(items, id)
+		items = append(items, i)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -1284,56 +2582,957 @@ func (q *Queries) GetOKAccountIds(ctx context.Context) ([]int32, error) {
 	return items, nil
 }
 
-const getProfileById = `-- name: GetProfileById :one
-SELECT id, facebook_id, name, bio, location, work, education, relationship_status, created_at, updated_at, scraped_by_id, is_scanned, hometown, locale, gender, birthday, email, phone, profile_url, is_analyzed, COALESCE((SELECT json_agg(c) FROM public.user_profile_category c WHERE c.user_profile_id = up.id), '[]'::json)::jsonb as categories
-FROM public.user_profile up WHERE id = $1
+const getLlmUsageSummary = `-- name: GetLlmUsageSummary :many
+SELECT u.key_id,
+  u.provider,
+  u.model,
+  u.category_id,
+  c.name AS category_name,
+  u.job,
+  SUM(u.prompt_tokens)::bigint AS prompt_tokens,
+  SUM(u.output_tokens)::bigint AS output_tokens,
+  SUM(u.cost)::float AS cost
+FROM public.llm_usage u
+LEFT JOIN public.category c ON c.id = u.category_id
+WHERE u.created_at >= NOW() - make_interval(days => $1::int)
+GROUP BY u.key_id, u.provider, u.model, u.category_id, c.name, u.job
+ORDER BY cost DESC
 `
 
-type GetProfileByIdRow struct {
-	ID                 int32          `json:"id"`
-	FacebookID         string         `json:"facebook_id"`
-	Name               sql.NullString `json:"name"`
-	Bio                sql.NullString `json:"bio"`
-	Location           sql.NullString `json:"location"`
-	Work               sql.NullString `json:"work"`
-	Education          sql.NullString `json:"education"`
-	RelationshipStatus sql.NullString `json:"relationship_status"`
-	CreatedAt          time.Time      `json:"created_at"`
-	UpdatedAt          time.Time      `json:"updated_at"`
-	ScrapedByID        int32          `json:"scraped_by_id"`
-	IsScanned          bool           `json:"is_scanned"`
-	Hometown           sql.NullString `json:"hometown"`
-	Locale             string         `json:"locale"`
-	Gender             sql.NullString `json:"gender"`
-	Birthday           sql.NullString `json:"birthday"`
-	Email              sql.NullString `json:"email"`
-	Phone              sql.NullString `json:"phone"`
-	ProfileUrl         string         `json:"profile_url"`
-	IsAnalyzed         sql.NullBool   `json:"is_analyzed"`
-	Categories         NullableJSON   `json:"categories"`
+type GetLlmUsageSummaryRow struct {
+	KeyID        sql.NullInt32  `json:"key_id"`
+	Provider     string         `json:"provider"`
+	Model        string         `json:"model"`
+	CategoryID   sql.NullInt32  `json:"category_id"`
+	CategoryName sql.NullString `json:"category_name"`
+	Job          string         `json:"job"`
+	PromptTokens int64          `json:"prompt_tokens"`
+	OutputTokens int64          `json:"output_tokens"`
+	Cost         float64        `json:"cost"`
 }
 
-func (q *Queries) GetProfileById(ctx context.Context, id int32) (GetProfileByIdRow, error) {
-	row := q.db.QueryRowContext(ctx, getProfileById, id)
-	var i GetProfileByIdRow
-	err := row.Scan(
-		&i.ID,
-		&i.FacebookID,
-		&i.Name,
-		&i.Bio,
-		&i.Location,
-		&i.Work,
-		&i.Education,
-		&i.RelationshipStatus,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.ScrapedByID,
-		&i.IsScanned,
-		&i.Hometown,
-		&i.Locale,
-		&i.Gender,
-		&i.Birthday,
-		&i.Email,
+func (q *Queries) GetLlmUsageSummary(ctx context.Context, days int32) ([]GetLlmUsageSummaryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLlmUsageSummary, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLlmUsageSummaryRow
+	for rows.Next() {
+		var i GetLlmUsageSummaryRow
+		if err := rows.Scan(
+			&i.KeyID,
+			&i.Provider,
+			&i.Model,
+			&i.CategoryID,
+			&i.CategoryName,
+			&i.Job,
+			&i.PromptTokens,
+			&i.OutputTokens,
+			&i.Cost,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLogs = `-- name: GetLogs :many
+SELECT l.id, l.account_id, l.action, l.target_id, l.description, l.created_at, a.username FROM public.log l
+LEFT JOIN public.account a ON l.account_id = a.id
+ORDER BY l.created_at DESC
+LIMIT $1 OFFSET $2
+`
+
+type GetLogsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+type GetLogsRow struct {
+	ID          int32          `json:"id"`
+	AccountID   sql.NullInt32  `json:"account_id"`
+	Action      string         `json:"action"`
+	TargetID    sql.NullInt32  `json:"target_id"`
+	Description sql.NullString `json:"description"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+	Username    sql.NullString `json:"username"`
+}
+
+func (q *Queries) GetLogs(ctx context.Context, arg GetLogsParams) ([]GetLogsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLogs, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLogsRow
+	for rows.Next() {
+		var i GetLogsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Action,
+			&i.TargetID,
+			&i.Description,
+			&i.CreatedAt,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLookalikeSeed = `-- name: GetLookalikeSeed :one
+SELECT id, category_id, name, created_at, updated_at FROM public.lookalike_seed WHERE id = $1
+`
+
+func (q *Queries) GetLookalikeSeed(ctx context.Context, id int32) (LookalikeSeed, error) {
+	row := q.db.QueryRowContext(ctx, getLookalikeSeed, id)
+	var i LookalikeSeed
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getLookalikeSeedEmbeddings = `-- name: GetLookalikeSeedEmbeddings :many
+SELECT sp.user_profile_id, ep.embedding
+FROM public.lookalike_seed_profile sp
+JOIN public.lookalike_seed s ON s.id = sp.seed_id
+JOIN public.embedded_profile ep ON ep.pid = sp.user_profile_id AND ep.cid = s.category_id
+WHERE sp.seed_id = $1
+ORDER BY sp.user_profile_id
+`
+
+type GetLookalikeSeedEmbeddingsRow struct {
+	UserProfileID int32       `json:"user_profile_id"`
+	Embedding     interface{} `json:"embedding"`
+}
+
+func (q *Queries) GetLookalikeSeedEmbeddings(ctx context.Context, seedID int32) ([]GetLookalikeSeedEmbeddingsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLookalikeSeedEmbeddings, seedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLookalikeSeedEmbeddingsRow
+	for rows.Next() {
+		var i GetLookalikeSeedEmbeddingsRow
+		if err := rows.Scan(&i.UserProfileID, &i.Embedding); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLookalikeSeeds = `-- name: GetLookalikeSeeds :many
+SELECT
+  s.id,
+  s.category_id,
+  s.name,
+  s.created_at,
+  s.updated_at,
+  COUNT(sp.user_profile_id) AS profile_count,
+  COUNT(ep.id) AS embedded_count
+FROM public.lookalike_seed s
+LEFT JOIN public.lookalike_seed_profile sp ON sp.seed_id = s.id
+LEFT JOIN public.embedded_profile ep ON ep.pid = sp.user_profile_id AND ep.cid = s.category_id
+WHERE s.category_id = $1
+GROUP BY s.id
+ORDER BY s.name
+`
+
+type GetLookalikeSeedsRow struct {
+	ID            int32     `json:"id"`
+	CategoryID    int32     `json:"category_id"`
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ProfileCount  int64     `json:"profile_count"`
+	EmbeddedCount int64     `json:"embedded_count"`
+}
+
+// Seeds of a category with their size, embedded_count is the number of seed
+// profiles with an embedding in the category.
+func (q *Queries) GetLookalikeSeeds(ctx context.Context, categoryID int32) ([]GetLookalikeSeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLookalikeSeeds, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLookalikeSeedsRow
+	for rows.Next() {
+		var i GetLookalikeSeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CategoryID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProfileCount,
+			&i.EmbeddedCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMergedProfileSurvivor = `-- name: GetMergedProfileSurvivor :one
+SELECT survivor_id FROM public.profile_merge
+WHERE merged_facebook_id = $1 AND survivor_id IS NOT NULL
+ORDER BY id DESC
+LIMIT 1
+`
+
+// Surviving profile of a facebook id that was merged away.
+func (q *Queries) GetMergedProfileSurvivor(ctx context.Context, mergedFacebookID string) (sql.NullInt32, error) {
+	row := q.db.QueryRowContext(ctx, getMergedProfileSurvivor, mergedFacebookID)
+	var survivor_id sql.NullInt32
+	err := row.Scan(&survivor_id)
+	return survivor_id, err
+}
+
+const getMergedProfileSurvivors = `-- name: GetMergedProfileSurvivors :many
+SELECT DISTINCT ON (merged_facebook_id) merged_facebook_id, survivor_id
+FROM public.profile_merge
+WHERE merged_facebook_id = ANY($1::text[])
+ORDER BY merged_facebook_id, id DESC
+`
+
+type GetMergedProfileSurvivorsRow struct {
+	MergedFacebookID string        `json:"merged_facebook_id"`
+	SurvivorID       sql.NullInt32 `json:"survivor_id"`
+}
+
+// Surviving profiles of facebook ids that were merged away.
+func (q *Queries) GetMergedProfileSurvivors(ctx context.Context, facebookIds []string) ([]GetMergedProfileSurvivorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getMergedProfileSurvivors, pq.Array(facebookIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMergedProfileSurvivorsRow
+	for rows.Next() {
+		var i GetMergedProfileSurvivorsRow
+		if err := rows.Scan(&i.MergedFacebookID, &i.SurvivorID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModelByCategory = `-- name: GetModelByCategory :one
+SELECT id, name, description, created_at, category_id FROM public.model WHERE category_id = $1
+`
+
+func (q *Queries) GetModelByCategory(ctx context.Context, categoryID sql.NullInt32) (Model, error) {
+	row := q.db.QueryRowContext(ctx, getModelByCategory, categoryID)
+	var i Model
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.CategoryID,
+	)
+	return i, err
+}
+
+const getModelByID = `-- name: GetModelByID :one
+SELECT id, name, description, created_at, category_id FROM public.model WHERE id = $1
+`
+
+func (q *Queries) GetModelByID(ctx context.Context, id int32) (Model, error) {
+	row := q.db.QueryRowContext(ctx, getModelByID, id)
+	var i Model
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.CategoryID,
+	)
+	return i, err
+}
+
+const getModelByName = `-- name: GetModelByName :one
+SELECT id, name, description, created_at, category_id FROM public.model WHERE name = $1 ORDER BY id LIMIT 1
+`
+
+func (q *Queries) GetModelByName(ctx context.Context, name string) (Model, error) {
+	row := q.db.QueryRowContext(ctx, getModelByName, name)
+	var i Model
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.CategoryID,
+	)
+	return i, err
+}
+
+const getModelDeployment = `-- name: GetModelDeployment :one
+SELECT category_id, champion_version_id, challenger_version_id, challenger_sample_rate, updated_at FROM public.model_deployment WHERE category_id = $1
+`
+
+func (q *Queries) GetModelDeployment(ctx context.Context, categoryID int32) (ModelDeployment, error) {
+	row := q.db.QueryRowContext(ctx, getModelDeployment, categoryID)
+	var i ModelDeployment
+	err := row.Scan(
+		&i.CategoryID,
+		&i.ChampionVersionID,
+		&i.ChallengerVersionID,
+		&i.ChallengerSampleRate,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getModelDrift = `-- name: GetModelDrift :many
+SELECT category_id, day, version_id, sample_size, score_mean, score_std, psi, ks, completeness, completeness_drift, alerts, is_alert, created_at FROM public.model_drift
+WHERE category_id = $1
+ORDER BY day DESC
+LIMIT $2
+`
+
+type GetModelDriftParams struct {
+	CategoryID int32 `json:"category_id"`
+	Limit      int32 `json:"limit"`
+}
+
+func (q *Queries) GetModelDrift(ctx context.Context, arg GetModelDriftParams) ([]ModelDrift, error) {
+	rows, err := q.db.QueryContext(ctx, getModelDrift, arg.CategoryID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModelDrift
+	for rows.Next() {
+		var i ModelDrift
+		if err := rows.Scan(
+			&i.CategoryID,
+			&i.Day,
+			&i.VersionID,
+			&i.SampleSize,
+			&i.ScoreMean,
+			&i.ScoreStd,
+			&i.Psi,
+			&i.Ks,
+			&i.Completeness,
+			&i.CompletenessDrift,
+			&i.Alerts,
+			&i.IsAlert,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModelDriftAlerts = `-- name: GetModelDriftAlerts :many
+SELECT category_id, day, version_id, sample_size, score_mean, score_std, psi, ks, completeness, completeness_drift, alerts, is_alert, created_at FROM public.model_drift
+WHERE is_alert AND day >= $1::date
+ORDER BY day DESC, category_id
+`
+
+func (q *Queries) GetModelDriftAlerts(ctx context.Context, since time.Time) ([]ModelDrift, error) {
+	rows, err := q.db.QueryContext(ctx, getModelDriftAlerts, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModelDrift
+	for rows.Next() {
+		var i ModelDrift
+		if err := rows.Scan(
+			&i.CategoryID,
+			&i.Day,
+			&i.VersionID,
+			&i.SampleSize,
+			&i.ScoreMean,
+			&i.ScoreStd,
+			&i.Psi,
+			&i.Ks,
+			&i.Completeness,
+			&i.CompletenessDrift,
+			&i.Alerts,
+			&i.IsAlert,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModelVersionByID = `-- name: GetModelVersionByID :one
+SELECT id, model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at FROM public.model_version WHERE id = $1
+`
+
+func (q *Queries) GetModelVersionByID(ctx context.Context, id int32) (ModelVersion, error) {
+	row := q.db.QueryRowContext(ctx, getModelVersionByID, id)
+	var i ModelVersion
+	err := row.Scan(
+		&i.ID,
+		&i.ModelID,
+		&i.Version,
+		&i.CategoryID,
+		&i.Path,
+		&i.Metrics,
+		&i.DatasetSnapshotID,
+		&i.DatasetSize,
+		&i.PromptService,
+		&i.PromptVersion,
+		&i.ParentVersionID,
+		&i.RequestID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getModelVersionByPath = `-- name: GetModelVersionByPath :one
+SELECT id, model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at FROM public.model_version WHERE path = $1
+`
+
+func (q *Queries) GetModelVersionByPath(ctx context.Context, path string) (ModelVersion, error) {
+	row := q.db.QueryRowContext(ctx, getModelVersionByPath, path)
+	var i ModelVersion
+	err := row.Scan(
+		&i.ID,
+		&i.ModelID,
+		&i.Version,
+		&i.CategoryID,
+		&i.Path,
+		&i.Metrics,
+		&i.DatasetSnapshotID,
+		&i.DatasetSize,
+		&i.PromptService,
+		&i.PromptVersion,
+		&i.ParentVersionID,
+		&i.RequestID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getModelVersionLineage = `-- name: GetModelVersionLineage :many
+WITH RECURSIVE lineage AS (
+    SELECT mv.id, mv.parent_version_id, 0 AS depth
+    FROM public.model_version mv WHERE mv.id = $1
+    UNION ALL
+    SELECT p.id, p.parent_version_id, l.depth + 1
+    FROM public.model_version p
+    JOIN lineage l ON p.id = l.parent_version_id
+    WHERE l.depth < 100
+)
+SELECT mv.id, mv.model_id, m.name AS model_name, mv.version, mv.category_id, mv.path, mv.metrics,
+    mv.dataset_snapshot_id, mv.dataset_size, mv.prompt_service, mv.prompt_version,
+    mv.parent_version_id, mv.created_at, l.depth::integer AS depth
+FROM lineage l
+JOIN public.model_version mv ON mv.id = l.id
+JOIN public.model m ON m.id = mv.model_id
+ORDER BY l.depth
+`
+
+type GetModelVersionLineageRow struct {
+	ID                int32          `json:"id"`
+	ModelID           int32          `json:"model_id"`
+	ModelName         string         `json:"model_name"`
+	Version           int32          `json:"version"`
+	CategoryID        sql.NullInt32  `json:"category_id"`
+	Path              string         `json:"path"`
+	Metrics           NullableJSON   `json:"metrics"`
+	DatasetSnapshotID sql.NullString `json:"dataset_snapshot_id"`
+	DatasetSize       sql.NullInt32  `json:"dataset_size"`
+	PromptService     sql.NullString `json:"prompt_service"`
+	PromptVersion     sql.NullInt32  `json:"prompt_version"`
+	ParentVersionID   sql.NullInt32  `json:"parent_version_id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	Depth             int32          `json:"depth"`
+}
+
+func (q *Queries) GetModelVersionLineage(ctx context.Context, id int32) ([]GetModelVersionLineageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getModelVersionLineage, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetModelVersionLineageRow
+	for rows.Next() {
+		var i GetModelVersionLineageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModelID,
+			&i.ModelName,
+			&i.Version,
+			&i.CategoryID,
+			&i.Path,
+			&i.Metrics,
+			&i.DatasetSnapshotID,
+			&i.DatasetSize,
+			&i.PromptService,
+			&i.PromptVersion,
+			&i.ParentVersionID,
+			&i.CreatedAt,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModelVersions = `-- name: GetModelVersions :many
+SELECT id, model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at FROM public.model_version WHERE model_id = $1 ORDER BY version DESC
+`
+
+func (q *Queries) GetModelVersions(ctx context.Context, modelID int32) ([]ModelVersion, error) {
+	rows, err := q.db.QueryContext(ctx, getModelVersions, modelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModelVersion
+	for rows.Next() {
+		var i ModelVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModelID,
+			&i.Version,
+			&i.CategoryID,
+			&i.Path,
+			&i.Metrics,
+			&i.DatasetSnapshotID,
+			&i.DatasetSize,
+			&i.PromptService,
+			&i.PromptVersion,
+			&i.ParentVersionID,
+			&i.RequestID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModels = `-- name: GetModels :many
+SELECT id, name, description, created_at, category_id FROM public.model
+ORDER BY created_at DESC
+`
+
+// Model Management queries
+func (q *Queries) GetModels(ctx context.Context) ([]Model, error) {
+	rows, err := q.db.QueryContext(ctx, getModels)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Model
+	for rows.Next() {
+		var i Model
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+			&i.CategoryID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModelsWithoutCategory = `-- name: GetModelsWithoutCategory :many
+SELECT id, name, description, created_at, category_id FROM public.model WHERE category_id IS NULL ORDER BY created_at DESC
+`
+
+func (q *Queries) GetModelsWithoutCategory(ctx context.Context) ([]Model, error) {
+	rows, err := q.db.QueryContext(ctx, getModelsWithoutCategory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Model
+	for rows.Next() {
+		var i Model
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+			&i.CategoryID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextModelVersion = `-- name: GetNextModelVersion :one
+SELECT (COALESCE(MAX(version), 0) + 1)::integer AS version
+FROM public.model_version WHERE model_id = $1
+`
+
+// Model registry queries
+func (q *Queries) GetNextModelVersion(ctx context.Context, modelID int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getNextModelVersion, modelID)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const getOKAccountIds = `-- name: GetOKAccountIds :many
+SELECT t.id
+FROM (SELECT a.id,
+  (SELECT COUNT(*) FROM public."group" g WHERE g.account_id = a.id) AS group_count
+  FROM public.account a
+  WHERE a.is_block = false AND a.access_token IS NOT NULL
+) t
+WHERE t.group_count > 0
+`
+
+func (q *Queries) GetOKAccountIds(ctx context.Context) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, getOKAccountIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPersonaMembers = `-- name: GetPersonaMembers :many
+SELECT
+  up.id AS profile_id,
+  up.profile_url,
+  up.name AS profile_name,
+  up.location,
+  up.work,
+  up.education,
+  up.bio,
+  up.gender,
+  up.relationship_status,
+  upc.final_score,
+  CAST(1 - (ep.embedding <=> p.centroid) AS DOUBLE PRECISION) AS similarity
+FROM public.embedded_profile ep
+JOIN public.persona p ON p.id = ep.persona_id
+JOIN public.user_profile up ON up.id = ep.pid
+LEFT JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = p.category_id
+WHERE ep.persona_id = $1
+ORDER BY ep.embedding <=> p.centroid
+LIMIT $2
+`
+
+type GetPersonaMembersParams struct {
+	PersonaID int32 `json:"persona_id"`
+	PageLimit int32 `json:"page_limit"`
+}
+
+type GetPersonaMembersRow struct {
+	ProfileID          int32           `json:"profile_id"`
+	ProfileUrl         string          `json:"profile_url"`
+	ProfileName        sql.NullString  `json:"profile_name"`
+	Location           sql.NullString  `json:"location"`
+	Work               sql.NullString  `json:"work"`
+	Education          sql.NullString  `json:"education"`
+	Bio                sql.NullString  `json:"bio"`
+	Gender             sql.NullString  `json:"gender"`
+	RelationshipStatus sql.NullString  `json:"relationship_status"`
+	FinalScore         sql.NullFloat64 `json:"final_score"`
+	Similarity         float64         `json:"similarity"`
+}
+
+// Members of a persona closest to its centroid.
+func (q *Queries) GetPersonaMembers(ctx context.Context, arg GetPersonaMembersParams) ([]GetPersonaMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPersonaMembers, arg.PersonaID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPersonaMembersRow
+	for rows.Next() {
+		var i GetPersonaMembersRow
+		if err := rows.Scan(
+			&i.ProfileID,
+			&i.ProfileUrl,
+			&i.ProfileName,
+			&i.Location,
+			&i.Work,
+			&i.Education,
+			&i.Bio,
+			&i.Gender,
+			&i.RelationshipStatus,
+			&i.FinalScore,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPersonaTrainingEmbeddings = `-- name: GetPersonaTrainingEmbeddings :many
+SELECT pid, embedding FROM public.embedded_profile
+WHERE cid = $1 AND embedding IS NOT NULL
+ORDER BY random()
+LIMIT $2
+`
+
+type GetPersonaTrainingEmbeddingsParams struct {
+	CategoryID int32 `json:"category_id"`
+	PageLimit  int32 `json:"page_limit"`
+}
+
+type GetPersonaTrainingEmbeddingsRow struct {
+	Pid       int32       `json:"pid"`
+	Embedding interface{} `json:"embedding"`
+}
+
+// Random sample of the embeddings of a category to fit the personas on.
+func (q *Queries) GetPersonaTrainingEmbeddings(ctx context.Context, arg GetPersonaTrainingEmbeddingsParams) ([]GetPersonaTrainingEmbeddingsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPersonaTrainingEmbeddings, arg.CategoryID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPersonaTrainingEmbeddingsRow
+	for rows.Next() {
+		var i GetPersonaTrainingEmbeddingsRow
+		if err := rows.Scan(&i.Pid, &i.Embedding); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPersonas = `-- name: GetPersonas :many
+SELECT
+  p.id,
+  p.category_id,
+  p.cluster,
+  p.label,
+  p.description,
+  p.created_at,
+  COUNT(ep.id) AS size,
+  AVG(upc.final_score) AS avg_final_score,
+  AVG(upc.model_score) AS avg_model_score
+FROM public.persona p
+LEFT JOIN public.embedded_profile ep ON ep.persona_id = p.id
+LEFT JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = p.category_id
+WHERE p.category_id = $1
+GROUP BY p.id
+ORDER BY size DESC, p.cluster
+`
+
+type GetPersonasRow struct {
+	ID            int32           `json:"id"`
+	CategoryID    int32           `json:"category_id"`
+	Cluster       int32           `json:"cluster"`
+	Label         sql.NullString  `json:"label"`
+	Description   sql.NullString  `json:"description"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Size          int64           `json:"size"`
+	AvgFinalScore sql.NullFloat64 `json:"avg_final_score"`
+	AvgModelScore sql.NullFloat64 `json:"avg_model_score"`
+}
+
+func (q *Queries) GetPersonas(ctx context.Context, categoryID int32) ([]GetPersonasRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPersonas, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPersonasRow
+	for rows.Next() {
+		var i GetPersonasRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CategoryID,
+			&i.Cluster,
+			&i.Label,
+			&i.Description,
+			&i.CreatedAt,
+			&i.Size,
+			&i.AvgFinalScore,
+			&i.AvgModelScore,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProfileById = `-- name: GetProfileById :one
+SELECT id, facebook_id, name, bio, location, work, education, relationship_status, created_at, updated_at, scraped_by_id, is_scanned, hometown, locale, gender, birthday, email, phone, profile_url, is_analyzed, COALESCE((SELECT json_agg(c) FROM public.user_profile_category c WHERE c.user_profile_id = up.id), '[]'::json)::jsonb as categories
+FROM public.user_profile up WHERE id = $1
+`
+
+type GetProfileByIdRow struct {
+	ID                 int32          `json:"id"`
+	FacebookID         string         `json:"facebook_id"`
+	Name               sql.NullString `json:"name"`
+	Bio                sql.NullString `json:"bio"`
+	Location           sql.NullString `json:"location"`
+	Work               sql.NullString `json:"work"`
+	Education          sql.NullString `json:"education"`
+	RelationshipStatus sql.NullString `json:"relationship_status"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	ScrapedByID        int32          `json:"scraped_by_id"`
+	IsScanned          bool           `json:"is_scanned"`
+	Hometown           sql.NullString `json:"hometown"`
+	Locale             string         `json:"locale"`
+	Gender             sql.NullString `json:"gender"`
+	Birthday           sql.NullString `json:"birthday"`
+	Email              sql.NullString `json:"email"`
+	Phone              sql.NullString `json:"phone"`
+	ProfileUrl         string         `json:"profile_url"`
+	IsAnalyzed         sql.NullBool   `json:"is_analyzed"`
+	Categories         NullableJSON   `json:"categories"`
+}
+
+func (q *Queries) GetProfileById(ctx context.Context, id int32) (GetProfileByIdRow, error) {
+	row := q.db.QueryRowContext(ctx, getProfileById, id)
+	var i GetProfileByIdRow
+	err := row.Scan(
+		&i.ID,
+		&i.FacebookID,
+		&i.Name,
+		&i.Bio,
+		&i.Location,
+		&i.Work,
+		&i.Education,
+		&i.RelationshipStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ScrapedByID,
+		&i.IsScanned,
+		&i.Hometown,
+		&i.Locale,
+		&i.Gender,
+		&i.Birthday,
+		&i.Email,
 		&i.Phone,
 		&i.ProfileUrl,
 		&i.IsAnalyzed,
@@ -1342,6 +3541,158 @@ func (q *Queries) GetProfileById(ctx context.Context, id int32) (GetProfileByIdR
 	return i, err
 }
 
+const getProfileCategory = `-- name: GetProfileCategory :one
+SELECT user_profile_id, category_id, created_at, model_score, gemini_score, final_score, final_score_at, label, intent_score, model_scored_at, model_version_id, model_input_hash, model_contributions FROM public.user_profile_category
+WHERE user_profile_id = $1 AND category_id = $2
+`
+
+type GetProfileCategoryParams struct {
+	UserProfileID int32 `json:"user_profile_id"`
+	CategoryID    int32 `json:"category_id"`
+}
+
+func (q *Queries) GetProfileCategory(ctx context.Context, arg GetProfileCategoryParams) (UserProfileCategory, error) {
+	row := q.db.QueryRowContext(ctx, getProfileCategory, arg.UserProfileID, arg.CategoryID)
+	var i UserProfileCategory
+	err := row.Scan(
+		&i.UserProfileID,
+		&i.CategoryID,
+		&i.CreatedAt,
+		&i.ModelScore,
+		&i.GeminiScore,
+		&i.FinalScore,
+		&i.FinalScoreAt,
+		&i.Label,
+		&i.IntentScore,
+		&i.ModelScoredAt,
+		&i.ModelVersionID,
+		&i.ModelInputHash,
+		&i.ModelContributions,
+	)
+	return i, err
+}
+
+const getProfileDuplicate = `-- name: GetProfileDuplicate :one
+SELECT id, profile_id, duplicate_id, signals, similarity, confidence, status, created_at, updated_at FROM public.profile_duplicate WHERE id = $1
+`
+
+func (q *Queries) GetProfileDuplicate(ctx context.Context, id int32) (ProfileDuplicate, error) {
+	row := q.db.QueryRowContext(ctx, getProfileDuplicate, id)
+	var i ProfileDuplicate
+	err := row.Scan(
+		&i.ID,
+		&i.ProfileID,
+		&i.DuplicateID,
+		&i.Signals,
+		&i.Similarity,
+		&i.Confidence,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProfileDuplicates = `-- name: GetProfileDuplicates :many
+SELECT
+  d.id,
+  d.signals,
+  d.similarity,
+  d.confidence,
+  d.status,
+  d.created_at,
+  d.updated_at,
+  a.id AS profile_id,
+  a.facebook_id AS profile_facebook_id,
+  a.name AS profile_name,
+  a.profile_url AS profile_url,
+  a.updated_at AS profile_updated_at,
+  b.id AS duplicate_id,
+  b.facebook_id AS duplicate_facebook_id,
+  b.name AS duplicate_name,
+  b.profile_url AS duplicate_profile_url,
+  b.updated_at AS duplicate_updated_at
+FROM public.profile_duplicate d
+JOIN public.user_profile a ON a.id = d.profile_id
+JOIN public.user_profile b ON b.id = d.duplicate_id
+WHERE d.status = $1 AND d.confidence >= $2::float8
+ORDER BY d.confidence DESC, d.id
+LIMIT $3 OFFSET $4
+`
+
+type GetProfileDuplicatesParams struct {
+	Status        string  `json:"status"`
+	MinConfidence float64 `json:"min_confidence"`
+	PageLimit     int32   `json:"page_limit"`
+	PageOffset    int32   `json:"page_offset"`
+}
+
+type GetProfileDuplicatesRow struct {
+	ID                  int32           `json:"id"`
+	Signals             NullableJSON    `json:"signals"`
+	Similarity          sql.NullFloat64 `json:"similarity"`
+	Confidence          float64         `json:"confidence"`
+	Status              string          `json:"status"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	ProfileID           int32           `json:"profile_id"`
+	ProfileFacebookID   string          `json:"profile_facebook_id"`
+	ProfileName         sql.NullString  `json:"profile_name"`
+	ProfileUrl          string          `json:"profile_url"`
+	ProfileUpdatedAt    time.Time       `json:"profile_updated_at"`
+	DuplicateID         int32           `json:"duplicate_id"`
+	DuplicateFacebookID string          `json:"duplicate_facebook_id"`
+	DuplicateName       sql.NullString  `json:"duplicate_name"`
+	DuplicateProfileUrl string          `json:"duplicate_profile_url"`
+	DuplicateUpdatedAt  time.Time       `json:"duplicate_updated_at"`
+}
+
+func (q *Queries) GetProfileDuplicates(ctx context.Context, arg GetProfileDuplicatesParams) ([]GetProfileDuplicatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getProfileDuplicates,
+		arg.Status,
+		arg.MinConfidence,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetProfileDuplicatesRow
+	for rows.Next() {
+		var i GetProfileDuplicatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Signals,
+			&i.Similarity,
+			&i.Confidence,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProfileID,
+			&i.ProfileFacebookID,
+			&i.ProfileName,
+			&i.ProfileUrl,
+			&i.ProfileUpdatedAt,
+			&i.DuplicateID,
+			&i.DuplicateFacebookID,
+			&i.DuplicateName,
+			&i.DuplicateProfileUrl,
+			&i.DuplicateUpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getProfileEmbedding = `-- name: GetProfileEmbedding :one
 SELECT embedding FROM public.embedded_profile WHERE pid = $1 AND cid = $2
 `
@@ -1397,6 +3748,77 @@ func (q *Queries) GetProfileIDForEmbedding(ctx context.Context, arg GetProfileID
 	return items, nil
 }
 
+const getProfileMerges = `-- name: GetProfileMerges :many
+SELECT id, survivor_id, merged_id, merged_facebook_id, snapshot, moved, signals, confidence, created_at FROM public.profile_merge
+ORDER BY id DESC
+LIMIT $1 OFFSET $2
+`
+
+type GetProfileMergesParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) GetProfileMerges(ctx context.Context, arg GetProfileMergesParams) ([]ProfileMerge, error) {
+	rows, err := q.db.QueryContext(ctx, getProfileMerges, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ProfileMerge
+	for rows.Next() {
+		var i ProfileMerge
+		if err := rows.Scan(
+			&i.ID,
+			&i.SurvivorID,
+			&i.MergedID,
+			&i.MergedFacebookID,
+			&i.Snapshot,
+			&i.Moved,
+			&i.Signals,
+			&i.Confidence,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProfileSnapshot = `-- name: GetProfileSnapshot :one
+SELECT
+  p.facebook_id,
+  CAST(jsonb_build_object(
+    'profile', to_jsonb(p),
+    'categories', COALESCE((
+      SELECT jsonb_agg(to_jsonb(upc)) FROM public.user_profile_category upc
+      WHERE upc.user_profile_id = p.id
+    ), '[]'::jsonb)
+  ) AS jsonb) AS snapshot
+FROM public.user_profile p
+WHERE p.id = $1
+`
+
+type GetProfileSnapshotRow struct {
+	FacebookID string       `json:"facebook_id"`
+	Snapshot   NullableJSON `json:"snapshot"`
+}
+
+// Merged profile and its category scores as they were before the merge.
+func (q *Queries) GetProfileSnapshot(ctx context.Context, id int32) (GetProfileSnapshotRow, error) {
+	row := q.db.QueryRowContext(ctx, getProfileSnapshot, id)
+	var i GetProfileSnapshotRow
+	err := row.Scan(&i.FacebookID, &i.Snapshot)
+	return i, err
+}
+
 const getProfileStats = `-- name: GetProfileStats :one
 SELECT
   (SELECT COUNT(*) FROM public.user_profile) AS total_profiles,
@@ -1685,32 +4107,48 @@ func (q *Queries) GetProfilesForExport(ctx context.Context, cid int32) ([]GetPro
 }
 
 const getProfilesForScoring = `-- name: GetProfilesForScoring :many
-SELECT up.id FROM public.user_profile up
-JOIN public.embedded_profile ep ON up.id = ep.pid AND ep.cid = $1
-JOIN public.user_profile_category upc ON up.id = upc.user_profile_id
-WHERE up.is_scanned = true AND upc.model_score IS NULL
-AND upc.category_id = $1
-LIMIT $2
+WITH candidate AS (
+    SELECT up.id, upc.model_score, upc.model_scored_at, upc.model_version_id, upc.model_input_hash,
+        md5(concat_ws('|', up.gender, up.relationship_status, up.locale, up.birthday, ep.created_at)) AS input_hash
+    FROM public.user_profile up
+    JOIN public.embedded_profile ep ON up.id = ep.pid AND ep.cid = $1
+    JOIN public.user_profile_category upc ON up.id = upc.user_profile_id AND upc.category_id = $1
+    WHERE up.is_scanned = true
+)
+SELECT id, input_hash::text AS input_hash FROM candidate
+WHERE model_score IS NULL
+    OR model_version_id IS DISTINCT FROM $2::int
+    OR model_input_hash IS DISTINCT FROM input_hash
+ORDER BY model_score IS NOT NULL, model_scored_at NULLS FIRST
+LIMIT $3
 `
 
 type GetProfilesForScoringParams struct {
-	Cid   int32 `json:"cid"`
-	Limit int32 `json:"limit"`
+	CategoryID int32         `json:"category_id"`
+	VersionID  sql.NullInt32 `json:"version_id"`
+	PageLimit  int32         `json:"page_limit"`
+}
+
+type GetProfilesForScoringRow struct {
+	ID        int32  `json:"id"`
+	InputHash string `json:"input_hash"`
 }
 
-func (q *Queries) GetProfilesForScoring(ctx context.Context, arg GetProfilesForScoringParams) ([]int32, error) {
-	rows, err := q.db.QueryContext(ctx, getProfilesForScoring, arg.Cid, arg.Limit)
+// Unscored profiles come first, then the ones scored by another model
+// version or from inputs that changed since, oldest scores first.
+func (q *Queries) GetProfilesForScoring(ctx context.Context, arg GetProfilesForScoringParams) ([]GetProfilesForScoringRow, error) {
+	rows, err := q.db.QueryContext(ctx, getProfilesForScoring, arg.CategoryID, arg.VersionID, arg.PageLimit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []int32
+	var items []GetProfilesForScoringRow
 	for rows.Next() {
-		var id int32
-		if err := rows.Scan(&id); err != nil {
+		var i GetProfilesForScoringRow
+		if err := rows.Scan(&i.ID, &i.InputHash); err != nil {
 			return nil, err
 		}
-		items = append(items, id)
+		items = append(items, i)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -1886,8 +4324,87 @@ func (q *Queries) GetPromptsByCategory(ctx context.Context, arg GetPromptsByCate
 	return items, nil
 }
 
+const getRecentFeatureCompleteness = `-- name: GetRecentFeatureCompleteness :one
+SELECT COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE EXISTS (
+        SELECT 1 FROM public.embedded_profile ep
+        WHERE ep.pid = up.id AND ep.cid = upc.category_id AND ep.embedding IS NOT NULL
+    )) AS with_embedding,
+    COUNT(*) FILTER (WHERE up.gender IS NOT NULL AND up.gender <> '') AS with_gender,
+    COUNT(*) FILTER (WHERE up.relationship_status IS NOT NULL AND up.relationship_status <> '') AS with_relationship_status,
+    COUNT(*) FILTER (WHERE up.locale <> '' AND up.locale <> 'NOT_SPECIFIED') AS with_locale
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+WHERE upc.category_id = $1 AND upc.model_version_id = $2
+  AND upc.model_score IS NOT NULL AND upc.model_scored_at >= $3::timestamp
+`
+
+type GetRecentFeatureCompletenessParams struct {
+	CategoryID int32         `json:"category_id"`
+	VersionID  sql.NullInt32 `json:"version_id"`
+	Since      time.Time     `json:"since"`
+}
+
+type GetRecentFeatureCompletenessRow struct {
+	Total                  int64 `json:"total"`
+	WithEmbedding          int64 `json:"with_embedding"`
+	WithGender             int64 `json:"with_gender"`
+	WithRelationshipStatus int64 `json:"with_relationship_status"`
+	WithLocale             int64 `json:"with_locale"`
+}
+
+func (q *Queries) GetRecentFeatureCompleteness(ctx context.Context, arg GetRecentFeatureCompletenessParams) (GetRecentFeatureCompletenessRow, error) {
+	row := q.db.QueryRowContext(ctx, getRecentFeatureCompleteness, arg.CategoryID, arg.VersionID, arg.Since)
+	var i GetRecentFeatureCompletenessRow
+	err := row.Scan(
+		&i.Total,
+		&i.WithEmbedding,
+		&i.WithGender,
+		&i.WithRelationshipStatus,
+		&i.WithLocale,
+	)
+	return i, err
+}
+
+const getRecentModelScores = `-- name: GetRecentModelScores :many
+SELECT upc.model_score::double precision AS model_score
+FROM public.user_profile_category upc
+WHERE upc.category_id = $1 AND upc.model_version_id = $2
+  AND upc.model_score IS NOT NULL AND upc.model_scored_at >= $3::timestamp
+`
+
+type GetRecentModelScoresParams struct {
+	CategoryID int32         `json:"category_id"`
+	VersionID  sql.NullInt32 `json:"version_id"`
+	Since      time.Time     `json:"since"`
+}
+
+// Drift monitoring queries
+func (q *Queries) GetRecentModelScores(ctx context.Context, arg GetRecentModelScoresParams) ([]float64, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentModelScores, arg.CategoryID, arg.VersionID, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []float64
+	for rows.Next() {
+		var model_score float64
+		if err := rows.Scan(&model_score); err != nil {
+			return nil, err
+		}
+		items = append(items, model_score)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRequestById = `-- name: GetRequestById :one
-SELECT id, progress, status, description, created_at, updated_at, error_message FROM public.request WHERE id = $1
+SELECT id, progress, status, description, created_at, updated_at, error_message, kind, result, started_at, finished_at FROM public.request WHERE id = $1
 `
 
 func (q *Queries) GetRequestById(ctx context.Context, id int32) (Request, error) {
@@ -1901,6 +4418,77 @@ func (q *Queries) GetRequestById(ctx context.Context, id int32) (Request, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ErrorMessage,
+		&i.Kind,
+		&i.Result,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const getRetrainPolicies = `-- name: GetRetrainPolicies :many
+SELECT category_id, model_name, is_enabled, interval_hours, min_new_profiles, rmse_margin, r2_margin, auto_tune, trials, last_run_at, profiles_at_last_run, last_request_id, updated_at FROM public.retrain_policy ORDER BY category_id
+`
+
+// Retraining queries
+func (q *Queries) GetRetrainPolicies(ctx context.Context) ([]RetrainPolicy, error) {
+	rows, err := q.db.QueryContext(ctx, getRetrainPolicies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RetrainPolicy
+	for rows.Next() {
+		var i RetrainPolicy
+		if err := rows.Scan(
+			&i.CategoryID,
+			&i.ModelName,
+			&i.IsEnabled,
+			&i.IntervalHours,
+			&i.MinNewProfiles,
+			&i.RmseMargin,
+			&i.R2Margin,
+			&i.AutoTune,
+			&i.Trials,
+			&i.LastRunAt,
+			&i.ProfilesAtLastRun,
+			&i.LastRequestID,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRetrainPolicy = `-- name: GetRetrainPolicy :one
+SELECT category_id, model_name, is_enabled, interval_hours, min_new_profiles, rmse_margin, r2_margin, auto_tune, trials, last_run_at, profiles_at_last_run, last_request_id, updated_at FROM public.retrain_policy WHERE category_id = $1
+`
+
+func (q *Queries) GetRetrainPolicy(ctx context.Context, categoryID int32) (RetrainPolicy, error) {
+	row := q.db.QueryRowContext(ctx, getRetrainPolicy, categoryID)
+	var i RetrainPolicy
+	err := row.Scan(
+		&i.CategoryID,
+		&i.ModelName,
+		&i.IsEnabled,
+		&i.IntervalHours,
+		&i.MinNewProfiles,
+		&i.RmseMargin,
+		&i.R2Margin,
+		&i.AutoTune,
+		&i.Trials,
+		&i.LastRunAt,
+		&i.ProfilesAtLastRun,
+		&i.LastRequestID,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -1980,6 +4568,77 @@ func (q *Queries) GetScoreDistribution(ctx context.Context, categoryID int32) ([
 	return items, nil
 }
 
+const getScoringPolicy = `-- name: GetScoringPolicy :one
+SELECT category_id, gemini_weight, model_weight, completeness_weight, min_completeness, decay_half_life_days, calibration_method, calibration, updated_at, intent_weight FROM public.scoring_policy WHERE category_id = $1
+`
+
+// Lead scoring queries
+func (q *Queries) GetScoringPolicy(ctx context.Context, categoryID int32) (ScoringPolicy, error) {
+	row := q.db.QueryRowContext(ctx, getScoringPolicy, categoryID)
+	var i ScoringPolicy
+	err := row.Scan(
+		&i.CategoryID,
+		&i.GeminiWeight,
+		&i.ModelWeight,
+		&i.CompletenessWeight,
+		&i.MinCompleteness,
+		&i.DecayHalfLifeDays,
+		&i.CalibrationMethod,
+		&i.Calibration,
+		&i.UpdatedAt,
+		&i.IntentWeight,
+	)
+	return i, err
+}
+
+const getShadowScores = `-- name: GetShadowScores :many
+SELECT mss.user_profile_id, mss.score, mss.champion_score, upc.gemini_score
+FROM public.model_shadow_score mss
+LEFT JOIN public.user_profile_category upc
+    ON upc.user_profile_id = mss.user_profile_id AND upc.category_id = mss.category_id
+WHERE mss.version_id = $1 AND mss.category_id = $2 AND mss.champion_score IS NOT NULL
+`
+
+type GetShadowScoresParams struct {
+	VersionID  int32 `json:"version_id"`
+	CategoryID int32 `json:"category_id"`
+}
+
+type GetShadowScoresRow struct {
+	UserProfileID int32           `json:"user_profile_id"`
+	Score         float64         `json:"score"`
+	ChampionScore sql.NullFloat64 `json:"champion_score"`
+	GeminiScore   sql.NullFloat64 `json:"gemini_score"`
+}
+
+func (q *Queries) GetShadowScores(ctx context.Context, arg GetShadowScoresParams) ([]GetShadowScoresRow, error) {
+	rows, err := q.db.QueryContext(ctx, getShadowScores, arg.VersionID, arg.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetShadowScoresRow
+	for rows.Next() {
+		var i GetShadowScoresRow
+		if err := rows.Scan(
+			&i.UserProfileID,
+			&i.Score,
+			&i.ChampionScore,
+			&i.GeminiScore,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStats = `-- name: GetStats :one
 SELECT
   (SELECT COUNT(*) FROM public."group") AS total_groups,
@@ -2040,6 +4699,114 @@ func (q *Queries) GetTimeSeriesData(ctx context.Context, categoryID int32) ([]Ge
 	return items, nil
 }
 
+const getTriggerRuleHits = `-- name: GetTriggerRuleHits :many
+SELECT h.id,
+  h.rule_id,
+  r.name AS rule_name,
+  h.user_profile_id,
+  up.name AS profile_name,
+  h.source_type,
+  h.source_id,
+  h.matched,
+  h.created_at
+FROM public.trigger_rule_hit h
+JOIN public.trigger_rule r ON r.id = h.rule_id
+JOIN public.user_profile up ON up.id = h.user_profile_id
+WHERE h.category_id = $1
+ORDER BY h.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetTriggerRuleHitsParams struct {
+	CategoryID int32 `json:"category_id"`
+	Limit      int32 `json:"limit"`
+	Offset     int32 `json:"offset"`
+}
+
+type GetTriggerRuleHitsRow struct {
+	ID            int32          `json:"id"`
+	RuleID        int32          `json:"rule_id"`
+	RuleName      string         `json:"rule_name"`
+	UserProfileID int32          `json:"user_profile_id"`
+	ProfileName   sql.NullString `json:"profile_name"`
+	SourceType    string         `json:"source_type"`
+	SourceID      int32          `json:"source_id"`
+	Matched       string         `json:"matched"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+func (q *Queries) GetTriggerRuleHits(ctx context.Context, arg GetTriggerRuleHitsParams) ([]GetTriggerRuleHitsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTriggerRuleHits, arg.CategoryID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTriggerRuleHitsRow
+	for rows.Next() {
+		var i GetTriggerRuleHitsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RuleID,
+			&i.RuleName,
+			&i.UserProfileID,
+			&i.ProfileName,
+			&i.SourceType,
+			&i.SourceID,
+			&i.Matched,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTriggerRulesByCategory = `-- name: GetTriggerRulesByCategory :many
+SELECT id, category_id, name, keywords, patterns, negative_keywords, language, is_active, created_at FROM public.trigger_rule
+WHERE category_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetTriggerRulesByCategory(ctx context.Context, categoryID int32) ([]TriggerRule, error) {
+	rows, err := q.db.QueryContext(ctx, getTriggerRulesByCategory, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TriggerRule
+	for rows.Next() {
+		var i TriggerRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CategoryID,
+			&i.Name,
+			pq.Array(&i.Keywords),
+			pq.Array(&i.Patterns),
+			pq.Array(&i.NegativeKeywords),
+			&i.Language,
+			&i.IsActive,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const importProfile = `-- name: ImportProfile :one
 INSERT INTO public.user_profile (facebook_id, name, bio, location, work, education, relationship_status, created_at, updated_at, scraped_by_id, is_scanned, hometown, locale, gender, birthday, email, phone, profile_url, is_analyzed)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 1, $10, $11, $12, $13, $14, $15, $16, $17, $18)
@@ -2131,54 +4898,588 @@ func (q *Queries) ImportProfile(ctx context.Context, arg ImportProfileParams) (U
 	return i, err
 }
 
-const logAction = `-- name: LogAction :exec
-INSERT INTO public.log (account_id, "action", target_id, description, created_at)
-VALUES ($1, $2, $3, $4, NOW())
+const interruptRequests = `-- name: InterruptRequests :execrows
+UPDATE public.request
+SET status = interrupted.code, updated_at = NOW(), finished_at = NOW(),
+error_message = COALESCE(request.error_message, 'Interrupted by a server restart')
+FROM public.request_status interrupted, public.request_status rs
+WHERE interrupted.name = 'interrupted'
+  AND rs.code = request.status
+  AND NOT rs.is_final
+`
+
+func (q *Queries) InterruptRequests(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, interruptRequests)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const lockModelVersions = `-- name: LockModelVersions :exec
+SELECT pg_advisory_xact_lock(hashtext($1::text))
+`
+
+// Serialises the registration of versions of a model until the end of the
+// transaction, so two runs can't take the same version number.
+func (q *Queries) LockModelVersions(ctx context.Context, name string) error {
+	_, err := q.db.ExecContext(ctx, lockModelVersions, name)
+	return err
+}
+
+const lockProfiles = `-- name: LockProfiles :many
+SELECT id FROM public.user_profile
+WHERE id = ANY($1::int[])
+ORDER BY id
+FOR UPDATE
+`
+
+// Locks both profiles of a merge for the rest of the transaction.
+func (q *Queries) LockProfiles(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, lockProfiles, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const logAction = `-- name: LogAction :exec
+INSERT INTO public.log (account_id, "action", target_id, description, created_at)
+VALUES ($1, $2, $3, $4, NOW())
+`
+
+type LogActionParams struct {
+	AccountID   sql.NullInt32  `json:"account_id"`
+	Action      string         `json:"action"`
+	TargetID    sql.NullInt32  `json:"target_id"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) LogAction(ctx context.Context, arg LogActionParams) error {
+	_, err := q.db.ExecContext(ctx, logAction,
+		arg.AccountID,
+		arg.Action,
+		arg.TargetID,
+		arg.Description,
+	)
+	return err
+}
+
+const markRetrainPolicyRun = `-- name: MarkRetrainPolicyRun :exec
+UPDATE public.retrain_policy
+SET last_run_at = NOW(),
+    profiles_at_last_run = $2,
+    last_request_id = $3
+WHERE category_id = $1
+`
+
+type MarkRetrainPolicyRunParams struct {
+	CategoryID        int32         `json:"category_id"`
+	ProfilesAtLastRun int32         `json:"profiles_at_last_run"`
+	LastRequestID     sql.NullInt32 `json:"last_request_id"`
+}
+
+func (q *Queries) MarkRetrainPolicyRun(ctx context.Context, arg MarkRetrainPolicyRunParams) error {
+	_, err := q.db.ExecContext(ctx, markRetrainPolicyRun, arg.CategoryID, arg.ProfilesAtLastRun, arg.LastRequestID)
+	return err
+}
+
+const moveProfileCategories = `-- name: MoveProfileCategories :execrows
+UPDATE public.user_profile_category SET user_profile_id = $1
+WHERE user_profile_id = $2
+  AND category_id NOT IN (
+    SELECT category_id FROM public.user_profile_category WHERE user_profile_id = $1
+  )
+`
+
+type MoveProfileCategoriesParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+// Moves the categories the survivor is not in yet.
+func (q *Queries) MoveProfileCategories(ctx context.Context, arg MoveProfileCategoriesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileCategories, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileComments = `-- name: MoveProfileComments :execrows
+UPDATE public.comment SET author_id = $1 WHERE author_id = $2
+`
+
+type MoveProfileCommentsParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+func (q *Queries) MoveProfileComments(ctx context.Context, arg MoveProfileCommentsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileComments, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileDuplicates = `-- name: MoveProfileDuplicates :execrows
+INSERT INTO public.profile_duplicate (profile_id, duplicate_id, signals, similarity, confidence, created_at)
+SELECT LEAST($1::int, d.other_id), GREATEST($1::int, d.other_id),
+  d.signals, d.similarity, d.confidence, d.created_at
+FROM (
+  SELECT CASE WHEN profile_id = $2::int THEN duplicate_id ELSE profile_id END AS other_id,
+    signals, similarity, confidence, created_at
+  FROM public.profile_duplicate
+  WHERE status = 'pending' AND (profile_id = $2 OR duplicate_id = $2)
+) d
+WHERE d.other_id <> $1
+ON CONFLICT (profile_id, duplicate_id) DO NOTHING
+`
+
+type MoveProfileDuplicatesParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+// Re-points the pending merge candidates of the merged profile to the
+// survivor. Pairs with the survivor itself, or already known for it, are left
+// to be dropped along with the merged profile.
+func (q *Queries) MoveProfileDuplicates(ctx context.Context, arg MoveProfileDuplicatesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileDuplicates, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileEmbeddings = `-- name: MoveProfileEmbeddings :execrows
+UPDATE public.embedded_profile SET pid = $1
+WHERE pid = $2
+  AND cid NOT IN (SELECT cid FROM public.embedded_profile WHERE pid = $1)
+`
+
+type MoveProfileEmbeddingsParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+func (q *Queries) MoveProfileEmbeddings(ctx context.Context, arg MoveProfileEmbeddingsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileEmbeddings, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileFinancialAnalyses = `-- name: MoveProfileFinancialAnalyses :execrows
+UPDATE public.financial_analysis SET user_profile_id = $1 WHERE user_profile_id = $2
+`
+
+type MoveProfileFinancialAnalysesParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+func (q *Queries) MoveProfileFinancialAnalyses(ctx context.Context, arg MoveProfileFinancialAnalysesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileFinancialAnalyses, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileMerges = `-- name: MoveProfileMerges :execrows
+UPDATE public.profile_merge SET survivor_id = $1 WHERE survivor_id = $2
+`
+
+type MoveProfileMergesParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+// Keeps earlier merges into the merged profile pointing at a live profile.
+func (q *Queries) MoveProfileMerges(ctx context.Context, arg MoveProfileMergesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileMerges, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileSeedMemberships = `-- name: MoveProfileSeedMemberships :execrows
+UPDATE public.lookalike_seed_profile m SET user_profile_id = $1
+WHERE m.user_profile_id = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM public.lookalike_seed_profile s
+    WHERE s.user_profile_id = $1 AND s.seed_id = m.seed_id
+  )
+`
+
+type MoveProfileSeedMembershipsParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+func (q *Queries) MoveProfileSeedMemberships(ctx context.Context, arg MoveProfileSeedMembershipsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileSeedMemberships, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileShadowScores = `-- name: MoveProfileShadowScores :execrows
+UPDATE public.model_shadow_score m SET user_profile_id = $1
+WHERE m.user_profile_id = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM public.model_shadow_score s
+    WHERE s.user_profile_id = $1 AND s.version_id = m.version_id AND s.category_id = m.category_id
+  )
+`
+
+type MoveProfileShadowScoresParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+func (q *Queries) MoveProfileShadowScores(ctx context.Context, arg MoveProfileShadowScoresParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileShadowScores, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const moveProfileTriggerRuleHits = `-- name: MoveProfileTriggerRuleHits :execrows
+UPDATE public.trigger_rule_hit m SET user_profile_id = $1
+WHERE m.user_profile_id = $2
+  AND NOT EXISTS (
+    SELECT 1 FROM public.trigger_rule_hit s
+    WHERE s.user_profile_id = $1 AND s.rule_id = m.rule_id
+      AND s.source_type = m.source_type AND s.source_id = m.source_id
+  )
+`
+
+type MoveProfileTriggerRuleHitsParams struct {
+	SurvivorID int32 `json:"survivor_id"`
+	MergedID   int32 `json:"merged_id"`
+}
+
+func (q *Queries) MoveProfileTriggerRuleHits(ctx context.Context, arg MoveProfileTriggerRuleHitsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveProfileTriggerRuleHits, arg.SurvivorID, arg.MergedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const promoteModelVersion = `-- name: PromoteModelVersion :one
+INSERT INTO public.model_deployment (category_id, champion_version_id, updated_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    champion_version_id = EXCLUDED.champion_version_id,
+    challenger_version_id = CASE
+        WHEN model_deployment.challenger_version_id = EXCLUDED.champion_version_id THEN NULL
+        ELSE model_deployment.challenger_version_id
+    END,
+    updated_at = NOW()
+RETURNING category_id, champion_version_id, challenger_version_id, challenger_sample_rate, updated_at
+`
+
+type PromoteModelVersionParams struct {
+	CategoryID        int32         `json:"category_id"`
+	ChampionVersionID sql.NullInt32 `json:"champion_version_id"`
+}
+
+func (q *Queries) PromoteModelVersion(ctx context.Context, arg PromoteModelVersionParams) (ModelDeployment, error) {
+	row := q.db.QueryRowContext(ctx, promoteModelVersion, arg.CategoryID, arg.ChampionVersionID)
+	var i ModelDeployment
+	err := row.Scan(
+		&i.CategoryID,
+		&i.ChampionVersionID,
+		&i.ChallengerVersionID,
+		&i.ChallengerSampleRate,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const resetCategoryModelScores = `-- name: ResetCategoryModelScores :execrows
+UPDATE public.user_profile_category
+SET model_score = NULL,
+    model_version_id = NULL,
+    model_input_hash = NULL,
+    model_contributions = NULL
+WHERE category_id = $1
+`
+
+func (q *Queries) ResetCategoryModelScores(ctx context.Context, categoryID int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resetCategoryModelScores, categoryID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetProfilesModelScore = `-- name: ResetProfilesModelScore :exec
+UPDATE public.user_profile_category
+SET model_score = NULL,
+    model_version_id = NULL,
+    model_input_hash = NULL,
+    model_contributions = NULL
+`
+
+func (q *Queries) ResetProfilesModelScore(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetProfilesModelScore)
+	return err
+}
+
+const resolveSeedProfiles = `-- name: ResolveSeedProfiles :many
+SELECT id, facebook_id FROM public.user_profile
+WHERE id = ANY($1::int[]) OR facebook_id = ANY($2::text[])
+`
+
+type ResolveSeedProfilesParams struct {
+	ProfileIds  []int32  `json:"profile_ids"`
+	FacebookIds []string `json:"facebook_ids"`
+}
+
+type ResolveSeedProfilesRow struct {
+	ID         int32  `json:"id"`
+	FacebookID string `json:"facebook_id"`
+}
+
+// Profiles matching uploaded profile IDs or Facebook IDs.
+func (q *Queries) ResolveSeedProfiles(ctx context.Context, arg ResolveSeedProfilesParams) ([]ResolveSeedProfilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, resolveSeedProfiles, pq.Array(arg.ProfileIds), pq.Array(arg.FacebookIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ResolveSeedProfilesRow
+	for rows.Next() {
+		var i ResolveSeedProfilesRow
+		if err := rows.Scan(&i.ID, &i.FacebookID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rollbackPrompt = `-- name: RollbackPrompt :exec
+DELETE FROM public.prompt pr WHERE
+pr.service_name = $1 AND pr.category_id = $2
+AND pr.version = (
+  SELECT MAX(version) FROM public.prompt p
+  WHERE p.service_name = $1 AND p.category_id = $2
+)
+`
+
+type RollbackPromptParams struct {
+	ServiceName string `json:"service_name"`
+	CategoryID  int32  `json:"category_id"`
+}
+
+func (q *Queries) RollbackPrompt(ctx context.Context, arg RollbackPromptParams) error {
+	_, err := q.db.ExecContext(ctx, rollbackPrompt, arg.ServiceName, arg.CategoryID)
+	return err
+}
+
+const searchProfilesByEmbedding = `-- name: SearchProfilesByEmbedding :many
+SELECT
+  p.id AS profile_id,
+  p.profile_url,
+  p.name AS profile_name,
+  p.location,
+  p.updated_at AS scanned_at,
+  upc.model_score,
+  upc.final_score,
+  CAST(1 - (ep.embedding <=> $1::vector) AS DOUBLE PRECISION) AS similarity
+FROM public.embedded_profile ep
+JOIN public.user_profile p ON p.id = ep.pid
+JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = ep.cid
+WHERE ep.cid = $2 AND p.is_scanned = true
+  AND ($3::text IS NULL
+    OR p.location ILIKE '%' || $3::text || '%'
+    OR p.hometown ILIKE '%' || $3::text || '%')
+  AND ($4::timestamp IS NULL OR p.updated_at >= $4::timestamp)
+  AND ($5::timestamp IS NULL OR p.updated_at < $5::timestamp)
+  AND ($6::float8 IS NULL OR COALESCE(upc.final_score, upc.model_score) >= $6::float8)
+ORDER BY ep.embedding <=> $1::vector
+LIMIT $7
+`
+
+type SearchProfilesByEmbeddingParams struct {
+	Query         interface{}     `json:"query"`
+	CategoryID    int32           `json:"category_id"`
+	Location      sql.NullString  `json:"location"`
+	ScannedAfter  sql.NullTime    `json:"scanned_after"`
+	ScannedBefore sql.NullTime    `json:"scanned_before"`
+	MinScore      sql.NullFloat64 `json:"min_score"`
+	PageLimit     int32           `json:"page_limit"`
+}
+
+type SearchProfilesByEmbeddingRow struct {
+	ProfileID   int32           `json:"profile_id"`
+	ProfileUrl  string          `json:"profile_url"`
+	ProfileName sql.NullString  `json:"profile_name"`
+	Location    sql.NullString  `json:"location"`
+	ScannedAt   time.Time       `json:"scanned_at"`
+	ModelScore  sql.NullFloat64 `json:"model_score"`
+	FinalScore  sql.NullFloat64 `json:"final_score"`
+	Similarity  float64         `json:"similarity"`
+}
+
+// Nearest profiles of a category to a query embedding. Filters are skipped
+// when NULL, min_score applies to the lead score or the model score.
+func (q *Queries) SearchProfilesByEmbedding(ctx context.Context, arg SearchProfilesByEmbeddingParams) ([]SearchProfilesByEmbeddingRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchProfilesByEmbedding,
+		arg.Query,
+		arg.CategoryID,
+		arg.Location,
+		arg.ScannedAfter,
+		arg.ScannedBefore,
+		arg.MinScore,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchProfilesByEmbeddingRow
+	for rows.Next() {
+		var i SearchProfilesByEmbeddingRow
+		if err := rows.Scan(
+			&i.ProfileID,
+			&i.ProfileUrl,
+			&i.ProfileName,
+			&i.Location,
+			&i.ScannedAt,
+			&i.ModelScore,
+			&i.FinalScore,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setModelChallenger = `-- name: SetModelChallenger :one
+INSERT INTO public.model_deployment (category_id, challenger_version_id, challenger_sample_rate, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    challenger_version_id = EXCLUDED.challenger_version_id,
+    challenger_sample_rate = EXCLUDED.challenger_sample_rate,
+    updated_at = NOW()
+RETURNING category_id, champion_version_id, challenger_version_id, challenger_sample_rate, updated_at
 `
 
-type LogActionParams struct {
-	AccountID   sql.NullInt32  `json:"account_id"`
-	Action      string         `json:"action"`
-	TargetID    sql.NullInt32  `json:"target_id"`
-	Description sql.NullString `json:"description"`
+type SetModelChallengerParams struct {
+	CategoryID           int32         `json:"category_id"`
+	ChallengerVersionID  sql.NullInt32 `json:"challenger_version_id"`
+	ChallengerSampleRate float64       `json:"challenger_sample_rate"`
 }
 
-func (q *Queries) LogAction(ctx context.Context, arg LogActionParams) error {
-	_, err := q.db.ExecContext(ctx, logAction,
-		arg.AccountID,
-		arg.Action,
-		arg.TargetID,
-		arg.Description,
+func (q *Queries) SetModelChallenger(ctx context.Context, arg SetModelChallengerParams) (ModelDeployment, error) {
+	row := q.db.QueryRowContext(ctx, setModelChallenger, arg.CategoryID, arg.ChallengerVersionID, arg.ChallengerSampleRate)
+	var i ModelDeployment
+	err := row.Scan(
+		&i.CategoryID,
+		&i.ChampionVersionID,
+		&i.ChallengerVersionID,
+		&i.ChallengerSampleRate,
+		&i.UpdatedAt,
 	)
-	return err
+	return i, err
 }
 
-const resetProfilesModelScore = `-- name: ResetProfilesModelScore :exec
-UPDATE public.user_profile_category
-SET model_score = NULL
+const setTriggerRuleActive = `-- name: SetTriggerRuleActive :exec
+UPDATE public.trigger_rule
+SET is_active = $2
+WHERE id = $1
 `
 
-func (q *Queries) ResetProfilesModelScore(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, resetProfilesModelScore)
+type SetTriggerRuleActiveParams struct {
+	ID       int32 `json:"id"`
+	IsActive bool  `json:"is_active"`
+}
+
+func (q *Queries) SetTriggerRuleActive(ctx context.Context, arg SetTriggerRuleActiveParams) error {
+	_, err := q.db.ExecContext(ctx, setTriggerRuleActive, arg.ID, arg.IsActive)
 	return err
 }
 
-const rollbackPrompt = `-- name: RollbackPrompt :exec
-DELETE FROM public.prompt pr WHERE
-pr.service_name = $1 AND pr.category_id = $2
-AND pr.version = (
-  SELECT MAX(version) FROM public.prompt p
-  WHERE p.service_name = $1 AND p.category_id = $2
-)
+const syncCommentIntents = `-- name: SyncCommentIntents :exec
+UPDATE public.comment c
+SET intent = COALESCE(s.intent, c.intent),
+    intent_confidence = COALESCE(s.intent_confidence, c.intent_confidence),
+    intents = COALESCE(s.intents, c.intents),
+    analyzed_at = COALESCE(s.analyzed_at, c.analyzed_at),
+    is_analyzed = s.done
+FROM (
+  SELECT cm.id, best.intent, best.intent_confidence, best.intents, best.analyzed_at,
+    NOT EXISTS (
+      SELECT 1 FROM public.post p
+      JOIN public.group_category gc ON gc.group_id = p.group_id
+      LEFT JOIN public.comment_intent ci ON ci.comment_id = cm.id AND ci.category_id = gc.category_id
+      WHERE p.id = cm.post_id
+        AND ci.analyzed_at IS NULL
+        AND COALESCE(ci.attempts, 0) < $1::int
+    ) AS done
+  FROM public.comment cm
+  LEFT JOIN LATERAL (
+    SELECT ci.intent, ci.intent_confidence, ci.intents, ci.analyzed_at
+    FROM public.comment_intent ci
+    WHERE ci.comment_id = cm.id AND ci.analyzed_at IS NOT NULL
+    ORDER BY ci.intent_confidence DESC NULLS LAST, ci.analyzed_at DESC
+    LIMIT 1
+  ) best ON true
+  WHERE cm.id = ANY($2::int[])
+) s
+WHERE c.id = s.id
 `
 
-type RollbackPromptParams struct {
-	ServiceName string `json:"service_name"`
-	CategoryID  int32  `json:"category_id"`
+type SyncCommentIntentsParams struct {
+	MaxAttempts int32   `json:"max_attempts"`
+	CommentIds  []int32 `json:"comment_ids"`
 }
 
-func (q *Queries) RollbackPrompt(ctx context.Context, arg RollbackPromptParams) error {
-	_, err := q.db.ExecContext(ctx, rollbackPrompt, arg.ServiceName, arg.CategoryID)
+// Copies the most confident intent over the categories to the comments, and
+// marks them analyzed once every category of their group classified them or
+// ran out of attempts.
+func (q *Queries) SyncCommentIntents(ctx context.Context, arg SyncCommentIntentsParams) error {
+	_, err := q.db.ExecContext(ctx, syncCommentIntents, arg.MaxAttempts, pq.Array(arg.CommentIds))
 	return err
 }
 
@@ -2282,6 +5583,54 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 	return i, err
 }
 
+const updateCommentIntent = `-- name: UpdateCommentIntent :exec
+INSERT INTO public.comment_intent (comment_id, category_id, intent, intent_confidence, intents, analyzed_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (comment_id, category_id) DO UPDATE SET
+  intent = EXCLUDED.intent,
+  intent_confidence = EXCLUDED.intent_confidence,
+  intents = EXCLUDED.intents,
+  analyzed_at = EXCLUDED.analyzed_at,
+  updated_at = NOW()
+`
+
+type UpdateCommentIntentParams struct {
+	CommentID        int32           `json:"comment_id"`
+	CategoryID       int32           `json:"category_id"`
+	Intent           sql.NullString  `json:"intent"`
+	IntentConfidence sql.NullFloat64 `json:"intent_confidence"`
+	Intents          NullableJSON    `json:"intents"`
+}
+
+func (q *Queries) UpdateCommentIntent(ctx context.Context, arg UpdateCommentIntentParams) error {
+	_, err := q.db.ExecContext(ctx, updateCommentIntent,
+		arg.CommentID,
+		arg.CategoryID,
+		arg.Intent,
+		arg.IntentConfidence,
+		arg.Intents,
+	)
+	return err
+}
+
+const updateFinalScore = `-- name: UpdateFinalScore :exec
+UPDATE public.user_profile_category
+SET final_score = $3,
+    final_score_at = NOW()
+WHERE user_profile_id = $1 AND category_id = $2
+`
+
+type UpdateFinalScoreParams struct {
+	UserProfileID int32           `json:"user_profile_id"`
+	CategoryID    int32           `json:"category_id"`
+	FinalScore    sql.NullFloat64 `json:"final_score"`
+}
+
+func (q *Queries) UpdateFinalScore(ctx context.Context, arg UpdateFinalScoreParams) error {
+	_, err := q.db.ExecContext(ctx, updateFinalScore, arg.UserProfileID, arg.CategoryID, arg.FinalScore)
+	return err
+}
+
 const updateGeminiAnalysisProfile = `-- name: UpdateGeminiAnalysisProfile :exec
 UPDATE public.user_profile
 SET is_analyzed = TRUE,
@@ -2321,7 +5670,8 @@ func (q *Queries) UpdateGeminiKeyUsage(ctx context.Context, arg UpdateGeminiKeyU
 
 const updateGeminiScore = `-- name: UpdateGeminiScore :exec
 UPDATE public.user_profile_category
-SET gemini_score = $3
+SET gemini_score = $3,
+    final_score_at = NULL
 WHERE user_profile_id = $1 AND category_id = $2
 `
 
@@ -2347,6 +5697,25 @@ func (q *Queries) UpdateGroupScannedAt(ctx context.Context, id int32) error {
 	return err
 }
 
+const updateIntentScore = `-- name: UpdateIntentScore :exec
+UPDATE public.user_profile_category
+SET intent_score = GREATEST(COALESCE(intent_score, 0), $1::float8),
+    final_score_at = NULL
+WHERE user_profile_id = $2
+AND category_id = $3
+`
+
+type UpdateIntentScoreParams struct {
+	IntentScore   float64 `json:"intent_score"`
+	UserProfileID int32   `json:"user_profile_id"`
+	CategoryID    int32   `json:"category_id"`
+}
+
+func (q *Queries) UpdateIntentScore(ctx context.Context, arg UpdateIntentScoreParams) error {
+	_, err := q.db.ExecContext(ctx, updateIntentScore, arg.IntentScore, arg.UserProfileID, arg.CategoryID)
+	return err
+}
+
 const updateModel = `-- name: UpdateModel :one
 UPDATE public.model
 SET name = $2,
@@ -2383,18 +5752,48 @@ func (q *Queries) UpdateModel(ctx context.Context, arg UpdateModelParams) (Model
 
 const updateModelScore = `-- name: UpdateModelScore :exec
 UPDATE public.user_profile_category
-SET model_score = $3
+SET model_score = $3,
+    model_scored_at = NOW(),
+    model_version_id = $4,
+    model_input_hash = $5,
+    model_contributions = $6,
+    final_score_at = NULL
 WHERE user_profile_id = $1 AND category_id = $2
 `
 
 type UpdateModelScoreParams struct {
-	UserProfileID int32           `json:"user_profile_id"`
-	CategoryID    int32           `json:"category_id"`
-	ModelScore    sql.NullFloat64 `json:"model_score"`
+	UserProfileID      int32           `json:"user_profile_id"`
+	CategoryID         int32           `json:"category_id"`
+	ModelScore         sql.NullFloat64 `json:"model_score"`
+	ModelVersionID     sql.NullInt32   `json:"model_version_id"`
+	ModelInputHash     sql.NullString  `json:"model_input_hash"`
+	ModelContributions NullableJSON    `json:"model_contributions"`
 }
 
 func (q *Queries) UpdateModelScore(ctx context.Context, arg UpdateModelScoreParams) error {
-	_, err := q.db.ExecContext(ctx, updateModelScore, arg.UserProfileID, arg.CategoryID, arg.ModelScore)
+	_, err := q.db.ExecContext(ctx, updateModelScore,
+		arg.UserProfileID,
+		arg.CategoryID,
+		arg.ModelScore,
+		arg.ModelVersionID,
+		arg.ModelInputHash,
+		arg.ModelContributions,
+	)
+	return err
+}
+
+const updatePersonaLabel = `-- name: UpdatePersonaLabel :exec
+UPDATE public.persona SET label = $2, description = $3 WHERE id = $1
+`
+
+type UpdatePersonaLabelParams struct {
+	ID          int32          `json:"id"`
+	Label       sql.NullString `json:"label"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) UpdatePersonaLabel(ctx context.Context, arg UpdatePersonaLabelParams) error {
+	_, err := q.db.ExecContext(ctx, updatePersonaLabel, arg.ID, arg.Label, arg.Description)
 	return err
 }
 
@@ -2476,6 +5875,43 @@ func (q *Queries) UpdateProfileAfterScan(ctx context.Context, arg UpdateProfileA
 	return i, err
 }
 
+const updateProfileDuplicateStatus = `-- name: UpdateProfileDuplicateStatus :execrows
+UPDATE public.profile_duplicate SET status = $2, updated_at = NOW() WHERE id = $1
+`
+
+type UpdateProfileDuplicateStatusParams struct {
+	ID     int32  `json:"id"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) UpdateProfileDuplicateStatus(ctx context.Context, arg UpdateProfileDuplicateStatusParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateProfileDuplicateStatus, arg.ID, arg.Status)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateProfileLabel = `-- name: UpdateProfileLabel :execrows
+UPDATE public.user_profile_category
+SET label = $3
+WHERE user_profile_id = $1 AND category_id = $2
+`
+
+type UpdateProfileLabelParams struct {
+	UserProfileID int32         `json:"user_profile_id"`
+	CategoryID    int32         `json:"category_id"`
+	Label         sql.NullInt16 `json:"label"`
+}
+
+func (q *Queries) UpdateProfileLabel(ctx context.Context, arg UpdateProfileLabelParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateProfileLabel, arg.UserProfileID, arg.CategoryID, arg.Label)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateProfileScanStatus = `-- name: UpdateProfileScanStatus :one
 UPDATE public.user_profile
 SET updated_at = NOW(),
@@ -2514,29 +5950,69 @@ func (q *Queries) UpdateProfileScanStatus(ctx context.Context, id int32) (UserPr
 
 const updateRequestStatus = `-- name: UpdateRequestStatus :exec
 UPDATE public.request
-SET status = $2, updated_at = NOW(), error_message = $3, progress = $4, description = $5
-WHERE id = $1
+SET status = $1, updated_at = NOW(), error_message = $2, progress = $3, description = $4,
+result = COALESCE($5, request.result),
+started_at = COALESCE(request.started_at, CASE WHEN rs.name <> 'queued' THEN NOW() END),
+finished_at = CASE WHEN rs.is_final THEN NOW() END
+FROM public.request_status rs
+WHERE request.id = $6 AND rs.code = $1
 `
 
 type UpdateRequestStatusParams struct {
-	ID           int32          `json:"id"`
 	Status       int16          `json:"status"`
 	ErrorMessage sql.NullString `json:"error_message"`
 	Progress     float64        `json:"progress"`
 	Description  sql.NullString `json:"description"`
+	Result       NullableJSON   `json:"result"`
+	ID           int32          `json:"id"`
 }
 
 func (q *Queries) UpdateRequestStatus(ctx context.Context, arg UpdateRequestStatusParams) error {
 	_, err := q.db.ExecContext(ctx, updateRequestStatus,
-		arg.ID,
 		arg.Status,
 		arg.ErrorMessage,
 		arg.Progress,
 		arg.Description,
+		arg.Result,
+		arg.ID,
 	)
 	return err
 }
 
+const updateScoringCalibration = `-- name: UpdateScoringCalibration :one
+INSERT INTO public.scoring_policy (category_id, calibration_method, calibration, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    calibration_method = EXCLUDED.calibration_method,
+    calibration = EXCLUDED.calibration,
+    updated_at = NOW()
+RETURNING category_id, gemini_weight, model_weight, completeness_weight, min_completeness, decay_half_life_days, calibration_method, calibration, updated_at, intent_weight
+`
+
+type UpdateScoringCalibrationParams struct {
+	CategoryID        int32        `json:"category_id"`
+	CalibrationMethod string       `json:"calibration_method"`
+	Calibration       NullableJSON `json:"calibration"`
+}
+
+func (q *Queries) UpdateScoringCalibration(ctx context.Context, arg UpdateScoringCalibrationParams) (ScoringPolicy, error) {
+	row := q.db.QueryRowContext(ctx, updateScoringCalibration, arg.CategoryID, arg.CalibrationMethod, arg.Calibration)
+	var i ScoringPolicy
+	err := row.Scan(
+		&i.CategoryID,
+		&i.GeminiWeight,
+		&i.ModelWeight,
+		&i.CompletenessWeight,
+		&i.MinCompleteness,
+		&i.DecayHalfLifeDays,
+		&i.CalibrationMethod,
+		&i.Calibration,
+		&i.UpdatedAt,
+		&i.IntentWeight,
+	)
+	return i, err
+}
+
 const upsertConfig = `-- name: UpsertConfig :one
 INSERT INTO public.config ("key", "value")
 VALUES ($1, $2)
@@ -2561,6 +6037,7 @@ INSERT INTO public.embedded_profile (pid, cid, embedding, created_at)
 VALUES ($1, $2, $3, NOW())
 ON CONFLICT (pid, cid) DO UPDATE SET
     embedding = EXCLUDED.embedding,
+    persona_id = NULL,
     created_at = NOW()
 `
 
@@ -2574,3 +6051,370 @@ func (q *Queries) UpsertEmbeddedProfiles(ctx context.Context, arg UpsertEmbedded
 	_, err := q.db.ExecContext(ctx, upsertEmbeddedProfiles, arg.Pid, arg.Cid, arg.Embedding)
 	return err
 }
+
+const upsertLlmBudget = `-- name: UpsertLlmBudget :one
+INSERT INTO public.llm_budget (category_id, period, max_tokens, max_cost, is_active, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+ON CONFLICT ((COALESCE(category_id, 0)), period) DO UPDATE
+SET max_tokens = EXCLUDED.max_tokens,
+  max_cost = EXCLUDED.max_cost,
+  is_active = EXCLUDED.is_active,
+  updated_at = NOW()
+RETURNING id, category_id, period, max_tokens, max_cost, is_active, created_at, updated_at
+`
+
+type UpsertLlmBudgetParams struct {
+	CategoryID sql.NullInt32   `json:"category_id"`
+	Period     string          `json:"period"`
+	MaxTokens  sql.NullInt64   `json:"max_tokens"`
+	MaxCost    sql.NullFloat64 `json:"max_cost"`
+	IsActive   bool            `json:"is_active"`
+}
+
+func (q *Queries) UpsertLlmBudget(ctx context.Context, arg UpsertLlmBudgetParams) (LlmBudget, error) {
+	row := q.db.QueryRowContext(ctx, upsertLlmBudget,
+		arg.CategoryID,
+		arg.Period,
+		arg.MaxTokens,
+		arg.MaxCost,
+		arg.IsActive,
+	)
+	var i LlmBudget
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.Period,
+		&i.MaxTokens,
+		&i.MaxCost,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertLlmCacheResponse = `-- name: UpsertLlmCacheResponse :exec
+INSERT INTO public.llm_cache (prompt_hash, model, response, hit_count, created_at, expires_at)
+VALUES ($1, $2, $3, 0, NOW(), $4)
+ON CONFLICT (prompt_hash) DO UPDATE
+SET model = EXCLUDED.model,
+  response = EXCLUDED.response,
+  hit_count = 0,
+  created_at = NOW(),
+  expires_at = EXCLUDED.expires_at
+`
+
+type UpsertLlmCacheResponseParams struct {
+	PromptHash string    `json:"prompt_hash"`
+	Model      string    `json:"model"`
+	Response   string    `json:"response"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (q *Queries) UpsertLlmCacheResponse(ctx context.Context, arg UpsertLlmCacheResponseParams) error {
+	_, err := q.db.ExecContext(ctx, upsertLlmCacheResponse,
+		arg.PromptHash,
+		arg.Model,
+		arg.Response,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const upsertLlmPrice = `-- name: UpsertLlmPrice :one
+INSERT INTO public.llm_price (model, provider, input_price, output_price, updated_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (model) DO UPDATE
+SET provider = EXCLUDED.provider,
+  input_price = EXCLUDED.input_price,
+  output_price = EXCLUDED.output_price,
+  updated_at = NOW()
+RETURNING model, provider, input_price, output_price, updated_at
+`
+
+type UpsertLlmPriceParams struct {
+	Model       string  `json:"model"`
+	Provider    string  `json:"provider"`
+	InputPrice  float64 `json:"input_price"`
+	OutputPrice float64 `json:"output_price"`
+}
+
+func (q *Queries) UpsertLlmPrice(ctx context.Context, arg UpsertLlmPriceParams) (LlmPrice, error) {
+	row := q.db.QueryRowContext(ctx, upsertLlmPrice,
+		arg.Model,
+		arg.Provider,
+		arg.InputPrice,
+		arg.OutputPrice,
+	)
+	var i LlmPrice
+	err := row.Scan(
+		&i.Model,
+		&i.Provider,
+		&i.InputPrice,
+		&i.OutputPrice,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertLookalikeSeed = `-- name: UpsertLookalikeSeed :one
+INSERT INTO public.lookalike_seed (category_id, name)
+VALUES ($1, $2)
+ON CONFLICT (category_id, name) DO UPDATE SET
+    updated_at = NOW()
+RETURNING id, category_id, name, created_at, updated_at
+`
+
+type UpsertLookalikeSeedParams struct {
+	CategoryID int32  `json:"category_id"`
+	Name       string `json:"name"`
+}
+
+func (q *Queries) UpsertLookalikeSeed(ctx context.Context, arg UpsertLookalikeSeedParams) (LookalikeSeed, error) {
+	row := q.db.QueryRowContext(ctx, upsertLookalikeSeed, arg.CategoryID, arg.Name)
+	var i LookalikeSeed
+	err := row.Scan(
+		&i.ID,
+		&i.CategoryID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertModelDrift = `-- name: UpsertModelDrift :one
+INSERT INTO public.model_drift (category_id, day, version_id, sample_size, score_mean, score_std, psi, ks, completeness, completeness_drift, alerts, is_alert, created_at)
+VALUES ($1, CURRENT_DATE, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+ON CONFLICT (category_id, day) DO UPDATE SET
+    version_id = EXCLUDED.version_id,
+    sample_size = EXCLUDED.sample_size,
+    score_mean = EXCLUDED.score_mean,
+    score_std = EXCLUDED.score_std,
+    psi = EXCLUDED.psi,
+    ks = EXCLUDED.ks,
+    completeness = EXCLUDED.completeness,
+    completeness_drift = EXCLUDED.completeness_drift,
+    alerts = EXCLUDED.alerts,
+    is_alert = EXCLUDED.is_alert,
+    created_at = NOW()
+RETURNING category_id, day, version_id, sample_size, score_mean, score_std, psi, ks, completeness, completeness_drift, alerts, is_alert, created_at
+`
+
+type UpsertModelDriftParams struct {
+	CategoryID        int32         `json:"category_id"`
+	VersionID         sql.NullInt32 `json:"version_id"`
+	SampleSize        int32         `json:"sample_size"`
+	ScoreMean         float64       `json:"score_mean"`
+	ScoreStd          float64       `json:"score_std"`
+	Psi               float64       `json:"psi"`
+	Ks                float64       `json:"ks"`
+	Completeness      NullableJSON  `json:"completeness"`
+	CompletenessDrift float64       `json:"completeness_drift"`
+	Alerts            NullableJSON  `json:"alerts"`
+	IsAlert           bool          `json:"is_alert"`
+}
+
+func (q *Queries) UpsertModelDrift(ctx context.Context, arg UpsertModelDriftParams) (ModelDrift, error) {
+	row := q.db.QueryRowContext(ctx, upsertModelDrift,
+		arg.CategoryID,
+		arg.VersionID,
+		arg.SampleSize,
+		arg.ScoreMean,
+		arg.ScoreStd,
+		arg.Psi,
+		arg.Ks,
+		arg.Completeness,
+		arg.CompletenessDrift,
+		arg.Alerts,
+		arg.IsAlert,
+	)
+	var i ModelDrift
+	err := row.Scan(
+		&i.CategoryID,
+		&i.Day,
+		&i.VersionID,
+		&i.SampleSize,
+		&i.ScoreMean,
+		&i.ScoreStd,
+		&i.Psi,
+		&i.Ks,
+		&i.Completeness,
+		&i.CompletenessDrift,
+		&i.Alerts,
+		&i.IsAlert,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertProfileDuplicate = `-- name: UpsertProfileDuplicate :execrows
+INSERT INTO public.profile_duplicate (profile_id, duplicate_id, signals, similarity, confidence)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (profile_id, duplicate_id) DO UPDATE SET
+  signals = EXCLUDED.signals,
+  similarity = EXCLUDED.similarity,
+  confidence = EXCLUDED.confidence,
+  updated_at = NOW()
+WHERE profile_duplicate.status = 'pending'
+`
+
+type UpsertProfileDuplicateParams struct {
+	ProfileID   int32           `json:"profile_id"`
+	DuplicateID int32           `json:"duplicate_id"`
+	Signals     NullableJSON    `json:"signals"`
+	Similarity  sql.NullFloat64 `json:"similarity"`
+	Confidence  float64         `json:"confidence"`
+}
+
+// Records a merge candidate. Dismissed candidates are left untouched.
+func (q *Queries) UpsertProfileDuplicate(ctx context.Context, arg UpsertProfileDuplicateParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, upsertProfileDuplicate,
+		arg.ProfileID,
+		arg.DuplicateID,
+		arg.Signals,
+		arg.Similarity,
+		arg.Confidence,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertRetrainPolicy = `-- name: UpsertRetrainPolicy :one
+INSERT INTO public.retrain_policy (category_id, model_name, is_enabled, interval_hours, min_new_profiles, rmse_margin, r2_margin, auto_tune, trials, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    model_name = EXCLUDED.model_name,
+    is_enabled = EXCLUDED.is_enabled,
+    interval_hours = EXCLUDED.interval_hours,
+    min_new_profiles = EXCLUDED.min_new_profiles,
+    rmse_margin = EXCLUDED.rmse_margin,
+    r2_margin = EXCLUDED.r2_margin,
+    auto_tune = EXCLUDED.auto_tune,
+    trials = EXCLUDED.trials,
+    updated_at = NOW()
+RETURNING category_id, model_name, is_enabled, interval_hours, min_new_profiles, rmse_margin, r2_margin, auto_tune, trials, last_run_at, profiles_at_last_run, last_request_id, updated_at
+`
+
+type UpsertRetrainPolicyParams struct {
+	CategoryID     int32         `json:"category_id"`
+	ModelName      string        `json:"model_name"`
+	IsEnabled      bool          `json:"is_enabled"`
+	IntervalHours  int32         `json:"interval_hours"`
+	MinNewProfiles int32         `json:"min_new_profiles"`
+	RmseMargin     float64       `json:"rmse_margin"`
+	R2Margin       float64       `json:"r2_margin"`
+	AutoTune       bool          `json:"auto_tune"`
+	Trials         sql.NullInt32 `json:"trials"`
+}
+
+func (q *Queries) UpsertRetrainPolicy(ctx context.Context, arg UpsertRetrainPolicyParams) (RetrainPolicy, error) {
+	row := q.db.QueryRowContext(ctx, upsertRetrainPolicy,
+		arg.CategoryID,
+		arg.ModelName,
+		arg.IsEnabled,
+		arg.IntervalHours,
+		arg.MinNewProfiles,
+		arg.RmseMargin,
+		arg.R2Margin,
+		arg.AutoTune,
+		arg.Trials,
+	)
+	var i RetrainPolicy
+	err := row.Scan(
+		&i.CategoryID,
+		&i.ModelName,
+		&i.IsEnabled,
+		&i.IntervalHours,
+		&i.MinNewProfiles,
+		&i.RmseMargin,
+		&i.R2Margin,
+		&i.AutoTune,
+		&i.Trials,
+		&i.LastRunAt,
+		&i.ProfilesAtLastRun,
+		&i.LastRequestID,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertScoringPolicy = `-- name: UpsertScoringPolicy :one
+INSERT INTO public.scoring_policy (category_id, gemini_weight, model_weight, completeness_weight, min_completeness, decay_half_life_days, intent_weight, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    gemini_weight = EXCLUDED.gemini_weight,
+    model_weight = EXCLUDED.model_weight,
+    completeness_weight = EXCLUDED.completeness_weight,
+    min_completeness = EXCLUDED.min_completeness,
+    decay_half_life_days = EXCLUDED.decay_half_life_days,
+    intent_weight = EXCLUDED.intent_weight,
+    updated_at = NOW()
+RETURNING category_id, gemini_weight, model_weight, completeness_weight, min_completeness, decay_half_life_days, calibration_method, calibration, updated_at, intent_weight
+`
+
+type UpsertScoringPolicyParams struct {
+	CategoryID         int32   `json:"category_id"`
+	GeminiWeight       float64 `json:"gemini_weight"`
+	ModelWeight        float64 `json:"model_weight"`
+	CompletenessWeight float64 `json:"completeness_weight"`
+	MinCompleteness    int32   `json:"min_completeness"`
+	DecayHalfLifeDays  float64 `json:"decay_half_life_days"`
+	IntentWeight       float64 `json:"intent_weight"`
+}
+
+func (q *Queries) UpsertScoringPolicy(ctx context.Context, arg UpsertScoringPolicyParams) (ScoringPolicy, error) {
+	row := q.db.QueryRowContext(ctx, upsertScoringPolicy,
+		arg.CategoryID,
+		arg.GeminiWeight,
+		arg.ModelWeight,
+		arg.CompletenessWeight,
+		arg.MinCompleteness,
+		arg.DecayHalfLifeDays,
+		arg.IntentWeight,
+	)
+	var i ScoringPolicy
+	err := row.Scan(
+		&i.CategoryID,
+		&i.GeminiWeight,
+		&i.ModelWeight,
+		&i.CompletenessWeight,
+		&i.MinCompleteness,
+		&i.DecayHalfLifeDays,
+		&i.CalibrationMethod,
+		&i.Calibration,
+		&i.UpdatedAt,
+		&i.IntentWeight,
+	)
+	return i, err
+}
+
+const upsertShadowScore = `-- name: UpsertShadowScore :exec
+INSERT INTO public.model_shadow_score (version_id, user_profile_id, category_id, score, champion_score, scored_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (version_id, user_profile_id, category_id) DO UPDATE SET
+    score = EXCLUDED.score,
+    champion_score = EXCLUDED.champion_score,
+    scored_at = NOW()
+`
+
+type UpsertShadowScoreParams struct {
+	VersionID     int32           `json:"version_id"`
+	UserProfileID int32           `json:"user_profile_id"`
+	CategoryID    int32           `json:"category_id"`
+	Score         float64         `json:"score"`
+	ChampionScore sql.NullFloat64 `json:"champion_score"`
+}
+
+func (q *Queries) UpsertShadowScore(ctx context.Context, arg UpsertShadowScoreParams) error {
+	_, err := q.db.ExecContext(ctx, upsertShadowScore,
+		arg.VersionID,
+		arg.UserProfileID,
+		arg.CategoryID,
+		arg.Score,
+		arg.ChampionScore,
+	)
+	return err
+}
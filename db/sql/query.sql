@@ -121,6 +121,22 @@ INSERT INTO public.user_profile_category (user_profile_id, category_id)
 VALUES ($1, $2)
 ON CONFLICT (user_profile_id, category_id) DO NOTHING;
 
+-- name: AddProfileToGroupCategories :exec
+INSERT INTO public.user_profile_category (user_profile_id, category_id)
+SELECT $1, gc.category_id
+FROM public.group_category gc
+WHERE gc.group_id = $2
+ON CONFLICT (user_profile_id, category_id) DO NOTHING;
+
+-- name: BackfillProfileCategoriesFromComments :execrows
+INSERT INTO public.user_profile_category (user_profile_id, category_id, created_at)
+SELECT DISTINCT c.author_id, gc.category_id, NOW()
+FROM public.comment c
+JOIN public.post p ON p.id = c.post_id
+JOIN public.group_category gc ON gc.group_id = p.group_id
+WHERE (sqlc.narg(category_id)::int IS NULL OR gc.category_id = sqlc.narg(category_id)::int)
+ON CONFLICT (user_profile_id, category_id) DO NOTHING;
+
 -- name: GetProfileById :one
 SELECT *, COALESCE((SELECT json_agg(c) FROM public.user_profile_category c WHERE c.user_profile_id = up.id), '[]'::json)::jsonb as categories
 FROM public.user_profile up WHERE id = $1;
@@ -210,6 +226,7 @@ INSERT INTO public.embedded_profile (pid, cid, embedding, created_at)
 VALUES ($1, $2, $3, NOW())
 ON CONFLICT (pid, cid) DO UPDATE SET
     embedding = EXCLUDED.embedding,
+    persona_id = NULL,
     created_at = NOW();
 
 -- name: CountProfiles :one
@@ -224,7 +241,18 @@ RETURNING *;
 
 -- name: ResetProfilesModelScore :exec
 UPDATE public.user_profile_category
-SET model_score = NULL;
+SET model_score = NULL,
+    model_version_id = NULL,
+    model_input_hash = NULL,
+    model_contributions = NULL;
+
+-- name: ResetCategoryModelScores :execrows
+UPDATE public.user_profile_category
+SET model_score = NULL,
+    model_version_id = NULL,
+    model_input_hash = NULL,
+    model_contributions = NULL
+WHERE category_id = $1;
 
 -- name: UpdateGeminiAnalysisProfile :exec
 UPDATE public.user_profile
@@ -234,7 +262,8 @@ WHERE id = $1;
 
 -- name: UpdateGeminiScore :exec
 UPDATE public.user_profile_category
-SET gemini_score = $3
+SET gemini_score = $3,
+    final_score_at = NULL
 WHERE user_profile_id = $1 AND category_id = $2;
 
 -- name: UpdateProfileAfterScan :one
@@ -261,17 +290,36 @@ SELECT up.*, ep.embedding, ep.cid as category_id FROM public.user_profile up
 JOIN public.embedded_profile ep ON up.id = ep.pid
 WHERE ep.cid = $1;
 
+-- Unscored profiles come first, then the ones scored by another model
+-- version or from inputs that changed since, oldest scores first.
 -- name: GetProfilesForScoring :many
-SELECT up.id FROM public.user_profile up
-JOIN public.embedded_profile ep ON up.id = ep.pid AND ep.cid = $1
-JOIN public.user_profile_category upc ON up.id = upc.user_profile_id
-WHERE up.is_scanned = true AND upc.model_score IS NULL
-AND upc.category_id = $1
-LIMIT $2;
+WITH candidate AS (
+    SELECT up.id, upc.model_score, upc.model_scored_at, upc.model_version_id, upc.model_input_hash,
+        md5(concat_ws('|', up.gender, up.relationship_status, up.locale, up.birthday, ep.created_at)) AS input_hash
+    FROM public.user_profile up
+    JOIN public.embedded_profile ep ON up.id = ep.pid AND ep.cid = @category_id
+    JOIN public.user_profile_category upc ON up.id = upc.user_profile_id AND upc.category_id = @category_id
+    WHERE up.is_scanned = true
+)
+SELECT id, input_hash::text AS input_hash FROM candidate
+WHERE model_score IS NULL
+    OR model_version_id IS DISTINCT FROM sqlc.narg(version_id)::int
+    OR model_input_hash IS DISTINCT FROM input_hash
+ORDER BY model_score IS NOT NULL, model_scored_at NULLS FIRST
+LIMIT @page_limit;
+
+-- name: GetProfileCategory :one
+SELECT * FROM public.user_profile_category
+WHERE user_profile_id = $1 AND category_id = $2;
 
 -- name: UpdateModelScore :exec
 UPDATE public.user_profile_category
-SET model_score = $3
+SET model_score = $3,
+    model_scored_at = NOW(),
+    model_version_id = $4,
+    model_input_hash = $5,
+    model_contributions = $6,
+    final_score_at = NULL
 WHERE user_profile_id = $1 AND category_id = $2;
 
 -- name: ImportProfile :one
@@ -447,18 +495,36 @@ WHERE api_key = $1
 RETURNING *;
 
 -- name: CreateRequest :one
-INSERT INTO public.request(description)
-VALUES ($1)
+INSERT INTO public.request(kind, description)
+VALUES ($1, $2)
 RETURNING id;
 
 -- name: UpdateRequestStatus :exec
 UPDATE public.request
-SET status = $2, updated_at = NOW(), error_message = $3, progress = $4, description = $5
-WHERE id = $1;
+SET status = sqlc.arg(status), updated_at = NOW(), error_message = sqlc.narg(error_message), progress = sqlc.arg(progress), description = sqlc.narg(description),
+result = COALESCE(sqlc.narg(result), request.result),
+started_at = COALESCE(request.started_at, CASE WHEN rs.name <> 'queued' THEN NOW() END),
+finished_at = CASE WHEN rs.is_final THEN NOW() END
+FROM public.request_status rs
+WHERE request.id = sqlc.arg(id) AND rs.code = sqlc.arg(status);
 
 -- name: GetRequestById :one
 SELECT * FROM public.request WHERE id = $1;
 
+-- name: InterruptRequests :execrows
+UPDATE public.request
+SET status = interrupted.code, updated_at = NOW(), finished_at = NOW(),
+error_message = COALESCE(request.error_message, 'Interrupted by a server restart')
+FROM public.request_status interrupted, public.request_status rs
+WHERE interrupted.name = 'interrupted'
+  AND rs.code = request.status
+  AND NOT rs.is_final;
+
+-- name: DeleteFinishedRequests :many
+DELETE FROM public.request
+WHERE finished_at < @finished_before::timestamp
+RETURNING id;
+
 -- name: GetProfileEmbedding :one
 SELECT embedding FROM public.embedded_profile WHERE pid = $1 AND cid = $2;
 
@@ -478,6 +544,46 @@ ORDER BY ep.embedding <=> (
   )
 LIMIT $2;
 
+-- Nearest profiles of a category to a query embedding. Filters are skipped
+-- when NULL, min_score applies to the lead score or the model score.
+-- name: SearchProfilesByEmbedding :many
+SELECT
+  p.id AS profile_id,
+  p.profile_url,
+  p.name AS profile_name,
+  p.location,
+  p.updated_at AS scanned_at,
+  upc.model_score,
+  upc.final_score,
+  CAST(1 - (ep.embedding <=> @query::vector) AS DOUBLE PRECISION) AS similarity
+FROM public.embedded_profile ep
+JOIN public.user_profile p ON p.id = ep.pid
+JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = ep.cid
+WHERE ep.cid = @category_id AND p.is_scanned = true
+  AND (sqlc.narg(location)::text IS NULL
+    OR p.location ILIKE '%' || sqlc.narg(location)::text || '%'
+    OR p.hometown ILIKE '%' || sqlc.narg(location)::text || '%')
+  AND (sqlc.narg(scanned_after)::timestamp IS NULL OR p.updated_at >= sqlc.narg(scanned_after)::timestamp)
+  AND (sqlc.narg(scanned_before)::timestamp IS NULL OR p.updated_at < sqlc.narg(scanned_before)::timestamp)
+  AND (sqlc.narg(min_score)::float8 IS NULL OR COALESCE(upc.final_score, upc.model_score) >= sqlc.narg(min_score)::float8)
+ORDER BY ep.embedding <=> @query::vector
+LIMIT @page_limit;
+
+-- Vector indexes of embedded_profile, invalid ones are left by failed
+-- concurrent builds.
+-- name: GetEmbeddingIndexes :many
+SELECT
+  i.indexname::text AS name,
+  i.indexdef::text AS definition,
+  pg_relation_size(c.oid)::bigint AS size_bytes,
+  x.indisvalid AS is_valid
+FROM pg_indexes i
+JOIN pg_class c ON c.relname = i.indexname AND c.relnamespace = 'public'::regnamespace
+JOIN pg_index x ON x.indexrelid = c.oid
+WHERE i.schemaname = 'public' AND i.tablename = 'embedded_profile'
+  AND i.indexdef LIKE '%vector_%_ops%'
+ORDER BY i.indexname;
+
 -- name: GetDashboardStats :one
 SELECT
   (SELECT COUNT(*) FROM public."group") AS total_groups,
@@ -490,7 +596,10 @@ SELECT
   (SELECT COUNT(*) FROM public.user_profile WHERE is_analyzed = true) AS analyzed_profiles,
   (SELECT COUNT(*) FROM public.account) AS total_accounts,
   (SELECT COUNT(*) FROM public.account WHERE is_block = false and access_token IS NOT NULL) AS active_accounts,
-  (SELECT COUNT(*) FROM public.account WHERE is_block = true) AS blocked_accounts;
+  (SELECT COUNT(*) FROM public.account WHERE is_block = true) AS blocked_accounts,
+  (SELECT COUNT(*) FROM public.llm_cache WHERE expires_at > NOW()) AS llm_cache_entries,
+  (SELECT COALESCE(SUM(hits), 0) FROM public.llm_cache_stat)::bigint AS llm_cache_hits,
+  (SELECT COALESCE(SUM(misses), 0) FROM public.llm_cache_stat)::bigint AS llm_cache_misses;
 
 -- name: GetTimeSeriesData :many
 SELECT 
@@ -610,4 +719,956 @@ SELECT * FROM public.model WHERE id = $1;
 SELECT * FROM public.model WHERE category_id = $1;
 
 -- name: GetModelsWithoutCategory :many
-SELECT * FROM public.model WHERE category_id IS NULL ORDER BY created_at DESC;
\ No newline at end of file
+SELECT * FROM public.model WHERE category_id IS NULL ORDER BY created_at DESC;
+
+-- name: GetModelByName :one
+SELECT * FROM public.model WHERE name = $1 ORDER BY id LIMIT 1;
+
+-- name: AssignModelCategory :exec
+UPDATE public.model
+SET category_id = CASE WHEN id = $1 THEN $2 ELSE NULL END
+WHERE id = $1 OR category_id = $2;
+
+-- Model registry queries
+-- name: GetNextModelVersion :one
+SELECT (COALESCE(MAX(version), 0) + 1)::integer AS version
+FROM public.model_version WHERE model_id = $1;
+
+-- Serialises the registration of versions of a model until the end of the
+-- transaction, so two runs can't take the same version number.
+-- name: LockModelVersions :exec
+SELECT pg_advisory_xact_lock(hashtext(@name::text));
+
+-- name: CreateModelVersion :one
+INSERT INTO public.model_version (model_id, version, category_id, path, metrics, dataset_snapshot_id, dataset_size, prompt_service, prompt_version, parent_version_id, request_id, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetModelVersionByID :one
+SELECT * FROM public.model_version WHERE id = $1;
+
+-- name: GetModelVersionByPath :one
+SELECT * FROM public.model_version WHERE path = $1;
+
+-- name: GetModelVersions :many
+SELECT * FROM public.model_version WHERE model_id = $1 ORDER BY version DESC;
+
+-- name: GetAllModelVersions :many
+SELECT * FROM public.model_version ORDER BY model_id, version;
+
+-- name: GetLatestModelVersion :one
+SELECT * FROM public.model_version WHERE model_id = $1 ORDER BY version DESC LIMIT 1;
+
+-- name: DeleteModelVersion :exec
+DELETE FROM public.model_version WHERE id = $1;
+
+-- name: GetModelVersionLineage :many
+WITH RECURSIVE lineage AS (
+    SELECT mv.id, mv.parent_version_id, 0 AS depth
+    FROM public.model_version mv WHERE mv.id = $1
+    UNION ALL
+    SELECT p.id, p.parent_version_id, l.depth + 1
+    FROM public.model_version p
+    JOIN lineage l ON p.id = l.parent_version_id
+    WHERE l.depth < 100
+)
+SELECT mv.id, mv.model_id, m.name AS model_name, mv.version, mv.category_id, mv.path, mv.metrics,
+    mv.dataset_snapshot_id, mv.dataset_size, mv.prompt_service, mv.prompt_version,
+    mv.parent_version_id, mv.created_at, l.depth::integer AS depth
+FROM lineage l
+JOIN public.model_version mv ON mv.id = l.id
+JOIN public.model m ON m.id = mv.model_id
+ORDER BY l.depth;
+
+-- name: GetModelDeployment :one
+SELECT * FROM public.model_deployment WHERE category_id = $1;
+
+-- name: PromoteModelVersion :one
+INSERT INTO public.model_deployment (category_id, champion_version_id, updated_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    champion_version_id = EXCLUDED.champion_version_id,
+    challenger_version_id = CASE
+        WHEN model_deployment.challenger_version_id = EXCLUDED.champion_version_id THEN NULL
+        ELSE model_deployment.challenger_version_id
+    END,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: SetModelChallenger :one
+INSERT INTO public.model_deployment (category_id, challenger_version_id, challenger_sample_rate, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    challenger_version_id = EXCLUDED.challenger_version_id,
+    challenger_sample_rate = EXCLUDED.challenger_sample_rate,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: UpsertShadowScore :exec
+INSERT INTO public.model_shadow_score (version_id, user_profile_id, category_id, score, champion_score, scored_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (version_id, user_profile_id, category_id) DO UPDATE SET
+    score = EXCLUDED.score,
+    champion_score = EXCLUDED.champion_score,
+    scored_at = NOW();
+
+-- name: GetShadowScores :many
+SELECT mss.user_profile_id, mss.score, mss.champion_score, upc.gemini_score
+FROM public.model_shadow_score mss
+LEFT JOIN public.user_profile_category upc
+    ON upc.user_profile_id = mss.user_profile_id AND upc.category_id = mss.category_id
+WHERE mss.version_id = $1 AND mss.category_id = $2 AND mss.champion_score IS NOT NULL;
+
+-- Retraining queries
+-- name: GetRetrainPolicies :many
+SELECT * FROM public.retrain_policy ORDER BY category_id;
+
+-- name: GetRetrainPolicy :one
+SELECT * FROM public.retrain_policy WHERE category_id = $1;
+
+-- name: UpsertRetrainPolicy :one
+INSERT INTO public.retrain_policy (category_id, model_name, is_enabled, interval_hours, min_new_profiles, rmse_margin, r2_margin, auto_tune, trials, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    model_name = EXCLUDED.model_name,
+    is_enabled = EXCLUDED.is_enabled,
+    interval_hours = EXCLUDED.interval_hours,
+    min_new_profiles = EXCLUDED.min_new_profiles,
+    rmse_margin = EXCLUDED.rmse_margin,
+    r2_margin = EXCLUDED.r2_margin,
+    auto_tune = EXCLUDED.auto_tune,
+    trials = EXCLUDED.trials,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteRetrainPolicy :exec
+DELETE FROM public.retrain_policy WHERE category_id = $1;
+
+-- name: MarkRetrainPolicyRun :exec
+UPDATE public.retrain_policy
+SET last_run_at = NOW(),
+    profiles_at_last_run = $2,
+    last_request_id = $3
+WHERE category_id = $1;
+
+-- name: CountLabeledProfiles :one
+SELECT COUNT(*) FROM public.user_profile_category
+WHERE category_id = $1 AND (gemini_score IS NOT NULL OR label IS NOT NULL);
+
+-- Drift monitoring queries
+-- name: GetRecentModelScores :many
+SELECT upc.model_score::double precision AS model_score
+FROM public.user_profile_category upc
+WHERE upc.category_id = @category_id AND upc.model_version_id = @version_id
+  AND upc.model_score IS NOT NULL AND upc.model_scored_at >= @since::timestamp;
+
+-- name: GetRecentFeatureCompleteness :one
+SELECT COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE EXISTS (
+        SELECT 1 FROM public.embedded_profile ep
+        WHERE ep.pid = up.id AND ep.cid = upc.category_id AND ep.embedding IS NOT NULL
+    )) AS with_embedding,
+    COUNT(*) FILTER (WHERE up.gender IS NOT NULL AND up.gender <> '') AS with_gender,
+    COUNT(*) FILTER (WHERE up.relationship_status IS NOT NULL AND up.relationship_status <> '') AS with_relationship_status,
+    COUNT(*) FILTER (WHERE up.locale <> '' AND up.locale <> 'NOT_SPECIFIED') AS with_locale
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+WHERE upc.category_id = @category_id AND upc.model_version_id = @version_id
+  AND upc.model_score IS NOT NULL AND upc.model_scored_at >= @since::timestamp;
+
+-- name: UpsertModelDrift :one
+INSERT INTO public.model_drift (category_id, day, version_id, sample_size, score_mean, score_std, psi, ks, completeness, completeness_drift, alerts, is_alert, created_at)
+VALUES ($1, CURRENT_DATE, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+ON CONFLICT (category_id, day) DO UPDATE SET
+    version_id = EXCLUDED.version_id,
+    sample_size = EXCLUDED.sample_size,
+    score_mean = EXCLUDED.score_mean,
+    score_std = EXCLUDED.score_std,
+    psi = EXCLUDED.psi,
+    ks = EXCLUDED.ks,
+    completeness = EXCLUDED.completeness,
+    completeness_drift = EXCLUDED.completeness_drift,
+    alerts = EXCLUDED.alerts,
+    is_alert = EXCLUDED.is_alert,
+    created_at = NOW()
+RETURNING *;
+
+-- name: GetModelDrift :many
+SELECT * FROM public.model_drift
+WHERE category_id = $1
+ORDER BY day DESC
+LIMIT $2;
+
+-- name: GetModelDriftAlerts :many
+SELECT * FROM public.model_drift
+WHERE is_alert AND day >= @since::date
+ORDER BY day DESC, category_id;
+
+-- Lead scoring queries
+-- name: GetScoringPolicy :one
+SELECT * FROM public.scoring_policy WHERE category_id = $1;
+
+-- name: UpsertScoringPolicy :one
+INSERT INTO public.scoring_policy (category_id, gemini_weight, model_weight, completeness_weight, min_completeness, decay_half_life_days, intent_weight, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    gemini_weight = EXCLUDED.gemini_weight,
+    model_weight = EXCLUDED.model_weight,
+    completeness_weight = EXCLUDED.completeness_weight,
+    min_completeness = EXCLUDED.min_completeness,
+    decay_half_life_days = EXCLUDED.decay_half_life_days,
+    intent_weight = EXCLUDED.intent_weight,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: UpdateScoringCalibration :one
+INSERT INTO public.scoring_policy (category_id, calibration_method, calibration, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (category_id) DO UPDATE SET
+    calibration_method = EXCLUDED.calibration_method,
+    calibration = EXCLUDED.calibration,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetLeadScoreInputs :many
+SELECT upc.user_profile_id,
+  upc.category_id,
+  upc.gemini_score,
+  upc.model_score,
+  upc.intent_score,
+  up.updated_at,
+  ((COALESCE(up.bio, '') != '')::int +
+  (COALESCE(up.location, '') != '')::int +
+  (COALESCE(up.work, '') != '')::int +
+  (COALESCE(up.locale, '') != '')::int +
+  (COALESCE(up.education, '') != '')::int +
+  (COALESCE(up.relationship_status, '') != '')::int +
+  (COALESCE(up.hometown, '') != '')::int +
+  (COALESCE(up.gender, '') != '')::int +
+  (COALESCE(up.birthday, '') != '')::int +
+  (COALESCE(up.email, '') != '')::int +
+  (COALESCE(up.phone, '') != '')::int)::int AS non_null_count
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+LEFT JOIN public.scoring_policy sp ON sp.category_id = upc.category_id
+WHERE upc.category_id = $1
+AND (upc.gemini_score IS NOT NULL OR upc.model_score IS NOT NULL OR upc.intent_score IS NOT NULL)
+AND (
+  upc.final_score_at IS NULL
+  OR upc.final_score_at < up.updated_at
+  OR upc.final_score_at < sp.updated_at
+  OR upc.final_score_at < NOW() - INTERVAL '1 day'
+)
+ORDER BY upc.final_score_at ASC NULLS FIRST
+LIMIT $2;
+
+-- name: UpdateFinalScore :exec
+UPDATE public.user_profile_category
+SET final_score = $3,
+    final_score_at = NOW()
+WHERE user_profile_id = $1 AND category_id = $2;
+
+-- name: GetCalibrationSamples :many
+SELECT upc.gemini_score,
+  upc.model_score,
+  upc.intent_score,
+  up.updated_at,
+  ((COALESCE(up.bio, '') != '')::int +
+  (COALESCE(up.location, '') != '')::int +
+  (COALESCE(up.work, '') != '')::int +
+  (COALESCE(up.locale, '') != '')::int +
+  (COALESCE(up.education, '') != '')::int +
+  (COALESCE(up.relationship_status, '') != '')::int +
+  (COALESCE(up.hometown, '') != '')::int +
+  (COALESCE(up.gender, '') != '')::int +
+  (COALESCE(up.birthday, '') != '')::int +
+  (COALESCE(up.email, '') != '')::int +
+  (COALESCE(up.phone, '') != '')::int)::int AS non_null_count,
+  upc.label
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+WHERE upc.category_id = $1
+AND upc.label IS NOT NULL
+AND (upc.gemini_score IS NOT NULL OR upc.model_score IS NOT NULL OR upc.intent_score IS NOT NULL);
+
+-- name: UpdateProfileLabel :execrows
+UPDATE public.user_profile_category
+SET label = $3
+WHERE user_profile_id = $1 AND category_id = $2;
+
+-- name: GetLeads :many
+SELECT up.id,
+  up.facebook_id,
+  up.name,
+  up.profile_url,
+  up.email,
+  up.phone,
+  up.location,
+  upc.gemini_score,
+  upc.model_score,
+  upc.final_score,
+  upc.final_score_at,
+  upc.label
+FROM public.user_profile_category upc
+JOIN public.user_profile up ON up.id = upc.user_profile_id
+WHERE upc.category_id = sqlc.arg(category_id)
+AND upc.final_score IS NOT NULL
+AND upc.final_score >= sqlc.arg(min_score)::float8
+ORDER BY
+  CASE WHEN sqlc.arg(ascending)::bool THEN upc.final_score END ASC,
+  CASE WHEN NOT sqlc.arg(ascending)::bool THEN upc.final_score END DESC,
+  up.id ASC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountLeads :one
+SELECT COUNT(*) FROM public.user_profile_category
+WHERE category_id = sqlc.arg(category_id)
+AND final_score IS NOT NULL
+AND final_score >= sqlc.arg(min_score)::float8;
+
+-- Comments of the category's groups not classified for the category yet,
+-- leaving out the ones that failed max_attempts times.
+-- name: GetCommentsForIntentAnalysis :many
+SELECT c.id, c.content, c.author_id, c.post_id, p.content AS post_content
+FROM public.comment c
+JOIN public.post p ON p.id = c.post_id
+JOIN public.group_category gc ON gc.group_id = p.group_id
+LEFT JOIN public.comment_intent ci ON ci.comment_id = c.id AND ci.category_id = gc.category_id
+WHERE c.is_analyzed = false
+  AND gc.category_id = @category_id
+  AND ci.analyzed_at IS NULL
+  AND COALESCE(ci.attempts, 0) < @max_attempts::int
+ORDER BY c.inserted_at ASC
+LIMIT @page_limit;
+
+-- Counts an attempt to classify the comments for a category, whether the
+-- model answers for them or not.
+-- name: AddCommentIntentAttempts :exec
+INSERT INTO public.comment_intent (comment_id, category_id, attempts)
+SELECT unnest(@comment_ids::int[]), @category_id::int, 1
+ON CONFLICT (comment_id, category_id) DO UPDATE SET
+  attempts = comment_intent.attempts + 1,
+  updated_at = NOW();
+
+-- name: UpdateCommentIntent :exec
+INSERT INTO public.comment_intent (comment_id, category_id, intent, intent_confidence, intents, analyzed_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (comment_id, category_id) DO UPDATE SET
+  intent = EXCLUDED.intent,
+  intent_confidence = EXCLUDED.intent_confidence,
+  intents = EXCLUDED.intents,
+  analyzed_at = EXCLUDED.analyzed_at,
+  updated_at = NOW();
+
+-- Copies the most confident intent over the categories to the comments, and
+-- marks them analyzed once every category of their group classified them or
+-- ran out of attempts.
+-- name: SyncCommentIntents :exec
+UPDATE public.comment c
+SET intent = COALESCE(s.intent, c.intent),
+    intent_confidence = COALESCE(s.intent_confidence, c.intent_confidence),
+    intents = COALESCE(s.intents, c.intents),
+    analyzed_at = COALESCE(s.analyzed_at, c.analyzed_at),
+    is_analyzed = s.done
+FROM (
+  SELECT cm.id, best.intent, best.intent_confidence, best.intents, best.analyzed_at,
+    NOT EXISTS (
+      SELECT 1 FROM public.post p
+      JOIN public.group_category gc ON gc.group_id = p.group_id
+      LEFT JOIN public.comment_intent ci ON ci.comment_id = cm.id AND ci.category_id = gc.category_id
+      WHERE p.id = cm.post_id
+        AND ci.analyzed_at IS NULL
+        AND COALESCE(ci.attempts, 0) < @max_attempts::int
+    ) AS done
+  FROM public.comment cm
+  LEFT JOIN LATERAL (
+    SELECT ci.intent, ci.intent_confidence, ci.intents, ci.analyzed_at
+    FROM public.comment_intent ci
+    WHERE ci.comment_id = cm.id AND ci.analyzed_at IS NOT NULL
+    ORDER BY ci.intent_confidence DESC NULLS LAST, ci.analyzed_at DESC
+    LIMIT 1
+  ) best ON true
+  WHERE cm.id = ANY(@comment_ids::int[])
+) s
+WHERE c.id = s.id;
+
+-- name: UpdateIntentScore :exec
+UPDATE public.user_profile_category
+SET intent_score = GREATEST(COALESCE(intent_score, 0), sqlc.arg(intent_score)::float8),
+    final_score_at = NULL
+WHERE user_profile_id = sqlc.arg(user_profile_id)
+AND category_id = sqlc.arg(category_id);
+
+-- Trigger rule queries
+-- name: GetActiveTriggerRules :many
+SELECT * FROM public.trigger_rule
+WHERE is_active = true
+ORDER BY category_id, id;
+
+-- name: GetTriggerRulesByCategory :many
+SELECT * FROM public.trigger_rule
+WHERE category_id = $1
+ORDER BY created_at DESC;
+
+-- name: CreateTriggerRule :one
+INSERT INTO public.trigger_rule (category_id, name, keywords, patterns, negative_keywords, language, is_active, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+RETURNING *;
+
+-- name: SetTriggerRuleActive :exec
+UPDATE public.trigger_rule
+SET is_active = $2
+WHERE id = $1;
+
+-- name: DeleteTriggerRule :exec
+DELETE FROM public.trigger_rule WHERE id = $1;
+
+-- name: CreateTriggerRuleHit :execrows
+INSERT INTO public.trigger_rule_hit (rule_id, category_id, user_profile_id, source_type, source_id, matched, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (rule_id, user_profile_id, source_type, source_id) DO NOTHING;
+
+-- name: GetTriggerRuleHits :many
+SELECT h.id,
+  h.rule_id,
+  r.name AS rule_name,
+  h.user_profile_id,
+  up.name AS profile_name,
+  h.source_type,
+  h.source_id,
+  h.matched,
+  h.created_at
+FROM public.trigger_rule_hit h
+JOIN public.trigger_rule r ON r.id = h.rule_id
+JOIN public.user_profile up ON up.id = h.user_profile_id
+WHERE h.category_id = $1
+ORDER BY h.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountTriggerRuleHits :one
+SELECT COUNT(*) FROM public.trigger_rule_hit WHERE category_id = $1;
+
+-- LLM response cache queries
+-- name: GetLlmCacheResponse :one
+UPDATE public.llm_cache
+SET hit_count = hit_count + 1
+WHERE prompt_hash = $1 AND expires_at > NOW()
+RETURNING response;
+
+-- name: UpsertLlmCacheResponse :exec
+INSERT INTO public.llm_cache (prompt_hash, model, response, hit_count, created_at, expires_at)
+VALUES ($1, $2, $3, 0, NOW(), $4)
+ON CONFLICT (prompt_hash) DO UPDATE
+SET model = EXCLUDED.model,
+  response = EXCLUDED.response,
+  hit_count = 0,
+  created_at = NOW(),
+  expires_at = EXCLUDED.expires_at;
+
+-- name: AddLlmCacheStat :exec
+INSERT INTO public.llm_cache_stat (date, hits, misses)
+VALUES (CURRENT_DATE, $1, $2)
+ON CONFLICT (date) DO UPDATE
+SET hits = llm_cache_stat.hits + EXCLUDED.hits,
+  misses = llm_cache_stat.misses + EXCLUDED.misses;
+
+-- name: DeleteLlmCache :execrows
+DELETE FROM public.llm_cache
+WHERE (sqlc.narg(model)::varchar IS NULL OR model = sqlc.narg(model)::varchar)
+  AND (NOT sqlc.arg(expired_only)::boolean OR expires_at <= NOW());
+
+-- LLM usage ledger queries
+-- name: CreateLlmUsage :exec
+INSERT INTO public.llm_usage (key_id, provider, model, category_id, job, prompt_tokens, output_tokens, cost, created_at)
+VALUES (
+  (SELECT id FROM public.gemini_key WHERE api_key = sqlc.arg(api_key)),
+  sqlc.arg(provider),
+  sqlc.arg(model),
+  sqlc.narg(category_id),
+  sqlc.arg(job),
+  sqlc.arg(prompt_tokens),
+  sqlc.arg(output_tokens),
+  COALESCE((
+    SELECT (sqlc.arg(prompt_tokens) * p.input_price + sqlc.arg(output_tokens) * p.output_price) / 1000000
+    FROM public.llm_price p
+    WHERE p.model = sqlc.arg(model)
+  ), 0),
+  NOW()
+);
+
+-- name: GetDailySpend :many
+SELECT
+  DATE_TRUNC('day', created_at)::date AS date,
+  SUM(prompt_tokens)::bigint AS prompt_tokens,
+  SUM(output_tokens)::bigint AS output_tokens,
+  SUM(cost)::float AS cost
+FROM public.llm_usage
+WHERE created_at >= NOW() - INTERVAL '6 months'
+  AND category_id = $1
+GROUP BY DATE_TRUNC('day', created_at)
+ORDER BY date;
+
+-- name: GetLlmUsageSummary :many
+SELECT u.key_id,
+  u.provider,
+  u.model,
+  u.category_id,
+  c.name AS category_name,
+  u.job,
+  SUM(u.prompt_tokens)::bigint AS prompt_tokens,
+  SUM(u.output_tokens)::bigint AS output_tokens,
+  SUM(u.cost)::float AS cost
+FROM public.llm_usage u
+LEFT JOIN public.category c ON c.id = u.category_id
+WHERE u.created_at >= NOW() - make_interval(days => sqlc.arg(days)::int)
+GROUP BY u.key_id, u.provider, u.model, u.category_id, c.name, u.job
+ORDER BY cost DESC;
+
+-- name: GetLlmPrices :many
+SELECT * FROM public.llm_price ORDER BY model;
+
+-- name: UpsertLlmPrice :one
+INSERT INTO public.llm_price (model, provider, input_price, output_price, updated_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (model) DO UPDATE
+SET provider = EXCLUDED.provider,
+  input_price = EXCLUDED.input_price,
+  output_price = EXCLUDED.output_price,
+  updated_at = NOW()
+RETURNING *;
+
+-- LLM budget queries
+-- name: GetLlmBudgetUsage :many
+SELECT b.id,
+  b.category_id,
+  b.period,
+  b.max_tokens,
+  b.max_cost,
+  b.is_active,
+  COALESCE(SUM(u.prompt_tokens + u.output_tokens), 0)::bigint AS used_tokens,
+  COALESCE(SUM(u.cost), 0)::float AS used_cost
+FROM public.llm_budget b
+LEFT JOIN public.llm_usage u
+  ON (b.category_id IS NULL OR u.category_id = b.category_id)
+  AND u.created_at >= DATE_TRUNC(CASE WHEN b.period = 'monthly' THEN 'month' ELSE 'day' END, NOW()::timestamp)
+GROUP BY b.id
+ORDER BY b.id;
+
+-- name: UpsertLlmBudget :one
+INSERT INTO public.llm_budget (category_id, period, max_tokens, max_cost, is_active, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+ON CONFLICT ((COALESCE(category_id, 0)), period) DO UPDATE
+SET max_tokens = EXCLUDED.max_tokens,
+  max_cost = EXCLUDED.max_cost,
+  is_active = EXCLUDED.is_active,
+  updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteLlmBudget :execrows
+DELETE FROM public.llm_budget WHERE id = $1;
+
+-- name: UpsertLookalikeSeed :one
+INSERT INTO public.lookalike_seed (category_id, name)
+VALUES ($1, $2)
+ON CONFLICT (category_id, name) DO UPDATE SET
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetLookalikeSeed :one
+SELECT * FROM public.lookalike_seed WHERE id = $1;
+
+-- Seeds of a category with their size, embedded_count is the number of seed
+-- profiles with an embedding in the category.
+-- name: GetLookalikeSeeds :many
+SELECT
+  s.id,
+  s.category_id,
+  s.name,
+  s.created_at,
+  s.updated_at,
+  COUNT(sp.user_profile_id) AS profile_count,
+  COUNT(ep.id) AS embedded_count
+FROM public.lookalike_seed s
+LEFT JOIN public.lookalike_seed_profile sp ON sp.seed_id = s.id
+LEFT JOIN public.embedded_profile ep ON ep.pid = sp.user_profile_id AND ep.cid = s.category_id
+WHERE s.category_id = $1
+GROUP BY s.id
+ORDER BY s.name;
+
+-- name: DeleteLookalikeSeed :execrows
+DELETE FROM public.lookalike_seed WHERE id = $1;
+
+-- name: ClearLookalikeSeedProfiles :exec
+DELETE FROM public.lookalike_seed_profile WHERE seed_id = $1;
+
+-- Profiles matching uploaded profile IDs or Facebook IDs.
+-- name: ResolveSeedProfiles :many
+SELECT id, facebook_id FROM public.user_profile
+WHERE id = ANY(@profile_ids::int[]) OR facebook_id = ANY(@facebook_ids::text[]);
+
+-- name: AddLookalikeSeedProfiles :execrows
+INSERT INTO public.lookalike_seed_profile (seed_id, user_profile_id)
+SELECT @seed_id::int, unnest(@profile_ids::int[])
+ON CONFLICT (seed_id, user_profile_id) DO NOTHING;
+
+-- name: GetLookalikeSeedEmbeddings :many
+SELECT sp.user_profile_id, ep.embedding
+FROM public.lookalike_seed_profile sp
+JOIN public.lookalike_seed s ON s.id = sp.seed_id
+JOIN public.embedded_profile ep ON ep.pid = sp.user_profile_id AND ep.cid = s.category_id
+WHERE sp.seed_id = $1
+ORDER BY sp.user_profile_id;
+
+-- Nearest profiles of a category to a query embedding, leaving out the
+-- profiles of the seed.
+-- name: FindLookalikeProfiles :many
+SELECT
+  p.id AS profile_id,
+  p.facebook_id,
+  p.profile_url,
+  p.name AS profile_name,
+  p.location,
+  upc.model_score,
+  upc.final_score,
+  CAST(1 - (ep.embedding <=> @query::vector) AS DOUBLE PRECISION) AS similarity
+FROM public.embedded_profile ep
+JOIN public.user_profile p ON p.id = ep.pid
+LEFT JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = ep.cid
+WHERE ep.cid = @category_id
+  AND NOT EXISTS (
+    SELECT 1 FROM public.lookalike_seed_profile sp
+    WHERE sp.seed_id = @seed_id AND sp.user_profile_id = ep.pid
+  )
+ORDER BY ep.embedding <=> @query::vector
+LIMIT @page_limit;
+
+-- Random sample of the embeddings of a category to fit the personas on.
+-- name: GetPersonaTrainingEmbeddings :many
+SELECT pid, embedding FROM public.embedded_profile
+WHERE cid = @category_id AND embedding IS NOT NULL
+ORDER BY random()
+LIMIT @page_limit;
+
+-- name: DeleteCategoryPersonas :execrows
+DELETE FROM public.persona WHERE category_id = $1;
+
+-- name: CreatePersona :one
+INSERT INTO public.persona (category_id, cluster, centroid)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- Moves every embedding of a category to its closest persona.
+-- name: AssignPersonas :execrows
+UPDATE public.embedded_profile ep SET persona_id = (
+  SELECT p.id FROM public.persona p
+  WHERE p.category_id = ep.cid
+  ORDER BY p.centroid <=> ep.embedding
+  LIMIT 1
+)
+WHERE ep.cid = $1 AND ep.embedding IS NOT NULL;
+
+-- name: UpdatePersonaLabel :exec
+UPDATE public.persona SET label = $2, description = $3 WHERE id = $1;
+
+-- name: GetPersonas :many
+SELECT
+  p.id,
+  p.category_id,
+  p.cluster,
+  p.label,
+  p.description,
+  p.created_at,
+  COUNT(ep.id) AS size,
+  AVG(upc.final_score) AS avg_final_score,
+  AVG(upc.model_score) AS avg_model_score
+FROM public.persona p
+LEFT JOIN public.embedded_profile ep ON ep.persona_id = p.id
+LEFT JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = p.category_id
+WHERE p.category_id = $1
+GROUP BY p.id
+ORDER BY size DESC, p.cluster;
+
+-- Members of a persona closest to its centroid.
+-- name: GetPersonaMembers :many
+SELECT
+  up.id AS profile_id,
+  up.profile_url,
+  up.name AS profile_name,
+  up.location,
+  up.work,
+  up.education,
+  up.bio,
+  up.gender,
+  up.relationship_status,
+  upc.final_score,
+  CAST(1 - (ep.embedding <=> p.centroid) AS DOUBLE PRECISION) AS similarity
+FROM public.embedded_profile ep
+JOIN public.persona p ON p.id = ep.persona_id
+JOIN public.user_profile up ON up.id = ep.pid
+LEFT JOIN public.user_profile_category upc ON upc.user_profile_id = ep.pid AND upc.category_id = p.category_id
+WHERE ep.persona_id = @persona_id
+ORDER BY ep.embedding <=> p.centroid
+LIMIT @page_limit;
+
+-- Pairs of profiles sharing an email, a phone number or a profile url. Values
+-- shared by more than max_group_size profiles are ignored as placeholders.
+-- name: FindExactDuplicateProfiles :many
+WITH profile_keys AS (
+  SELECT id, 'email' AS signal, lower(trim(email)) AS value
+  FROM public.user_profile
+  WHERE email IS NOT NULL AND trim(email) <> ''
+  UNION ALL
+  SELECT id, 'phone' AS signal, right(regexp_replace(phone, '\D', '', 'g'), 9) AS value
+  FROM public.user_profile
+  WHERE length(regexp_replace(COALESCE(phone, ''), '\D', '', 'g')) >= 9
+  UNION ALL
+  SELECT id, 'profile_url' AS signal, rtrim(regexp_replace(lower(profile_url), '^https?://(www\.|m\.)?', ''), '/') AS value
+  FROM public.user_profile
+  WHERE profile_url <> 'NOT_SPECIFIED' AND profile_url <> ''
+), shared AS (
+  SELECT signal, value FROM profile_keys
+  GROUP BY signal, value
+  HAVING COUNT(*) BETWEEN 2 AND @max_group_size::int
+)
+SELECT
+  a.id AS profile_id,
+  b.id AS duplicate_id,
+  CAST(array_agg(DISTINCT a.signal) AS TEXT[]) AS signals
+FROM shared s
+JOIN profile_keys a ON a.signal = s.signal AND a.value = s.value
+JOIN profile_keys b ON b.signal = s.signal AND b.value = s.value AND b.id > a.id
+GROUP BY a.id, b.id;
+
+-- Nearest neighbours of a page of the embeddings of a category. Embeddings
+-- without a neighbour above min_similarity come back once with a NULL
+-- duplicate so the caller can keep paging by embedding_id.
+-- name: FindEmbeddingDuplicates :many
+SELECT
+  ep.id AS embedding_id,
+  ep.pid AS profile_id,
+  p.name AS profile_name,
+  n.pid AS duplicate_id,
+  dp.name AS duplicate_name,
+  n.similarity
+FROM (
+  SELECT id, pid, embedding FROM public.embedded_profile
+  WHERE cid = @category_id AND id > @after_id AND embedding IS NOT NULL
+  ORDER BY id
+  LIMIT @page_limit
+) ep
+JOIN public.user_profile p ON p.id = ep.pid
+LEFT JOIN LATERAL (
+  SELECT o.pid, CAST(1 - (o.embedding <=> ep.embedding) AS DOUBLE PRECISION) AS similarity
+  FROM public.embedded_profile o
+  WHERE o.cid = @category_id AND o.pid <> ep.pid
+  ORDER BY o.embedding <=> ep.embedding
+  LIMIT @neighbors::int
+) n ON n.similarity >= @min_similarity::float8
+LEFT JOIN public.user_profile dp ON dp.id = n.pid
+ORDER BY ep.id;
+
+-- Records a merge candidate. Dismissed candidates are left untouched.
+-- name: UpsertProfileDuplicate :execrows
+INSERT INTO public.profile_duplicate (profile_id, duplicate_id, signals, similarity, confidence)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (profile_id, duplicate_id) DO UPDATE SET
+  signals = EXCLUDED.signals,
+  similarity = EXCLUDED.similarity,
+  confidence = EXCLUDED.confidence,
+  updated_at = NOW()
+WHERE profile_duplicate.status = 'pending';
+
+-- name: GetProfileDuplicates :many
+SELECT
+  d.id,
+  d.signals,
+  d.similarity,
+  d.confidence,
+  d.status,
+  d.created_at,
+  d.updated_at,
+  a.id AS profile_id,
+  a.facebook_id AS profile_facebook_id,
+  a.name AS profile_name,
+  a.profile_url AS profile_url,
+  a.updated_at AS profile_updated_at,
+  b.id AS duplicate_id,
+  b.facebook_id AS duplicate_facebook_id,
+  b.name AS duplicate_name,
+  b.profile_url AS duplicate_profile_url,
+  b.updated_at AS duplicate_updated_at
+FROM public.profile_duplicate d
+JOIN public.user_profile a ON a.id = d.profile_id
+JOIN public.user_profile b ON b.id = d.duplicate_id
+WHERE d.status = @status AND d.confidence >= @min_confidence::float8
+ORDER BY d.confidence DESC, d.id
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: CountProfileDuplicates :one
+SELECT COUNT(*) FROM public.profile_duplicate
+WHERE status = @status AND confidence >= @min_confidence::float8;
+
+-- name: GetProfileDuplicate :one
+SELECT * FROM public.profile_duplicate WHERE id = $1;
+
+-- name: UpdateProfileDuplicateStatus :execrows
+UPDATE public.profile_duplicate SET status = $2, updated_at = NOW() WHERE id = $1;
+
+-- Locks both profiles of a merge for the rest of the transaction.
+-- name: LockProfiles :many
+SELECT id FROM public.user_profile
+WHERE id = ANY(@ids::int[])
+ORDER BY id
+FOR UPDATE;
+
+-- Merged profile and its category scores as they were before the merge.
+-- name: GetProfileSnapshot :one
+SELECT
+  p.facebook_id,
+  CAST(jsonb_build_object(
+    'profile', to_jsonb(p),
+    'categories', COALESCE((
+      SELECT jsonb_agg(to_jsonb(upc)) FROM public.user_profile_category upc
+      WHERE upc.user_profile_id = p.id
+    ), '[]'::jsonb)
+  ) AS jsonb) AS snapshot
+FROM public.user_profile p
+WHERE p.id = $1;
+
+-- name: MoveProfileComments :execrows
+UPDATE public.comment SET author_id = @survivor_id WHERE author_id = @merged_id;
+
+-- name: MoveProfileFinancialAnalyses :execrows
+UPDATE public.financial_analysis SET user_profile_id = @survivor_id WHERE user_profile_id = @merged_id;
+
+-- Moves the categories the survivor is not in yet.
+-- name: MoveProfileCategories :execrows
+UPDATE public.user_profile_category SET user_profile_id = @survivor_id
+WHERE user_profile_id = @merged_id
+  AND category_id NOT IN (
+    SELECT category_id FROM public.user_profile_category WHERE user_profile_id = @survivor_id
+  );
+
+-- Fills the scores the survivor lacks in the categories both profiles are in.
+-- Model fields are taken together so they stay consistent with each other.
+-- name: FillProfileCategoryScores :execrows
+UPDATE public.user_profile_category s SET
+  model_score = CASE WHEN s.model_score IS NULL THEN m.model_score ELSE s.model_score END,
+  model_scored_at = CASE WHEN s.model_score IS NULL THEN m.model_scored_at ELSE s.model_scored_at END,
+  model_version_id = CASE WHEN s.model_score IS NULL THEN m.model_version_id ELSE s.model_version_id END,
+  model_input_hash = CASE WHEN s.model_score IS NULL THEN m.model_input_hash ELSE s.model_input_hash END,
+  model_contributions = CASE WHEN s.model_score IS NULL THEN m.model_contributions ELSE s.model_contributions END,
+  gemini_score = COALESCE(s.gemini_score, m.gemini_score),
+  final_score = CASE WHEN s.final_score IS NULL THEN m.final_score ELSE s.final_score END,
+  final_score_at = CASE WHEN s.final_score IS NULL THEN m.final_score_at ELSE s.final_score_at END,
+  intent_score = COALESCE(s.intent_score, m.intent_score),
+  label = COALESCE(s.label, m.label)
+FROM public.user_profile_category m
+WHERE s.user_profile_id = @survivor_id
+  AND m.user_profile_id = @merged_id
+  AND m.category_id = s.category_id;
+
+-- name: MoveProfileEmbeddings :execrows
+UPDATE public.embedded_profile SET pid = @survivor_id
+WHERE pid = @merged_id
+  AND cid NOT IN (SELECT cid FROM public.embedded_profile WHERE pid = @survivor_id);
+
+-- name: DeleteProfileEmbeddings :execrows
+DELETE FROM public.embedded_profile WHERE pid = $1;
+
+-- name: MoveProfileShadowScores :execrows
+UPDATE public.model_shadow_score m SET user_profile_id = @survivor_id
+WHERE m.user_profile_id = @merged_id
+  AND NOT EXISTS (
+    SELECT 1 FROM public.model_shadow_score s
+    WHERE s.user_profile_id = @survivor_id AND s.version_id = m.version_id AND s.category_id = m.category_id
+  );
+
+-- name: MoveProfileTriggerRuleHits :execrows
+UPDATE public.trigger_rule_hit m SET user_profile_id = @survivor_id
+WHERE m.user_profile_id = @merged_id
+  AND NOT EXISTS (
+    SELECT 1 FROM public.trigger_rule_hit s
+    WHERE s.user_profile_id = @survivor_id AND s.rule_id = m.rule_id
+      AND s.source_type = m.source_type AND s.source_id = m.source_id
+  );
+
+-- name: MoveProfileSeedMemberships :execrows
+UPDATE public.lookalike_seed_profile m SET user_profile_id = @survivor_id
+WHERE m.user_profile_id = @merged_id
+  AND NOT EXISTS (
+    SELECT 1 FROM public.lookalike_seed_profile s
+    WHERE s.user_profile_id = @survivor_id AND s.seed_id = m.seed_id
+  );
+
+-- Keeps earlier merges into the merged profile pointing at a live profile.
+-- name: MoveProfileMerges :execrows
+UPDATE public.profile_merge SET survivor_id = @survivor_id WHERE survivor_id = @merged_id;
+
+-- Re-points the pending merge candidates of the merged profile to the
+-- survivor. Pairs with the survivor itself, or already known for it, are left
+-- to be dropped along with the merged profile.
+-- name: MoveProfileDuplicates :execrows
+INSERT INTO public.profile_duplicate (profile_id, duplicate_id, signals, similarity, confidence, created_at)
+SELECT LEAST(@survivor_id::int, d.other_id), GREATEST(@survivor_id::int, d.other_id),
+  d.signals, d.similarity, d.confidence, d.created_at
+FROM (
+  SELECT CASE WHEN profile_id = @merged_id::int THEN duplicate_id ELSE profile_id END AS other_id,
+    signals, similarity, confidence, created_at
+  FROM public.profile_duplicate
+  WHERE status = 'pending' AND (profile_id = @merged_id OR duplicate_id = @merged_id)
+) d
+WHERE d.other_id <> @survivor_id
+ON CONFLICT (profile_id, duplicate_id) DO NOTHING;
+
+-- Fills the fields the survivor lacks from the merged profile.
+-- name: FillMergedProfileFields :exec
+UPDATE public.user_profile s SET
+  name = COALESCE(s.name, m.name),
+  bio = COALESCE(s.bio, m.bio),
+  location = COALESCE(s.location, m.location),
+  work = COALESCE(s.work, m.work),
+  education = COALESCE(s.education, m.education),
+  relationship_status = COALESCE(s.relationship_status, m.relationship_status),
+  hometown = COALESCE(s.hometown, m.hometown),
+  gender = COALESCE(s.gender, m.gender),
+  birthday = COALESCE(s.birthday, m.birthday),
+  email = COALESCE(s.email, m.email),
+  phone = COALESCE(s.phone, m.phone),
+  locale = CASE WHEN s.locale = 'NOT_SPECIFIED' THEN m.locale ELSE s.locale END,
+  profile_url = CASE WHEN s.profile_url = 'NOT_SPECIFIED' THEN m.profile_url ELSE s.profile_url END,
+  is_scanned = s.is_scanned OR m.is_scanned,
+  updated_at = NOW()
+FROM public.user_profile m
+WHERE s.id = @survivor_id AND m.id = @merged_id;
+
+-- name: DeleteUserProfile :execrows
+DELETE FROM public.user_profile WHERE id = $1;
+
+-- name: CreateProfileMerge :one
+INSERT INTO public.profile_merge (survivor_id, merged_id, merged_facebook_id, snapshot, moved, signals, confidence)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetProfileMerges :many
+SELECT * FROM public.profile_merge
+ORDER BY id DESC
+LIMIT $1 OFFSET $2;
+
+-- name: CountProfileMerges :one
+SELECT COUNT(*) FROM public.profile_merge;
+
+-- Surviving profile of a facebook id that was merged away.
+-- name: GetMergedProfileSurvivor :one
+SELECT survivor_id FROM public.profile_merge
+WHERE merged_facebook_id = $1 AND survivor_id IS NOT NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- Surviving profiles of facebook ids that were merged away.
+-- name: GetMergedProfileSurvivors :many
+SELECT DISTINCT ON (merged_facebook_id) merged_facebook_id, survivor_id
+FROM public.profile_merge
+WHERE merged_facebook_id = ANY(@facebook_ids::text[])
+ORDER BY merged_facebook_id, id DESC;
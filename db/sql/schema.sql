@@ -108,7 +108,11 @@ CREATE TABLE public.comment (
     inserted_at timestamp without time zone NOT NULL,
     post_id integer NOT NULL,
     author_id integer NOT NULL,
-    comment_id character varying NOT NULL
+    comment_id character varying NOT NULL,
+    intent character varying(32),
+    intent_confidence double precision,
+    intents jsonb DEFAULT '{}'::jsonb NOT NULL,
+    analyzed_at timestamp without time zone
 );
 
 
@@ -132,6 +136,36 @@ CREATE SEQUENCE public.comment_id_seq
 ALTER SEQUENCE public.comment_id_seq OWNED BY public.comment.id;
 
 
+--
+-- Name: comment_intent; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.comment_intent (
+    comment_id integer NOT NULL,
+    category_id integer NOT NULL,
+    intent character varying(32),
+    intent_confidence double precision,
+    intents jsonb DEFAULT '{}'::jsonb NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    analyzed_at timestamp without time zone,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: TABLE comment_intent; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON TABLE public.comment_intent IS 'Intent of a comment as classified with the business description of a category';
+
+
+--
+-- Name: COLUMN comment_intent.attempts; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.comment_intent.attempts IS 'Classification attempts, answered or not. The comment is skipped after COMMENT_INTENT_MAX_ATTEMPTS';
+
+
 --
 -- Name: config; Type: TABLE; Schema: public; Owner: -
 --
@@ -172,7 +206,8 @@ CREATE TABLE public.embedded_profile (
     pid integer NOT NULL,
     created_at timestamp without time zone DEFAULT now(),
     embedding public.vector(1024),
-    cid integer NOT NULL
+    cid integer NOT NULL,
+    persona_id integer
 );
 
 
@@ -341,24 +376,27 @@ ALTER SEQUENCE public.group_id_seq OWNED BY public."group".id;
 
 
 --
--- Name: log; Type: TABLE; Schema: public; Owner: -
+-- Name: llm_budget; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.log (
+CREATE TABLE public.llm_budget (
     id integer NOT NULL,
-    account_id integer,
-    action character varying NOT NULL,
-    target_id integer,
-    description text,
-    created_at timestamp without time zone DEFAULT now()
+    category_id integer,
+    period character varying(16) NOT NULL,
+    max_tokens bigint,
+    max_cost double precision,
+    is_active boolean DEFAULT true NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT llm_budget_period_check CHECK (((period)::text = ANY ((ARRAY['daily'::character varying, 'monthly'::character varying])::text[])))
 );
 
 
 --
--- Name: log_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: llm_budget_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.log_id_seq
+CREATE SEQUENCE public.llm_budget_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -368,65 +406,73 @@ CREATE SEQUENCE public.log_id_seq
 
 
 --
--- Name: log_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: llm_budget_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.log_id_seq OWNED BY public.log.id;
+ALTER SEQUENCE public.llm_budget_id_seq OWNED BY public.llm_budget.id;
 
 
 --
--- Name: model; Type: TABLE; Schema: public; Owner: -
+-- Name: llm_cache; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.model (
-    id integer NOT NULL,
-    name character varying NOT NULL,
-    description character varying,
-    created_at timestamp without time zone NOT NULL,
-    category_id integer
+CREATE TABLE public.llm_cache (
+    prompt_hash character(64) NOT NULL,
+    model character varying(64) NOT NULL,
+    response text NOT NULL,
+    hit_count integer DEFAULT 0 NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    expires_at timestamp without time zone NOT NULL
 );
 
 
 --
--- Name: model_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: llm_cache_stat; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.model_id_seq
-    AS integer
-    START WITH 1
-    INCREMENT BY 1
-    NO MINVALUE
-    NO MAXVALUE
-    CACHE 1;
+CREATE TABLE public.llm_cache_stat (
+    date date NOT NULL,
+    hits bigint DEFAULT 0 NOT NULL,
+    misses bigint DEFAULT 0 NOT NULL
+);
 
 
 --
--- Name: model_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: llm_price; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.model_id_seq OWNED BY public.model.id;
+CREATE TABLE public.llm_price (
+    model character varying(64) NOT NULL,
+    provider character varying(32) DEFAULT 'gemini'::character varying NOT NULL,
+    input_price double precision DEFAULT 0 NOT NULL,
+    output_price double precision DEFAULT 0 NOT NULL,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: post; Type: TABLE; Schema: public; Owner: -
+-- Name: llm_usage; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.post (
+CREATE TABLE public.llm_usage (
     id integer NOT NULL,
-    post_id character varying NOT NULL,
-    content character varying NOT NULL,
-    created_at timestamp without time zone NOT NULL,
-    inserted_at timestamp without time zone NOT NULL,
-    group_id integer NOT NULL,
-    is_analyzed boolean NOT NULL
+    key_id integer,
+    provider character varying(32) NOT NULL,
+    model character varying(64) NOT NULL,
+    category_id integer,
+    job character varying(64) NOT NULL,
+    prompt_tokens bigint DEFAULT 0 NOT NULL,
+    output_tokens bigint DEFAULT 0 NOT NULL,
+    cost double precision DEFAULT 0 NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
 );
 
 
 --
--- Name: post_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: llm_usage_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.post_id_seq
+CREATE SEQUENCE public.llm_usage_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -436,32 +482,31 @@ CREATE SEQUENCE public.post_id_seq
 
 
 --
--- Name: post_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: llm_usage_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.post_id_seq OWNED BY public.post.id;
+ALTER SEQUENCE public.llm_usage_id_seq OWNED BY public.llm_usage.id;
 
 
 --
--- Name: prompt; Type: TABLE; Schema: public; Owner: -
+-- Name: log; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.prompt (
+CREATE TABLE public.log (
     id integer NOT NULL,
-    content character varying NOT NULL,
-    service_name character varying NOT NULL,
-    version integer NOT NULL,
-    created_by character varying NOT NULL,
-    created_at timestamp without time zone NOT NULL,
-    category_id integer NOT NULL
+    account_id integer,
+    action character varying NOT NULL,
+    target_id integer,
+    description text,
+    created_at timestamp without time zone DEFAULT now()
 );
 
 
 --
--- Name: prompt_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: log_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.prompt_id_seq
+CREATE SEQUENCE public.log_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -471,33 +516,37 @@ CREATE SEQUENCE public.prompt_id_seq
 
 
 --
--- Name: prompt_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: log_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.prompt_id_seq OWNED BY public.prompt.id;
+ALTER SEQUENCE public.log_id_seq OWNED BY public.log.id;
 
 
 --
--- Name: proxy; Type: TABLE; Schema: public; Owner: -
+-- Name: lookalike_seed; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.proxy (
+CREATE TABLE public.lookalike_seed (
     id integer NOT NULL,
-    ip character varying NOT NULL,
-    port character varying NOT NULL,
-    username character varying NOT NULL,
-    password character varying NOT NULL,
-    is_active boolean NOT NULL,
-    created_at timestamp without time zone NOT NULL,
-    updated_at timestamp without time zone NOT NULL
+    category_id integer NOT NULL,
+    name character varying(255) NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL
 );
 
 
 --
--- Name: proxy_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: TABLE lookalike_seed; Type: COMMENT; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.proxy_id_seq
+COMMENT ON TABLE public.lookalike_seed IS 'Named list of converted customers of a category, the source of lookalike audiences';
+
+
+--
+-- Name: lookalike_seed_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.lookalike_seed_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -507,32 +556,41 @@ CREATE SEQUENCE public.proxy_id_seq
 
 
 --
--- Name: proxy_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: lookalike_seed_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.proxy_id_seq OWNED BY public.proxy.id;
+ALTER SEQUENCE public.lookalike_seed_id_seq OWNED BY public.lookalike_seed.id;
 
 
 --
--- Name: request; Type: TABLE; Schema: public; Owner: -
+-- Name: lookalike_seed_profile; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.request (
+CREATE TABLE public.lookalike_seed_profile (
+    seed_id integer NOT NULL,
+    user_profile_id integer NOT NULL,
+    added_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: model; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.model (
     id integer NOT NULL,
-    progress double precision DEFAULT 0 NOT NULL,
-    status smallint DEFAULT 0 NOT NULL,
-    description character varying(50),
-    created_at timestamp without time zone DEFAULT now(),
-    updated_at timestamp without time zone DEFAULT now(),
-    error_message text
+    name character varying NOT NULL,
+    description character varying,
+    created_at timestamp without time zone NOT NULL,
+    category_id integer
 );
 
 
 --
--- Name: request_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: model_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.request_id_seq
+CREATE SEQUENCE public.model_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -542,445 +600,1441 @@ CREATE SEQUENCE public.request_id_seq
 
 
 --
--- Name: request_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: model_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.request_id_seq OWNED BY public.request.id;
+ALTER SEQUENCE public.model_id_seq OWNED BY public.model.id;
 
 
 --
--- Name: user_profile; Type: TABLE; Schema: public; Owner: -
+-- Name: model_deployment; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.user_profile (
-    id integer NOT NULL,
-    facebook_id character varying NOT NULL,
-    name character varying,
-    bio text,
-    location character varying,
-    work text,
-    education text,
-    relationship_status character varying,
-    created_at timestamp without time zone NOT NULL,
-    updated_at timestamp without time zone NOT NULL,
-    scraped_by_id integer NOT NULL,
-    is_scanned boolean DEFAULT false NOT NULL,
-    hometown character varying,
-    locale character varying(16) DEFAULT 'NOT_SPECIFIED'::character varying NOT NULL,
-    gender character varying(16),
-    birthday character varying(10),
-    email character varying(100),
-    phone character varying(12),
-    profile_url character varying DEFAULT 'NOT_SPECIFIED'::character varying NOT NULL,
-    is_analyzed boolean DEFAULT false
+CREATE TABLE public.model_deployment (
+    category_id integer NOT NULL,
+    champion_version_id integer,
+    challenger_version_id integer,
+    challenger_sample_rate double precision DEFAULT 0.1 NOT NULL,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT model_deployment_sample_rate_check CHECK (((challenger_sample_rate >= (0)::double precision) AND (challenger_sample_rate <= (1)::double precision)))
 );
 
 
 --
--- Name: user_profile_category; Type: TABLE; Schema: public; Owner: -
+-- Name: model_drift; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.user_profile_category (
-    user_profile_id integer NOT NULL,
+CREATE TABLE public.model_drift (
     category_id integer NOT NULL,
-    created_at timestamp without time zone DEFAULT now() NOT NULL,
-    model_score double precision,
-    gemini_score double precision
+    day date NOT NULL,
+    version_id integer,
+    sample_size integer NOT NULL,
+    score_mean double precision NOT NULL,
+    score_std double precision NOT NULL,
+    psi double precision NOT NULL,
+    ks double precision NOT NULL,
+    completeness jsonb DEFAULT '{}'::jsonb NOT NULL,
+    completeness_drift double precision DEFAULT 0 NOT NULL,
+    alerts jsonb DEFAULT '[]'::jsonb NOT NULL,
+    is_alert boolean DEFAULT false NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
 );
 
 
 --
--- Name: user_profile_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: COLUMN model_drift.completeness; Type: COMMENT; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.user_profile_id_seq
-    AS integer
-    START WITH 1
-    INCREMENT BY 1
-    NO MINVALUE
-    NO MAXVALUE
-    CACHE 1;
+COMMENT ON COLUMN public.model_drift.completeness IS 'Share of recently scored profiles with each input feature filled in';
 
 
 --
--- Name: user_profile_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: COLUMN model_drift.completeness_drift; Type: COMMENT; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.user_profile_id_seq OWNED BY public.user_profile.id;
+COMMENT ON COLUMN public.model_drift.completeness_drift IS 'Largest difference with the completeness of the training set';
 
 
 --
--- Name: account id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: model_shadow_score; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.account ALTER COLUMN id SET DEFAULT nextval('public.account_id_seq'::regclass);
+CREATE TABLE public.model_shadow_score (
+    version_id integer NOT NULL,
+    user_profile_id integer NOT NULL,
+    category_id integer NOT NULL,
+    score double precision NOT NULL,
+    champion_score double precision,
+    scored_at timestamp without time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: category id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: model_version; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.category ALTER COLUMN id SET DEFAULT nextval('public.category_id_seq'::regclass);
+CREATE TABLE public.model_version (
+    id integer NOT NULL,
+    model_id integer NOT NULL,
+    version integer NOT NULL,
+    category_id integer,
+    path character varying NOT NULL,
+    metrics jsonb DEFAULT '{}'::jsonb NOT NULL,
+    dataset_snapshot_id character varying(64),
+    dataset_size integer,
+    prompt_service character varying,
+    prompt_version integer,
+    parent_version_id integer,
+    request_id integer,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: comment id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: COLUMN model_version.path; Type: COMMENT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.comment ALTER COLUMN id SET DEFAULT nextval('public.comment_id_seq'::regclass);
+COMMENT ON COLUMN public.model_version.path IS 'Folder of the version, relative to python/resources/models';
 
 
 --
--- Name: config id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: COLUMN model_version.dataset_snapshot_id; Type: COMMENT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.config ALTER COLUMN id SET DEFAULT nextval('public.config_id_seq'::regclass);
+COMMENT ON COLUMN public.model_version.dataset_snapshot_id IS 'Hash of the (profile, gemini score) pairs the version was trained on';
 
 
 --
--- Name: embedded_profile id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: COLUMN model_version.parent_version_id; Type: COMMENT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.embedded_profile ALTER COLUMN id SET DEFAULT nextval('public.embedded_profile_id_seq'::regclass);
+COMMENT ON COLUMN public.model_version.parent_version_id IS 'Champion of the category when the version was trained';
 
 
 --
--- Name: financial_analysis id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: model_version_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.financial_analysis ALTER COLUMN id SET DEFAULT nextval('public.financial_analysis_id_seq'::regclass);
+CREATE SEQUENCE public.model_version_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: gemini_key id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: model_version_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.gemini_key ALTER COLUMN id SET DEFAULT nextval('public.gemini_key_id_seq'::regclass);
+ALTER SEQUENCE public.model_version_id_seq OWNED BY public.model_version.id;
 
 
 --
--- Name: group id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: persona; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public."group" ALTER COLUMN id SET DEFAULT nextval('public.group_id_seq'::regclass);
+CREATE TABLE public.persona (
+    id integer NOT NULL,
+    category_id integer NOT NULL,
+    cluster integer NOT NULL,
+    centroid public.vector(1024) NOT NULL,
+    label character varying(255),
+    description text,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: log id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: COLUMN persona.centroid; Type: COMMENT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.log ALTER COLUMN id SET DEFAULT nextval('public.log_id_seq'::regclass);
+COMMENT ON COLUMN public.persona.centroid IS 'Normalized mean of the embeddings of the cluster';
 
 
 --
--- Name: model id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: persona_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.model ALTER COLUMN id SET DEFAULT nextval('public.model_id_seq'::regclass);
+CREATE SEQUENCE public.persona_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: post id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: persona_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.post ALTER COLUMN id SET DEFAULT nextval('public.post_id_seq'::regclass);
+ALTER SEQUENCE public.persona_id_seq OWNED BY public.persona.id;
 
 
 --
--- Name: prompt id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: post; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.prompt ALTER COLUMN id SET DEFAULT nextval('public.prompt_id_seq'::regclass);
+CREATE TABLE public.post (
+    id integer NOT NULL,
+    post_id character varying NOT NULL,
+    content character varying NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    inserted_at timestamp without time zone NOT NULL,
+    group_id integer NOT NULL,
+    is_analyzed boolean NOT NULL
+);
 
 
 --
--- Name: proxy id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: post_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.proxy ALTER COLUMN id SET DEFAULT nextval('public.proxy_id_seq'::regclass);
+CREATE SEQUENCE public.post_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: request id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: post_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.request ALTER COLUMN id SET DEFAULT nextval('public.request_id_seq'::regclass);
+ALTER SEQUENCE public.post_id_seq OWNED BY public.post.id;
 
 
 --
--- Name: user_profile id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: profile_duplicate; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.user_profile ALTER COLUMN id SET DEFAULT nextval('public.user_profile_id_seq'::regclass);
+CREATE TABLE public.profile_duplicate (
+    id integer NOT NULL,
+    profile_id integer NOT NULL,
+    duplicate_id integer NOT NULL,
+    signals jsonb DEFAULT '[]'::jsonb NOT NULL,
+    similarity double precision,
+    confidence double precision NOT NULL,
+    status character varying(16) DEFAULT 'pending'::character varying NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT profile_duplicate_order_check CHECK ((profile_id < duplicate_id)),
+    CONSTRAINT profile_duplicate_status_check CHECK (((status)::text = ANY ((ARRAY['pending'::character varying, 'dismissed'::character varying])::text[])))
+);
 
 
 --
--- Name: account account_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: COLUMN profile_duplicate.signals; Type: COMMENT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.account
-    ADD CONSTRAINT account_pkey PRIMARY KEY (id);
+COMMENT ON COLUMN public.profile_duplicate.signals IS 'Signals pointing to the same person: email, phone, profile_url, embedding';
 
 
 --
--- Name: account account_username_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: profile_duplicate_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.account
-    ADD CONSTRAINT account_username_key UNIQUE (username);
-
-
+CREATE SEQUENCE public.profile_duplicate_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: profile_duplicate_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.profile_duplicate_id_seq OWNED BY public.profile_duplicate.id;
+
+
+--
+-- Name: profile_merge; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.profile_merge (
+    id integer NOT NULL,
+    survivor_id integer,
+    merged_id integer NOT NULL,
+    merged_facebook_id character varying NOT NULL,
+    snapshot jsonb NOT NULL,
+    moved jsonb DEFAULT '{}'::jsonb NOT NULL,
+    signals jsonb DEFAULT '[]'::jsonb NOT NULL,
+    confidence double precision,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN profile_merge.snapshot; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.profile_merge.snapshot IS 'Merged user_profile row and its categories before the merge';
+
+
+--
+-- Name: COLUMN profile_merge.moved; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.profile_merge.moved IS 'Number of rows re-pointed to the survivor, per table';
+
+
+--
+-- Name: profile_merge_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.profile_merge_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: profile_merge_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.profile_merge_id_seq OWNED BY public.profile_merge.id;
+
+
+--
+-- Name: prompt; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.prompt (
+    id integer NOT NULL,
+    content character varying NOT NULL,
+    service_name character varying NOT NULL,
+    version integer NOT NULL,
+    created_by character varying NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    category_id integer NOT NULL
+);
+
+
+--
+-- Name: prompt_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.prompt_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: prompt_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.prompt_id_seq OWNED BY public.prompt.id;
+
+
+--
+-- Name: proxy; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.proxy (
+    id integer NOT NULL,
+    ip character varying NOT NULL,
+    port character varying NOT NULL,
+    username character varying NOT NULL,
+    password character varying NOT NULL,
+    is_active boolean NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+--
+-- Name: proxy_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.proxy_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: proxy_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.proxy_id_seq OWNED BY public.proxy.id;
+
+
+--
+-- Name: request; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.request (
+    id integer NOT NULL,
+    progress double precision DEFAULT 0 NOT NULL,
+    status smallint DEFAULT 0 NOT NULL,
+    description character varying(255),
+    created_at timestamp without time zone DEFAULT now(),
+    updated_at timestamp without time zone DEFAULT now(),
+    error_message text,
+    kind character varying(32) DEFAULT 'unknown'::character varying NOT NULL,
+    result jsonb,
+    started_at timestamp without time zone,
+    finished_at timestamp without time zone
+);
+
+
+--
+-- Name: COLUMN request.kind; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.request.kind IS 'Job kind, e.g. ml.train or profile.import';
+
+
+--
+-- Name: COLUMN request.result; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.request.result IS 'Job result, e.g. counts or the name of an exported file';
+
+
+--
+-- Name: request_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.request_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: request_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.request_id_seq OWNED BY public.request.id;
+
+
+--
+-- Name: request_status; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.request_status (
+    code smallint NOT NULL,
+    name character varying(16) NOT NULL,
+    is_final boolean DEFAULT false NOT NULL
+);
+
+
+--
+-- Name: retrain_policy; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.retrain_policy (
+    category_id integer NOT NULL,
+    model_name character varying(128) NOT NULL,
+    is_enabled boolean DEFAULT true NOT NULL,
+    interval_hours integer DEFAULT 0 NOT NULL,
+    min_new_profiles integer DEFAULT 0 NOT NULL,
+    rmse_margin double precision DEFAULT 0 NOT NULL,
+    r2_margin double precision DEFAULT 0 NOT NULL,
+    auto_tune boolean DEFAULT false NOT NULL,
+    trials integer,
+    last_run_at timestamp without time zone,
+    profiles_at_last_run integer DEFAULT 0 NOT NULL,
+    last_request_id integer,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL,
+    CONSTRAINT retrain_policy_interval_hours_check CHECK ((interval_hours >= 0)),
+    CONSTRAINT retrain_policy_min_new_profiles_check CHECK ((min_new_profiles >= 0))
+);
+
+
+--
+-- Name: COLUMN retrain_policy.interval_hours; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.retrain_policy.interval_hours IS 'Retrain every N hours, 0 disables the schedule';
+
+
+--
+-- Name: COLUMN retrain_policy.min_new_profiles; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.retrain_policy.min_new_profiles IS 'Retrain once N profiles were labelled or gemini-scored since the last run, 0 disables it';
+
+
+--
+-- Name: COLUMN retrain_policy.profiles_at_last_run; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.retrain_policy.profiles_at_last_run IS 'Labelled or gemini-scored profiles of the category when the last run started';
+
+
+--
+-- Name: scoring_policy; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.scoring_policy (
+    category_id integer NOT NULL,
+    gemini_weight double precision DEFAULT 0.5 NOT NULL,
+    model_weight double precision DEFAULT 0.4 NOT NULL,
+    completeness_weight double precision DEFAULT 0.1 NOT NULL,
+    min_completeness integer DEFAULT 0 NOT NULL,
+    decay_half_life_days double precision DEFAULT 0 NOT NULL,
+    calibration_method character varying(16) DEFAULT 'none'::character varying NOT NULL,
+    calibration jsonb DEFAULT '{}'::jsonb NOT NULL,
+    updated_at timestamp without time zone DEFAULT now() NOT NULL,
+    intent_weight double precision DEFAULT 0.3 NOT NULL
+);
+
+
+--
+-- Name: trigger_rule; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.trigger_rule (
+    id integer NOT NULL,
+    category_id integer NOT NULL,
+    name character varying(128) NOT NULL,
+    keywords text[] DEFAULT '{}'::text[] NOT NULL,
+    patterns text[] DEFAULT '{}'::text[] NOT NULL,
+    negative_keywords text[] DEFAULT '{}'::text[] NOT NULL,
+    language character varying(8),
+    is_active boolean DEFAULT true NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: trigger_rule_hit; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.trigger_rule_hit (
+    id integer NOT NULL,
+    rule_id integer NOT NULL,
+    category_id integer NOT NULL,
+    user_profile_id integer NOT NULL,
+    source_type character varying(16) NOT NULL,
+    source_id integer NOT NULL,
+    matched text NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: trigger_rule_hit_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.trigger_rule_hit_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: trigger_rule_hit_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.trigger_rule_hit_id_seq OWNED BY public.trigger_rule_hit.id;
+
+
+--
+-- Name: trigger_rule_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.trigger_rule_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: trigger_rule_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.trigger_rule_id_seq OWNED BY public.trigger_rule.id;
+
+
+--
+-- Name: user_profile; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.user_profile (
+    id integer NOT NULL,
+    facebook_id character varying NOT NULL,
+    name character varying,
+    bio text,
+    location character varying,
+    work text,
+    education text,
+    relationship_status character varying,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    scraped_by_id integer NOT NULL,
+    is_scanned boolean DEFAULT false NOT NULL,
+    hometown character varying,
+    locale character varying(16) DEFAULT 'NOT_SPECIFIED'::character varying NOT NULL,
+    gender character varying(16),
+    birthday character varying(10),
+    email character varying(100),
+    phone character varying(12),
+    profile_url character varying DEFAULT 'NOT_SPECIFIED'::character varying NOT NULL,
+    is_analyzed boolean DEFAULT false
+);
+
+
+--
+-- Name: user_profile_category; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.user_profile_category (
+    user_profile_id integer NOT NULL,
+    category_id integer NOT NULL,
+    created_at timestamp without time zone DEFAULT now() NOT NULL,
+    model_score double precision,
+    gemini_score double precision,
+    final_score double precision,
+    final_score_at timestamp without time zone,
+    label smallint,
+    intent_score double precision,
+    model_scored_at timestamp without time zone,
+    model_version_id integer,
+    model_input_hash character varying(32),
+    model_contributions jsonb
+);
+
+
+--
+-- Name: COLUMN user_profile_category.model_version_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.user_profile_category.model_version_id IS 'Model version that produced model_score, NULL for unversioned models';
+
+
+--
+-- Name: COLUMN user_profile_category.model_input_hash; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.user_profile_category.model_input_hash IS 'Hash of the profile inputs model_score was computed from';
+
+
+--
+-- Name: COLUMN user_profile_category.model_contributions; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.user_profile_category.model_contributions IS 'SHAP contribution of each model input to model_score, with the embedding summed into one';
+
+
+--
+-- Name: user_profile_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.user_profile_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: user_profile_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.user_profile_id_seq OWNED BY public.user_profile.id;
+
+
+--
+-- Name: account id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account ALTER COLUMN id SET DEFAULT nextval('public.account_id_seq'::regclass);
+
+
+--
+-- Name: category id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.category ALTER COLUMN id SET DEFAULT nextval('public.category_id_seq'::regclass);
+
+
+--
+-- Name: comment id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comment ALTER COLUMN id SET DEFAULT nextval('public.comment_id_seq'::regclass);
+
+
+--
+-- Name: config id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.config ALTER COLUMN id SET DEFAULT nextval('public.config_id_seq'::regclass);
+
+
+--
+-- Name: embedded_profile id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.embedded_profile ALTER COLUMN id SET DEFAULT nextval('public.embedded_profile_id_seq'::regclass);
+
+
+--
+-- Name: financial_analysis id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.financial_analysis ALTER COLUMN id SET DEFAULT nextval('public.financial_analysis_id_seq'::regclass);
+
+
+--
+-- Name: gemini_key id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.gemini_key ALTER COLUMN id SET DEFAULT nextval('public.gemini_key_id_seq'::regclass);
+
+
+--
+-- Name: group id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public."group" ALTER COLUMN id SET DEFAULT nextval('public.group_id_seq'::regclass);
+
+
+--
+-- Name: llm_budget id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_budget ALTER COLUMN id SET DEFAULT nextval('public.llm_budget_id_seq'::regclass);
+
+
+--
+-- Name: llm_usage id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_usage ALTER COLUMN id SET DEFAULT nextval('public.llm_usage_id_seq'::regclass);
+
+
+--
+-- Name: log id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.log ALTER COLUMN id SET DEFAULT nextval('public.log_id_seq'::regclass);
+
+
+--
+-- Name: lookalike_seed id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lookalike_seed ALTER COLUMN id SET DEFAULT nextval('public.lookalike_seed_id_seq'::regclass);
+
+
+--
+-- Name: model id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model ALTER COLUMN id SET DEFAULT nextval('public.model_id_seq'::regclass);
+
+
+--
+-- Name: model_version id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_version ALTER COLUMN id SET DEFAULT nextval('public.model_version_id_seq'::regclass);
+
+
+--
+-- Name: persona id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.persona ALTER COLUMN id SET DEFAULT nextval('public.persona_id_seq'::regclass);
+
+
+--
+-- Name: post id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.post ALTER COLUMN id SET DEFAULT nextval('public.post_id_seq'::regclass);
+
+
+--
+-- Name: profile_duplicate id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_duplicate ALTER COLUMN id SET DEFAULT nextval('public.profile_duplicate_id_seq'::regclass);
+
+
+--
+-- Name: profile_merge id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_merge ALTER COLUMN id SET DEFAULT nextval('public.profile_merge_id_seq'::regclass);
+
+
+--
+-- Name: prompt id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.prompt ALTER COLUMN id SET DEFAULT nextval('public.prompt_id_seq'::regclass);
+
+
+--
+-- Name: proxy id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.proxy ALTER COLUMN id SET DEFAULT nextval('public.proxy_id_seq'::regclass);
+
+
+--
+-- Name: request id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request ALTER COLUMN id SET DEFAULT nextval('public.request_id_seq'::regclass);
+
+
+--
+-- Name: trigger_rule id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.trigger_rule ALTER COLUMN id SET DEFAULT nextval('public.trigger_rule_id_seq'::regclass);
+
+
+--
+-- Name: trigger_rule_hit id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.trigger_rule_hit ALTER COLUMN id SET DEFAULT nextval('public.trigger_rule_hit_id_seq'::regclass);
+
+
+--
+-- Name: user_profile id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_profile ALTER COLUMN id SET DEFAULT nextval('public.user_profile_id_seq'::regclass);
+
+
+--
+-- Name: account account_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account
+    ADD CONSTRAINT account_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: account account_username_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account
+    ADD CONSTRAINT account_username_key UNIQUE (username);
+
+
+--
+-- Name: category category_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.category
+    ADD CONSTRAINT category_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: comment comment_comment_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comment
+    ADD CONSTRAINT comment_comment_id_key UNIQUE (comment_id);
+
+
+--
+-- Name: comment_intent comment_intent_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comment_intent
+    ADD CONSTRAINT comment_intent_pkey PRIMARY KEY (comment_id, category_id);
+
+
+--
+-- Name: comment comment_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comment
+    ADD CONSTRAINT comment_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: config config_key_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.config
+    ADD CONSTRAINT config_key_key UNIQUE (key);
+
+
+--
+-- Name: config config_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.config
+    ADD CONSTRAINT config_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: embedded_profile embedded_profile_pid_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.embedded_profile
+    ADD CONSTRAINT embedded_profile_pid_key UNIQUE (pid, cid);
+
+
+--
+-- Name: embedded_profile embedded_profile_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.embedded_profile
+    ADD CONSTRAINT embedded_profile_pkey PRIMARY KEY (id);
+
+
 --
--- Name: category category_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: financial_analysis financial_analysis_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.financial_analysis
+    ADD CONSTRAINT financial_analysis_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: gemini_key gemini_key_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.gemini_key
+    ADD CONSTRAINT gemini_key_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: goose_db_version goose_db_version_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.goose_db_version
+    ADD CONSTRAINT goose_db_version_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: group_category group_category_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.group_category
+    ADD CONSTRAINT group_category_pkey PRIMARY KEY (group_id, category_id);
+
+
+--
+-- Name: group group_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public."group"
+    ADD CONSTRAINT group_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: llm_budget llm_budget_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_budget
+    ADD CONSTRAINT llm_budget_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: llm_cache llm_cache_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_cache
+    ADD CONSTRAINT llm_cache_pkey PRIMARY KEY (prompt_hash);
+
+
+--
+-- Name: llm_cache_stat llm_cache_stat_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_cache_stat
+    ADD CONSTRAINT llm_cache_stat_pkey PRIMARY KEY (date);
+
+
+--
+-- Name: llm_price llm_price_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_price
+    ADD CONSTRAINT llm_price_pkey PRIMARY KEY (model);
+
+
+--
+-- Name: llm_usage llm_usage_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_usage
+    ADD CONSTRAINT llm_usage_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: log log_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.log
+    ADD CONSTRAINT log_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: lookalike_seed lookalike_seed_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lookalike_seed
+    ADD CONSTRAINT lookalike_seed_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: lookalike_seed_profile lookalike_seed_profile_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lookalike_seed_profile
+    ADD CONSTRAINT lookalike_seed_profile_pkey PRIMARY KEY (seed_id, user_profile_id);
+
+
+--
+-- Name: model model_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model
+    ADD CONSTRAINT model_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: model_deployment model_deployment_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_deployment
+    ADD CONSTRAINT model_deployment_pkey PRIMARY KEY (category_id);
+
+
+--
+-- Name: model_drift model_drift_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_drift
+    ADD CONSTRAINT model_drift_pkey PRIMARY KEY (category_id, day);
+
+
+--
+-- Name: model_shadow_score model_shadow_score_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_shadow_score
+    ADD CONSTRAINT model_shadow_score_pkey PRIMARY KEY (version_id, user_profile_id, category_id);
+
+
+--
+-- Name: model_version model_version_model_id_version_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_version
+    ADD CONSTRAINT model_version_model_id_version_key UNIQUE (model_id, version);
+
+
+--
+-- Name: model_version model_version_path_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_version
+    ADD CONSTRAINT model_version_path_key UNIQUE (path);
+
+
+--
+-- Name: model_version model_version_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_version
+    ADD CONSTRAINT model_version_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: persona persona_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.persona
+    ADD CONSTRAINT persona_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: post post_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.post
+    ADD CONSTRAINT post_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: post post_post_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.post
+    ADD CONSTRAINT post_post_id_key UNIQUE (post_id);
+
+
+--
+-- Name: profile_duplicate profile_duplicate_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_duplicate
+    ADD CONSTRAINT profile_duplicate_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: profile_merge profile_merge_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_merge
+    ADD CONSTRAINT profile_merge_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: prompt prompt_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.prompt
+    ADD CONSTRAINT prompt_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: proxy proxy_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.proxy
+    ADD CONSTRAINT proxy_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: request request_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request
+    ADD CONSTRAINT request_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: request_status request_status_name_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_status
+    ADD CONSTRAINT request_status_name_key UNIQUE (name);
+
+
+--
+-- Name: request_status request_status_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request_status
+    ADD CONSTRAINT request_status_pkey PRIMARY KEY (code);
+
+
+--
+-- Name: retrain_policy retrain_policy_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.retrain_policy
+    ADD CONSTRAINT retrain_policy_pkey PRIMARY KEY (category_id);
+
+
+--
+-- Name: scoring_policy scoring_policy_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scoring_policy
+    ADD CONSTRAINT scoring_policy_pkey PRIMARY KEY (category_id);
+
+
+--
+-- Name: category uq_category_name; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
 ALTER TABLE ONLY public.category
-    ADD CONSTRAINT category_pkey PRIMARY KEY (id);
+    ADD CONSTRAINT uq_category_name UNIQUE (name);
 
 
 --
--- Name: comment comment_comment_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: group uq_group_account; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.comment
-    ADD CONSTRAINT comment_comment_id_key UNIQUE (comment_id);
+ALTER TABLE ONLY public."group"
+    ADD CONSTRAINT uq_group_account UNIQUE (group_id, account_id);
 
 
 --
--- Name: comment comment_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: proxy uq_ip_port_username; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.comment
-    ADD CONSTRAINT comment_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.proxy
+    ADD CONSTRAINT uq_ip_port_username UNIQUE (ip, port, username);
 
 
 --
--- Name: config config_key_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: lookalike_seed uq_lookalike_seed_category_name; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.config
-    ADD CONSTRAINT config_key_key UNIQUE (key);
+ALTER TABLE ONLY public.lookalike_seed
+    ADD CONSTRAINT uq_lookalike_seed_category_name UNIQUE (category_id, name);
 
 
 --
--- Name: config config_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: model uq_model_name; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.config
-    ADD CONSTRAINT config_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.model
+    ADD CONSTRAINT uq_model_name UNIQUE (name);
 
 
 --
--- Name: embedded_profile embedded_profile_pid_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: persona uq_persona_category_cluster; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.embedded_profile
-    ADD CONSTRAINT embedded_profile_pid_key UNIQUE (pid, cid);
+ALTER TABLE ONLY public.persona
+    ADD CONSTRAINT uq_persona_category_cluster UNIQUE (category_id, cluster);
+
+
+--
+-- Name: profile_duplicate uq_profile_duplicate_pair; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_duplicate
+    ADD CONSTRAINT uq_profile_duplicate_pair UNIQUE (profile_id, duplicate_id);
+
+
+--
+-- Name: prompt uq_prompt_service_name_category; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.prompt
+    ADD CONSTRAINT uq_prompt_service_name_category UNIQUE (service_name, category_id);
+
+
+--
+-- Name: prompt uq_service_category_version; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.prompt
+    ADD CONSTRAINT uq_service_category_version UNIQUE (service_name, category_id, version);
+
+
+--
+-- Name: prompt uq_service_name_version; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.prompt
+    ADD CONSTRAINT uq_service_name_version UNIQUE (service_name, version);
 
 
 --
--- Name: embedded_profile embedded_profile_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: trigger_rule trigger_rule_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.embedded_profile
-    ADD CONSTRAINT embedded_profile_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.trigger_rule
+    ADD CONSTRAINT trigger_rule_pkey PRIMARY KEY (id);
 
 
 --
--- Name: financial_analysis financial_analysis_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: trigger_rule_hit trigger_rule_hit_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.financial_analysis
-    ADD CONSTRAINT financial_analysis_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.trigger_rule_hit
+    ADD CONSTRAINT trigger_rule_hit_pkey PRIMARY KEY (id);
 
 
 --
--- Name: gemini_key gemini_key_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: trigger_rule_hit uq_trigger_rule_hit_source; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.gemini_key
-    ADD CONSTRAINT gemini_key_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.trigger_rule_hit
+    ADD CONSTRAINT uq_trigger_rule_hit_source UNIQUE (rule_id, user_profile_id, source_type, source_id);
 
 
 --
--- Name: goose_db_version goose_db_version_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: user_profile_category user_profile_category_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.goose_db_version
-    ADD CONSTRAINT goose_db_version_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.user_profile_category
+    ADD CONSTRAINT user_profile_category_pkey PRIMARY KEY (user_profile_id, category_id);
 
 
 --
--- Name: group_category group_category_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: user_profile user_profile_facebook_id_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.group_category
-    ADD CONSTRAINT group_category_pkey PRIMARY KEY (group_id, category_id);
+ALTER TABLE ONLY public.user_profile
+    ADD CONSTRAINT user_profile_facebook_id_key UNIQUE (facebook_id);
 
 
 --
--- Name: group group_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: user_profile user_profile_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public."group"
-    ADD CONSTRAINT group_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.user_profile
+    ADD CONSTRAINT user_profile_pkey PRIMARY KEY (id);
 
 
 --
--- Name: log log_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_comment_intent_category_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.log
-    ADD CONSTRAINT log_pkey PRIMARY KEY (id);
+CREATE INDEX idx_comment_intent_category_id ON public.comment_intent USING btree (category_id);
 
 
 --
--- Name: model model_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_comment_not_analyzed; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.model
-    ADD CONSTRAINT model_pkey PRIMARY KEY (id);
+CREATE INDEX idx_comment_not_analyzed ON public.comment USING btree (inserted_at) WHERE (is_analyzed = false);
 
 
 --
--- Name: post post_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_embedded_profile_cid; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.post
-    ADD CONSTRAINT post_pkey PRIMARY KEY (id);
+CREATE INDEX idx_embedded_profile_cid ON public.embedded_profile USING btree (cid);
 
 
 --
--- Name: post post_post_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_embedded_profile_persona_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.post
-    ADD CONSTRAINT post_post_id_key UNIQUE (post_id);
+CREATE INDEX idx_embedded_profile_persona_id ON public.embedded_profile USING btree (persona_id);
 
 
 --
--- Name: prompt prompt_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_group_category_category_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.prompt
-    ADD CONSTRAINT prompt_pkey PRIMARY KEY (id);
+CREATE INDEX idx_group_category_category_id ON public.group_category USING btree (category_id);
 
 
 --
--- Name: proxy proxy_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_llm_cache_expires_at; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.proxy
-    ADD CONSTRAINT proxy_pkey PRIMARY KEY (id);
+CREATE INDEX idx_llm_cache_expires_at ON public.llm_cache USING btree (expires_at);
 
 
 --
--- Name: request request_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_llm_usage_category_id_created_at; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.request
-    ADD CONSTRAINT request_pkey PRIMARY KEY (id);
+CREATE INDEX idx_llm_usage_category_id_created_at ON public.llm_usage USING btree (category_id, created_at);
 
 
 --
--- Name: category uq_category_name; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_llm_usage_created_at; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.category
-    ADD CONSTRAINT uq_category_name UNIQUE (name);
+CREATE INDEX idx_llm_usage_created_at ON public.llm_usage USING btree (created_at);
 
 
 --
--- Name: group uq_group_account; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_model_drift_is_alert; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public."group"
-    ADD CONSTRAINT uq_group_account UNIQUE (group_id, account_id);
+CREATE INDEX idx_model_drift_is_alert ON public.model_drift USING btree (day) WHERE is_alert;
 
 
 --
--- Name: proxy uq_ip_port_username; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_model_shadow_score_category_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.proxy
-    ADD CONSTRAINT uq_ip_port_username UNIQUE (ip, port, username);
+CREATE INDEX idx_model_shadow_score_category_id ON public.model_shadow_score USING btree (category_id);
 
 
 --
--- Name: model uq_model_name; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_profile_duplicate_status; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.model
-    ADD CONSTRAINT uq_model_name UNIQUE (name);
+CREATE INDEX idx_profile_duplicate_status ON public.profile_duplicate USING btree (status, confidence);
 
 
 --
--- Name: prompt uq_prompt_service_name_category; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_profile_merge_merged_facebook_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.prompt
-    ADD CONSTRAINT uq_prompt_service_name_category UNIQUE (service_name, category_id);
+CREATE INDEX idx_profile_merge_merged_facebook_id ON public.profile_merge USING btree (merged_facebook_id);
 
 
 --
--- Name: prompt uq_service_category_version; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_profile_merge_survivor_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.prompt
-    ADD CONSTRAINT uq_service_category_version UNIQUE (service_name, category_id, version);
+CREATE INDEX idx_profile_merge_survivor_id ON public.profile_merge USING btree (survivor_id);
 
 
 --
--- Name: prompt uq_service_name_version; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_request_finished_at; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.prompt
-    ADD CONSTRAINT uq_service_name_version UNIQUE (service_name, version);
+CREATE INDEX idx_request_finished_at ON public.request USING btree (finished_at) WHERE (finished_at IS NOT NULL);
 
 
 --
--- Name: user_profile_category user_profile_category_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_request_status; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.user_profile_category
-    ADD CONSTRAINT user_profile_category_pkey PRIMARY KEY (user_profile_id, category_id);
+CREATE INDEX idx_request_status ON public.request USING btree (status);
 
 
 --
--- Name: user_profile user_profile_facebook_id_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_trigger_rule_category_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.user_profile
-    ADD CONSTRAINT user_profile_facebook_id_key UNIQUE (facebook_id);
+CREATE INDEX idx_trigger_rule_category_id ON public.trigger_rule USING btree (category_id);
 
 
 --
--- Name: user_profile user_profile_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: idx_trigger_rule_hit_user_profile_id; Type: INDEX; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.user_profile
-    ADD CONSTRAINT user_profile_pkey PRIMARY KEY (id);
+CREATE INDEX idx_trigger_rule_hit_user_profile_id ON public.trigger_rule_hit USING btree (user_profile_id);
 
 
 --
--- Name: embedded_profile_embedding_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: idx_user_profile_category_category_id; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE INDEX embedded_profile_embedding_idx ON public.embedded_profile USING ivfflat (embedding public.vector_cosine_ops) WITH (lists='100');
+CREATE INDEX idx_user_profile_category_category_id ON public.user_profile_category USING btree (category_id);
 
 
 --
--- Name: idx_group_category_category_id; Type: INDEX; Schema: public; Owner: -
+-- Name: idx_user_profile_category_final_score; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_group_category_category_id ON public.group_category USING btree (category_id);
+CREATE INDEX idx_user_profile_category_final_score ON public.user_profile_category USING btree (category_id, final_score DESC NULLS LAST);
 
 
 --
--- Name: idx_user_profile_category_category_id; Type: INDEX; Schema: public; Owner: -
+-- Name: idx_user_profile_category_model_scored_at; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE INDEX idx_user_profile_category_category_id ON public.user_profile_category USING btree (category_id);
+CREATE INDEX idx_user_profile_category_model_scored_at ON public.user_profile_category USING btree (category_id, model_scored_at) WHERE (model_scored_at IS NOT NULL);
 
 
 --
@@ -997,6 +2051,13 @@ CREATE INDEX ix_config_id ON public.config USING btree (id);
 CREATE UNIQUE INDEX ix_config_key ON public.config USING btree (key);
 
 
+--
+-- Name: uq_llm_budget_category_period; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX uq_llm_budget_category_period ON public.llm_budget USING btree (COALESCE(category_id, 0), period);
+
+
 --
 -- Name: uq_model_category_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -1020,6 +2081,22 @@ ALTER TABLE ONLY public.comment
     ADD CONSTRAINT comment_author_id_fkey FOREIGN KEY (author_id) REFERENCES public.user_profile(id);
 
 
+--
+-- Name: comment_intent comment_intent_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comment_intent
+    ADD CONSTRAINT comment_intent_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: comment_intent comment_intent_comment_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comment_intent
+    ADD CONSTRAINT comment_intent_comment_id_fkey FOREIGN KEY (comment_id) REFERENCES public.comment(id) ON DELETE CASCADE;
+
+
 --
 -- Name: comment comment_post_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1036,6 +2113,14 @@ ALTER TABLE ONLY public.embedded_profile
     ADD CONSTRAINT embedded_profile_category_id_fkey FOREIGN KEY (cid) REFERENCES public.category(id) ON UPDATE CASCADE ON DELETE SET NULL;
 
 
+--
+-- Name: embedded_profile embedded_profile_persona_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.embedded_profile
+    ADD CONSTRAINT embedded_profile_persona_id_fkey FOREIGN KEY (persona_id) REFERENCES public.persona(id) ON DELETE SET NULL;
+
+
 --
 -- Name: financial_analysis financial_analysis_prompt_used_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1076,6 +2161,30 @@ ALTER TABLE ONLY public.group_category
     ADD CONSTRAINT group_category_group_id_fkey FOREIGN KEY (group_id) REFERENCES public."group"(id) ON DELETE CASCADE;
 
 
+--
+-- Name: llm_budget llm_budget_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_budget
+    ADD CONSTRAINT llm_budget_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: llm_usage llm_usage_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_usage
+    ADD CONSTRAINT llm_usage_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE SET NULL;
+
+
+--
+-- Name: llm_usage llm_usage_key_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.llm_usage
+    ADD CONSTRAINT llm_usage_key_id_fkey FOREIGN KEY (key_id) REFERENCES public.gemini_key(id) ON DELETE SET NULL;
+
+
 --
 -- Name: log log_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1084,6 +2193,30 @@ ALTER TABLE ONLY public.log
     ADD CONSTRAINT log_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.account(id) ON UPDATE CASCADE ON DELETE CASCADE;
 
 
+--
+-- Name: lookalike_seed lookalike_seed_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lookalike_seed
+    ADD CONSTRAINT lookalike_seed_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: lookalike_seed_profile lookalike_seed_profile_seed_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lookalike_seed_profile
+    ADD CONSTRAINT lookalike_seed_profile_seed_id_fkey FOREIGN KEY (seed_id) REFERENCES public.lookalike_seed(id) ON DELETE CASCADE;
+
+
+--
+-- Name: lookalike_seed_profile lookalike_seed_profile_user_profile_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lookalike_seed_profile
+    ADD CONSTRAINT lookalike_seed_profile_user_profile_id_fkey FOREIGN KEY (user_profile_id) REFERENCES public.user_profile(id) ON DELETE CASCADE;
+
+
 --
 -- Name: model model_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1092,6 +2225,102 @@ ALTER TABLE ONLY public.model
     ADD CONSTRAINT model_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE SET NULL;
 
 
+--
+-- Name: model_deployment model_deployment_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_deployment
+    ADD CONSTRAINT model_deployment_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: model_deployment model_deployment_challenger_version_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_deployment
+    ADD CONSTRAINT model_deployment_challenger_version_id_fkey FOREIGN KEY (challenger_version_id) REFERENCES public.model_version(id) ON DELETE SET NULL;
+
+
+--
+-- Name: model_deployment model_deployment_champion_version_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_deployment
+    ADD CONSTRAINT model_deployment_champion_version_id_fkey FOREIGN KEY (champion_version_id) REFERENCES public.model_version(id) ON DELETE SET NULL;
+
+
+--
+-- Name: model_drift model_drift_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_drift
+    ADD CONSTRAINT model_drift_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: model_drift model_drift_version_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_drift
+    ADD CONSTRAINT model_drift_version_id_fkey FOREIGN KEY (version_id) REFERENCES public.model_version(id) ON DELETE SET NULL;
+
+
+--
+-- Name: model_shadow_score model_shadow_score_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_shadow_score
+    ADD CONSTRAINT model_shadow_score_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: model_shadow_score model_shadow_score_user_profile_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_shadow_score
+    ADD CONSTRAINT model_shadow_score_user_profile_id_fkey FOREIGN KEY (user_profile_id) REFERENCES public.user_profile(id) ON DELETE CASCADE;
+
+
+--
+-- Name: model_shadow_score model_shadow_score_version_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_shadow_score
+    ADD CONSTRAINT model_shadow_score_version_id_fkey FOREIGN KEY (version_id) REFERENCES public.model_version(id) ON DELETE CASCADE;
+
+
+--
+-- Name: model_version model_version_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_version
+    ADD CONSTRAINT model_version_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE SET NULL;
+
+
+--
+-- Name: model_version model_version_model_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_version
+    ADD CONSTRAINT model_version_model_id_fkey FOREIGN KEY (model_id) REFERENCES public.model(id) ON DELETE CASCADE;
+
+
+--
+-- Name: model_version model_version_parent_version_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.model_version
+    ADD CONSTRAINT model_version_parent_version_id_fkey FOREIGN KEY (parent_version_id) REFERENCES public.model_version(id) ON DELETE SET NULL;
+
+
+--
+-- Name: persona persona_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.persona
+    ADD CONSTRAINT persona_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
 --
 -- Name: post post_group_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1100,6 +2329,30 @@ ALTER TABLE ONLY public.post
     ADD CONSTRAINT post_group_id_fkey FOREIGN KEY (group_id) REFERENCES public."group"(id);
 
 
+--
+-- Name: profile_duplicate profile_duplicate_duplicate_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_duplicate
+    ADD CONSTRAINT profile_duplicate_duplicate_id_fkey FOREIGN KEY (duplicate_id) REFERENCES public.user_profile(id) ON DELETE CASCADE;
+
+
+--
+-- Name: profile_duplicate profile_duplicate_profile_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_duplicate
+    ADD CONSTRAINT profile_duplicate_profile_id_fkey FOREIGN KEY (profile_id) REFERENCES public.user_profile(id) ON DELETE CASCADE;
+
+
+--
+-- Name: profile_merge profile_merge_survivor_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.profile_merge
+    ADD CONSTRAINT profile_merge_survivor_id_fkey FOREIGN KEY (survivor_id) REFERENCES public.user_profile(id) ON DELETE SET NULL;
+
+
 --
 -- Name: prompt prompt_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1108,6 +2361,62 @@ ALTER TABLE ONLY public.prompt
     ADD CONSTRAINT prompt_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
 
 
+--
+-- Name: request request_status_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.request
+    ADD CONSTRAINT request_status_fkey FOREIGN KEY (status) REFERENCES public.request_status(code);
+
+
+--
+-- Name: retrain_policy retrain_policy_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.retrain_policy
+    ADD CONSTRAINT retrain_policy_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: scoring_policy scoring_policy_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.scoring_policy
+    ADD CONSTRAINT scoring_policy_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: trigger_rule trigger_rule_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.trigger_rule
+    ADD CONSTRAINT trigger_rule_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: trigger_rule_hit trigger_rule_hit_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.trigger_rule_hit
+    ADD CONSTRAINT trigger_rule_hit_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
+
+
+--
+-- Name: trigger_rule_hit trigger_rule_hit_rule_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.trigger_rule_hit
+    ADD CONSTRAINT trigger_rule_hit_rule_id_fkey FOREIGN KEY (rule_id) REFERENCES public.trigger_rule(id) ON DELETE CASCADE;
+
+
+--
+-- Name: trigger_rule_hit trigger_rule_hit_user_profile_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.trigger_rule_hit
+    ADD CONSTRAINT trigger_rule_hit_user_profile_id_fkey FOREIGN KEY (user_profile_id) REFERENCES public.user_profile(id) ON DELETE CASCADE;
+
+
 --
 -- Name: user_profile_category user_profile_category_category_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1116,6 +2425,14 @@ ALTER TABLE ONLY public.user_profile_category
     ADD CONSTRAINT user_profile_category_category_id_fkey FOREIGN KEY (category_id) REFERENCES public.category(id) ON DELETE CASCADE;
 
 
+--
+-- Name: user_profile_category user_profile_category_model_version_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.user_profile_category
+    ADD CONSTRAINT user_profile_category_model_version_id_fkey FOREIGN KEY (model_version_id) REFERENCES public.model_version(id) ON DELETE SET NULL;
+
+
 --
 -- Name: user_profile_category user_profile_category_user_profile_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/pkg/explain"
 	"github.com/qxbao/asfpc/pkg/generative"
 	"github.com/qxbao/asfpc/pkg/utils/python"
 )
@@ -11,10 +12,40 @@ import (
 type GeminiScoringTaskInput struct {
 	Ctx     context.Context
 	Gs      *generative.GenerativeService
+	Cache   *generative.ResponseCache
 	Prompt  string
 	Profile *db.GetProfilesAnalysisCronjobRow
 }
 
+type GeminiBatchScoringTaskInput struct {
+	Ctx                 context.Context
+	Gs                  *generative.GenerativeService
+	Cache               *generative.ResponseCache
+	Template            string
+	BusinessDescription string
+	Items               []GeminiScoringItem
+}
+
+// GeminiScoringItem keeps the single-profile prompt of a batched profile. It
+// is the cache key and the prompt used when the profile is retried alone.
+type GeminiScoringItem struct {
+	Prompt  string
+	Profile *db.GetProfilesAnalysisCronjobRow
+}
+
+type GeminiScoringResult struct {
+	ProfileID int32
+	Err       error
+}
+
+type CommentIntentTaskInput struct {
+	Ctx        context.Context
+	Gs         *generative.GenerativeService
+	CategoryID int32
+	Prompt     string
+	Comments   []db.GetCommentsForIntentAnalysisRow
+}
+
 type GeminiEmbeddingTaskInput struct {
 	Ctx context.Context
 	Id  int32
@@ -25,8 +56,101 @@ type FindSimilarProfilesDTO struct {
 	ProfileID  *int32 `query:"profile_id" validate:"required"`
 	CategoryID *int32 `query:"category_id" validate:"required"`
 	TopK       *int32 `query:"top_k" validate:"min=1,max=20"`
+	// EfSearch overrides VECTOR_SEARCH_EF_SEARCH for this request
+	EfSearch *int `query:"ef_search"`
+}
+
+type SearchProfilesDTO struct {
+	Query      string `query:"q"`
+	CategoryID *int32 `query:"category_id"`
+	// Location matches the location or the hometown, case-insensitive
+	Location *string `query:"location"`
+	// ScannedAfter and ScannedBefore take a date (2006-01-02) or RFC 3339
+	ScannedAfter  *string  `query:"scanned_after"`
+	ScannedBefore *string  `query:"scanned_before"`
+	MinScore      *float64 `query:"min_score"`
+	TopK          *int32   `query:"top_k"`
+	// EfSearch overrides VECTOR_SEARCH_EF_SEARCH for this request
+	EfSearch *int `query:"ef_search"`
+}
+
+type ClusterPersonasDTO struct {
+	CategoryID *int32 `json:"category_id" validate:"required"`
+	// K is the number of personas, PERSONA_CLUSTERS by default
+	K *int `json:"k"`
+	// Label asks Gemini for a label of each persona, true by default
+	Label *bool `json:"label"`
+}
+
+type GetPersonasDTO struct {
+	CategoryID *int32 `query:"category_id" validate:"required"`
+	// Members is the number of representative members of each persona
+	Members *int32 `query:"members"`
+}
+
+type PersonaSummary struct {
+	db.GetPersonasRow
+	Members []db.GetPersonaMembersRow `json:"members"`
+}
+
+type RebuildEmbeddingIndexDTO struct {
+	// Mode is reindex (default) or rebuild
+	Mode string `json:"mode"`
+	// CategoryIDs limits the rebuild, empty means every category
+	CategoryIDs []int32 `json:"category_ids"`
 }
 
 type AddAllProfilesToCategoryDTO struct {
 	CategoryID *int32 `json:"category_id" validate:"required"`
 }
+
+type BackfillProfileCategoriesDTO struct {
+	CategoryID *int32 `json:"category_id"` // nullable, all categories when empty
+}
+
+type ClearLLMCacheDTO struct {
+	Model       *string `json:"model"`        // nullable, all models when empty
+	ExpiredOnly bool    `json:"expired_only"` // only drop entries past their TTL
+}
+
+type ExplainProfileDTO struct {
+	CategoryID *int32 `query:"category_id" validate:"required"`
+	// Rationale asks Gemini why the profile got its score, true by default
+	Rationale *bool `query:"rationale"`
+}
+
+type ProfileExplanation struct {
+	ProfileID      int32                `json:"profile_id"`
+	CategoryID     int32                `json:"category_id"`
+	ModelScore     *float64             `json:"model_score"`
+	GeminiScore    *float64             `json:"gemini_score"`
+	FinalScore     *float64             `json:"final_score"`
+	ModelVersionID *int32               `json:"model_version_id"`
+	Explanation    *explain.Explanation `json:"explanation"`
+	// ExplanationSource is "stored" when the contributions were saved with the
+	// score and "live" when they were computed for this request
+	ExplanationSource string  `json:"explanation_source,omitempty"`
+	Rationale         *string `json:"rationale"`
+	RationaleError    string  `json:"rationale_error,omitempty"`
+}
+
+type DetectDuplicatesDTO struct {
+	// CategoryIDs limits the embedding comparison, empty means every category
+	CategoryIDs []int32 `json:"category_ids"`
+}
+
+type GetProfileDuplicatesDTO struct {
+	Page  *int32 `query:"page"`
+	Limit *int32 `query:"limit"`
+	// Status is pending (default) or dismissed
+	Status        string   `query:"status"`
+	MinConfidence *float64 `query:"min_confidence"`
+}
+
+type MergeProfilesDTO struct {
+	// CandidateID accepts a merge candidate, its older profile survives
+	// unless survivor_id and merged_id are given
+	CandidateID *int32 `json:"candidate_id"`
+	SurvivorID  *int32 `json:"survivor_id"`
+	MergedID    *int32 `json:"merged_id"`
+}
@@ -16,4 +16,17 @@ type AddGroupCategoryRequest struct {
 	CategoryId int32  `json:"category_id" validate:"required"`
 }
 
-type DeleteGroupCategoryRequest = AddGroupCategoryRequest
\ No newline at end of file
+type DeleteGroupCategoryRequest = AddGroupCategoryRequest
+type AddTriggerRuleRequest struct {
+	CategoryID       int32    `json:"category_id" validate:"required"`
+	Name             string   `json:"name" validate:"required"`
+	Keywords         []string `json:"keywords"`
+	Patterns         []string `json:"patterns"`
+	NegativeKeywords []string `json:"negative_keywords"`
+	Language         string   `json:"language"` // "vi", "en" or empty for any
+}
+
+type SetTriggerRuleActiveRequest struct {
+	ID       int32 `json:"id" validate:"required"`
+	IsActive bool  `json:"is_active"`
+}
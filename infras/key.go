@@ -6,4 +6,22 @@ type AddGeminiKeyDTO struct {
 
 type DeleteGeminiKeyDTO struct {
 	KeyID int32 `json:"key_id" binding:"required"`
-}
\ No newline at end of file
+}
+type GetLLMUsageDTO struct {
+	Days *int32 `query:"days"`
+}
+
+type UpsertLLMPriceDTO struct {
+	Model       string  `json:"model"`
+	Provider    string  `json:"provider"`
+	InputPrice  float64 `json:"input_price"`  // USD per 1M prompt tokens
+	OutputPrice float64 `json:"output_price"` // USD per 1M output tokens
+}
+
+type UpsertLLMBudgetDTO struct {
+	CategoryID *int32   `json:"category_id"` // nullable, global budget when empty
+	Period     string   `json:"period"`      // daily or monthly
+	MaxTokens  *int64   `json:"max_tokens"`
+	MaxCost    *float64 `json:"max_cost"` // USD
+	IsActive   *bool    `json:"is_active"`
+}
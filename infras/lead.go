@@ -0,0 +1,62 @@
+package infras
+
+import "github.com/qxbao/asfpc/db"
+
+type GetLeadsDTO struct {
+	CategoryID *int32   `query:"category_id" validate:"required"`
+	Page       *int32   `query:"page"`
+	Limit      *int32   `query:"limit"`
+	MinScore   *float64 `query:"min_score"`
+	Order      string   `query:"order"` // "asc" or "desc" (default)
+}
+
+type ExportLeadsDTO struct {
+	CategoryID *int32   `query:"category_id" validate:"required"`
+	MinScore   *float64 `query:"min_score"`
+}
+
+type UpsertScoringPolicyDTO struct {
+	CategoryID         int32   `json:"category_id" validate:"required"`
+	GeminiWeight       float64 `json:"gemini_weight"`
+	ModelWeight        float64 `json:"model_weight"`
+	CompletenessWeight float64 `json:"completeness_weight"`
+	IntentWeight       float64 `json:"intent_weight"`
+	MinCompleteness    int32   `json:"min_completeness"`
+	DecayHalfLifeDays  float64 `json:"decay_half_life_days"`
+}
+
+type CalibrateLeadsDTO struct {
+	CategoryID int32  `json:"category_id" validate:"required"`
+	Method     string `json:"method" validate:"required"` // "platt", "isotonic" or "none"
+}
+
+type LabelLeadDTO struct {
+	ProfileID  int32 `json:"profile_id" validate:"required"`
+	CategoryID int32 `json:"category_id" validate:"required"`
+	Label      *bool `json:"label"` // null clears the label
+}
+
+type UploadLookalikeSeedDTO struct {
+	CategoryID int32  `json:"category_id" validate:"required"`
+	Name       string `json:"name" validate:"required"`
+	// ProfileIDs and FacebookIDs identify the seed profiles, either works
+	ProfileIDs  []int32  `json:"profile_ids"`
+	FacebookIDs []string `json:"facebook_ids"`
+	// Replace drops the profiles already in the seed
+	Replace bool `json:"replace"`
+}
+
+type GetLookalikesDTO struct {
+	SeedID   *int32 `query:"seed_id" validate:"required"`
+	Mode     string `query:"mode"` // "centroid" (default) or "multi"
+	TopK     *int32 `query:"top_k"`
+	EfSearch *int   `query:"ef_search"`
+}
+
+type LookalikeSeedUpload struct {
+	Seed    db.LookalikeSeed `json:"seed"`
+	Matched int              `json:"matched"`
+	Added   int64            `json:"added"`
+	// Unmatched lists the uploaded ids without a profile
+	Unmatched []string `json:"unmatched"`
+}
@@ -7,6 +7,10 @@ type MLTrainDTO struct {
 	CategoryID *int32  `json:"category_id,omitempty"`
 }
 
+type CancelTrainingDTO struct {
+	RequestID int32 `json:"request_id" validate:"required"`
+}
+
 type WithModelNameDTO struct {
 	ModelName string `json:"model_name" query:"model_name" validate:"required"`
 }
@@ -21,3 +21,32 @@ type AssignModelToCategoryRequest struct {
 	ModelID    int32 `json:"model_id" validate:"required"`
 	CategoryID int32 `json:"category_id" validate:"required"`
 }
+
+type PromoteModelVersionRequest struct {
+	VersionID int32 `json:"version_id" validate:"required"`
+	// CategoryID defaults to the category the version was trained for
+	CategoryID *int32 `json:"category_id"`
+}
+
+type SetModelChallengerRequest struct {
+	CategoryID int32 `json:"category_id" validate:"required"`
+	// VersionID clears the challenger when null
+	VersionID  *int32   `json:"version_id"`
+	SampleRate *float64 `json:"sample_rate" validate:"omitempty,min=0,max=1"`
+}
+
+type UpsertRetrainPolicyRequest struct {
+	CategoryID int32 `json:"category_id" validate:"required"`
+	// ModelName is the model new versions are added to, Category_<id> by
+	// default
+	ModelName string `json:"model_name"`
+	IsEnabled *bool  `json:"is_enabled"`
+	// IntervalHours and MinNewProfiles trigger a retrain, 0 disables them
+	IntervalHours  int32 `json:"interval_hours" validate:"min=0"`
+	MinNewProfiles int32 `json:"min_new_profiles" validate:"min=0"`
+	// Margins the new version must beat the champion by to be promoted
+	RMSEMargin float64 `json:"rmse_margin" validate:"min=0"`
+	R2Margin   float64 `json:"r2_margin" validate:"min=0"`
+	AutoTune   bool    `json:"auto_tune"`
+	Trials     *int32  `json:"trials" validate:"omitempty,min=1"`
+}
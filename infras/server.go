@@ -3,10 +3,18 @@ package infras
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/pkg/jobs"
 	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/progress"
+	"github.com/qxbao/asfpc/pkg/utils/python"
 	"go.uber.org/fx"
 )
 
@@ -16,6 +24,12 @@ type Server struct {
 	Database     *sql.DB
 	Queries      *db.Queries
 	Echo         *echo.Echo
+	// Python is the long lived python worker, nil when it is disabled.
+	Python       *python.Worker
+	// Progress pushes request updates to their streams.
+	Progress     *progress.Broker
+	// Jobs runs the background requests.
+	Jobs         *jobs.Runner
 }
 
 var logger = lg.GetLogger("Infras")
@@ -36,6 +50,100 @@ func (s *Server) GetConfig(ctx context.Context, key string, replace string) stri
 	return config.Value
 }
 
+// GetIntConfig returns a config holding a positive integer, falling back when
+// the stored value is not one.
+func (s *Server) GetIntConfig(ctx context.Context, key string, fallback int) int {
+	v, err := strconv.Atoi(strings.TrimSpace(s.GetConfig(ctx, key, strconv.Itoa(fallback))))
+	if err != nil || v <= 0 {
+		logger.Warnf("Invalid %s, using %d", key, fallback)
+		return fallback
+	}
+	return v
+}
+
+// GetFloatConfig returns a config holding a number in [min, max], falling back
+// when the stored value is not one.
+func (s *Server) GetFloatConfig(ctx context.Context, key string, fallback, min, max float64) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s.GetConfig(ctx, key, strconv.FormatFloat(fallback, 'f', -1, 64))), 64)
+	if err != nil || v < min || v > max {
+		logger.Warnf("Invalid %s, using %g", key, fallback)
+		return fallback
+	}
+	return v
+}
+
+// PythonClient applies the python process limits from the config table, so
+// changed settings take effect on the next task, and returns a client that
+// uses the worker when it is enabled.
+func (s *Server) PythonClient(ctx context.Context) *python.Client {
+	python.SetLimits(python.LimitsFromConfig(func(key, defaultValue string) string {
+		return s.GetConfig(ctx, key, defaultValue)
+	}, python.DefaultLimits()))
+	return python.NewClient(s.Python)
+}
+
+// CreateRequest records a queued request of the given kind.
+func (s *Server) CreateRequest(ctx context.Context, kind jobs.Kind, description string) (int32, error) {
+	return s.Queries.CreateRequest(ctx, db.CreateRequestParams{
+		Kind:        string(kind),
+		Description: sql.NullString{String: truncate(description, 255), Valid: description != ""},
+	})
+}
+
+// UpdateRequest writes a request's status and publishes it to the request's
+// stream. Details are only published.
+func (s *Server) UpdateRequest(ctx context.Context, u jobs.Update) error {
+	var result db.NullableJSON
+	if u.Result != nil {
+		data, err := json.Marshal(u.Result)
+		if err != nil {
+			return fmt.Errorf("failed to encode request result: %w", err)
+		}
+		result = data
+	}
+	err := s.Queries.UpdateRequestStatus(ctx, db.UpdateRequestStatusParams{
+		ID:           u.ID,
+		Status:       int16(u.Status),
+		ErrorMessage: sql.NullString{String: u.ErrorMessage, Valid: u.ErrorMessage != ""},
+		Progress:     u.Progress,
+		Description:  sql.NullString{String: truncate(u.Description, 255), Valid: u.Description != ""},
+		Result:       result,
+	})
+	if err != nil {
+		return err
+	}
+	if s.Progress != nil {
+		s.Progress.Publish(progress.Event{
+			RequestID:    u.ID,
+			Status:       int16(u.Status),
+			Progress:     u.Progress,
+			Description:  u.Description,
+			ErrorMessage: u.ErrorMessage,
+			Details:      u.Details,
+			UpdatedAt:    time.Now(),
+			Final:        u.Status.Final(),
+		})
+	}
+	return nil
+}
+
+func (s *Server) InterruptRequests(ctx context.Context) (int64, error) {
+	return s.Queries.InterruptRequests(ctx)
+}
+
+func (s *Server) DeleteFinishedRequests(ctx context.Context, finishedBefore time.Time) ([]int32, error) {
+	return s.Queries.DeleteFinishedRequests(ctx, finishedBefore)
+}
+
+// truncate cuts v to at most n runes to fit a varchar column.
+func truncate(v string, n int) string {
+	r := []rune(v)
+	if len(r) <= n {
+		return v
+	}
+	return string(r[:n])
+}
+
 func (s *Server) GetConfigs(ctx context.Context) (map[string]string, error) {
 	configs, err := s.Queries.GetAllConfigs(ctx)
 	if err != nil {
@@ -5,7 +5,9 @@ import (
 	"github.com/qxbao/asfpc/server/modules/cron"
 	"github.com/qxbao/asfpc/server/modules/database"
 	"github.com/qxbao/asfpc/server/modules/routes"
+	"github.com/qxbao/asfpc/server/modules/runner"
 	"github.com/qxbao/asfpc/server/modules/seeding"
+	"github.com/qxbao/asfpc/server/modules/worker"
 	"go.uber.org/fx"
 )
 
@@ -14,6 +16,8 @@ func main() {
 		database.DatabaseModule,
 		server.ServerModule,
 		seeding.SeedModule,
+		worker.WorkerModule,
+		runner.RunnerModule,
 		cron.CronModule,
 		routes.RoutesModule,
 	)
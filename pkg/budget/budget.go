@@ -0,0 +1,113 @@
+package budget
+
+import (
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+)
+
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// Budget is a spend limit with the usage of its current window. A nil
+// CategoryID is the global budget, a nil limit is not enforced.
+type Budget struct {
+	ID         int32
+	CategoryID *int32
+	Period     string
+	MaxTokens  *int64
+	MaxCost    *float64
+	UsedTokens int64
+	UsedCost   float64
+}
+
+// Guard tells which LLM work may still be dispatched.
+type Guard struct {
+	global     []Budget
+	byCategory map[int32][]Budget
+}
+
+func ValidPeriod(period string) bool {
+	return period == PeriodDaily || period == PeriodMonthly
+}
+
+// FromRow converts an active llm_budget row with its window usage.
+func FromRow(row db.GetLlmBudgetUsageRow) Budget {
+	b := Budget{
+		ID:         row.ID,
+		Period:     row.Period,
+		UsedTokens: row.UsedTokens,
+		UsedCost:   row.UsedCost,
+	}
+	if row.CategoryID.Valid {
+		b.CategoryID = &row.CategoryID.Int32
+	}
+	if row.MaxTokens.Valid {
+		b.MaxTokens = &row.MaxTokens.Int64
+	}
+	if row.MaxCost.Valid {
+		b.MaxCost = &row.MaxCost.Float64
+	}
+	return b
+}
+
+func (b Budget) Exhausted() bool {
+	if b.MaxTokens != nil && b.UsedTokens >= *b.MaxTokens {
+		return true
+	}
+	if b.MaxCost != nil && b.UsedCost >= *b.MaxCost {
+		return true
+	}
+	return false
+}
+
+// WindowStart returns the start of the budget window containing now.
+func (b Budget) WindowStart(now time.Time) time.Time {
+	y, m, d := now.Date()
+	if b.Period == PeriodMonthly {
+		return time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// ResetsAt returns when the window containing now ends and dispatching resumes.
+func (b Budget) ResetsAt(now time.Time) time.Time {
+	start := b.WindowStart(now)
+	if b.Period == PeriodMonthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func NewGuard(budgets []Budget) Guard {
+	g := Guard{byCategory: make(map[int32][]Budget)}
+	for _, b := range budgets {
+		if b.CategoryID == nil {
+			g.global = append(g.global, b)
+			continue
+		}
+		g.byCategory[*b.CategoryID] = append(g.byCategory[*b.CategoryID], b)
+	}
+	return g
+}
+
+// Global returns the first exhausted global budget.
+func (g Guard) Global() (Budget, bool) {
+	return firstExhausted(g.global)
+}
+
+// Category returns the first exhausted budget of the category.
+func (g Guard) Category(categoryID int32) (Budget, bool) {
+	return firstExhausted(g.byCategory[categoryID])
+}
+
+func firstExhausted(budgets []Budget) (Budget, bool) {
+	for _, b := range budgets {
+		if b.Exhausted() {
+			return b, true
+		}
+	}
+	return Budget{}, false
+}
@@ -0,0 +1,94 @@
+package budget
+
+import (
+	"testing"
+	"time"
+)
+
+func i64(v int64) *int64 {
+	return &v
+}
+
+func f64(v float64) *float64 {
+	return &v
+}
+
+func i32(v int32) *int32 {
+	return &v
+}
+
+// TestExhausted tests token and cost limits
+func TestExhausted(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget Budget
+		want   bool
+	}{
+		{"No limits", Budget{UsedTokens: 1 << 40, UsedCost: 1e9}, false},
+		{"Under token limit", Budget{MaxTokens: i64(100), UsedTokens: 99}, false},
+		{"At token limit", Budget{MaxTokens: i64(100), UsedTokens: 100}, true},
+		{"Over cost limit", Budget{MaxCost: f64(1.5), UsedCost: 1.51}, true},
+		{"Cost limit hit before tokens", Budget{MaxTokens: i64(1000), MaxCost: f64(1), UsedTokens: 10, UsedCost: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.Exhausted(); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestResetsAt tests the end of daily and monthly windows
+func TestResetsAt(t *testing.T) {
+	now := time.Date(2025, 12, 31, 15, 4, 5, 0, time.UTC)
+
+	daily := Budget{Period: PeriodDaily}
+	if got, want := daily.ResetsAt(now), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected daily reset at %v, got %v", want, got)
+	}
+
+	monthly := Budget{Period: PeriodMonthly}
+	if got, want := monthly.WindowStart(now), time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected monthly window start %v, got %v", want, got)
+	}
+	if got, want := monthly.ResetsAt(now), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected monthly reset at %v, got %v", want, got)
+	}
+}
+
+// TestGuard tests that global and category budgets are checked separately
+func TestGuard(t *testing.T) {
+	g := NewGuard([]Budget{
+		{ID: 1, Period: PeriodMonthly, MaxCost: f64(10), UsedCost: 2},
+		{ID: 2, CategoryID: i32(1), Period: PeriodDaily, MaxTokens: i64(100), UsedTokens: 150},
+		{ID: 3, CategoryID: i32(2), Period: PeriodDaily, MaxTokens: i64(100), UsedTokens: 10},
+	})
+
+	if _, ok := g.Global(); ok {
+		t.Error("Expected global budget to have room")
+	}
+	if b, ok := g.Category(1); !ok || b.ID != 2 {
+		t.Errorf("Expected category 1 to be exhausted by budget 2, got %d (ok=%v)", b.ID, ok)
+	}
+	if _, ok := g.Category(2); ok {
+		t.Error("Expected category 2 to have room")
+	}
+	if _, ok := g.Category(3); ok {
+		t.Error("Expected category without budget to have room")
+	}
+}
+
+// TestExhaustedErrorMessage tests the scope, usage and reset time in the message
+func TestExhaustedErrorMessage(t *testing.T) {
+	now := time.Date(2025, 12, 15, 10, 0, 0, 0, time.UTC)
+	err := &ExhaustedError{
+		Budget: Budget{CategoryID: i32(4), Period: PeriodDaily, MaxTokens: i64(100), UsedTokens: 120, UsedCost: 0.5},
+		Job:    "persona_label",
+	}
+	want := "daily category 4 budget exhausted for persona_label (120 tokens, 0.5000 USD used), resuming at 2025-12-16T00:00:00Z"
+	if got := err.message(now); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
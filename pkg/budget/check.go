@@ -0,0 +1,101 @@
+package budget
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+)
+
+var logger = lg.GetLogger("Budget")
+
+// logged remembers the window each exhausted budget was reported for, so
+// budget_exhausted is logged once per window instead of on every run.
+var logged sync.Map
+
+// ExhaustedError is returned by Check when a budget leaves no room for job.
+type ExhaustedError struct {
+	Budget Budget
+	Job    string
+}
+
+func (e *ExhaustedError) Error() string {
+	return e.message(time.Now())
+}
+
+func (e *ExhaustedError) message(now time.Time) string {
+	b := e.Budget
+	scope := "global"
+	if b.CategoryID != nil {
+		scope = fmt.Sprintf("category %d", *b.CategoryID)
+	}
+	return fmt.Sprintf("%s %s budget exhausted for %s (%d tokens, %.4f USD used), resuming at %s",
+		b.Period, scope, e.Job, b.UsedTokens, b.UsedCost, b.ResetsAt(now).Format(time.RFC3339))
+}
+
+// Load returns a guard over the active LLM budgets with their current usage.
+func Load(ctx context.Context, queries *db.Queries) (Guard, error) {
+	rows, err := queries.GetLlmBudgetUsage(ctx)
+	if err != nil {
+		return Guard{}, fmt.Errorf("failed to load LLM budgets: %w", err)
+	}
+	budgets := make([]Budget, 0, len(rows))
+	for _, row := range rows {
+		if row.IsActive {
+			budgets = append(budgets, FromRow(row))
+		}
+	}
+	return NewGuard(budgets), nil
+}
+
+// Check tells whether job may call the LLM for a category. It fails closed:
+// budgets that cannot be loaded block the call like exhausted ones.
+func Check(ctx context.Context, queries *db.Queries, job string, categoryID int32) error {
+	guard, err := Load(ctx, queries)
+	if err != nil {
+		return err
+	}
+	if b, exhausted := guard.Global(); exhausted {
+		return Report(ctx, queries, job, b)
+	}
+	if b, exhausted := guard.Category(categoryID); exhausted {
+		return Report(ctx, queries, job, b)
+	}
+	return nil
+}
+
+// Report logs a budget_exhausted action for b, once per budget window and
+// job, and returns the matching error.
+func Report(ctx context.Context, queries *db.Queries, job string, b Budget) error {
+	now := time.Now()
+	exhausted := &ExhaustedError{Budget: b, Job: job}
+	msg := exhausted.message(now)
+
+	key := fmt.Sprintf("%s:%d", job, b.ID)
+	window := b.WindowStart(now)
+	if last, ok := logged.Load(key); ok && last.(time.Time).Equal(window) {
+		logger.Info(msg)
+		return exhausted
+	}
+	logged.Store(key, window)
+
+	targetID := sql.NullInt32{}
+	if b.CategoryID != nil {
+		targetID = sql.NullInt32{Int32: *b.CategoryID, Valid: true}
+	}
+	queries.LogAction(ctx, db.LogActionParams{
+		Action: "budget_exhausted",
+		Description: sql.NullString{
+			String: msg,
+			Valid:  true,
+		},
+		TargetID:  targetID,
+		AccountID: sql.NullInt32{Int32: 0, Valid: false},
+	})
+	logger.Warn(msg)
+	return exhausted
+}
@@ -0,0 +1,221 @@
+// Package cluster groups embeddings with spherical k-means: vectors are
+// normalized and compared with cosine similarity, like the vector indexes.
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+type Options struct {
+	K             int
+	MaxIterations int
+	// Tolerance stops the iterations once the mean distance improves by less
+	Tolerance float64
+	Seed      int64
+}
+
+func DefaultOptions(k int) Options {
+	return Options{K: k, MaxIterations: 50, Tolerance: 1e-4, Seed: 1}
+}
+
+type Result struct {
+	// Centroids are normalized
+	Centroids   [][]float32
+	Assignments []int
+	Sizes       []int
+	// Distance is the mean cosine distance of the vectors to their centroid
+	Distance   float64
+	Iterations int
+}
+
+// KMeans clusters the vectors, starting from k-means++ centroids.
+func KMeans(vectors [][]float32, opts Options) (*Result, error) {
+	if opts.K < 1 {
+		return nil, fmt.Errorf("k must be at least 1, got %d", opts.K)
+	}
+	if len(vectors) < opts.K {
+		return nil, fmt.Errorf("need at least %d vectors, got %d", opts.K, len(vectors))
+	}
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = DefaultOptions(opts.K).MaxIterations
+	}
+
+	dim := len(vectors[0])
+	points := make([][]float32, len(vectors))
+	for i, v := range vectors {
+		if len(v) != dim {
+			return nil, fmt.Errorf("vector %d has %d dimensions, expected %d", i, len(v), dim)
+		}
+		points[i] = Normalize(v)
+		if points[i] == nil {
+			return nil, fmt.Errorf("vector %d is zero", i)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	result := &Result{
+		Centroids:   seed(points, opts.K, rng),
+		Assignments: make([]int, len(points)),
+	}
+	distances := make([]float64, len(points))
+	previous := math.Inf(1)
+	for result.Iterations < opts.MaxIterations {
+		result.Iterations++
+		result.Distance = 0
+		for i, p := range points {
+			result.Assignments[i], distances[i] = Nearest(result.Centroids, p)
+			result.Distance += distances[i]
+		}
+		result.Distance /= float64(len(points))
+
+		result.Centroids = update(points, result.Assignments, distances, opts.K, dim)
+		if previous-result.Distance < opts.Tolerance {
+			break
+		}
+		previous = result.Distance
+	}
+
+	result.Sizes = make([]int, opts.K)
+	result.Distance = 0
+	for i, p := range points {
+		var d float64
+		result.Assignments[i], d = Nearest(result.Centroids, p)
+		result.Sizes[result.Assignments[i]]++
+		result.Distance += d
+	}
+	result.Distance /= float64(len(points))
+	return result, nil
+}
+
+// Nearest returns the closest centroid and its cosine distance, v and the
+// centroids being normalized.
+func Nearest(centroids [][]float32, v []float32) (int, float64) {
+	best, bestDistance := 0, math.Inf(1)
+	for i, c := range centroids {
+		if d := 1 - dot(c, v); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best, bestDistance
+}
+
+// Normalize returns v scaled to unit length, nil for a zero vector.
+func Normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return nil
+	}
+	n := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / n)
+	}
+	return out
+}
+
+var errEmpty = errors.New("empty cluster")
+
+// seed picks the first centroids with k-means++, each next centroid is drawn
+// with a probability growing with its distance to the picked ones.
+func seed(points [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := [][]float32{points[rng.Intn(len(points))]}
+	distances := make([]float64, len(points))
+	for i := range distances {
+		distances[i] = math.Inf(1)
+	}
+	for len(centroids) < k {
+		last := centroids[len(centroids)-1]
+		var total float64
+		for i, p := range points {
+			d := 1 - dot(last, p)
+			d = d * d
+			if d < distances[i] {
+				distances[i] = d
+			}
+			total += distances[i]
+		}
+		if total == 0 {
+			// every point is a centroid already, duplicates keep k stable
+			centroids = append(centroids, points[rng.Intn(len(points))])
+			continue
+		}
+		target := rng.Float64() * total
+		pick := len(points) - 1
+		for i, d := range distances {
+			if target -= d; target <= 0 {
+				pick = i
+				break
+			}
+		}
+		centroids = append(centroids, points[pick])
+	}
+	return centroids
+}
+
+// update moves the centroids to the normalized mean of their points. An
+// empty cluster takes over the point farthest from its centroid.
+func update(points [][]float32, assignments []int, distances []float64, k, dim int) [][]float32 {
+	sums := make([][]float64, k)
+	for i := range sums {
+		sums[i] = make([]float64, dim)
+	}
+	for i, p := range points {
+		s := sums[assignments[i]]
+		for j, x := range p {
+			s[j] += float64(x)
+		}
+	}
+
+	taken := make(map[int]bool)
+	centroids := make([][]float32, k)
+	for c, s := range sums {
+		centroid, err := normalize64(s)
+		if errors.Is(err, errEmpty) {
+			far := farthest(distances, taken)
+			taken[far] = true
+			centroid = points[far]
+		}
+		centroids[c] = centroid
+	}
+	return centroids
+}
+
+func farthest(distances []float64, taken map[int]bool) int {
+	best := 0
+	for i, d := range distances {
+		if !taken[i] && (taken[best] || d > distances[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func normalize64(v []float64) ([]float32, error) {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	if sum == 0 {
+		return nil, errEmpty
+	}
+	n := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(x / n)
+	}
+	return out, nil
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"math"
+	"testing"
+)
+
+// TestKMeans tests that two separated groups end up in two clusters
+func TestKMeans(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0.1, 0}, {1, 0, 0.1}, {2, 0.1, 0.1},
+		{0, 1, 0.1}, {0.1, 1, 0}, {0, 3, 0.2},
+	}
+	result, err := KMeans(vectors, DefaultOptions(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	a, b := result.Assignments[0], result.Assignments[3]
+	if a == b {
+		t.Fatalf("Expected the groups in different clusters, got %v", result.Assignments)
+	}
+	for i, c := range result.Assignments {
+		want := a
+		if i >= 3 {
+			want = b
+		}
+		if c != want {
+			t.Errorf("Vector %d: expected cluster %d, got %d", i, want, c)
+		}
+	}
+	if result.Sizes[a] != 3 || result.Sizes[b] != 3 {
+		t.Errorf("Expected sizes 3 and 3, got %v", result.Sizes)
+	}
+	if result.Distance > 0.05 {
+		t.Errorf("Expected a small mean distance, got %f", result.Distance)
+	}
+	for i, c := range result.Centroids {
+		if n := math.Sqrt(dot(c, c)); math.Abs(n-1) > 1e-6 {
+			t.Errorf("Centroid %d is not normalized: %f", i, n)
+		}
+	}
+}
+
+// TestKMeansDuplicates tests that k stays stable when points repeat
+func TestKMeansDuplicates(t *testing.T) {
+	vectors := [][]float32{{1, 0}, {1, 0}, {1, 0}}
+	result, err := KMeans(vectors, DefaultOptions(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Centroids) != 2 {
+		t.Errorf("Expected 2 centroids, got %d", len(result.Centroids))
+	}
+}
+
+// TestKMeansErrors tests the invalid inputs
+func TestKMeansErrors(t *testing.T) {
+	if _, err := KMeans([][]float32{{1, 0}}, DefaultOptions(2)); err == nil {
+		t.Error("Expected an error with fewer vectors than clusters")
+	}
+	if _, err := KMeans([][]float32{{1, 0}, {1}}, DefaultOptions(1)); err == nil {
+		t.Error("Expected an error for mismatched dimensions")
+	}
+	if _, err := KMeans([][]float32{{0, 0}}, DefaultOptions(1)); err == nil {
+		t.Error("Expected an error for a zero vector")
+	}
+	if _, err := KMeans([][]float32{{1, 0}}, DefaultOptions(0)); err == nil {
+		t.Error("Expected an error for k = 0")
+	}
+}
+
+// TestNearest tests the cosine distance to the closest centroid
+func TestNearest(t *testing.T) {
+	centroids := [][]float32{{1, 0}, {0, 1}}
+	i, d := Nearest(centroids, Normalize([]float32{1, 3}))
+	if i != 1 {
+		t.Errorf("Expected centroid 1, got %d", i)
+	}
+	if want := 1 - 3/math.Sqrt(10); math.Abs(d-want) > 1e-6 {
+		t.Errorf("Expected distance %f, got %f", want, d)
+	}
+}
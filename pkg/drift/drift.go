@@ -0,0 +1,214 @@
+package drift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Reference is the score distribution and feature completeness of a model
+// at training time, read from its metadata.json.
+type Reference struct {
+	Min  float64
+	Max  float64
+	Mean float64
+	Std  float64
+	// Quantiles are the deciles of the test predictions. Models trained
+	// before they were recorded fall back to a normal approximation.
+	Quantiles    []float64
+	Completeness map[string]float64
+}
+
+// ParseReference reads the reference out of a model's metrics.
+func ParseReference(metrics []byte) (Reference, error) {
+	var m struct {
+		PredictionStats *struct {
+			Min       float64   `json:"min"`
+			Max       float64   `json:"max"`
+			Mean      float64   `json:"mean"`
+			Std       float64   `json:"std"`
+			Quantiles []float64 `json:"quantiles"`
+		} `json:"prediction_stats"`
+		FeatureCompleteness map[string]float64 `json:"feature_completeness"`
+	}
+	if err := json.Unmarshal(metrics, &m); err != nil {
+		return Reference{}, fmt.Errorf("invalid metrics: %w", err)
+	}
+	if m.PredictionStats == nil {
+		return Reference{}, errors.New("metrics have no prediction_stats")
+	}
+	ps := m.PredictionStats
+	ref := Reference{
+		Min:          ps.Min,
+		Max:          ps.Max,
+		Mean:         ps.Mean,
+		Std:          ps.Std,
+		Completeness: m.FeatureCompleteness,
+	}
+	if len(ps.Quantiles) >= 2 && slices.IsSorted(ps.Quantiles) {
+		ref.Quantiles = ps.Quantiles
+	}
+	return ref, nil
+}
+
+// normal deciles, used as bin edges without recorded quantiles
+var normalDeciles = []float64{-1.2816, -0.8416, -0.5244, -0.2533, 0, 0.2533, 0.5244, 0.8416, 1.2816}
+
+// CDF is the share of training-time scores at or below x.
+func (r Reference) CDF(x float64) float64 {
+	if q := r.Quantiles; len(q) >= 2 {
+		if x < q[0] {
+			return 0
+		}
+		if x >= q[len(q)-1] {
+			return 1
+		}
+		step := 1 / float64(len(q)-1)
+		i := sort.SearchFloat64s(q, x)
+		// q[i-1] < x <= q[i], ties take the highest quantile
+		for i < len(q)-1 && q[i+1] <= x {
+			i++
+		}
+		if q[i] == x {
+			return float64(i) * step
+		}
+		lo, hi := q[i-1], q[i]
+		return (float64(i-1) + (x-lo)/(hi-lo)) * step
+	}
+	if r.Std <= 0 {
+		if x < r.Mean {
+			return 0
+		}
+		return 1
+	}
+	return 0.5 * math.Erfc(-(x-r.Mean)/(r.Std*math.Sqrt2))
+}
+
+// edges are the inner bin edges used for the PSI, ten bins of about equal
+// expected share.
+func (r Reference) edges() []float64 {
+	if q := r.Quantiles; len(q) >= 2 {
+		return slices.Compact(slices.Clone(q[1 : len(q)-1]))
+	}
+	edges := make([]float64, len(normalDeciles))
+	for i, z := range normalDeciles {
+		edges[i] = r.Mean + z*r.Std
+	}
+	return slices.Compact(edges)
+}
+
+// psiFloor keeps empty bins from making the PSI infinite.
+const psiFloor = 1e-4
+
+// PSI is the population stability index of scores against the reference.
+// Below 0.1 is usually read as stable and above 0.25 as a major shift.
+func PSI(ref Reference, scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	edges := ref.edges()
+	actual := make([]float64, len(edges)+1)
+	for _, s := range scores {
+		actual[sort.Search(len(edges), func(i int) bool { return s < edges[i] })]++
+	}
+
+	var psi, prev float64
+	for i := range actual {
+		cum := 1.0
+		if i < len(edges) {
+			cum = ref.CDF(math.Nextafter(edges[i], math.Inf(-1)))
+		}
+		expected := max(cum-prev, psiFloor)
+		prev = cum
+		a := max(actual[i]/float64(len(scores)), psiFloor)
+		psi += (a - expected) * math.Log(a/expected)
+	}
+	return psi
+}
+
+// KS is the Kolmogorov-Smirnov statistic of scores against the reference,
+// the largest gap between both cumulative distributions.
+func KS(ref Reference, scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(scores)
+	slices.Sort(sorted)
+	n := float64(len(sorted))
+	var d float64
+	for i, s := range sorted {
+		cdf := ref.CDF(s)
+		d = max(d, math.Abs(float64(i+1)/n-cdf), math.Abs(cdf-float64(i)/n))
+	}
+	return d
+}
+
+// Thresholds above which drift raises an alert, zero disables a check.
+type Thresholds struct {
+	PSI          float64
+	KS           float64
+	Completeness float64
+}
+
+// Report is the drift of recent scores and inputs from the reference.
+type Report struct {
+	SampleSize int     `json:"sample_size"`
+	Mean       float64 `json:"mean"`
+	Std        float64 `json:"std"`
+	PSI        float64 `json:"psi"`
+	KS         float64 `json:"ks"`
+	// CompletenessDrift is the largest difference between the recent and
+	// training completeness of a feature.
+	CompletenessDrift float64  `json:"completeness_drift"`
+	Alerts            []string `json:"alerts"`
+}
+
+// Measure compares recent scores and feature completeness with the
+// reference. Features missing from either side are not compared.
+func Measure(ref Reference, scores []float64, completeness map[string]float64, th Thresholds) Report {
+	r := Report{SampleSize: len(scores), Alerts: make([]string, 0)}
+	if len(scores) > 0 {
+		var sum, sq float64
+		for _, s := range scores {
+			sum += s
+		}
+		r.Mean = sum / float64(len(scores))
+		for _, s := range scores {
+			sq += (s - r.Mean) * (s - r.Mean)
+		}
+		r.Std = math.Sqrt(sq / float64(len(scores)))
+		r.PSI = PSI(ref, scores)
+		r.KS = KS(ref, scores)
+	}
+
+	worst := ""
+	features := make([]string, 0, len(completeness))
+	for f := range completeness {
+		features = append(features, f)
+	}
+	slices.Sort(features)
+	for _, f := range features {
+		trained, ok := ref.Completeness[f]
+		if !ok {
+			continue
+		}
+		if diff := math.Abs(completeness[f] - trained); diff > r.CompletenessDrift {
+			r.CompletenessDrift, worst = diff, f
+		}
+	}
+
+	if th.PSI > 0 && r.PSI > th.PSI {
+		r.Alerts = append(r.Alerts, fmt.Sprintf("score PSI %.3f above %.3f", r.PSI, th.PSI))
+	}
+	if th.KS > 0 && r.KS > th.KS {
+		r.Alerts = append(r.Alerts, fmt.Sprintf("score KS %.3f above %.3f", r.KS, th.KS))
+	}
+	if th.Completeness > 0 && r.CompletenessDrift > th.Completeness {
+		r.Alerts = append(r.Alerts, fmt.Sprintf("%s completeness %.2f from training, %.2f now",
+			worst, ref.Completeness[worst], completeness[worst]))
+	}
+	return r
+}
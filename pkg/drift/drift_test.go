@@ -0,0 +1,114 @@
+package drift
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func uniformReference() Reference {
+	q := make([]float64, 11)
+	for i := range q {
+		q[i] = float64(i) / 10
+	}
+	return Reference{Min: 0, Max: 1, Mean: 0.5, Std: 0.2887, Quantiles: q}
+}
+
+func uniform(n int, lo, hi float64) []float64 {
+	r := rand.New(rand.NewPCG(1, 2))
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = lo + r.Float64()*(hi-lo)
+	}
+	return scores
+}
+
+// TestParseReference tests reading the reference out of metadata.json
+func TestParseReference(t *testing.T) {
+	ref, err := ParseReference([]byte(`{
+		"rmse": 0.1,
+		"prediction_stats": {"min": 0, "max": 1, "mean": 0.5, "std": 0.2, "quantiles": [0, 0.5, 1]},
+		"feature_completeness": {"gender": 0.8}
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ref.Quantiles) != 3 || ref.Completeness["gender"] != 0.8 {
+		t.Errorf("Unexpected reference: %+v", ref)
+	}
+
+	if _, err := ParseReference([]byte(`{"rmse": 0.1}`)); err == nil {
+		t.Error("Expected an error without prediction_stats")
+	}
+	ref, _ = ParseReference([]byte(`{"prediction_stats": {"mean": 0.5, "std": 0.2, "quantiles": [1, 0]}}`))
+	if ref.Quantiles != nil {
+		t.Error("Expected unsorted quantiles to be ignored")
+	}
+}
+
+// TestCDF tests both the quantile and the normal reference
+func TestCDF(t *testing.T) {
+	ref := uniformReference()
+	for _, tt := range []struct{ x, want float64 }{{-1, 0}, {0.25, 0.25}, {0.5, 0.5}, {2, 1}} {
+		if got := ref.CDF(tt.x); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("CDF(%v): expected %v, got %v", tt.x, tt.want, got)
+		}
+	}
+
+	normal := Reference{Mean: 0.5, Std: 0.1}
+	if got := normal.CDF(0.5); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("Expected the normal CDF at the mean to be 0.5, got %v", got)
+	}
+	if got := normal.CDF(0.7); math.Abs(got-0.9772) > 1e-3 {
+		t.Errorf("Expected the normal CDF two std above to be 0.977, got %v", got)
+	}
+}
+
+// TestPSIAndKS tests that shifted scores drift and matching ones do not
+func TestPSIAndKS(t *testing.T) {
+	ref := uniformReference()
+
+	same := uniform(5000, 0, 1)
+	if psi := PSI(ref, same); psi > 0.02 {
+		t.Errorf("Expected a stable PSI, got %.4f", psi)
+	}
+	if ks := KS(ref, same); ks > 0.03 {
+		t.Errorf("Expected a small KS, got %.4f", ks)
+	}
+
+	shifted := uniform(5000, 0.4, 1)
+	if psi := PSI(ref, shifted); psi < 0.25 {
+		t.Errorf("Expected a major PSI shift, got %.4f", psi)
+	}
+	if ks := KS(ref, shifted); math.Abs(ks-0.4) > 0.03 {
+		t.Errorf("Expected KS about 0.4, got %.4f", ks)
+	}
+
+	if PSI(ref, nil) != 0 || KS(ref, nil) != 0 {
+		t.Error("Expected no drift without scores")
+	}
+}
+
+// TestMeasure tests the alerts raised by each threshold
+func TestMeasure(t *testing.T) {
+	ref := uniformReference()
+	ref.Completeness = map[string]float64{"gender": 0.9, "locale": 0.5}
+	th := Thresholds{PSI: 0.2, KS: 0.15, Completeness: 0.2}
+
+	r := Measure(ref, uniform(2000, 0, 1), map[string]float64{"gender": 0.85, "locale": 0.55, "embedding": 0}, th)
+	if len(r.Alerts) != 0 {
+		t.Errorf("Expected no alerts, got %v", r.Alerts)
+	}
+	if math.Abs(r.CompletenessDrift-0.05) > 1e-9 {
+		t.Errorf("Expected completeness drift 0.05, got %v", r.CompletenessDrift)
+	}
+
+	r = Measure(ref, uniform(2000, 0.5, 1), map[string]float64{"gender": 0.3}, th)
+	if len(r.Alerts) != 3 {
+		t.Errorf("Expected PSI, KS and completeness alerts, got %v", r.Alerts)
+	}
+
+	if r := Measure(ref, uniform(2000, 0.5, 1), nil, Thresholds{}); len(r.Alerts) != 0 {
+		t.Errorf("Expected disabled thresholds not to alert, got %v", r.Alerts)
+	}
+}
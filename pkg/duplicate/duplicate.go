@@ -0,0 +1,102 @@
+// Package duplicate scores how likely two profiles are the same person.
+package duplicate
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Signal string
+
+const (
+	SignalEmail      Signal = "email"
+	SignalPhone      Signal = "phone"
+	SignalProfileURL Signal = "profile_url"
+	SignalEmbedding  Signal = "embedding"
+)
+
+// Weights are the probability that a single signal means the same person.
+var Weights = map[Signal]float64{
+	SignalEmail:      0.9,
+	SignalPhone:      0.8,
+	SignalProfileURL: 0.95,
+	SignalEmbedding:  0.6,
+}
+
+// Pair is a candidate, ProfileID is always the lower ID.
+type Pair struct {
+	ProfileID   int32
+	DuplicateID int32
+}
+
+func NewPair(a, b int32) Pair {
+	if a > b {
+		a, b = b, a
+	}
+	return Pair{ProfileID: a, DuplicateID: b}
+}
+
+type Evidence struct {
+	Exact map[Signal]bool
+	// Similarity is the cosine similarity of the embeddings, zero if unknown
+	Similarity float64
+	// SameName tells the normalized names are equal
+	SameName bool
+}
+
+// Signals lists the signals that count toward the confidence, sorted.
+func (e Evidence) Signals(minSimilarity float64) []Signal {
+	signals := make([]Signal, 0, len(e.Exact)+1)
+	for s, ok := range e.Exact {
+		if ok {
+			signals = append(signals, s)
+		}
+	}
+	if e.embedding(minSimilarity) > 0 {
+		signals = append(signals, SignalEmbedding)
+	}
+	sort.Slice(signals, func(i, j int) bool { return signals[i] < signals[j] })
+	return signals
+}
+
+// Confidence combines the signals as independent evidence (noisy-or).
+// Similar embeddings only count for profiles with the same name: empty
+// profiles embed alike whoever they belong to.
+func (e Evidence) Confidence(minSimilarity float64) float64 {
+	miss := 1.0
+	for s, ok := range e.Exact {
+		if ok {
+			miss *= 1 - Weights[s]
+		}
+	}
+	miss *= 1 - e.embedding(minSimilarity)*Weights[SignalEmbedding]
+	return 1 - miss
+}
+
+// embedding scales the similarity above the threshold to [0, 1].
+func (e Evidence) embedding(minSimilarity float64) float64 {
+	if !e.SameName || minSimilarity >= 1 || e.Similarity < minSimilarity {
+		return 0
+	}
+	return min((e.Similarity-minSimilarity)/(1-minSimilarity), 1)
+}
+
+// NormalizeName folds case, spaces and punctuation so names can be compared.
+func NormalizeName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package duplicate
+
+import (
+	"math"
+	"testing"
+)
+
+// TestConfidence tests the noisy-or of the signals
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		name     string
+		evidence Evidence
+		want     float64
+	}{
+		{"email", Evidence{Exact: map[Signal]bool{SignalEmail: true}}, 0.9},
+		{"email and phone", Evidence{Exact: map[Signal]bool{SignalEmail: true, SignalPhone: true}}, 0.98},
+		{"embedding without name", Evidence{Similarity: 1}, 0},
+		{"embedding with name", Evidence{Similarity: 1, SameName: true}, 0.6},
+		{"embedding halfway", Evidence{Similarity: 0.975, SameName: true}, 0.3},
+		{"below threshold", Evidence{Similarity: 0.9, SameName: true}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.evidence.Confidence(0.95); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected %f, got %f", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestSignals tests that only counted signals are listed
+func TestSignals(t *testing.T) {
+	e := Evidence{Exact: map[Signal]bool{SignalProfileURL: true, SignalPhone: false}, Similarity: 0.99, SameName: true}
+	got := e.Signals(0.95)
+	if len(got) != 2 || got[0] != SignalEmbedding || got[1] != SignalProfileURL {
+		t.Errorf("Unexpected signals %v", got)
+	}
+	e.SameName = false
+	if got := e.Signals(0.95); len(got) != 1 {
+		t.Errorf("Expected the embedding not to count, got %v", got)
+	}
+}
+
+// TestNormalizeName tests folding case, spaces and punctuation
+func TestNormalizeName(t *testing.T) {
+	if got := NormalizeName("  Nguyễn   Văn-An. "); got != "nguyễn văn an" {
+		t.Errorf("Unexpected name %q", got)
+	}
+	if NewPair(5, 2) != (Pair{ProfileID: 2, DuplicateID: 5}) {
+		t.Error("Expected the lower ID first")
+	}
+}
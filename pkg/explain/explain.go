@@ -0,0 +1,81 @@
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// BiasFeature is the contribution every score starts from, the mean
+// prediction of the model.
+const BiasFeature = "bias"
+
+type Contribution struct {
+	Feature string  `json:"feature"`
+	Value   float64 `json:"value"`
+}
+
+// Explanation is a score broken down into the contributions of its inputs,
+// largest first.
+type Explanation struct {
+	Score         float64        `json:"score"`
+	Base          float64        `json:"base"`
+	Contributions []Contribution `json:"contributions"`
+}
+
+// Parse reads contributions stored as a JSON object.
+func Parse(data []byte) (map[string]float64, error) {
+	var contributions map[string]float64
+	if err := json.Unmarshal(data, &contributions); err != nil {
+		return nil, fmt.Errorf("invalid contributions: %w", err)
+	}
+	return contributions, nil
+}
+
+// Rank orders the contributions by their absolute value. The score is the
+// base plus every contribution.
+func Rank(contributions map[string]float64) Explanation {
+	e := Explanation{Contributions: make([]Contribution, 0, len(contributions))}
+	for feature, value := range contributions {
+		if feature == BiasFeature {
+			e.Base = value
+			continue
+		}
+		e.Contributions = append(e.Contributions, Contribution{Feature: feature, Value: value})
+	}
+	slices.SortFunc(e.Contributions, func(a, b Contribution) int {
+		if c := -cmpAbs(a.Value, b.Value); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Feature, b.Feature)
+	})
+
+	e.Score = e.Base
+	for _, c := range e.Contributions {
+		e.Score += c.Value
+	}
+	return e
+}
+
+func cmpAbs(a, b float64) int {
+	switch a, b := math.Abs(a), math.Abs(b); {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Describe lists the contributions as lines such as "embedding: +0.120",
+// for prompts and logs.
+func (e Explanation) Describe() string {
+	lines := make([]string, 0, len(e.Contributions)+1)
+	lines = append(lines, fmt.Sprintf("%s: %.3f", BiasFeature, e.Base))
+	for _, c := range e.Contributions {
+		lines = append(lines, fmt.Sprintf("%s: %+.3f", c.Feature, c.Value))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,48 @@
+package explain
+
+import (
+	"math"
+	"testing"
+)
+
+// TestRank tests the ordering and the reconstructed score
+func TestRank(t *testing.T) {
+	e := Rank(map[string]float64{
+		"bias":                0.4,
+		"embedding":           0.3,
+		"gender":              -0.05,
+		"locale":              0.05,
+		"relationship_status": -0.1,
+	})
+
+	if e.Base != 0.4 {
+		t.Errorf("Expected base 0.4, got %v", e.Base)
+	}
+	if math.Abs(e.Score-0.6) > 1e-9 {
+		t.Errorf("Expected score 0.6, got %v", e.Score)
+	}
+	want := []string{"embedding", "relationship_status", "gender", "locale"}
+	if len(e.Contributions) != len(want) {
+		t.Fatalf("Expected %d contributions, got %d", len(want), len(e.Contributions))
+	}
+	for i, f := range want {
+		if e.Contributions[i].Feature != f {
+			t.Errorf("Expected %s at %d, got %s", f, i, e.Contributions[i].Feature)
+		}
+	}
+}
+
+// TestParseAndDescribe tests reading stored contributions back
+func TestParseAndDescribe(t *testing.T) {
+	contributions, err := Parse([]byte(`{"bias": 0.5, "age": -0.125}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := Rank(contributions).Describe(); got != "bias: 0.500\nage: -0.125" {
+		t.Errorf("Unexpected description: %q", got)
+	}
+
+	if _, err := Parse([]byte(`[1, 2]`)); err == nil {
+		t.Error("Expected an error for a JSON array")
+	}
+}
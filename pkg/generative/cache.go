@@ -0,0 +1,89 @@
+package generative
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+)
+
+// ResponseCache stores parsed LLM responses keyed by a hash of the rendered
+// prompt and the model id. A nil cache is valid and always misses, so callers
+// do not need to branch when caching is disabled.
+type ResponseCache struct {
+	Queries *db.Queries
+	TTL     time.Duration
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+func NewResponseCache(queries *db.Queries, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		Queries: queries,
+		TTL:     ttl,
+	}
+}
+
+// CacheKey returns the content address of a prompt for the given model.
+func CacheKey(model, prompt string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached response for the prompt. Lookup errors are counted
+// as misses so a broken cache never blocks scoring.
+func (rc *ResponseCache) Get(ctx context.Context, model, prompt string) (string, bool) {
+	if rc == nil {
+		return "", false
+	}
+	response, err := rc.Queries.GetLlmCacheResponse(ctx, CacheKey(model, prompt))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			lg.GetLogger("GenerativeService").Warnf("Failed to read LLM cache: %v", err)
+		}
+		rc.misses.Add(1)
+		return "", false
+	}
+	rc.hits.Add(1)
+	return response, true
+}
+
+func (rc *ResponseCache) Set(ctx context.Context, model, prompt, response string) error {
+	if rc == nil {
+		return nil
+	}
+	return rc.Queries.UpsertLlmCacheResponse(ctx, db.UpsertLlmCacheResponseParams{
+		PromptHash: CacheKey(model, prompt),
+		Model:      model,
+		Response:   response,
+		ExpiresAt:  time.Now().Add(rc.TTL),
+	})
+}
+
+func (rc *ResponseCache) Stats() (hits, misses int64) {
+	if rc == nil {
+		return 0, 0
+	}
+	return rc.hits.Load(), rc.misses.Load()
+}
+
+// SaveStats adds the counters collected since the last call to today's
+// hit/miss totals.
+func (rc *ResponseCache) SaveStats(ctx context.Context) error {
+	if rc == nil {
+		return nil
+	}
+	hits, misses := rc.hits.Swap(0), rc.misses.Swap(0)
+	if hits == 0 && misses == 0 {
+		return nil
+	}
+	return rc.Queries.AddLlmCacheStat(ctx, db.AddLlmCacheStatParams{
+		Hits:   hits,
+		Misses: misses,
+	})
+}
@@ -0,0 +1,38 @@
+package generative
+
+import (
+	"context"
+	"testing"
+)
+
+// TestCacheKey tests that keys depend on both the model and the prompt
+func TestCacheKey(t *testing.T) {
+	key := CacheKey("gemini-2.5-flash-lite", "prompt")
+	if len(key) != 64 {
+		t.Errorf("Expected 64 hex chars, got %d", len(key))
+	}
+	if key != CacheKey("gemini-2.5-flash-lite", "prompt") {
+		t.Error("Expected identical input to produce the same key")
+	}
+	if key == CacheKey("gemini-2.5-flash", "prompt") {
+		t.Error("Expected different models to produce different keys")
+	}
+	if CacheKey("a", "bc") == CacheKey("ab", "c") {
+		t.Error("Expected model and prompt boundary to be part of the key")
+	}
+}
+
+// TestNilCache tests that a disabled cache always misses
+func TestNilCache(t *testing.T) {
+	var rc *ResponseCache
+	ctx := context.Background()
+	if _, ok := rc.Get(ctx, "model", "prompt"); ok {
+		t.Error("Expected nil cache to miss")
+	}
+	if err := rc.Set(ctx, "model", "prompt", "0.5"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := rc.SaveStats(ctx); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -2,17 +2,40 @@ package generative
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
+
 	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/pkg/retry"
 	"google.golang.org/genai"
 )
 
+const Provider = "gemini"
+
 type GenerativeService struct {
 	APIKey  string
 	Model   string
 	Context context.Context
 	client  *genai.Client
+	Retry   retry.Policy
 	Usage   int64
+	mu      sync.Mutex
+	ledger  map[ledgerKey]Usage
+}
+
+// Usage counts the tokens of one or more requests. Output tokens include the
+// thinking tokens, which are billed at the output price.
+type Usage struct {
+	PromptTokens int64
+	OutputTokens int64
+}
+
+type ledgerKey struct {
+	categoryID int32
+	job        string
 }
 
 func GetGenerativeService(apiKey, model string) *GenerativeService {
@@ -20,7 +43,40 @@ func GetGenerativeService(apiKey, model string) *GenerativeService {
 		APIKey:  apiKey,
 		Model:   model,
 		Context: context.Background(),
+		Retry:   retry.DefaultPolicy(),
 		Usage:   0,
+		ledger:  make(map[ledgerKey]Usage),
+	}
+}
+
+func (u Usage) Total() int64 {
+	return u.PromptTokens + u.OutputTokens
+}
+
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens: u.PromptTokens + other.PromptTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+	}
+}
+
+// Split apportions the usage of a batched request over n items.
+func (u Usage) Split(n int) []Usage {
+	prompt, output := Apportion(u.PromptTokens, n), Apportion(u.OutputTokens, n)
+	shares := make([]Usage, n)
+	for i := range shares {
+		shares[i] = Usage{PromptTokens: prompt[i], OutputTokens: output[i]}
+	}
+	return shares
+}
+
+func usageFromMetadata(m *genai.GenerateContentResponseUsageMetadata) Usage {
+	if m == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens: int64(m.PromptTokenCount) + int64(m.ToolUsePromptTokenCount),
+		OutputTokens: int64(m.CandidatesTokenCount) + int64(m.ThoughtsTokenCount),
 	}
 }
 
@@ -34,15 +90,77 @@ func (gs *GenerativeService) Init() error {
 }
 
 func (gs *GenerativeService) GenerateText(prompt string) (string, error) {
-	response, err := gs.client.Models.GenerateContent(gs.Context, gs.Model, genai.Text(prompt), nil)
+	text, _, err := gs.GenerateTextWithUsage(prompt)
+	return text, err
+}
+
+// GenerateTextWithUsage is GenerateText that also returns the tokens counted
+// for this request, so callers can attribute usage with Record.
+func (gs *GenerativeService) GenerateTextWithUsage(prompt string) (string, Usage, error) {
+	response, err := retry.Do(gs.Context, gs.Retry, classifyError, func() (*genai.GenerateContentResponse, error) {
+		return gs.client.Models.GenerateContent(gs.Context, gs.Model, genai.Text(prompt), nil)
+	})
 
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %v", err)
+		return "", Usage{}, fmt.Errorf("failed to generate content: %v", err)
 	}
 
-	gs.Usage += int64(response.UsageMetadata.PromptTokenCount)
+	usage := usageFromMetadata(response.UsageMetadata)
+	gs.mu.Lock()
+	gs.Usage += usage.Total()
+	gs.mu.Unlock()
 
-	return response.Text(), nil
+	return response.Text(), usage, nil
+}
+
+// classifyError retries rate limits, server errors and network failures. The
+// delay from a google.rpc.RetryInfo detail is used as the Retry-After.
+func classifyError(err error) retry.Decision {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return retry.Decision{Retry: retry.IsNetworkError(err)}
+	}
+	d := retry.Decision{Retry: retry.RetryableStatus(apiErr.Code)}
+	for _, detail := range apiErr.Details {
+		if detail["@type"] != "type.googleapis.com/google.rpc.RetryInfo" {
+			continue
+		}
+		if delay, ok := detail["retryDelay"].(string); ok {
+			if after, err := time.ParseDuration(delay); err == nil {
+				d.After = after
+			}
+		}
+	}
+	return d
+}
+
+// Record attributes usage to a job and a category in the usage ledger written
+// by SaveUsage. Pass 0 as categoryID for usage not tied to a category.
+func (gs *GenerativeService) Record(categoryID int32, job string, usage Usage) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.ledger == nil {
+		gs.ledger = make(map[ledgerKey]Usage)
+	}
+	key := ledgerKey{categoryID: categoryID, job: job}
+	gs.ledger[key] = gs.ledger[key].Add(usage)
+}
+
+// Apportion splits the tokens of a batched request evenly over n items. The
+// remainder goes to the first items so the shares add up to the total.
+func Apportion(total int64, n int) []int64 {
+	if n <= 0 {
+		return nil
+	}
+	shares := make([]int64, n)
+	base, rest := total/int64(n), total%int64(n)
+	for i := range shares {
+		shares[i] = base
+		if int64(i) < rest {
+			shares[i]++
+		}
+	}
+	return shares
 }
 
 // @Deprecated
@@ -57,13 +175,35 @@ func (gs *GenerativeService) GenerateText(prompt string) (string, error) {
 // 	return response.Embeddings[0].Values, nil
 // }
 
+// SaveUsage adds the tokens used since the last save to the key and writes
+// the recorded usage to the ledger.
 func (gs *GenerativeService) SaveUsage(ctx context.Context, queries *db.Queries) error {
+	gs.mu.Lock()
+	total, ledger := gs.Usage, gs.ledger
+	gs.Usage, gs.ledger = 0, make(map[ledgerKey]Usage)
+	gs.mu.Unlock()
+
 	_, err := queries.UpdateGeminiKeyUsage(ctx, db.UpdateGeminiKeyUsageParams{
 		ApiKey:    gs.APIKey,
-		TokenUsed: gs.Usage,
+		TokenUsed: total,
 	})
 	if err != nil {
 		return err
 	}
+
+	for key, usage := range ledger {
+		err := queries.CreateLlmUsage(ctx, db.CreateLlmUsageParams{
+			ApiKey:       gs.APIKey,
+			Provider:     Provider,
+			Model:        gs.Model,
+			CategoryID:   sql.NullInt32{Int32: key.categoryID, Valid: key.categoryID != 0},
+			Job:          key.job,
+			PromptTokens: usage.PromptTokens,
+			OutputTokens: usage.OutputTokens,
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,95 @@
+package generative
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// TestApportion tests that batch token shares add up to the total
+func TestApportion(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int64
+		n     int
+		want  []int64
+	}{
+		{"Even split", 90, 3, []int64{30, 30, 30}},
+		{"Remainder to first items", 11, 3, []int64{4, 4, 3}},
+		{"Fewer tokens than items", 2, 4, []int64{1, 1, 0, 0}},
+		{"No items", 10, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Apportion(tt.total, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d shares, got %d", len(tt.want), len(got))
+			}
+			var sum int64
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Share %d: expected %d, got %d", i, tt.want[i], got[i])
+				}
+				sum += got[i]
+			}
+			if tt.n > 0 && sum != tt.total {
+				t.Errorf("Expected shares to sum to %d, got %d", tt.total, sum)
+			}
+		})
+	}
+}
+
+// TestUsageSplit tests that prompt and output tokens are apportioned separately
+func TestUsageSplit(t *testing.T) {
+	usage := Usage{PromptTokens: 100, OutputTokens: 7}
+	shares := usage.Split(3)
+	if len(shares) != 3 {
+		t.Fatalf("Expected 3 shares, got %d", len(shares))
+	}
+
+	var sum Usage
+	for _, s := range shares {
+		sum = sum.Add(s)
+	}
+	if sum != usage {
+		t.Errorf("Expected shares to sum to %+v, got %+v", usage, sum)
+	}
+	if shares[0].Total() != 34+3 {
+		t.Errorf("Expected first share of 37 tokens, got %d", shares[0].Total())
+	}
+}
+
+// TestClassifyError tests which Gemini API errors are retried
+func TestClassifyError(t *testing.T) {
+	t.Run("Rate limit with retry info", func(t *testing.T) {
+		err := genai.APIError{Code: 429, Details: []map[string]any{
+			{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "13s"},
+		}}
+		d := classifyError(fmt.Errorf("wrapped: %w", err))
+		if !d.Retry || d.After != 13*time.Second {
+			t.Errorf("Expected retry after 13s, got %+v", d)
+		}
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		if d := classifyError(genai.APIError{Code: 503}); !d.Retry {
+			t.Error("Expected 503 to be retried")
+		}
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		if d := classifyError(genai.APIError{Code: 403}); d.Retry {
+			t.Error("Expected 403 to be fatal")
+		}
+	})
+
+	t.Run("Unknown error", func(t *testing.T) {
+		if d := classifyError(errors.New("boom")); d.Retry {
+			t.Error("Expected unknown errors to be fatal")
+		}
+	})
+}
@@ -0,0 +1,473 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	lg "github.com/qxbao/asfpc/pkg/logger"
+)
+
+// Kind tells what a request does. It is stored in request.kind.
+type Kind string
+
+const (
+	KindTrain            Kind = "ml.train"
+	KindRetrain          Kind = "ml.retrain"
+	KindProfileImport    Kind = "profile.import"
+	KindProfileExport    Kind = "profile.export"
+	KindLeadExport       Kind = "lead.export"
+	KindAccountToken     Kind = "account.token"
+	KindCategoryBulk     Kind = "category.bulk"
+	KindCategoryBackfill Kind = "category.backfill"
+	KindEmbeddingIndex   Kind = "embedding.index"
+	KindLookalikeExport  Kind = "lookalike.export"
+	KindPersonaCluster   Kind = "persona.cluster"
+	KindProfileDedup     Kind = "profile.dedup"
+)
+
+// Status mirrors the request_status table.
+type Status int16
+
+const (
+	StatusQueued      Status = 0
+	StatusRunning     Status = 1
+	StatusDone        Status = 2
+	StatusFailed      Status = 3
+	StatusCancelled   Status = 4
+	StatusInterrupted Status = 5
+)
+
+// Final reports whether a request in this status will not change anymore.
+func (s Status) Final() bool {
+	return s >= StatusDone
+}
+
+func (s Status) String() string {
+	switch s {
+	case StatusQueued:
+		return "queued"
+	case StatusRunning:
+		return "running"
+	case StatusDone:
+		return "done"
+	case StatusFailed:
+		return "failed"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusInterrupted:
+		return "interrupted"
+	}
+	return "unknown"
+}
+
+var (
+	ErrQueueFull = errors.New("job queue is full")
+	ErrStopped   = errors.New("job runner is stopped")
+)
+
+// Update is a change of a request row. Details are published to the
+// request's stream but not stored, Result is stored once the job is done.
+type Update struct {
+	ID           int32
+	Status       Status
+	Progress     float64
+	Description  string
+	ErrorMessage string
+	Details      map[string]any
+	Result       any
+}
+
+// Store persists requests.
+type Store interface {
+	CreateRequest(ctx context.Context, kind Kind, description string) (int32, error)
+	UpdateRequest(ctx context.Context, u Update) error
+	// InterruptRequests marks the requests left unfinished by a previous run.
+	InterruptRequests(ctx context.Context) (int64, error)
+	DeleteFinishedRequests(ctx context.Context, finishedBefore time.Time) ([]int32, error)
+}
+
+// Job is a unit of background work. Run returns the result stored on the
+// request, it should return promptly once ctx is done.
+type Job struct {
+	Kind        Kind
+	Description string
+	// Timeout bounds Run, zero means no timeout.
+	Timeout time.Duration
+	Run     func(ctx context.Context, r *Reporter) (any, error)
+}
+
+type Options struct {
+	Workers   int
+	QueueSize int
+	// Dir holds the files written by jobs, one directory per request.
+	Dir string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Workers:   4,
+		QueueSize: 100,
+		Dir:       filepath.Join(os.TempDir(), "asfpc-requests"),
+	}
+}
+
+// Runner runs jobs on a bounded pool of workers and records them in the
+// request table.
+type Runner struct {
+	store  Store
+	opts   Options
+	queue  chan *task
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	tasks    map[int32]*task
+	started  bool
+	stopping bool
+}
+
+type task struct {
+	id       int32
+	job      Job
+	ctx      context.Context
+	cancel   context.CancelFunc
+	reporter *Reporter
+
+	// guarded by Runner.mu
+	started   bool
+	finished  bool
+	cancelled bool
+}
+
+var logger = lg.GetLogger("JobRunner")
+
+func NewRunner(store Store, opts Options) *Runner {
+	def := DefaultOptions()
+	if opts.Workers <= 0 {
+		opts.Workers = def.Workers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = def.QueueSize
+	}
+	if opts.Dir == "" {
+		opts.Dir = def.Dir
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		store:  store,
+		opts:   opts,
+		queue:  make(chan *task, opts.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		tasks:  make(map[int32]*task),
+	}
+}
+
+// Start marks the requests left over by a previous run as interrupted and
+// starts the workers.
+func (r *Runner) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return nil
+	}
+	n, err := r.store.InterruptRequests(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to mark interrupted requests: %w", err)
+	}
+	if n > 0 {
+		logger.Warnf("Marked %d unfinished requests as interrupted", n)
+	}
+	for range r.opts.Workers {
+		r.wg.Add(1)
+		go r.work()
+	}
+	r.started = true
+	return nil
+}
+
+// Stop cancels every job and waits for the workers. Running and queued jobs
+// end up interrupted.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	r.stopping = true
+	r.mu.Unlock()
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for {
+		select {
+		case t := <-r.queue:
+			r.finish(t, nil, context.Canceled)
+		default:
+			return nil
+		}
+	}
+}
+
+// Submit records job as a queued request and returns its id.
+func (r *Runner) Submit(ctx context.Context, job Job) (int32, error) {
+	r.mu.Lock()
+	stopping := r.stopping
+	r.mu.Unlock()
+	if stopping {
+		return 0, ErrStopped
+	}
+
+	id, err := r.store.CreateRequest(ctx, job.Kind, job.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	t := &task{id: id, job: job}
+	t.ctx, t.cancel = context.WithCancel(r.ctx)
+	t.reporter = &Reporter{runner: r, id: id, description: job.Description}
+
+	r.mu.Lock()
+	r.tasks[id] = t
+	r.mu.Unlock()
+
+	select {
+	case r.queue <- t:
+		return id, nil
+	default:
+		r.finish(t, nil, ErrQueueFull)
+		return id, ErrQueueFull
+	}
+}
+
+// Cancel stops a queued or running job. It returns false when the request is
+// not handled by this runner or already finished.
+func (r *Runner) Cancel(id int32) bool {
+	r.mu.Lock()
+	t, ok := r.tasks[id]
+	if !ok {
+		r.mu.Unlock()
+		return false
+	}
+	t.cancelled = true
+	started := t.started
+	r.mu.Unlock()
+
+	t.cancel()
+	if !started {
+		// a worker would only pick it up once one is free
+		r.finish(t, nil, context.Canceled)
+	}
+	return true
+}
+
+// Path returns the directory holding the files of request id.
+func (r *Runner) Path(id int32) string {
+	return filepath.Join(r.opts.Dir, strconv.Itoa(int(id)))
+}
+
+// Purge deletes the requests finished before now-retention with their files.
+func (r *Runner) Purge(ctx context.Context, retention time.Duration) (int, error) {
+	ids, err := r.store.DeleteFinishedRequests(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := os.RemoveAll(r.Path(id)); err != nil {
+			logger.Warnf("Failed to remove files of request %d: %v", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case t := <-r.queue:
+			r.run(t)
+		}
+	}
+}
+
+func (r *Runner) run(t *task) {
+	r.mu.Lock()
+	if t.finished {
+		r.mu.Unlock()
+		return
+	}
+	t.started = true
+	r.mu.Unlock()
+
+	if t.ctx.Err() != nil {
+		r.finish(t, nil, t.ctx.Err())
+		return
+	}
+
+	ctx := t.ctx
+	if t.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.job.Timeout)
+		defer cancel()
+	}
+
+	t.reporter.update(StatusRunning, 0, "", nil)
+	result, err := func() (result any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("job panicked: %v", p)
+			}
+		}()
+		return t.job.Run(ctx, t.reporter)
+	}()
+	r.finish(t, result, err)
+}
+
+// finish records the outcome of t and forgets it.
+func (r *Runner) finish(t *task, result any, err error) {
+	r.mu.Lock()
+	if t.finished {
+		r.mu.Unlock()
+		return
+	}
+	t.finished = true
+	delete(r.tasks, t.id)
+	cancelled, stopping := t.cancelled, r.stopping
+	r.mu.Unlock()
+	t.cancel()
+
+	u := Update{ID: t.id, Result: result}
+	t.reporter.mu.Lock()
+	t.reporter.done = true
+	u.Progress = t.reporter.progress
+	t.reporter.mu.Unlock()
+
+	switch {
+	case err == nil:
+		u.Status, u.Progress, u.Description = StatusDone, 1, "Completed."
+	case cancelled:
+		u.Status, u.Description = StatusCancelled, "Cancelled."
+	case stopping && errors.Is(err, context.Canceled):
+		u.Status, u.Description = StatusInterrupted, "Interrupted by server shutdown."
+	case errors.Is(err, context.DeadlineExceeded):
+		u.Status, u.Description = StatusFailed, "Timed out."
+	default:
+		u.Status, u.Description = StatusFailed, "Failed."
+	}
+	if err != nil {
+		u.ErrorMessage = err.Error()
+		logger.Warnf("Request %d (%s) %s: %v", t.id, t.job.Kind, u.Status, err)
+	} else {
+		logger.Infof("Request %d (%s) done", t.id, t.job.Kind)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.store.UpdateRequest(ctx, u); err != nil {
+		logger.Errorf("Failed to record the outcome of request %d: %v", t.id, err)
+	}
+}
+
+// Reporter lets a job report its progress.
+type Reporter struct {
+	runner *Runner
+	id     int32
+
+	mu          sync.Mutex
+	progress    float64
+	description string
+	done        bool
+}
+
+// StatusCode is the HTTP status to answer a failed Submit with: 503 when the
+// queue is full, so callers know to retry, 500 otherwise.
+func StatusCode(err error) int {
+	if errors.Is(err, ErrQueueFull) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func (r *Reporter) ID() int32 {
+	return r.id
+}
+
+// Progress records progress in [0, 1]. An empty message keeps the previous
+// description, details are only pushed to the request's stream. Services run
+// outside a request get a nil reporter, which ignores progress.
+func (r *Reporter) Progress(progress float64, message string, details map[string]any) {
+	if r == nil {
+		return
+	}
+	r.update(StatusRunning, progress, message, details)
+}
+
+// File returns the path of a file to keep with the request, creating its
+// directory. Files are deleted with the request.
+func (r *Reporter) File(name string) (string, error) {
+	dir := r.runner.Path(r.id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(name)), nil
+}
+
+// WriteJSON encodes v into a file kept with the request.
+func (r *Reporter) WriteJSON(name string, v any) error {
+	path, err := r.File(name)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := json.NewEncoder(f).Encode(v); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return f.Close()
+}
+
+func (r *Reporter) update(status Status, progress float64, message string, details map[string]any) {
+	r.mu.Lock()
+	if r.done {
+		r.mu.Unlock()
+		return
+	}
+	r.progress = progress
+	if message != "" {
+		r.description = message
+	}
+	u := Update{
+		ID:          r.id,
+		Status:      status,
+		Progress:    progress,
+		Description: r.description,
+		Details:     details,
+	}
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.runner.store.UpdateRequest(ctx, u); err != nil {
+		logger.Errorf("Failed to update progress of request %d: %v", r.id, err)
+	}
+}
@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore keeps requests in memory and signals every update.
+type memStore struct {
+	mu          sync.Mutex
+	next        int32
+	rows        map[int32]Update
+	finished    map[int32]time.Time
+	updates     chan Update
+	interrupted int64
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		rows:     make(map[int32]Update),
+		finished: make(map[int32]time.Time),
+		updates:  make(chan Update, 100),
+	}
+}
+
+func (m *memStore) CreateRequest(_ context.Context, _ Kind, description string) (int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	m.rows[m.next] = Update{ID: m.next, Status: StatusQueued, Description: description}
+	return m.next, nil
+}
+
+func (m *memStore) UpdateRequest(_ context.Context, u Update) error {
+	m.mu.Lock()
+	m.rows[u.ID] = u
+	if u.Status.Final() {
+		m.finished[u.ID] = time.Now()
+	}
+	m.mu.Unlock()
+	m.updates <- u
+	return nil
+}
+
+func (m *memStore) InterruptRequests(context.Context) (int64, error) {
+	return m.interrupted, nil
+}
+
+func (m *memStore) DeleteFinishedRequests(_ context.Context, finishedBefore time.Time) ([]int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []int32
+	for id, at := range m.finished {
+		if at.Before(finishedBefore) {
+			delete(m.finished, id)
+			delete(m.rows, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// waitFinal returns the final update of request id.
+func (m *memStore) waitFinal(t *testing.T, id int32) Update {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case u := <-m.updates:
+			if u.ID == id && u.Status.Final() {
+				return u
+			}
+		case <-timeout:
+			t.Fatalf("Request %d did not finish", id)
+		}
+	}
+}
+
+func newRunner(t *testing.T, store Store, opts Options) *Runner {
+	opts.Dir = t.TempDir()
+	r := NewRunner(store, opts)
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		r.Stop(ctx)
+	})
+	return r
+}
+
+// TestRunnerOutcomes tests the status recorded for each way a job can end
+func TestRunnerOutcomes(t *testing.T) {
+	store := newMemStore()
+	r := newRunner(t, store, Options{Workers: 2})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		job    Job
+		status Status
+	}{
+		{"Done", Job{Run: func(ctx context.Context, rep *Reporter) (any, error) {
+			rep.Progress(0.5, "halfway", nil)
+			return map[string]int{"count": 3}, nil
+		}}, StatusDone},
+		{"Failed", Job{Run: func(context.Context, *Reporter) (any, error) {
+			return nil, errors.New("boom")
+		}}, StatusFailed},
+		{"Panicked", Job{Run: func(context.Context, *Reporter) (any, error) {
+			panic("oops")
+		}}, StatusFailed},
+		{"Timed out", Job{Timeout: 10 * time.Millisecond, Run: func(ctx context.Context, _ *Reporter) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}}, StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := r.Submit(ctx, tt.job)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if u := store.waitFinal(t, id); u.Status != tt.status {
+				t.Errorf("Expected %s, got %s (%s)", tt.status, u.Status, u.ErrorMessage)
+			}
+		})
+	}
+}
+
+// TestRunnerCancel tests cancelling running and queued jobs
+func TestRunnerCancel(t *testing.T) {
+	store := newMemStore()
+	r := newRunner(t, store, Options{Workers: 1})
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	blocking := Job{Run: func(ctx context.Context, _ *Reporter) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	running, _ := r.Submit(ctx, blocking)
+	<-started
+	queued, _ := r.Submit(ctx, Job{Run: func(context.Context, *Reporter) (any, error) {
+		t.Error("Cancelled job should not run")
+		return nil, nil
+	}})
+
+	if !r.Cancel(queued) {
+		t.Fatal("Expected queued job to be cancellable")
+	}
+	if u := store.waitFinal(t, queued); u.Status != StatusCancelled {
+		t.Errorf("Expected queued job to be cancelled, got %s", u.Status)
+	}
+	if !r.Cancel(running) {
+		t.Fatal("Expected running job to be cancellable")
+	}
+	if u := store.waitFinal(t, running); u.Status != StatusCancelled {
+		t.Errorf("Expected running job to be cancelled, got %s", u.Status)
+	}
+	if r.Cancel(running) {
+		t.Error("Expected finished job not to be cancellable")
+	}
+}
+
+// TestRunnerQueueFull tests that jobs beyond the queue size are rejected
+func TestRunnerQueueFull(t *testing.T) {
+	store := newMemStore()
+	r := newRunner(t, store, Options{Workers: 1, QueueSize: 1})
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.Submit(ctx, Job{Run: func(context.Context, *Reporter) (any, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}})
+	<-started
+	defer close(release)
+
+	if _, err := r.Submit(ctx, Job{Run: func(context.Context, *Reporter) (any, error) { return nil, nil }}); err != nil {
+		t.Fatalf("Expected the queue to take one job, got %v", err)
+	}
+	id, err := r.Submit(ctx, Job{Run: func(context.Context, *Reporter) (any, error) { return nil, nil }})
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+	if u := store.waitFinal(t, id); u.Status != StatusFailed {
+		t.Errorf("Expected rejected job to fail, got %s", u.Status)
+	}
+}
+
+// TestRunnerStop tests that stopping interrupts running jobs
+func TestRunnerStop(t *testing.T) {
+	store := newMemStore()
+	r := NewRunner(store, Options{Workers: 1, Dir: t.TempDir()})
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	id, _ := r.Submit(context.Background(), Job{Run: func(ctx context.Context, _ *Reporter) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Stop(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if u := store.waitFinal(t, id); u.Status != StatusInterrupted {
+		t.Errorf("Expected interrupted, got %s", u.Status)
+	}
+	if _, err := r.Submit(context.Background(), Job{}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected ErrStopped, got %v", err)
+	}
+}
+
+// TestRunnerPurge tests that purged requests lose their files
+func TestRunnerPurge(t *testing.T) {
+	store := newMemStore()
+	r := newRunner(t, store, Options{Workers: 1})
+
+	var path string
+	id, _ := r.Submit(context.Background(), Job{Run: func(_ context.Context, rep *Reporter) (any, error) {
+		var err error
+		path, err = rep.File("export.json")
+		if err != nil {
+			return nil, err
+		}
+		return nil, os.WriteFile(path, []byte("[]"), 0o644)
+	}})
+	store.waitFinal(t, id)
+
+	if n, err := r.Purge(context.Background(), time.Hour); err != nil || n != 0 {
+		t.Fatalf("Expected nothing purged within retention, got %d (err=%v)", n, err)
+	}
+	if n, err := r.Purge(context.Background(), -time.Second); err != nil || n != 1 {
+		t.Fatalf("Expected one purged request, got %d (err=%v)", n, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected request files to be removed, got %v", err)
+	}
+}
+
+// TestReporterWriteJSON tests that a JSON file lands with the request
+func TestReporterWriteJSON(t *testing.T) {
+	store := newMemStore()
+	r := newRunner(t, store, Options{Workers: 1})
+
+	id, _ := r.Submit(context.Background(), Job{Run: func(_ context.Context, rep *Reporter) (any, error) {
+		return nil, rep.WriteJSON("export.json", []int{1, 2})
+	}})
+	if u := store.waitFinal(t, id); u.Status != StatusDone {
+		t.Fatalf("Expected job to complete, got %s (%s)", u.Status, u.Description)
+	}
+	data, err := os.ReadFile(filepath.Join(r.Path(id), "export.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[1,2]\n" {
+		t.Errorf("Expected [1,2], got %q", data)
+	}
+}
+
+// TestStatusCode tests the HTTP status of Submit errors
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{ErrQueueFull, 503},
+		{fmt.Errorf("submit: %w", ErrQueueFull), 503},
+		{ErrStopped, 500},
+		{errors.New("db down"), 500},
+	}
+	for _, tt := range tests {
+		if got := StatusCode(tt.err); got != tt.want {
+			t.Errorf("StatusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestNilReporterProgress tests that services run outside a request can
+// report progress
+func TestNilReporterProgress(t *testing.T) {
+	var r *Reporter
+	r.Progress(0.5, "halfway", nil)
+}
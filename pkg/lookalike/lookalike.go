@@ -0,0 +1,145 @@
+// Package lookalike builds the queries of a lookalike audience from the
+// embeddings of a seed list and ranks what they return.
+package lookalike
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type Mode string
+
+const (
+	// ModeCentroid queries with the mean of the seed embeddings, it finds
+	// profiles like the seed as a whole.
+	ModeCentroid Mode = "centroid"
+	// ModeMulti queries with each seed embedding and keeps the best match of
+	// every profile, it keeps the distinct groups of a mixed seed apart.
+	ModeMulti Mode = "multi"
+)
+
+var ErrEmptySeed = errors.New("seed has no embedded profiles")
+
+// ParseMode reads a mode, empty means centroid.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ModeCentroid, nil
+	case ModeCentroid, ModeMulti:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown mode %q, expected centroid or multi", s)
+}
+
+// Centroid is the normalized mean of the normalized vectors. Cosine distance
+// ignores the length, so every seed weighs the same.
+func Centroid(vectors [][]float32) ([]float32, error) {
+	if len(vectors) == 0 {
+		return nil, ErrEmptySeed
+	}
+	dim := len(vectors[0])
+	sum := make([]float64, dim)
+	for i, v := range vectors {
+		if len(v) != dim {
+			return nil, fmt.Errorf("vector %d has %d dimensions, expected %d", i, len(v), dim)
+		}
+		n := norm(v)
+		if n == 0 {
+			continue
+		}
+		for j, x := range v {
+			sum[j] += float64(x) / n
+		}
+	}
+
+	var total float64
+	for _, x := range sum {
+		total += x * x
+	}
+	if total == 0 {
+		return nil, errors.New("seed embeddings cancel out")
+	}
+	total = math.Sqrt(total)
+	centroid := make([]float32, dim)
+	for j, x := range sum {
+		centroid[j] = float32(x / total)
+	}
+	return centroid, nil
+}
+
+// Sample picks at most k of n indexes, evenly spread. It bounds the number of
+// queries of the multi mode.
+func Sample(n, k int) []int {
+	if k <= 0 || n <= 0 {
+		return nil
+	}
+	if k > n {
+		k = n
+	}
+	indexes := make([]int, k)
+	for i := range indexes {
+		indexes[i] = i * n / k
+	}
+	return indexes
+}
+
+type Candidate struct {
+	ProfileID  int32
+	Similarity float64
+}
+
+type Match struct {
+	ProfileID  int32   `json:"profile_id"`
+	Similarity float64 `json:"similarity"`
+	// Hits is the number of seed queries that returned the profile
+	Hits int `json:"hits"`
+}
+
+// Merge combines the results of several queries, a profile keeps its best
+// similarity. Ties go to the profile found by more queries. At most k
+// matches are returned.
+func Merge(results [][]Candidate, k int) []Match {
+	byID := make(map[int32]*Match)
+	for _, result := range results {
+		for _, c := range result {
+			m, ok := byID[c.ProfileID]
+			if !ok {
+				m = &Match{ProfileID: c.ProfileID, Similarity: c.Similarity}
+				byID[c.ProfileID] = m
+			}
+			m.Hits++
+			if c.Similarity > m.Similarity {
+				m.Similarity = c.Similarity
+			}
+		}
+	}
+
+	matches := make([]Match, 0, len(byID))
+	for _, m := range byID {
+		matches = append(matches, *m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		if matches[i].Hits != matches[j].Hits {
+			return matches[i].Hits > matches[j].Hits
+		}
+		return matches[i].ProfileID < matches[j].ProfileID
+	})
+	if k >= 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+func norm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,82 @@
+package lookalike
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCentroid tests that every seed weighs the same whatever its length
+func TestCentroid(t *testing.T) {
+	centroid, err := Centroid([][]float32{{10, 0}, {0, 1}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := float32(1 / math.Sqrt2)
+	for i, x := range centroid {
+		if math.Abs(float64(x-want)) > 1e-6 {
+			t.Errorf("Expected %f at %d, got %f", want, i, x)
+		}
+	}
+
+	if _, err := Centroid(nil); err != ErrEmptySeed {
+		t.Errorf("Expected ErrEmptySeed, got %v", err)
+	}
+	if _, err := Centroid([][]float32{{1, 0}, {1}}); err == nil {
+		t.Error("Expected an error for mismatched dimensions")
+	}
+	if _, err := Centroid([][]float32{{1, 0}, {-1, 0}}); err == nil {
+		t.Error("Expected an error for opposite vectors")
+	}
+}
+
+// TestSample tests that the sample is spread and bounded
+func TestSample(t *testing.T) {
+	got := Sample(10, 3)
+	want := []int{0, 3, 6}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+	if got := Sample(2, 5); len(got) != 2 {
+		t.Errorf("Expected 2 indexes, got %v", got)
+	}
+	if got := Sample(5, 0); got != nil {
+		t.Errorf("Expected no indexes, got %v", got)
+	}
+}
+
+// TestMerge tests that a profile keeps its best similarity and ties go to
+// the profile with more hits
+func TestMerge(t *testing.T) {
+	matches := Merge([][]Candidate{
+		{{ProfileID: 1, Similarity: 0.9}, {ProfileID: 2, Similarity: 0.8}},
+		{{ProfileID: 2, Similarity: 0.7}, {ProfileID: 3, Similarity: 0.8}, {ProfileID: 1, Similarity: 0.95}},
+	}, 2)
+
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+	if matches[0].ProfileID != 1 || matches[0].Similarity != 0.95 || matches[0].Hits != 2 {
+		t.Errorf("Unexpected first match %+v", matches[0])
+	}
+	if matches[1].ProfileID != 2 || matches[1].Hits != 2 {
+		t.Errorf("Expected profile 2 to win the tie, got %+v", matches[1])
+	}
+}
+
+// TestParseMode tests the default and unknown modes
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode(""); err != nil || m != ModeCentroid {
+		t.Errorf("Expected centroid, got %q (%v)", m, err)
+	}
+	if m, err := ParseMode("Multi"); err != nil || m != ModeMulti {
+		t.Errorf("Expected multi, got %q (%v)", m, err)
+	}
+	if _, err := ParseMode("knn"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
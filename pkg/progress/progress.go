@@ -0,0 +1,92 @@
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a change of a request row. Details carries task specific values
+// that are not stored, e.g. the current optuna trial and best RMSE.
+type Event struct {
+	RequestID    int32          `json:"request_id"`
+	Status       int16          `json:"status"`
+	Progress     float64        `json:"progress"`
+	Description  string         `json:"description,omitempty"`
+	ErrorMessage string         `json:"error_message,omitempty"`
+	Details      map[string]any `json:"details,omitempty"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	// Final is set on the last event of a request, subscribers are closed
+	// once it is delivered.
+	Final bool `json:"final"`
+}
+
+// Broker fans request events out to their subscribers. Publishing never
+// blocks: a subscriber that falls behind loses its oldest events, so the
+// latest state and the final event always get through.
+type Broker struct {
+	mu     sync.Mutex
+	size   int
+	topics map[int32]map[chan Event]struct{}
+}
+
+func NewBroker(size int) *Broker {
+	if size <= 0 {
+		size = 1
+	}
+	return &Broker{size: size, topics: make(map[int32]map[chan Event]struct{})}
+}
+
+// Subscribe returns the events of request id, published from now on. The
+// channel is closed after the final event or when cancel is called.
+func (b *Broker) Subscribe(id int32) (<-chan Event, func()) {
+	ch := make(chan Event, b.size)
+
+	b.mu.Lock()
+	subs, ok := b.topics[id]
+	if !ok {
+		subs = make(map[chan Event]struct{})
+		b.topics[id] = subs
+	}
+	subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.topics[id][ch]; ok {
+			b.remove(id, ch)
+		}
+	}
+}
+
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.topics[e.RequestID] {
+		select {
+		case ch <- e:
+		default:
+			// drop the oldest event to make room
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+		if e.Final {
+			b.remove(e.RequestID, ch)
+		}
+	}
+}
+
+// remove closes ch and forgets it. b.mu must be held.
+func (b *Broker) remove(id int32, ch chan Event) {
+	close(ch)
+	delete(b.topics[id], ch)
+	if len(b.topics[id]) == 0 {
+		delete(b.topics, id)
+	}
+}
@@ -0,0 +1,64 @@
+package progress
+
+import "testing"
+
+// TestBrokerPublish tests delivery by request and closing on the final event
+func TestBrokerPublish(t *testing.T) {
+	b := NewBroker(4)
+	events, cancel := b.Subscribe(1)
+	defer cancel()
+	other, cancelOther := b.Subscribe(2)
+	defer cancelOther()
+
+	b.Publish(Event{RequestID: 1, Progress: 0.5})
+	b.Publish(Event{RequestID: 1, Progress: 1, Final: true})
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+	if len(got) != 2 || got[0].Progress != 0.5 || !got[1].Final {
+		t.Errorf("Unexpected events: %v", got)
+	}
+	select {
+	case e := <-other:
+		t.Errorf("Expected no event for another request, got %v", e)
+	default:
+	}
+}
+
+// TestBrokerSlowSubscriber tests that a full subscriber keeps the latest events
+func TestBrokerSlowSubscriber(t *testing.T) {
+	b := NewBroker(2)
+	events, cancel := b.Subscribe(1)
+	defer cancel()
+
+	for i := 1; i <= 5; i++ {
+		b.Publish(Event{RequestID: 1, Progress: float64(i) / 10})
+	}
+	b.Publish(Event{RequestID: 1, Progress: 1, Final: true})
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+	if len(got) != 2 || got[0].Progress != 0.5 || !got[1].Final {
+		t.Errorf("Expected the last progress and the final event, got %v", got)
+	}
+}
+
+// TestBrokerCancel tests that cancelling closes the channel once
+func TestBrokerCancel(t *testing.T) {
+	b := NewBroker(1)
+	events, cancel := b.Subscribe(1)
+	cancel()
+	cancel()
+
+	if _, ok := <-events; ok {
+		t.Error("Expected the channel to be closed")
+	}
+	b.Publish(Event{RequestID: 1, Final: true})
+	if len(b.topics) != 0 {
+		t.Errorf("Expected no topics left, got %d", len(b.topics))
+	}
+}
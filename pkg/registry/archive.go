@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RequiredFiles make up a model version folder, as written by training and
+// zipped by the export.
+var RequiredFiles = []string{"model.json", "encoders.pkl", "scalers.pkl", "metadata.json"}
+
+var ErrArchiveTooLarge = errors.New("archive is too large")
+
+// Metadata is the part of metadata.json an imported model must have.
+type Metadata struct {
+	RMSE    float64 `json:"rmse"`
+	R2      float64 `json:"r2"`
+	MAE     float64 `json:"mae"`
+	SavedAt string  `json:"saved_at,omitempty"`
+}
+
+// ParseMetadata checks metadata.json against what training writes.
+func ParseMetadata(data []byte) (Metadata, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Metadata{}, fmt.Errorf("metadata.json is not a JSON object: %w", err)
+	}
+	for _, key := range []string{"rmse", "r2", "mae"} {
+		var v float64
+		if err := json.Unmarshal(fields[key], &v); err != nil {
+			return Metadata{}, fmt.Errorf("metadata.json: %s must be a number", key)
+		}
+	}
+
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Metadata{}, fmt.Errorf("metadata.json: %w", err)
+	}
+	if m.SavedAt != "" {
+		if _, err := time.Parse(time.RFC3339Nano, m.SavedAt); err != nil {
+			return Metadata{}, fmt.Errorf("metadata.json: saved_at is not a timestamp")
+		}
+	}
+	return m, nil
+}
+
+// ExtractArchive writes a model archive to dir and returns its metadata. Only
+// the flat layout of the export is accepted: folders, paths leaving dir,
+// unknown or duplicate files and more than maxBytes of content are rejected.
+func ExtractArchive(zr *zip.Reader, dir string, maxBytes int64) (Metadata, error) {
+	seen := make(map[string]bool, len(RequiredFiles))
+	for _, f := range zr.File {
+		name := f.Name
+		if strings.ContainsAny(name, `/\`) || name != path.Base(name) || name == "." || name == ".." {
+			return Metadata{}, fmt.Errorf("unexpected path in archive: %q", name)
+		}
+		if !slices.Contains(RequiredFiles, name) {
+			return Metadata{}, fmt.Errorf("unexpected file in archive: %q", name)
+		}
+		if seen[name] {
+			return Metadata{}, fmt.Errorf("duplicate file in archive: %q", name)
+		}
+		seen[name] = true
+		if f.Mode()&os.ModeType != 0 {
+			return Metadata{}, fmt.Errorf("%s is not a regular file", name)
+		}
+		if f.UncompressedSize64 > uint64(maxBytes) {
+			return Metadata{}, ErrArchiveTooLarge
+		}
+
+		n, err := extractFile(f, filepath.Join(dir, name), maxBytes)
+		if err != nil {
+			return Metadata{}, err
+		}
+		maxBytes -= n
+	}
+
+	for _, name := range RequiredFiles {
+		if !seen[name] {
+			return Metadata{}, fmt.Errorf("missing %s in archive", name)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return Metadata{}, err
+	}
+	meta, err := ParseMetadata(data)
+	if err != nil {
+		return Metadata{}, err
+	}
+	model, err := os.ReadFile(filepath.Join(dir, "model.json"))
+	if err != nil {
+		return Metadata{}, err
+	}
+	if !json.Valid(model) {
+		return Metadata{}, errors.New("model.json is not valid JSON")
+	}
+	return meta, nil
+}
+
+// extractFile copies f to dst, failing once more than limit bytes were read
+// whatever the archive claims the size is.
+func extractFile(f *zip.File, dst string, limit int64) (int64, error) {
+	src, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, io.LimitReader(src, limit+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("failed to extract %s: %w", f.Name, err)
+	}
+	if n > limit {
+		return n, ErrArchiveTooLarge
+	}
+	return n, nil
+}
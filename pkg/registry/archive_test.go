@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testMetadata = `{"rmse": 0.12, "r2": 0.8, "mae": 0.09, "saved_at": "2025-10-24T09:00:00.123456+00:00"}`
+
+type entry struct {
+	name string
+	body string
+}
+
+func validEntries() []entry {
+	return []entry{
+		{"model.json", `{"learner": {}}`},
+		{"encoders.pkl", "encoders"},
+		{"scalers.pkl", "scalers"},
+		{"metadata.json", testMetadata},
+	}
+}
+
+func archive(t *testing.T, entries []entry) *zip.Reader {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, e := range entries {
+		f, err := w.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(e.body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+// TestExtractArchive tests extracting an exported model
+func TestExtractArchive(t *testing.T) {
+	dir := t.TempDir()
+	meta, err := ExtractArchive(archive(t, validEntries()), dir, 1<<20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if meta.RMSE != 0.12 {
+		t.Errorf("Expected rmse 0.12, got %v", meta.RMSE)
+	}
+	for _, name := range RequiredFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s to be extracted: %v", name, err)
+		}
+	}
+}
+
+// TestExtractArchiveRejects tests the archives that must not be imported
+func TestExtractArchiveRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func([]entry) []entry
+	}{
+		{"Path traversal", func(e []entry) []entry { return append(e, entry{"../evil.json", "{}"}) }},
+		{"Nested path", func(e []entry) []entry { return append(e, entry{"v1/model.json", "{}"}) }},
+		{"Windows path", func(e []entry) []entry { return append(e, entry{`..\evil.json`, "{}"}) }},
+		{"Unknown file", func(e []entry) []entry { return append(e, entry{"run.sh", "echo"}) }},
+		{"Duplicate file", func(e []entry) []entry { return append(e, entry{"model.json", "{}"}) }},
+		{"Missing file", func(e []entry) []entry { return e[:3] }},
+		{"Bad metadata", func(e []entry) []entry { e[3].body = `{"rmse": "low"}`; return e }},
+		{"Bad model", func(e []entry) []entry { e[0].body = "not json"; return e }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := ExtractArchive(archive(t, tt.modify(validEntries())), dir, 1<<20); err == nil {
+				t.Error("Expected the archive to be rejected")
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "evil.json")); err == nil {
+				t.Error("Expected nothing written outside the directory")
+			}
+		})
+	}
+}
+
+// TestExtractArchiveTooLarge tests the size limit
+func TestExtractArchiveTooLarge(t *testing.T) {
+	entries := validEntries()
+	entries[1].body = string(bytes.Repeat([]byte("x"), 1024))
+	if _, err := ExtractArchive(archive(t, entries), t.TempDir(), 512); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Errorf("Expected ErrArchiveTooLarge, got %v", err)
+	}
+}
+
+// TestParseMetadata tests the metadata schema
+func TestParseMetadata(t *testing.T) {
+	tests := []struct {
+		data  string
+		valid bool
+	}{
+		{testMetadata, true},
+		{`{"rmse": 0.1, "r2": 0.5, "mae": 0.1}`, true},
+		{`[]`, false},
+		{`{"rmse": 0.1, "r2": 0.5}`, false},
+		{`{"rmse": 0.1, "r2": 0.5, "mae": 0.1, "saved_at": "yesterday"}`, false},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseMetadata([]byte(tt.data)); (err == nil) != tt.valid {
+			t.Errorf("ParseMetadata(%s): expected valid=%v, got %v", tt.data, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"fmt"
+	"time"
+)
+
+// Gate decides whether a retrained version replaces the champion. Margins
+// are absolute: with RMSEMargin 0.01 a champion at 0.20 RMSE is only beaten
+// by 0.19 or less.
+type Gate struct {
+	RMSEMargin float64
+	R2Margin   float64
+}
+
+// Decision is the outcome of a gate, Reason is meant for the log.
+type Decision struct {
+	Promote bool   `json:"promote"`
+	Reason  string `json:"reason"`
+}
+
+// Evaluate compares a candidate to the champion, which is nil when the
+// category has none yet. The candidate must beat both metrics.
+func (g Gate) Evaluate(candidate Metadata, champion *Metadata) Decision {
+	if champion == nil {
+		return Decision{
+			Promote: true,
+			Reason:  fmt.Sprintf("no champion, promoted with RMSE %.4f, R² %.4f", candidate.RMSE, candidate.R2),
+		}
+	}
+
+	maxRMSE := champion.RMSE - g.RMSEMargin
+	minR2 := champion.R2 + g.R2Margin
+	reason := fmt.Sprintf("RMSE %.4f vs champion %.4f (needs <= %.4f), R² %.4f vs champion %.4f (needs >= %.4f)",
+		candidate.RMSE, champion.RMSE, maxRMSE, candidate.R2, champion.R2, minR2)
+	if candidate.RMSE <= maxRMSE && candidate.R2 >= minR2 {
+		return Decision{Promote: true, Reason: "promoted: " + reason}
+	}
+	return Decision{Promote: false, Reason: "kept champion: " + reason}
+}
+
+// Schedule tells when a category is retrained. Zero fields are disabled.
+type Schedule struct {
+	Interval time.Duration
+	// MinNewProfiles is the number of profiles labelled or scored by gemini
+	// since the last run that triggers a retrain.
+	MinNewProfiles int64
+}
+
+// Due returns why a retrain is due, or an empty string when it is not.
+// lastRun is zero when the category was never retrained.
+func (s Schedule) Due(lastRun time.Time, newProfiles int64, now time.Time) string {
+	if s.Interval > 0 && (lastRun.IsZero() || now.Sub(lastRun) >= s.Interval) {
+		if lastRun.IsZero() {
+			return "first scheduled run"
+		}
+		return fmt.Sprintf("last run %s ago", now.Sub(lastRun).Round(time.Minute))
+	}
+	if s.MinNewProfiles > 0 && newProfiles >= s.MinNewProfiles {
+		return fmt.Sprintf("%d new profiles since the last run", newProfiles)
+	}
+	return ""
+}
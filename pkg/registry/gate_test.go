@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGateEvaluate tests promotion against the champion's metrics
+func TestGateEvaluate(t *testing.T) {
+	champion := &Metadata{RMSE: 0.20, R2: 0.70}
+	gate := Gate{RMSEMargin: 0.01, R2Margin: 0.02}
+
+	tests := []struct {
+		name      string
+		candidate Metadata
+		champion  *Metadata
+		promote   bool
+	}{
+		{"No champion", Metadata{RMSE: 0.5, R2: 0.1}, nil, true},
+		{"Beats both", Metadata{RMSE: 0.18, R2: 0.75}, champion, true},
+		{"Exactly the margin", Metadata{RMSE: 0.19, R2: 0.72}, champion, true},
+		{"RMSE within margin", Metadata{RMSE: 0.195, R2: 0.75}, champion, false},
+		{"R² within margin", Metadata{RMSE: 0.18, R2: 0.71}, champion, false},
+		{"Worse", Metadata{RMSE: 0.25, R2: 0.60}, champion, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := gate.Evaluate(tt.candidate, tt.champion)
+			if d.Promote != tt.promote {
+				t.Errorf("Expected promote=%v, got %v (%s)", tt.promote, d.Promote, d.Reason)
+			}
+			if d.Reason == "" {
+				t.Error("Expected a reason")
+			}
+		})
+	}
+}
+
+// TestScheduleDue tests when a retrain is due
+func TestScheduleDue(t *testing.T) {
+	now := time.Date(2025, 10, 25, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		lastRun  time.Time
+		newCount int64
+		due      bool
+	}{
+		{"Disabled", Schedule{}, time.Time{}, 1000, false},
+		{"Never run", Schedule{Interval: time.Hour}, time.Time{}, 0, true},
+		{"Interval elapsed", Schedule{Interval: time.Hour}, now.Add(-2 * time.Hour), 0, true},
+		{"Interval not elapsed", Schedule{Interval: time.Hour}, now.Add(-time.Minute), 0, false},
+		{"Enough new profiles", Schedule{MinNewProfiles: 100}, now.Add(-time.Minute), 150, true},
+		{"Too few new profiles", Schedule{MinNewProfiles: 100}, now.Add(-time.Minute), 99, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Due(tt.lastRun, tt.newCount, now); (got != "") != tt.due {
+				t.Errorf("Expected due=%v, got %q", tt.due, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+)
+
+// DefaultSampleRate is the share of newly scored profiles a challenger
+// shadow-scores when no rate is configured.
+const DefaultSampleRate = 0.1
+
+var modelName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidName reports whether name can be used as a model folder name.
+func ValidName(name string) bool {
+	return len(name) <= 128 && modelName.MatchString(name) && name != "." && name != ".."
+}
+
+// VersionPath is the folder of a model version, relative to the models
+// directory. Versions are never overwritten, retraining writes a new one.
+func VersionPath(name string, version int32) string {
+	return fmt.Sprintf("%s/v%d", name, version)
+}
+
+// Sampled tells whether a challenger shadow-scores profileID. The choice is
+// stable for a given version so a profile is either always or never sampled.
+func Sampled(versionID, profileID int32, rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%d", versionID, profileID)
+	return float64(h.Sum32())/float64(math.MaxUint32) < rate
+}
+
+// Sample is one profile scored by both the champion and the challenger.
+// Label is its gemini score when there is one.
+type Sample struct {
+	Champion   float64
+	Challenger float64
+	Label      *float64
+}
+
+// Comparison summarises a challenger's shadow scores against the champion.
+type Comparison struct {
+	Samples     int     `json:"samples"`
+	MeanAbsDiff float64 `json:"mean_abs_diff"`
+	// Correlation is the Pearson correlation of both scores, 0 when either
+	// is constant.
+	Correlation float64 `json:"correlation"`
+	Labelled    int     `json:"labelled"`
+	// RMSE against the gemini labels, only set when there are labelled
+	// samples.
+	ChampionRMSE   *float64 `json:"champion_rmse,omitempty"`
+	ChallengerRMSE *float64 `json:"challenger_rmse,omitempty"`
+	// ChallengerBetter is set when the challenger has a lower RMSE on the
+	// labelled samples.
+	ChallengerBetter bool `json:"challenger_better"`
+}
+
+func Compare(samples []Sample) Comparison {
+	c := Comparison{Samples: len(samples)}
+	if len(samples) == 0 {
+		return c
+	}
+
+	var sumA, sumB, sumDiff float64
+	var sqA, sqB float64
+	for _, s := range samples {
+		sumA += s.Champion
+		sumB += s.Challenger
+		sumDiff += math.Abs(s.Champion - s.Challenger)
+		if s.Label != nil {
+			c.Labelled++
+			sqA += (s.Champion - *s.Label) * (s.Champion - *s.Label)
+			sqB += (s.Challenger - *s.Label) * (s.Challenger - *s.Label)
+		}
+	}
+	n := float64(len(samples))
+	c.MeanAbsDiff = sumDiff / n
+
+	meanA, meanB := sumA/n, sumB/n
+	var cov, varA, varB float64
+	for _, s := range samples {
+		da, db := s.Champion-meanA, s.Challenger-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA > 0 && varB > 0 {
+		c.Correlation = cov / math.Sqrt(varA*varB)
+	}
+
+	if c.Labelled > 0 {
+		a := math.Sqrt(sqA / float64(c.Labelled))
+		b := math.Sqrt(sqB / float64(c.Labelled))
+		c.ChampionRMSE, c.ChallengerRMSE = &a, &b
+		c.ChallengerBetter = b < a
+	}
+	return c
+}
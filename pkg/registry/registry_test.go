@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"math"
+	"testing"
+)
+
+func f64(v float64) *float64 {
+	return &v
+}
+
+// TestValidName tests which model names may become folders
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Model_20251024", true},
+		{"xgb-v2.1", true},
+		{"", false},
+		{"..", false},
+		{"../models", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{".hidden", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidName(tt.name); got != tt.want {
+			t.Errorf("ValidName(%q): expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestSampled tests that sampling is stable and close to the rate
+func TestSampled(t *testing.T) {
+	if Sampled(1, 1, 0) || !Sampled(1, 1, 1) {
+		t.Error("Expected rates 0 and 1 to sample nothing and everything")
+	}
+
+	hits := 0
+	for pid := range int32(10000) {
+		if Sampled(7, pid, 0.2) {
+			hits++
+		}
+		if Sampled(7, pid, 0.2) != Sampled(7, pid, 0.2) {
+			t.Fatalf("Expected a stable choice for profile %d", pid)
+		}
+	}
+	if hits < 1800 || hits > 2200 {
+		t.Errorf("Expected about 2000 sampled profiles, got %d", hits)
+	}
+}
+
+// TestCompare tests the comparison of shadow scores
+func TestCompare(t *testing.T) {
+	if c := Compare(nil); c.Samples != 0 || c.ChampionRMSE != nil {
+		t.Errorf("Expected an empty comparison, got %+v", c)
+	}
+
+	c := Compare([]Sample{
+		{Champion: 0.2, Challenger: 0.1, Label: f64(0.1)},
+		{Champion: 0.5, Challenger: 0.5, Label: f64(0.5)},
+		{Champion: 0.8, Challenger: 0.9},
+	})
+	if c.Samples != 3 || c.Labelled != 2 {
+		t.Fatalf("Unexpected counts: %+v", c)
+	}
+	if math.Abs(c.MeanAbsDiff-0.2/3) > 1e-9 {
+		t.Errorf("Expected mean abs diff %.4f, got %.4f", 0.2/3, c.MeanAbsDiff)
+	}
+	if c.Correlation < 0.99 {
+		t.Errorf("Expected strongly correlated scores, got %.4f", c.Correlation)
+	}
+	if *c.ChallengerRMSE != 0 || !c.ChallengerBetter {
+		t.Errorf("Expected the challenger to match the labels, got %+v", c)
+	}
+}
@@ -0,0 +1,166 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Policy configures jittered exponential backoff for one integration.
+type Policy struct {
+	MaxAttempts int // total attempts including the first one
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Decision is how a failed attempt should be handled. After is the delay the
+// server asked for, 0 when it did not ask for one.
+type Decision struct {
+	Retry bool
+	After time.Duration
+}
+
+// Classifier decides whether an error returned by an attempt is transient.
+type Classifier func(error) Decision
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// FromConfig reads <PREFIX>_RETRY_MAX_ATTEMPTS, <PREFIX>_RETRY_BASE_DELAY_MS
+// and <PREFIX>_RETRY_MAX_DELAY_MS, falling back to def for missing or invalid
+// values.
+func FromConfig(get func(key, defaultValue string) string, prefix string, def Policy) Policy {
+	p := def
+	if v, err := strconv.Atoi(get(prefix+"_RETRY_MAX_ATTEMPTS", strconv.Itoa(def.MaxAttempts))); err == nil && v > 0 {
+		p.MaxAttempts = v
+	}
+	if v, err := strconv.Atoi(get(prefix+"_RETRY_BASE_DELAY_MS", strconv.FormatInt(def.BaseDelay.Milliseconds(), 10))); err == nil && v >= 0 {
+		p.BaseDelay = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(get(prefix+"_RETRY_MAX_DELAY_MS", strconv.FormatInt(def.MaxDelay.Milliseconds(), 10))); err == nil && v >= 0 {
+		p.MaxDelay = time.Duration(v) * time.Millisecond
+	}
+	return p
+}
+
+// Backoff returns the delay before retry number attempt (starting at 0): a
+// random value between half and all of BaseDelay*2^attempt, capped at MaxDelay.
+func (p Policy) Backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	ceiling := float64(p.BaseDelay) * math.Exp2(float64(attempt))
+	if p.MaxDelay > 0 {
+		ceiling = math.Min(ceiling, float64(p.MaxDelay))
+	}
+	half := ceiling / 2
+	return time.Duration(half + rand.Float64()*half)
+}
+
+// Delay honors the server's requested delay, capped at MaxDelay, and falls
+// back to Backoff otherwise.
+func (p Policy) Delay(attempt int, d Decision) time.Duration {
+	if d.After > 0 {
+		if p.MaxDelay > 0 && d.After > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return d.After
+	}
+	return p.Backoff(attempt)
+}
+
+var sleep = func(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Do runs fn until it succeeds, the error is not retryable, the attempts are
+// used up or ctx is done. The last error is returned as is.
+func Do[T any](ctx context.Context, p Policy, classify Classifier, fn func() (T, error)) (T, error) {
+	attempts := max(p.MaxAttempts, 1)
+	for attempt := 0; ; attempt++ {
+		result, err := fn()
+		if err == nil {
+			return result, nil
+		}
+		if attempt+1 >= attempts {
+			return result, err
+		}
+		d := classify(err)
+		if !d.Retry {
+			return result, err
+		}
+		if sleepErr := sleep(ctx, p.Delay(attempt, d)); sleepErr != nil {
+			return result, err
+		}
+	}
+}
+
+// RetryableStatus reports whether an HTTP status is worth retrying: 408, 429
+// and 5xx other than 501. Other 4xx, auth errors included, are fatal.
+func RetryableStatus(status int) bool {
+	switch {
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	case status == http.StatusNotImplemented:
+		return false
+	case status >= 500:
+		return true
+	}
+	return false
+}
+
+// IsNetworkError reports whether err is a transient transport failure.
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
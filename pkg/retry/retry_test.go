@@ -0,0 +1,154 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+var errFatal = errors.New("fatal")
+
+func classify(err error) Decision {
+	return Decision{Retry: errors.Is(err, errTransient)}
+}
+
+// stubSleep records the requested delays instead of sleeping
+func stubSleep(t *testing.T) *[]time.Duration {
+	delays := []time.Duration{}
+	orig := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = orig })
+	return &delays
+}
+
+// TestDo tests retry, give-up and fatal error handling
+func TestDo(t *testing.T) {
+	p := Policy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}
+
+	t.Run("Succeeds after transient errors", func(t *testing.T) {
+		delays := stubSleep(t)
+		calls := 0
+		got, err := Do(context.Background(), p, classify, func() (int, error) {
+			calls++
+			if calls < 3 {
+				return 0, errTransient
+			}
+			return 42, nil
+		})
+		if err != nil || got != 42 {
+			t.Fatalf("Expected 42, got %d (err=%v)", got, err)
+		}
+		if len(*delays) != 2 {
+			t.Errorf("Expected 2 waits, got %d", len(*delays))
+		}
+	})
+
+	t.Run("Stops on fatal error", func(t *testing.T) {
+		stubSleep(t)
+		calls := 0
+		_, err := Do(context.Background(), p, classify, func() (int, error) {
+			calls++
+			return 0, errFatal
+		})
+		if !errors.Is(err, errFatal) || calls != 1 {
+			t.Errorf("Expected a single fatal attempt, got %d calls (err=%v)", calls, err)
+		}
+	})
+
+	t.Run("Gives up after max attempts", func(t *testing.T) {
+		stubSleep(t)
+		calls := 0
+		_, err := Do(context.Background(), p, classify, func() (int, error) {
+			calls++
+			return 0, errTransient
+		})
+		if !errors.Is(err, errTransient) || calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d (err=%v)", calls, err)
+		}
+	})
+
+	t.Run("Honors requested delay", func(t *testing.T) {
+		delays := stubSleep(t)
+		calls := 0
+		after := func(error) Decision { return Decision{Retry: true, After: 5 * time.Second} }
+		Do(context.Background(), p, after, func() (int, error) {
+			calls++
+			if calls == 1 {
+				return 0, errTransient
+			}
+			return 1, nil
+		})
+		if len(*delays) != 1 || (*delays)[0] != time.Second {
+			t.Errorf("Expected the requested delay capped at 1s, got %v", *delays)
+		}
+	})
+
+	t.Run("Stops when context is done", func(t *testing.T) {
+		stubSleep(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		calls := 0
+		Do(ctx, p, classify, func() (int, error) {
+			calls++
+			return 0, errTransient
+		})
+		if calls != 1 {
+			t.Errorf("Expected 1 attempt, got %d", calls)
+		}
+	})
+}
+
+// TestBackoff tests that delays grow and stay within bounds
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 8; attempt++ {
+		ceiling := min(p.BaseDelay<<attempt, p.MaxDelay)
+		for i := 0; i < 20; i++ {
+			d := p.Backoff(attempt)
+			if d < ceiling/2 || d > ceiling {
+				t.Fatalf("Attempt %d: expected delay in [%v, %v], got %v", attempt, ceiling/2, ceiling, d)
+			}
+		}
+	}
+}
+
+// TestRetryableStatus tests HTTP status classification
+func TestRetryableStatus(t *testing.T) {
+	tests := map[int]bool{
+		200: false,
+		400: false,
+		401: false,
+		403: false,
+		404: false,
+		408: true,
+		429: true,
+		500: true,
+		501: false,
+		503: true,
+	}
+	for status, want := range tests {
+		if got := RetryableStatus(status); got != want {
+			t.Errorf("Status %d: expected %v, got %v", status, want, got)
+		}
+	}
+}
+
+// TestParseRetryAfter tests seconds and HTTP date values
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 10, 24, 12, 0, 0, 0, time.UTC)
+
+	if d, ok := ParseRetryAfter("7", now); !ok || d != 7*time.Second {
+		t.Errorf("Expected 7s, got %v (ok=%v)", d, ok)
+	}
+	if d, ok := ParseRetryAfter("Fri, 24 Oct 2025 12:00:30 GMT", now); !ok || d != 30*time.Second {
+		t.Errorf("Expected 30s, got %v (ok=%v)", d, ok)
+	}
+	if _, ok := ParseRetryAfter("soon", now); ok {
+		t.Error("Expected invalid value to be rejected")
+	}
+}
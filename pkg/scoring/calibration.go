@@ -0,0 +1,182 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	CalibrationNone     = "none"
+	CalibrationPlatt    = "platt"
+	CalibrationIsotonic = "isotonic"
+)
+
+// MinCalibrationSamples is the smallest labeled set we fit a calibration on.
+const MinCalibrationSamples = 10
+
+// Calibration maps a raw blended score to a probability of conversion.
+// Platt scaling uses A and B, isotonic regression uses the X/Y step points.
+type Calibration struct {
+	Method  string    `json:"method"`
+	A       float64   `json:"a,omitempty"`
+	B       float64   `json:"b,omitempty"`
+	X       []float64 `json:"x,omitempty"`
+	Y       []float64 `json:"y,omitempty"`
+	Samples int       `json:"samples"`
+}
+
+func (c *Calibration) Apply(score float64) float64 {
+	switch c.Method {
+	case CalibrationPlatt:
+		return 1 / (1 + math.Exp(c.A*score+c.B))
+	case CalibrationIsotonic:
+		return c.interpolate(score)
+	default:
+		return score
+	}
+}
+
+func (c *Calibration) interpolate(score float64) float64 {
+	n := len(c.X)
+	if n == 0 || n != len(c.Y) {
+		return score
+	}
+	if score <= c.X[0] {
+		return c.Y[0]
+	}
+	if score >= c.X[n-1] {
+		return c.Y[n-1]
+	}
+	i := sort.SearchFloat64s(c.X, score)
+	x0, x1 := c.X[i-1], c.X[i]
+	y0, y1 := c.Y[i-1], c.Y[i]
+	if x1 == x0 {
+		return y1
+	}
+	return y0 + (y1-y0)*(score-x0)/(x1-x0)
+}
+
+// Fit fits the requested calibration method on raw scores and binary labels.
+func Fit(method string, scores []float64, labels []bool) (*Calibration, error) {
+	if len(scores) != len(labels) {
+		return nil, fmt.Errorf("scores and labels length mismatch: %d != %d", len(scores), len(labels))
+	}
+	if len(scores) < MinCalibrationSamples {
+		return nil, fmt.Errorf("not enough labeled samples: need %d, got %d", MinCalibrationSamples, len(scores))
+	}
+	positives := 0
+	for _, l := range labels {
+		if l {
+			positives++
+		}
+	}
+	if positives == 0 || positives == len(labels) {
+		return nil, fmt.Errorf("labeled samples must contain both positive and negative examples")
+	}
+
+	switch method {
+	case CalibrationPlatt:
+		return fitPlatt(scores, labels, positives), nil
+	case CalibrationIsotonic:
+		return fitIsotonic(scores, labels), nil
+	default:
+		return nil, fmt.Errorf("unknown calibration method: %s", method)
+	}
+}
+
+// fitPlatt follows Platt (1999) with the Lin et al. target smoothing and a
+// plain Newton iteration on the two parameters.
+func fitPlatt(scores []float64, labels []bool, positives int) *Calibration {
+	negatives := len(labels) - positives
+	hiTarget := (float64(positives) + 1) / (float64(positives) + 2)
+	loTarget := 1 / (float64(negatives) + 2)
+
+	targets := make([]float64, len(labels))
+	for i, l := range labels {
+		if l {
+			targets[i] = hiTarget
+		} else {
+			targets[i] = loTarget
+		}
+	}
+
+	a := 0.0
+	b := math.Log((float64(negatives) + 1) / (float64(positives) + 1))
+	for iter := 0; iter < 100; iter++ {
+		var g1, g2, h11, h22, h21 float64
+		h11, h22 = 1e-12, 1e-12
+		for i, s := range scores {
+			p := 1 / (1 + math.Exp(a*s+b))
+			d := targets[i] - p
+			w := p * (1 - p)
+			g1 += s * d
+			g2 += d
+			h11 += s * s * w
+			h22 += w
+			h21 += s * w
+		}
+		if math.Abs(g1) < 1e-9 && math.Abs(g2) < 1e-9 {
+			break
+		}
+		det := h11*h22 - h21*h21
+		if det == 0 {
+			break
+		}
+		da := -(h22*g1 - h21*g2) / det
+		db := -(-h21*g1 + h11*g2) / det
+		a += da
+		b += db
+		if math.Abs(da) < 1e-10 && math.Abs(db) < 1e-10 {
+			break
+		}
+	}
+
+	return &Calibration{Method: CalibrationPlatt, A: a, B: b, Samples: len(scores)}
+}
+
+// fitIsotonic runs pool-adjacent-violators over the samples sorted by score.
+func fitIsotonic(scores []float64, labels []bool) *Calibration {
+	idx := make([]int, len(scores))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return scores[idx[i]] < scores[idx[j]] })
+
+	type block struct {
+		sumX, sumY, weight float64
+	}
+	blocks := make([]block, 0, len(idx))
+	for _, i := range idx {
+		y := 0.0
+		if labels[i] {
+			y = 1
+		}
+		blocks = append(blocks, block{sumX: scores[i], sumY: y, weight: 1})
+		for len(blocks) > 1 {
+			last := blocks[len(blocks)-1]
+			prev := blocks[len(blocks)-2]
+			if prev.sumY/prev.weight <= last.sumY/last.weight {
+				break
+			}
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{
+				sumX:   prev.sumX + last.sumX,
+				sumY:   prev.sumY + last.sumY,
+				weight: prev.weight + last.weight,
+			})
+		}
+	}
+
+	c := &Calibration{
+		Method:  CalibrationIsotonic,
+		X:       make([]float64, len(blocks)),
+		Y:       make([]float64, len(blocks)),
+		Samples: len(scores),
+	}
+	for i, bl := range blocks {
+		c.X[i] = bl.sumX / bl.weight
+		c.Y[i] = bl.sumY / bl.weight
+	}
+	return c
+}
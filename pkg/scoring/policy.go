@@ -0,0 +1,47 @@
+package scoring
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+)
+
+// PolicyFromRow builds a Policy from the stored scoring_policy row,
+// decoding the fitted calibration when one is active.
+func PolicyFromRow(row db.ScoringPolicy) (Policy, error) {
+	p := Policy{
+		GeminiWeight:       row.GeminiWeight,
+		ModelWeight:        row.ModelWeight,
+		CompletenessWeight: row.CompletenessWeight,
+		IntentWeight:       row.IntentWeight,
+		MinCompleteness:    row.MinCompleteness,
+		DecayHalfLifeDays:  row.DecayHalfLifeDays,
+	}
+	if row.CalibrationMethod == "" || row.CalibrationMethod == CalibrationNone {
+		return p, nil
+	}
+	var c Calibration
+	if err := json.Unmarshal(row.Calibration, &c); err != nil {
+		return p, fmt.Errorf("invalid calibration for category %d: %w", row.CategoryID, err)
+	}
+	p.Calibration = &c
+	return p, nil
+}
+
+// NewInput converts nullable score columns into a blend Input.
+func NewInput(gemini, model, intent sql.NullFloat64, nonNullCount int32, updatedAt time.Time) Input {
+	in := Input{NonNullCount: nonNullCount, UpdatedAt: updatedAt}
+	if gemini.Valid {
+		in.GeminiScore = &gemini.Float64
+	}
+	if model.Valid {
+		in.ModelScore = &model.Float64
+	}
+	if intent.Valid {
+		in.IntentScore = &intent.Float64
+	}
+	return in
+}
@@ -0,0 +1,107 @@
+package scoring
+
+import (
+	"math"
+	"time"
+)
+
+// MaxCompleteness is the number of profile fields counted by non_null_count.
+const MaxCompleteness = 11
+
+type Policy struct {
+	GeminiWeight       float64
+	ModelWeight        float64
+	CompletenessWeight float64
+	IntentWeight       float64
+	MinCompleteness    int32
+	DecayHalfLifeDays  float64
+	Calibration        *Calibration
+}
+
+type Input struct {
+	GeminiScore  *float64
+	ModelScore   *float64
+	IntentScore  *float64
+	NonNullCount int32
+	UpdatedAt    time.Time
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		GeminiWeight:       0.5,
+		ModelWeight:        0.4,
+		CompletenessWeight: 0.1,
+		IntentWeight:       0.3,
+		MinCompleteness:    0,
+		DecayHalfLifeDays:  0,
+	}
+}
+
+// Raw blends the available scores with the policy weights. Weights of missing
+// scores are dropped so a profile scored by only one source is not penalized.
+// The second return value is false when there is nothing to blend.
+func (p Policy) Raw(in Input) (float64, bool) {
+	if in.GeminiScore == nil && in.ModelScore == nil && in.IntentScore == nil {
+		return 0, false
+	}
+
+	var sum, weights float64
+	if in.GeminiScore != nil && p.GeminiWeight > 0 {
+		sum += p.GeminiWeight * clamp(*in.GeminiScore)
+		weights += p.GeminiWeight
+	}
+	if in.ModelScore != nil && p.ModelWeight > 0 {
+		sum += p.ModelWeight * clamp(*in.ModelScore)
+		weights += p.ModelWeight
+	}
+	if in.IntentScore != nil && p.IntentWeight > 0 {
+		sum += p.IntentWeight * clamp(*in.IntentScore)
+		weights += p.IntentWeight
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	if p.CompletenessWeight > 0 {
+		sum += p.CompletenessWeight * clamp(float64(in.NonNullCount)/MaxCompleteness)
+		weights += p.CompletenessWeight
+	}
+	return sum / weights, true
+}
+
+// Decay returns the multiplier applied for the age of the profile data.
+func (p Policy) Decay(updatedAt, now time.Time) float64 {
+	if p.DecayHalfLifeDays <= 0 || updatedAt.IsZero() {
+		return 1
+	}
+	ageDays := now.Sub(updatedAt).Hours() / 24
+	if ageDays <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, ageDays/p.DecayHalfLifeDays)
+}
+
+// Final computes the lead score: blend, calibrate, then decay by recency.
+// Profiles under the minimum completeness get a score of 0.
+func (p Policy) Final(in Input, now time.Time) (float64, bool) {
+	raw, ok := p.Raw(in)
+	if !ok {
+		return 0, false
+	}
+	if in.NonNullCount < p.MinCompleteness {
+		return 0, true
+	}
+	if p.Calibration != nil {
+		raw = p.Calibration.Apply(raw)
+	}
+	return clamp(raw * p.Decay(in.UpdatedAt, now)), true
+}
+
+func clamp(v float64) float64 {
+	if math.IsNaN(v) || v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,147 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+// TestRaw tests weight normalization over the available scores
+func TestRaw(t *testing.T) {
+	p := DefaultPolicy()
+
+	t.Run("No scores", func(t *testing.T) {
+		if _, ok := p.Raw(Input{}); ok {
+			t.Error("Expected no score without gemini or model score")
+		}
+	})
+
+	t.Run("Both scores", func(t *testing.T) {
+		got, ok := p.Raw(Input{GeminiScore: ptr(1), ModelScore: ptr(0), NonNullCount: MaxCompleteness})
+		if !ok {
+			t.Fatal("Expected a score")
+		}
+		want := (0.5*1 + 0.4*0 + 0.1*1) / 1.0
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("Expected %f, got %f", want, got)
+		}
+	})
+
+	t.Run("Missing model score is not penalized", func(t *testing.T) {
+		got, _ := p.Raw(Input{GeminiScore: ptr(0.8), NonNullCount: MaxCompleteness})
+		want := (0.5*0.8 + 0.1*1) / 0.6
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("Expected %f, got %f", want, got)
+		}
+	})
+
+	t.Run("Intent score alone", func(t *testing.T) {
+		got, ok := p.Raw(Input{IntentScore: ptr(1), NonNullCount: 0})
+		if !ok {
+			t.Fatal("Expected a score from intent only")
+		}
+		want := 0.3 / 0.4
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("Expected %f, got %f", want, got)
+		}
+	})
+
+	t.Run("Scores are clamped", func(t *testing.T) {
+		got, _ := p.Raw(Input{GeminiScore: ptr(5), ModelScore: ptr(-2), NonNullCount: MaxCompleteness})
+		if got < 0 || got > 1 {
+			t.Errorf("Expected score in [0,1], got %f", got)
+		}
+	})
+}
+
+// TestFinal tests completeness gating and recency decay
+func TestFinal(t *testing.T) {
+	now := time.Date(2025, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Below min completeness", func(t *testing.T) {
+		p := DefaultPolicy()
+		p.MinCompleteness = 5
+		got, ok := p.Final(Input{GeminiScore: ptr(0.9), NonNullCount: 2}, now)
+		if !ok || got != 0 {
+			t.Errorf("Expected 0, got %f (ok=%v)", got, ok)
+		}
+	})
+
+	t.Run("Half life decay", func(t *testing.T) {
+		p := Policy{GeminiWeight: 1, DecayHalfLifeDays: 10}
+		got, _ := p.Final(Input{GeminiScore: ptr(0.8), UpdatedAt: now.AddDate(0, 0, -10)}, now)
+		if math.Abs(got-0.4) > 1e-9 {
+			t.Errorf("Expected 0.4, got %f", got)
+		}
+	})
+
+	t.Run("No decay when disabled", func(t *testing.T) {
+		p := Policy{GeminiWeight: 1}
+		got, _ := p.Final(Input{GeminiScore: ptr(0.8), UpdatedAt: now.AddDate(-1, 0, 0)}, now)
+		if math.Abs(got-0.8) > 1e-9 {
+			t.Errorf("Expected 0.8, got %f", got)
+		}
+	})
+}
+
+func samples() ([]float64, []bool) {
+	scores := make([]float64, 0, 40)
+	labels := make([]bool, 0, 40)
+	for i := 0; i < 40; i++ {
+		s := float64(i) / 40
+		scores = append(scores, s)
+		labels = append(labels, s > 0.6 || i%7 == 0)
+	}
+	return scores, labels
+}
+
+// TestFit tests Platt and isotonic calibration
+func TestFit(t *testing.T) {
+	scores, labels := samples()
+
+	t.Run("Not enough samples", func(t *testing.T) {
+		if _, err := Fit(CalibrationPlatt, scores[:3], labels[:3]); err == nil {
+			t.Error("Expected error for too few samples")
+		}
+	})
+
+	t.Run("Single class", func(t *testing.T) {
+		all := make([]bool, len(scores))
+		if _, err := Fit(CalibrationIsotonic, scores, all); err == nil {
+			t.Error("Expected error for single class labels")
+		}
+	})
+
+	t.Run("Unknown method", func(t *testing.T) {
+		if _, err := Fit("magic", scores, labels); err == nil {
+			t.Error("Expected error for unknown method")
+		}
+	})
+
+	for _, method := range []string{CalibrationPlatt, CalibrationIsotonic} {
+		t.Run("Monotonic "+method, func(t *testing.T) {
+			c, err := Fit(method, scores, labels)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			prev := -1.0
+			for s := 0.0; s <= 1.0; s += 0.05 {
+				v := c.Apply(s)
+				if v < 0 || v > 1 {
+					t.Fatalf("Expected probability in [0,1], got %f", v)
+				}
+				if v+1e-9 < prev {
+					t.Fatalf("Expected non-decreasing calibration, %f after %f", v, prev)
+				}
+				prev = v
+			}
+			if c.Apply(0.9) <= c.Apply(0.1) {
+				t.Error("Expected higher scores to map to higher probabilities")
+			}
+		})
+	}
+}
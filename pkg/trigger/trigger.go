@@ -0,0 +1,150 @@
+package trigger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/qxbao/asfpc/db"
+)
+
+const (
+	LanguageVietnamese = "vi"
+	LanguageEnglish    = "en"
+)
+
+type Rule struct {
+	ID               int32
+	CategoryID       int32
+	Keywords         []string
+	Patterns         []*regexp.Regexp
+	NegativeKeywords []string
+	Language         string
+}
+
+type Hit struct {
+	RuleID     int32
+	CategoryID int32
+	Matched    string
+}
+
+// Compile lowercases the keywords and compiles the patterns of a stored rule.
+// Patterns are matched case-insensitively.
+func Compile(row db.TriggerRule) (*Rule, error) {
+	r := &Rule{
+		ID:               row.ID,
+		CategoryID:       row.CategoryID,
+		Keywords:         normalizeAll(row.Keywords),
+		NegativeKeywords: normalizeAll(row.NegativeKeywords),
+		Language:         strings.ToLower(row.Language.String),
+	}
+	for _, p := range row.Patterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in rule %d: %v", p, row.ID, err)
+		}
+		r.Patterns = append(r.Patterns, re)
+	}
+	return r, nil
+}
+
+// Match returns the first keyword or pattern match in text. Any negative
+// keyword or a language mismatch rejects the text.
+func (r *Rule) Match(text string) (string, bool) {
+	normalized := strings.ToLower(text)
+	if strings.TrimSpace(normalized) == "" {
+		return "", false
+	}
+	if r.Language != "" && DetectLanguage(text) != r.Language {
+		return "", false
+	}
+	for _, neg := range r.NegativeKeywords {
+		if strings.Contains(normalized, neg) {
+			return "", false
+		}
+	}
+	for _, kw := range r.Keywords {
+		if strings.Contains(normalized, kw) {
+			return kw, true
+		}
+	}
+	for _, re := range r.Patterns {
+		if m := re.FindString(text); m != "" {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+type RuleSet []*Rule
+
+// Load compiles all rules, skipping the ones with invalid patterns.
+func Load(rows []db.TriggerRule) (RuleSet, []error) {
+	rules := make(RuleSet, 0, len(rows))
+	var errs []error
+	for _, row := range rows {
+		r, err := Compile(row)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules, errs
+}
+
+// Evaluate returns at most one hit per category.
+func (rs RuleSet) Evaluate(text string) []Hit {
+	var hits []Hit
+	seen := make(map[int32]bool)
+	for _, r := range rs {
+		if seen[r.CategoryID] {
+			continue
+		}
+		if m, ok := r.Match(text); ok {
+			seen[r.CategoryID] = true
+			hits = append(hits, Hit{RuleID: r.ID, CategoryID: r.CategoryID, Matched: m})
+		}
+	}
+	return hits
+}
+
+// DetectLanguage is a cheap heuristic: text with Vietnamese diacritics is
+// Vietnamese, anything else with latin letters is English.
+func DetectLanguage(text string) string {
+	for _, ch := range text {
+		if isVietnameseRune(ch) {
+			return LanguageVietnamese
+		}
+	}
+	for _, ch := range text {
+		if unicode.IsLetter(ch) {
+			return LanguageEnglish
+		}
+	}
+	return ""
+}
+
+func isVietnameseRune(ch rune) bool {
+	switch unicode.ToLower(ch) {
+	case 'đ', 'ă', 'â', 'ê', 'ô', 'ơ', 'ư':
+		return true
+	}
+	// Combined tone marks live in the Latin Extended Additional block.
+	return ch >= 0x1EA0 && ch <= 0x1EF9
+}
+
+func normalizeAll(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package trigger
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/qxbao/asfpc/db"
+)
+
+func mustCompile(t *testing.T, row db.TriggerRule) *Rule {
+	t.Helper()
+	r, err := Compile(row)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return r
+}
+
+// TestMatch tests keyword, pattern and negative keyword matching
+func TestMatch(t *testing.T) {
+	r := mustCompile(t, db.TriggerRule{
+		ID:               1,
+		CategoryID:       2,
+		Keywords:         []string{"Looking for", " cần mua "},
+		Patterns:         []string{`recommend(ed)? an? \w+`},
+		NegativeKeywords: []string{"not looking for"},
+	})
+
+	tests := []struct {
+		name    string
+		text    string
+		matched string
+		ok      bool
+	}{
+		{"Keyword case-insensitive", "LOOKING FOR a gaming mouse", "looking for", true},
+		{"Vietnamese keyword", "Mình Cần Mua bàn phím cơ", "cần mua", true},
+		{"Pattern", "Can anyone recommend a keyboard?", "recommend a keyboard", true},
+		{"Negative keyword wins", "I'm not looking for anything", "", false},
+		{"No match", "Nice photo!", "", false},
+		{"Empty text", "   ", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, ok := r.Match(tt.text)
+			if ok != tt.ok || matched != tt.matched {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.matched, tt.ok, matched, ok)
+			}
+		})
+	}
+}
+
+// TestMatchLanguage tests the language filter of a rule
+func TestMatchLanguage(t *testing.T) {
+	r := mustCompile(t, db.TriggerRule{
+		Keywords: []string{"vga"},
+		Language: sql.NullString{String: "vi", Valid: true},
+	})
+
+	if _, ok := r.Match("Bán VGA cũ giá tốt"); !ok {
+		t.Error("Expected Vietnamese text to match")
+	}
+	if _, ok := r.Match("Selling a used VGA card"); ok {
+		t.Error("Expected English text to be rejected")
+	}
+}
+
+// TestCompileInvalidPattern tests that broken regexes are reported
+func TestCompileInvalidPattern(t *testing.T) {
+	if _, err := Compile(db.TriggerRule{ID: 3, Patterns: []string{"("}}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+
+	rules, errs := Load([]db.TriggerRule{
+		{ID: 1, Keywords: []string{"a"}},
+		{ID: 2, Patterns: []string{"["}},
+	})
+	if len(rules) != 1 || len(errs) != 1 {
+		t.Errorf("Expected 1 rule and 1 error, got %d rules and %d errors", len(rules), len(errs))
+	}
+}
+
+// TestEvaluate tests that each category gets at most one hit
+func TestEvaluate(t *testing.T) {
+	rules, _ := Load([]db.TriggerRule{
+		{ID: 1, CategoryID: 1, Keywords: []string{"mouse"}},
+		{ID: 2, CategoryID: 1, Keywords: []string{"keyboard"}},
+		{ID: 3, CategoryID: 2, Keywords: []string{"keyboard"}},
+	})
+
+	hits := rules.Evaluate("need a mouse and keyboard")
+	if len(hits) != 2 {
+		t.Fatalf("Expected 2 hits, got %d", len(hits))
+	}
+	if hits[0].RuleID != 1 || hits[1].RuleID != 3 {
+		t.Errorf("Unexpected hits: %+v", hits)
+	}
+}
+
+// TestDetectLanguage tests the diacritic based language heuristic
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"Xin chào, mình cần tư vấn": LanguageVietnamese,
+		"đi đâu":                    LanguageVietnamese,
+		"hello there":               LanguageEnglish,
+		"12345 !!!":                 "",
+	}
+	for text, want := range tests {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
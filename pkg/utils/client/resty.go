@@ -1,9 +1,50 @@
 package client
 
-import "resty.dev/v3"
+import (
+	"time"
+
+	"github.com/qxbao/asfpc/pkg/retry"
+	"resty.dev/v3"
+)
 
 func NewRestyClient() *resty.Client {
+	return NewRestyClientWithPolicy(retry.DefaultPolicy())
+}
+
+// WithPolicy returns a client factory that retries with the given policy.
+func WithPolicy(p retry.Policy) func() *resty.Client {
+	return func() *resty.Client {
+		return NewRestyClientWithPolicy(p)
+	}
+}
+
+// NewRestyClientWithPolicy returns a client that retries network errors, 408,
+// 429 and 5xx responses with jittered exponential backoff. Retry-After is
+// honored up to the policy's max delay.
+func NewRestyClientWithPolicy(p retry.Policy) *resty.Client {
 	c := resty.New()
-	c.SetRetryCount(3)
+	c.SetRetryCount(max(p.MaxAttempts-1, 0))
+	c.SetRetryWaitTime(p.BaseDelay)
+	c.SetRetryMaxWaitTime(p.MaxDelay)
+	c.SetRetryDefaultConditions(false)
+	c.AddRetryConditions(RetryCondition)
+	c.SetRetryStrategy(func(res *resty.Response, err error) (time.Duration, error) {
+		d := retry.Decision{Retry: true}
+		if res != nil {
+			d.After, _ = retry.ParseRetryAfter(res.Header().Get("Retry-After"), time.Now())
+		}
+		attempt := 0
+		if res != nil && res.Request != nil {
+			attempt = max(res.Request.Attempt-1, 0)
+		}
+		return p.Delay(attempt, d), nil
+	})
 	return c
-}
\ No newline at end of file
+}
+
+func RetryCondition(res *resty.Response, err error) bool {
+	if err != nil {
+		return retry.IsNetworkError(err)
+	}
+	return res != nil && retry.RetryableStatus(res.StatusCode())
+}
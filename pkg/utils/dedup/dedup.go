@@ -0,0 +1,311 @@
+package dedup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/duplicate"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+)
+
+// DedupService finds profiles that belong to the same person and merges
+// them into one user_profile row.
+type DedupService struct {
+	Server *infras.Server
+}
+
+var logger = lg.GetLogger("DedupService")
+
+const (
+	StatusPending   = "pending"
+	StatusDismissed = "dismissed"
+)
+
+var (
+	ErrProfileNotFound   = errors.New("profile not found")
+	ErrCandidateNotFound = errors.New("merge candidate not found")
+	ErrCandidateMismatch = errors.New("profiles do not match the merge candidate")
+	ErrCandidateClosed   = errors.New("merge candidate is not pending")
+	ErrSameProfile       = errors.New("cannot merge a profile into itself")
+)
+
+type Params struct {
+	// CategoryIDs limits the embedding pass, every category when empty
+	CategoryIDs []int32
+}
+
+type Report struct {
+	Exact      int   `json:"exact"`
+	Similar    int   `json:"similar"`
+	Candidates int   `json:"candidates"`
+	Recorded   int64 `json:"recorded"`
+}
+
+// Detect proposes merge candidates from shared emails, phone numbers and
+// profile urls, and from near identical embeddings of profiles with the same
+// name. Candidates below DEDUP_MIN_CONFIDENCE are not recorded.
+func (s *DedupService) Detect(ctx context.Context, p Params, r *jobs.Reporter) (*Report, error) {
+	minSimilarity := s.Server.GetFloatConfig(ctx, "DEDUP_SIMILARITY_THRESHOLD", 0.97, 0, 1)
+	minConfidence := s.Server.GetFloatConfig(ctx, "DEDUP_MIN_CONFIDENCE", 0.5, 0, 1)
+	evidence := make(map[duplicate.Pair]*duplicate.Evidence)
+	get := func(pair duplicate.Pair) *duplicate.Evidence {
+		e, ok := evidence[pair]
+		if !ok {
+			e = &duplicate.Evidence{Exact: make(map[duplicate.Signal]bool)}
+			evidence[pair] = e
+		}
+		return e
+	}
+	report := &Report{}
+
+	r.Progress(0, "Matching emails, phone numbers and profile urls...", nil)
+	exact, err := s.Server.Queries.FindExactDuplicateProfiles(ctx, int32(s.Server.GetIntConfig(ctx, "DEDUP_MAX_GROUP_SIZE", 5)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to match profiles: %w", err)
+	}
+	for _, row := range exact {
+		e := get(duplicate.NewPair(row.ProfileID, row.DuplicateID))
+		for _, signal := range row.Signals {
+			e.Exact[duplicate.Signal(signal)] = true
+		}
+	}
+	report.Exact = len(exact)
+
+	categoryIDs := p.CategoryIDs
+	if len(categoryIDs) == 0 {
+		categories, err := s.Server.Queries.GetCategories(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get categories: %w", err)
+		}
+		for _, category := range categories {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+	}
+	similar := make(map[duplicate.Pair]bool)
+	for i, categoryID := range categoryIDs {
+		r.Progress(0.1+0.8*float64(i)/float64(len(categoryIDs)), fmt.Sprintf("Comparing embeddings of category %d...", categoryID), nil)
+		if err := s.compare(ctx, categoryID, minSimilarity, func(pair duplicate.Pair, similarity float64, sameName bool) {
+			e := get(pair)
+			e.Similarity = max(e.Similarity, similarity)
+			e.SameName = e.SameName || sameName
+			similar[pair] = true
+		}); err != nil {
+			return report, err
+		}
+	}
+	report.Similar = len(similar)
+
+	r.Progress(0.9, "Recording merge candidates...", nil)
+	for pair, e := range evidence {
+		confidence := e.Confidence(minSimilarity)
+		if confidence < minConfidence {
+			continue
+		}
+		report.Candidates++
+		signals, err := json.Marshal(e.Signals(minSimilarity))
+		if err != nil {
+			return report, err
+		}
+		n, err := s.Server.Queries.UpsertProfileDuplicate(ctx, db.UpsertProfileDuplicateParams{
+			ProfileID:   pair.ProfileID,
+			DuplicateID: pair.DuplicateID,
+			Signals:     signals,
+			Similarity:  sql.NullFloat64{Float64: e.Similarity, Valid: e.Similarity > 0},
+			Confidence:  confidence,
+		})
+		if err != nil {
+			return report, fmt.Errorf("failed to record candidate %d/%d: %w", pair.ProfileID, pair.DuplicateID, err)
+		}
+		report.Recorded += n
+	}
+	return report, nil
+}
+
+// compare pages through the embeddings of a category and calls found for
+// every neighbour above minSimilarity.
+func (s *DedupService) compare(ctx context.Context, categoryID int32, minSimilarity float64, found func(duplicate.Pair, float64, bool)) error {
+	pageSize := int32(s.Server.GetIntConfig(ctx, "DEDUP_PAGE_SIZE", 500))
+	neighbors := int32(s.Server.GetIntConfig(ctx, "DEDUP_NEIGHBORS", 3))
+	var after int32
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rows, err := s.Server.Queries.FindEmbeddingDuplicates(ctx, db.FindEmbeddingDuplicatesParams{
+			CategoryID:    categoryID,
+			AfterID:       after,
+			PageLimit:     pageSize,
+			Neighbors:     neighbors,
+			MinSimilarity: minSimilarity,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to compare embeddings of category %d: %w", categoryID, err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		for _, row := range rows {
+			after = max(after, row.EmbeddingID)
+			if !row.DuplicateID.Valid || !row.Similarity.Valid {
+				continue
+			}
+			name := duplicate.NormalizeName(row.ProfileName.String)
+			found(
+				duplicate.NewPair(row.ProfileID, row.DuplicateID.Int32),
+				row.Similarity.Float64,
+				name != "" && name == duplicate.NormalizeName(row.DuplicateName.String),
+			)
+		}
+	}
+}
+
+type MergeParams struct {
+	SurvivorID int32
+	MergedID   int32
+	// CandidateID is the merge candidate being accepted, if any
+	CandidateID *int32
+}
+
+// Merge re-points everything that belongs to the merged profile to the
+// survivor, fills the survivor's missing fields and deletes the merged
+// profile. The merged row is kept as a snapshot in profile_merge.
+func (s *DedupService) Merge(ctx context.Context, p MergeParams) (*db.ProfileMerge, error) {
+	if p.SurvivorID == p.MergedID {
+		return nil, ErrSameProfile
+	}
+
+	tx, err := s.Server.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := s.Server.Queries.WithTx(tx)
+
+	locked, err := queries.LockProfiles(ctx, []int32{p.SurvivorID, p.MergedID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock profiles: %w", err)
+	}
+	if len(locked) != 2 {
+		return nil, ErrProfileNotFound
+	}
+	// the candidate is read only once the profiles are locked, so a dismiss
+	// that landed while waiting for the locks is not overridden
+	var (
+		signals    db.NullableJSON = []byte("[]")
+		confidence sql.NullFloat64
+	)
+	if p.CandidateID != nil {
+		candidate, err := queries.GetProfileDuplicate(ctx, *p.CandidateID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrCandidateNotFound
+			}
+			return nil, err
+		}
+		if duplicate.NewPair(p.SurvivorID, p.MergedID) != duplicate.NewPair(candidate.ProfileID, candidate.DuplicateID) {
+			return nil, ErrCandidateMismatch
+		}
+		if candidate.Status != StatusPending {
+			return nil, ErrCandidateClosed
+		}
+		signals = candidate.Signals
+		confidence = sql.NullFloat64{Float64: candidate.Confidence, Valid: true}
+	}
+	snapshot, err := queries.GetProfileSnapshot(ctx, p.MergedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot profile: %w", err)
+	}
+
+	moved := make(map[string]int64)
+	steps := []struct {
+		name string
+		run  func(context.Context, int32, int32) (int64, error)
+	}{
+		{"comments", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileComments(ctx, db.MoveProfileCommentsParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"financial_analyses", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileFinancialAnalyses(ctx, db.MoveProfileFinancialAnalysesParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"category_scores", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.FillProfileCategoryScores(ctx, db.FillProfileCategoryScoresParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"categories", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileCategories(ctx, db.MoveProfileCategoriesParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"embeddings", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileEmbeddings(ctx, db.MoveProfileEmbeddingsParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"shadow_scores", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileShadowScores(ctx, db.MoveProfileShadowScoresParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"trigger_rule_hits", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileTriggerRuleHits(ctx, db.MoveProfileTriggerRuleHitsParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"lookalike_seeds", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileSeedMemberships(ctx, db.MoveProfileSeedMembershipsParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"merges", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileMerges(ctx, db.MoveProfileMergesParams{SurvivorID: survivor, MergedID: merged})
+		}},
+		{"duplicates", func(ctx context.Context, survivor, merged int32) (int64, error) {
+			return queries.MoveProfileDuplicates(ctx, db.MoveProfileDuplicatesParams{SurvivorID: survivor, MergedID: merged})
+		}},
+	}
+	for _, step := range steps {
+		if moved[step.name], err = step.run(ctx, p.SurvivorID, p.MergedID); err != nil {
+			return nil, fmt.Errorf("failed to move %s: %w", step.name, err)
+		}
+	}
+	// embedded_profile has no foreign key, the embeddings the survivor
+	// already had a category for would otherwise be left behind
+	if moved["dropped_embeddings"], err = queries.DeleteProfileEmbeddings(ctx, p.MergedID); err != nil {
+		return nil, fmt.Errorf("failed to delete embeddings: %w", err)
+	}
+	if err := queries.FillMergedProfileFields(ctx, db.FillMergedProfileFieldsParams{
+		SurvivorID: p.SurvivorID,
+		MergedID:   p.MergedID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to fill profile fields: %w", err)
+	}
+	if _, err := queries.DeleteUserProfile(ctx, p.MergedID); err != nil {
+		return nil, fmt.Errorf("failed to delete profile: %w", err)
+	}
+
+	movedJSON, err := json.Marshal(moved)
+	if err != nil {
+		return nil, err
+	}
+	merge, err := queries.CreateProfileMerge(ctx, db.CreateProfileMergeParams{
+		SurvivorID:       sql.NullInt32{Int32: p.SurvivorID, Valid: true},
+		MergedID:         p.MergedID,
+		MergedFacebookID: snapshot.FacebookID,
+		Snapshot:         snapshot.Snapshot,
+		Moved:            movedJSON,
+		Signals:          signals,
+		Confidence:       confidence,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record merge: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.Server.Queries.LogAction(ctx, db.LogActionParams{
+		Action: "merge_profiles",
+		Description: sql.NullString{
+			String: fmt.Sprintf("Merged profile %d (%s) into %d", p.MergedID, snapshot.FacebookID, p.SurvivorID),
+			Valid:  true,
+		},
+		TargetID:  sql.NullInt32{Int32: p.SurvivorID, Valid: true},
+		AccountID: sql.NullInt32{Valid: false},
+	})
+	return &merge, nil
+}
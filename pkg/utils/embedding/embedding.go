@@ -0,0 +1,266 @@
+package embedding
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/vectorindex"
+)
+
+// IndexService manages the per-category vector indexes of embedded_profile.
+type IndexService struct {
+	Server *infras.Server
+}
+
+var logger = lg.GetLogger("IndexService")
+
+type Mode string
+
+const (
+	// ModeReindex rebuilds the existing indexes in place, missing ones are
+	// created.
+	ModeReindex Mode = "reindex"
+	// ModeRebuild drops and recreates the indexes with the configured spec,
+	// needed after a change of method or parameters.
+	ModeRebuild Mode = "rebuild"
+)
+
+type Index struct {
+	Name       string             `json:"name"`
+	CategoryID *int32             `json:"category_id"`
+	Method     vectorindex.Method `json:"method"`
+	Definition string             `json:"definition"`
+	SizeBytes  int64              `json:"size_bytes"`
+	IsValid    bool               `json:"is_valid"`
+}
+
+type Report struct {
+	Mode      Mode     `json:"mode"`
+	Created   []string `json:"created"`
+	Reindexed []string `json:"reindexed"`
+	Dropped   []string `json:"dropped"`
+}
+
+// Spec reads the index spec from the config.
+func (s *IndexService) Spec(ctx context.Context) (vectorindex.Spec, error) {
+	spec := vectorindex.DefaultSpec()
+	method, err := vectorindex.ParseMethod(s.Server.GetConfig(ctx, "VECTOR_INDEX_METHOD", string(spec.Method)))
+	if err != nil {
+		return spec, err
+	}
+	spec.Method = method
+	spec.M = s.Server.GetIntConfig(ctx, "VECTOR_INDEX_HNSW_M", spec.M)
+	spec.EfConstruction = s.Server.GetIntConfig(ctx, "VECTOR_INDEX_HNSW_EF_CONSTRUCTION", spec.EfConstruction)
+	spec.Lists = s.Server.GetIntConfig(ctx, "VECTOR_INDEX_IVFFLAT_LISTS", spec.Lists)
+	return spec, spec.Validate()
+}
+
+// EfSearch is the default ef_search of similarity queries.
+func (s *IndexService) EfSearch(ctx context.Context) int {
+	return s.Server.GetIntConfig(ctx, "VECTOR_SEARCH_EF_SEARCH", 40)
+}
+
+// ResolveEfSearch is the ef_search of a similarity query returning limit
+// rows. HNSW returns at most ef_search rows, so it is raised to the limit.
+// Rows removed by filters are made up for by the iterative scan of
+// WithEfSearch, not by ef_search.
+func (s *IndexService) ResolveEfSearch(ctx context.Context, requested *int, limit int32) (int, error) {
+	ef := s.EfSearch(ctx)
+	if requested != nil {
+		if *requested < vectorindex.MinEfSearch || *requested > vectorindex.MaxEfSearch {
+			return 0, fmt.Errorf("ef_search must be between %d and %d",
+				vectorindex.MinEfSearch, vectorindex.MaxEfSearch)
+		}
+		ef = *requested
+	}
+	return min(max(ef, int(limit)), vectorindex.MaxEfSearch), nil
+}
+
+func (s *IndexService) List(ctx context.Context) ([]Index, error) {
+	rows, err := s.Server.Queries.GetEmbeddingIndexes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding indexes: %w", err)
+	}
+	indexes := make([]Index, 0, len(rows))
+	for _, row := range rows {
+		index := Index{
+			Name:       row.Name,
+			Definition: row.Definition,
+			SizeBytes:  row.SizeBytes,
+			IsValid:    row.IsValid,
+		}
+		if id, ok := vectorindex.CategoryOf(row.Name); ok {
+			index.CategoryID = &id
+		}
+		index.Method, _ = vectorindex.MethodOf(row.Definition)
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// Ensure creates the index of a category if it does not exist yet.
+func (s *IndexService) Ensure(ctx context.Context, categoryID int32) error {
+	spec, err := s.Spec(ctx)
+	if err != nil {
+		return fmt.Errorf("invalid vector index config: %w", err)
+	}
+	stmt, err := vectorindex.Create(spec, categoryID, true)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, stmt)
+}
+
+// Drop removes the index of a category.
+func (s *IndexService) Drop(ctx context.Context, categoryID int32) error {
+	return s.exec(ctx, vectorindex.Drop(categoryID, true))
+}
+
+// Rebuild reindexes or rebuilds the indexes of the given categories, all
+// categories when none are given. Indexes of deleted categories are dropped.
+// Statements run concurrently so searches keep working meanwhile.
+func (s *IndexService) Rebuild(ctx context.Context, mode Mode, categoryIDs []int32, r *jobs.Reporter) (*Report, error) {
+	if mode != ModeReindex && mode != ModeRebuild {
+		return nil, fmt.Errorf("unknown mode %q, expected reindex or rebuild", mode)
+	}
+	spec, err := s.Spec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vector index config: %w", err)
+	}
+
+	categories, err := s.Server.Queries.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	existing, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int32]bool, len(categories))
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+	indexed := make(map[int32]Index, len(existing))
+	report := &Report{Mode: mode, Created: []string{}, Reindexed: []string{}, Dropped: []string{}}
+	for _, index := range existing {
+		if index.CategoryID == nil {
+			continue
+		}
+		if !known[*index.CategoryID] {
+			if err := s.exec(ctx, vectorindex.Drop(*index.CategoryID, true)); err != nil {
+				return report, err
+			}
+			report.Dropped = append(report.Dropped, index.Name)
+			continue
+		}
+		indexed[*index.CategoryID] = index
+	}
+
+	targets := categoryIDs
+	if len(targets) == 0 {
+		for _, category := range categories {
+			targets = append(targets, category.ID)
+		}
+	}
+
+	for i, categoryID := range targets {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if !known[categoryID] {
+			return report, fmt.Errorf("category %d not found", categoryID)
+		}
+		name := vectorindex.IndexName(categoryID)
+		if r != nil {
+			r.Progress(float64(i)/float64(len(targets)), fmt.Sprintf("Indexing %s...", name), nil)
+		}
+
+		index, ok := indexed[categoryID]
+		// invalid indexes are leftovers of failed builds, reindexing them
+		// in place is not supported concurrently
+		if ok && (mode == ModeRebuild || !index.IsValid || index.Method != spec.Method) {
+			if err := s.exec(ctx, vectorindex.Drop(categoryID, true)); err != nil {
+				return report, err
+			}
+			ok = false
+		}
+		if ok {
+			if err := s.exec(ctx, vectorindex.Reindex(categoryID, true)); err != nil {
+				return report, err
+			}
+			report.Reindexed = append(report.Reindexed, name)
+			continue
+		}
+		stmt, err := vectorindex.Create(spec, categoryID, true)
+		if err != nil {
+			return report, err
+		}
+		if err := s.exec(ctx, stmt); err != nil {
+			return report, err
+		}
+		report.Created = append(report.Created, name)
+	}
+
+	logger.Infof("Vector indexes %s: %d created, %d reindexed, %d dropped",
+		mode, len(report.Created), len(report.Reindexed), len(report.Dropped))
+	return report, nil
+}
+
+// IterativeScan is how HNSW searches keep scanning when filters remove
+// candidates. Set VECTOR_SEARCH_ITERATIVE_SCAN to off for pgvector before 0.8.
+func (s *IndexService) IterativeScan(ctx context.Context) vectorindex.IterativeScan {
+	mode, err := vectorindex.ParseIterativeScan(s.Server.GetConfig(ctx, "VECTOR_SEARCH_ITERATIVE_SCAN", string(vectorindex.ScanStrict)))
+	if err != nil {
+		logger.Warnf("Invalid VECTOR_SEARCH_ITERATIVE_SCAN, using %s: %v", vectorindex.ScanStrict, err)
+		return vectorindex.ScanStrict
+	}
+	return mode
+}
+
+// WithEfSearch runs fn in a transaction with hnsw.ef_search and the iterative
+// scan set, the settings end with the transaction. Filtered searches then
+// still return as many rows as asked for. It has no effect on IVFFlat indexes.
+func (s *IndexService) WithEfSearch(ctx context.Context, efSearch int, fn func(q *db.Queries) error) error {
+	stmt, err := vectorindex.SetEfSearch(efSearch)
+	if err != nil {
+		return err
+	}
+	var scanStmt string
+	if mode := s.IterativeScan(ctx); mode != vectorindex.ScanOff {
+		if scanStmt, err = vectorindex.SetIterativeScan(mode); err != nil {
+			return err
+		}
+	}
+	tx, err := s.Server.Database.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to set ef_search: %w", err)
+	}
+	if scanStmt != "" {
+		if _, err := tx.ExecContext(ctx, scanStmt); err != nil {
+			return fmt.Errorf("failed to set iterative scan: %w", err)
+		}
+	}
+	if err := fn(s.Server.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *IndexService) exec(ctx context.Context, stmt string) error {
+	logger.Infof("Running %s", stmt)
+	if _, err := s.Server.Database.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to run %q: %w", stmt, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/lookalike"
+)
+
+// LookalikeService expands a seed list into the profiles of its category
+// closest to it.
+type LookalikeService struct {
+	Server *infras.Server
+}
+
+type LookalikeParams struct {
+	Seed db.LookalikeSeed
+	Mode lookalike.Mode
+	TopK int32
+	// EfSearch overrides VECTOR_SEARCH_EF_SEARCH
+	EfSearch *int
+}
+
+type Lookalike struct {
+	db.FindLookalikeProfilesRow
+	// Hits is the number of seed queries that returned the profile, always 1
+	// for centroid queries
+	Hits int `json:"hits"`
+}
+
+// Segment is the exported audience of a seed.
+type Segment struct {
+	SeedID      int32          `json:"seed_id"`
+	SeedName    string         `json:"seed_name"`
+	CategoryID  int32          `json:"category_id"`
+	Mode        lookalike.Mode `json:"mode"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Profiles    []Lookalike    `json:"profiles"`
+}
+
+// Find returns the top lookalikes of the seed, seed profiles excluded.
+func (s *LookalikeService) Find(ctx context.Context, p LookalikeParams) ([]Lookalike, error) {
+	indexService := IndexService{Server: s.Server}
+	rows, err := s.Server.Queries.GetLookalikeSeedEmbeddings(ctx, p.Seed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seed embeddings: %w", err)
+	}
+	vectors := make([][]float32, 0, len(rows))
+	for _, row := range rows {
+		var v db.Vector
+		if err := v.Scan(row.Embedding); err != nil {
+			return nil, fmt.Errorf("invalid embedding of profile %d: %w", row.UserProfileID, err)
+		}
+		vectors = append(vectors, v)
+	}
+
+	var queries [][]float32
+	switch p.Mode {
+	case lookalike.ModeCentroid:
+		centroid, err := lookalike.Centroid(vectors)
+		if err != nil {
+			return nil, err
+		}
+		queries = [][]float32{centroid}
+	case lookalike.ModeMulti:
+		if len(vectors) == 0 {
+			return nil, lookalike.ErrEmptySeed
+		}
+		for _, i := range lookalike.Sample(len(vectors), indexService.Server.GetIntConfig(ctx, "LOOKALIKE_MAX_QUERIES", 20)) {
+			queries = append(queries, vectors[i])
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q", p.Mode)
+	}
+
+	// The seed members are the nearest neighbours of their own centroid and
+	// are filtered out after the index scan, the candidate list makes room
+	// for them too
+	ef, err := indexService.ResolveEfSearch(ctx, p.EfSearch, p.TopK+int32(len(vectors)))
+	if err != nil {
+		return nil, err
+	}
+
+	details := make(map[int32]db.FindLookalikeProfilesRow)
+	results := make([][]lookalike.Candidate, 0, len(queries))
+	err = indexService.WithEfSearch(ctx, ef, func(q *db.Queries) error {
+		for _, query := range queries {
+			rows, err := q.FindLookalikeProfiles(ctx, db.FindLookalikeProfilesParams{
+				Query:      db.Vector(query),
+				CategoryID: p.Seed.CategoryID,
+				SeedID:     p.Seed.ID,
+				PageLimit:  p.TopK,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to find lookalikes: %w", err)
+			}
+			candidates := make([]lookalike.Candidate, len(rows))
+			for i, row := range rows {
+				candidates[i] = lookalike.Candidate{ProfileID: row.ProfileID, Similarity: row.Similarity}
+				details[row.ProfileID] = row
+			}
+			results = append(results, candidates)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := lookalike.Merge(results, int(p.TopK))
+	lookalikes := make([]Lookalike, len(matches))
+	for i, m := range matches {
+		row := details[m.ProfileID]
+		row.Similarity = m.Similarity
+		lookalikes[i] = Lookalike{FindLookalikeProfilesRow: row, Hits: m.Hits}
+	}
+	return lookalikes, nil
+}
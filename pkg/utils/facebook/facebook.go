@@ -144,6 +144,35 @@ func (fg FacebookGraph) GenerateFBAccessTokenIOS(username string, password strin
 	return atResponse.AccessToken, nil
 }
 
+// transientGraphCodes are Graph API error codes for throttling and temporary
+// failures. Facebook reports them with 4xx statuses, so the status alone does
+// not tell them apart from auth errors.
+var transientGraphCodes = map[int]bool{1: true, 2: true, 4: true, 17: true, 32: true, 341: true, 613: true}
+
+type graphErrorResponse struct {
+	Error *struct {
+		Code        int  `json:"code"`
+		IsTransient bool `json:"is_transient"`
+	} `json:"error"`
+}
+
+// IsTransientGraphError reports whether a Graph API error body is worth
+// retrying.
+func IsTransientGraphError(body []byte) bool {
+	var res graphErrorResponse
+	if err := json.Unmarshal(body, &res); err != nil || res.Error == nil {
+		return false
+	}
+	return res.Error.IsTransient || transientGraphCodes[res.Error.Code]
+}
+
+func graphRetryCondition(res *resty.Response, err error) bool {
+	if err != nil || res == nil || res.StatusCode() < 400 {
+		return false
+	}
+	return IsTransientGraphError([]byte(res.String()))
+}
+
 func graphQuery[T any](path string, kwargs *map[string]string, newClient func() *resty.Client) (T, error) {
 	c := newClient()
 	defer c.Close()
@@ -153,6 +182,7 @@ func graphQuery[T any](path string, kwargs *map[string]string, newClient func()
 	fullURL := fmt.Sprintf("%s/%s", GraphURL, path)
 
 	resp, err := c.R().
+		AddRetryConditions(graphRetryCondition).
 		SetQueryParams(*kwargs).
 		SetHeader("User-Agent", GetRandomAndroidUA()).
 		Get(fullURL)
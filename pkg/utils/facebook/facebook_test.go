@@ -302,3 +302,27 @@ func TestGetUserDetails(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+// TestIsTransientGraphError tests Graph API error classification
+func TestIsTransientGraphError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"Rate limited application", `{"error": {"message": "Application request limit reached", "code": 4}}`, true},
+		{"Rate limited user", `{"error": {"message": "User request limit reached", "code": 17}}`, true},
+		{"Marked transient", `{"error": {"message": "Unexpected error", "code": 999, "is_transient": true}}`, true},
+		{"Invalid OAuth token", `{"error": {"message": "Invalid OAuth token", "type": "OAuthException", "code": 190}}`, false},
+		{"Not an error", `{"data": []}`, false},
+		{"Not JSON", `<html></html>`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientGraphError([]byte(tt.body)); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package persona
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/budget"
+	"github.com/qxbao/asfpc/pkg/cluster"
+	"github.com/qxbao/asfpc/pkg/generative"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/retry"
+	"github.com/qxbao/asfpc/pkg/utils/prompt"
+)
+
+// PersonaService groups the profiles of a category into personas by their
+// embeddings and names them with Gemini.
+type PersonaService struct {
+	Server *infras.Server
+}
+
+var logger = lg.GetLogger("PersonaService")
+
+const (
+	jobPersonaLabel = "persona_label"
+	labelModel      = "gemini-2.5-flash-lite"
+)
+
+type Params struct {
+	CategoryID int32
+	K          int
+	// Label asks Gemini for a label of each persona
+	Label bool
+}
+
+type Report struct {
+	Clusters   int      `json:"clusters"`
+	Sample     int      `json:"sample"`
+	Assigned   int64    `json:"assigned"`
+	Distance   float64  `json:"distance"`
+	Iterations int      `json:"iterations"`
+	Labeled    int      `json:"labeled"`
+	Errors     []string `json:"errors"`
+}
+
+type Label struct {
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// Cluster fits k-means on a sample of the category's embeddings and replaces
+// its personas. Every embedding is then assigned to its closest persona.
+func (s *PersonaService) Cluster(ctx context.Context, p Params, r *jobs.Reporter) (*Report, error) {
+	if p.K < 2 {
+		return nil, fmt.Errorf("k must be at least 2, got %d", p.K)
+	}
+	rows, err := s.Server.Queries.GetPersonaTrainingEmbeddings(ctx, db.GetPersonaTrainingEmbeddingsParams{
+		CategoryID: p.CategoryID,
+		PageLimit:  int32(s.Server.GetIntConfig(ctx, "PERSONA_SAMPLE_SIZE", 20000)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embeddings: %w", err)
+	}
+	vectors := make([][]float32, 0, len(rows))
+	for _, row := range rows {
+		var v db.Vector
+		if err := v.Scan(row.Embedding); err != nil {
+			return nil, fmt.Errorf("invalid embedding of profile %d: %w", row.Pid, err)
+		}
+		vectors = append(vectors, v)
+	}
+	if len(vectors) < p.K {
+		return nil, fmt.Errorf("category has %d embedded profiles, at least %d are needed", len(vectors), p.K)
+	}
+
+	r.Progress(0.1, fmt.Sprintf("Clustering %d profiles into %d personas...", len(vectors), p.K), nil)
+	result, err := cluster.KMeans(vectors, cluster.DefaultOptions(p.K))
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		Clusters:   p.K,
+		Sample:     len(vectors),
+		Distance:   result.Distance,
+		Iterations: result.Iterations,
+		Errors:     []string{},
+	}
+
+	r.Progress(0.5, "Assigning profiles to personas...", nil)
+	personas, err := s.replace(ctx, p.CategoryID, result.Centroids, report)
+	if err != nil {
+		return report, err
+	}
+
+	if p.Label {
+		for i, persona := range personas {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			r.Progress(0.6+0.4*float64(i)/float64(len(personas)), fmt.Sprintf("Labeling persona %d...", persona.Cluster), nil)
+			if _, err := s.Label(ctx, persona); err != nil {
+				var exhausted *budget.ExhaustedError
+				if errors.As(err, &exhausted) {
+					report.Errors = append(report.Errors, fmt.Sprintf("labeling of %d personas skipped: %v", len(personas)-i, err))
+					break
+				}
+				logger.Warnf("Failed to label persona %d: %v", persona.ID, err)
+				report.Errors = append(report.Errors, fmt.Sprintf("persona %d: %v", persona.Cluster, err))
+				continue
+			}
+			report.Labeled++
+		}
+	}
+
+	s.Server.Queries.LogAction(ctx, db.LogActionParams{
+		Action: "cluster_personas",
+		Description: sql.NullString{
+			String: fmt.Sprintf("Grouped %d profiles into %d personas", report.Assigned, p.K),
+			Valid:  true,
+		},
+		TargetID:  sql.NullInt32{Int32: p.CategoryID, Valid: true},
+		AccountID: sql.NullInt32{Valid: false},
+	})
+	return report, nil
+}
+
+// replace swaps the personas of the category in one transaction, so the
+// listing never sees a half assigned category.
+func (s *PersonaService) replace(ctx context.Context, categoryID int32, centroids [][]float32, report *Report) ([]db.Persona, error) {
+	tx, err := s.Server.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := s.Server.Queries.WithTx(tx)
+
+	if _, err := queries.DeleteCategoryPersonas(ctx, categoryID); err != nil {
+		return nil, fmt.Errorf("failed to delete personas: %w", err)
+	}
+	personas := make([]db.Persona, len(centroids))
+	for i, centroid := range centroids {
+		personas[i], err = queries.CreatePersona(ctx, db.CreatePersonaParams{
+			CategoryID: categoryID,
+			Cluster:    int32(i),
+			Centroid:   db.Vector(centroid),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create persona: %w", err)
+		}
+	}
+	if report.Assigned, err = queries.AssignPersonas(ctx, categoryID); err != nil {
+		return nil, fmt.Errorf("failed to assign personas: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return personas, nil
+}
+
+// Label names a persona after the members closest to its centroid.
+func (s *PersonaService) Label(ctx context.Context, persona db.Persona) (*Label, error) {
+	members, err := s.Server.Queries.GetPersonaMembers(ctx, db.GetPersonaMembersParams{
+		PersonaID: persona.ID,
+		PageLimit: int32(s.Server.GetIntConfig(ctx, "PERSONA_LABEL_SAMPLES", 10)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("persona has no members")
+	}
+
+	type memberPayload struct {
+		Location           string `json:"location,omitempty"`
+		Work               string `json:"work,omitempty"`
+		Education          string `json:"education,omitempty"`
+		Bio                string `json:"bio,omitempty"`
+		Gender             string `json:"gender,omitempty"`
+		RelationshipStatus string `json:"relationship_status,omitempty"`
+	}
+	payload := make([]memberPayload, len(members))
+	for i, m := range members {
+		payload[i] = memberPayload{
+			Location:           m.Location.String,
+			Work:               m.Work.String,
+			Education:          m.Education.String,
+			Bio:                m.Bio.String,
+			Gender:             m.Gender.String,
+			RelationshipStatus: m.RelationshipStatus.String,
+		}
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	promptService := prompt.PromptService{Server: s.Server}
+	template, err := promptService.GetPrompt(ctx, "gemini-persona-1", persona.CategoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt (gemini-persona-1): %v", err)
+	}
+	businessDesc, err := promptService.GetPrompt(ctx, "business-description", persona.CategoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt (business-description): %v", err)
+	}
+	content := promptService.ReplacePrompt(template.Content, businessDesc.Content, string(payloadJSON))
+
+	response, err := s.generate(ctx, persona.CategoryID, content)
+	if err != nil {
+		return nil, err
+	}
+	label, err := parseLabel(response)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Server.Queries.UpdatePersonaLabel(ctx, db.UpdatePersonaLabelParams{
+		ID:          persona.ID,
+		Label:       sql.NullString{String: label.Label, Valid: true},
+		Description: sql.NullString{String: label.Description, Valid: label.Description != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save label: %w", err)
+	}
+	return label, nil
+}
+
+// generate asks Gemini through the response cache.
+func (s *PersonaService) generate(ctx context.Context, categoryID int32, content string) (string, error) {
+	var cache *generative.ResponseCache
+	if strings.ToLower(s.Server.GetConfig(ctx, "LLM_CACHE_ENABLED_BOOL", "TRUE")) == "true" {
+		cache = generative.NewResponseCache(s.Server.Queries, time.Duration(s.Server.GetIntConfig(ctx, "LLM_CACHE_TTL_HOURS", 168))*time.Hour)
+	}
+	defer func() {
+		if err := cache.SaveStats(ctx); err != nil {
+			logger.Warnf("Failed to save LLM cache stats: %v", err)
+		}
+	}()
+
+	if response, ok := cache.Get(ctx, labelModel, content); ok {
+		return response, nil
+	}
+	if err := budget.Check(ctx, s.Server.Queries, jobPersonaLabel, categoryID); err != nil {
+		return "", err
+	}
+
+	apiKey, err := s.Server.Queries.GetGeminiKeyForUse(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gemini key: %v", err)
+	}
+	gs := generative.GetGenerativeService(apiKey.ApiKey, labelModel)
+	gs.Retry = retry.FromConfig(func(key, defaultValue string) string {
+		return s.Server.GetConfig(ctx, key, defaultValue)
+	}, "GEMINI", retry.DefaultPolicy())
+	if err := gs.Init(); err != nil {
+		return "", fmt.Errorf("failed to initialize generative service: %v", err)
+	}
+
+	response, usage, err := gs.GenerateTextWithUsage(content)
+	gs.Record(categoryID, jobPersonaLabel, usage)
+	if saveErr := gs.SaveUsage(ctx, s.Server.Queries); saveErr != nil {
+		logger.Warnf("Failed to save LLM usage: %v", saveErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate text: %v", err)
+	}
+	if _, err := parseLabel(response); err == nil {
+		if err := cache.Set(ctx, gs.Model, content, response); err != nil {
+			logger.Warnf("Failed to cache persona label: %v", err)
+		}
+	}
+	return response, nil
+}
+
+// parseLabel reads the JSON answer of Gemini, code fences included.
+func parseLabel(response string) (*Label, error) {
+	text := strings.TrimSpace(response)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+
+	label := new(Label)
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), label); err != nil {
+		return nil, fmt.Errorf("invalid label response: %w", err)
+	}
+	label.Label = strings.TrimSpace(label.Label)
+	label.Description = strings.TrimSpace(label.Description)
+	if label.Label == "" {
+		return nil, fmt.Errorf("label response has no label")
+	}
+	if len([]rune(label.Label)) > 255 {
+		label.Label = string([]rune(label.Label)[:255])
+	}
+	return label, nil
+}
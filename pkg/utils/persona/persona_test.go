@@ -0,0 +1,21 @@
+package persona
+
+import "testing"
+
+// TestParseLabel tests reading Gemini answers with and without code fences
+func TestParseLabel(t *testing.T) {
+	label, err := parseLabel("```json\n{\"label\": \" Sinh viên IT \", \"description\": \"Học CNTT tại Hà Nội\"}\n```")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if label.Label != "Sinh viên IT" || label.Description != "Học CNTT tại Hà Nội" {
+		t.Errorf("Unexpected label %+v", label)
+	}
+
+	if _, err := parseLabel(`{"label": "", "description": "x"}`); err == nil {
+		t.Error("Expected an error for an empty label")
+	}
+	if _, err := parseLabel("Sinh viên IT"); err == nil {
+		t.Error("Expected an error for plain text")
+	}
+}
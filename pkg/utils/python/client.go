@@ -0,0 +1,214 @@
+package python
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Client runs python tasks with typed params and results. Tasks served by the
+// worker go through it when one is set, the others start a process each.
+type Client struct {
+	Service *PythonService
+	Worker  *Worker
+}
+
+func NewClient(worker *Worker) *Client {
+	return &Client{
+		Service: NewPythonService(os.Getenv("PYTHON_ENV_NAME"), true, false, nil),
+		Worker:  worker,
+	}
+}
+
+type PredictParams struct {
+	Targets    []int32
+	ModelName  string
+	CategoryID int32
+	// Shadow scores every target with ModelName, ignoring the category's
+	// model override and the scores already stored.
+	Shadow bool
+	// Rescore scores every target again instead of returning the score
+	// already stored for it.
+	Rescore bool
+}
+
+// Prediction is an explained score. Contributions are the SHAP values of
+// each input, they add up to the score together with "bias".
+type Prediction struct {
+	Score         float64            `json:"score"`
+	Contributions map[string]float64 `json:"contributions"`
+}
+
+type EmbedParams struct {
+	Targets        []int32
+	CategoryID     int32
+	EmbeddingModel string
+}
+
+type EmbedResult struct {
+	Success int `json:"success"`
+	Total   int `json:"total"`
+}
+
+type EmbedTextParams struct {
+	Text       string
+	CategoryID int32
+}
+
+type TrainParams struct {
+	ModelName  string
+	RequestID  int32
+	AutoTune   bool
+	Trials     *int
+	CategoryID *int32
+}
+
+type TrainResult struct {
+	ModelName   string         `json:"model_name"`
+	TestResults map[string]any `json:"test_results"`
+	Dataset     TrainDataset   `json:"dataset"`
+}
+
+// TrainDataset identifies the profiles a model was trained on.
+type TrainDataset struct {
+	SnapshotID string `json:"snapshot_id"`
+	Size       int    `json:"size"`
+}
+
+type LoginResult struct {
+	IsBlocked bool `json:"is_blocked"`
+}
+
+// Predict returns the model score of each target. Profiles that could not be
+// found are left out.
+func (c *Client) Predict(ctx context.Context, p PredictParams) (map[int32]float64, error) {
+	var raw map[string]*float64
+	err := c.call(ctx, "predict", predictParams(p), &raw)
+	if err != nil {
+		return nil, err
+	}
+	return byProfileID(raw), nil
+}
+
+// Explain scores every target again and returns the contributions of each
+// input to its score. Profiles that could not be found are left out.
+func (c *Client) Explain(ctx context.Context, p PredictParams) (map[int32]Prediction, error) {
+	params := predictParams(p)
+	params["explain"] = "True"
+	var raw map[string]*Prediction
+	err := c.call(ctx, "predict", params, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return byProfileID(raw), nil
+}
+
+func predictParams(p PredictParams) map[string]string {
+	params := map[string]string{
+		"targets":     joinIDs(p.Targets),
+		"model-name":  p.ModelName,
+		"category-id": strconv.Itoa(int(p.CategoryID)),
+	}
+	if p.Shadow {
+		params["shadow"] = "True"
+	}
+	if p.Rescore {
+		params["rescore"] = "True"
+	}
+	return params
+}
+
+// byProfileID keys the results of a task by profile ID, dropping the
+// profiles without result.
+func byProfileID[T any](raw map[string]*T) map[int32]T {
+	results := make(map[int32]T, len(raw))
+	for id, v := range raw {
+		if v == nil {
+			continue
+		}
+		pid, err := strconv.ParseInt(id, 10, 32)
+		if err != nil {
+			continue
+		}
+		results[int32(pid)] = *v
+	}
+	return results
+}
+
+func (c *Client) Embed(ctx context.Context, p EmbedParams) (EmbedResult, error) {
+	var result EmbedResult
+	err := c.call(ctx, "embed", map[string]string{
+		"targets":         joinIDs(p.Targets),
+		"embedding-model": p.EmbeddingModel,
+		"category-id":     strconv.Itoa(int(p.CategoryID)),
+	}, &result)
+	return result, err
+}
+
+// EmbedText embeds a free-text query with the embedding model of the
+// category, so it can be compared with the profile embeddings.
+func (c *Client) EmbedText(ctx context.Context, p EmbedTextParams) ([]float32, error) {
+	var result struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	err := c.call(ctx, "embed-text", map[string]string{
+		"text":        p.Text,
+		"category-id": strconv.Itoa(int(p.CategoryID)),
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Embedding) == 0 {
+		return nil, ErrNoResult
+	}
+	return result.Embedding, nil
+}
+
+func (c *Client) Train(ctx context.Context, p TrainParams, onProgress func(Progress)) (TrainResult, error) {
+	params := map[string]string{
+		"model-name": p.ModelName,
+		"auto-tune":  "False",
+		"request-id": strconv.Itoa(int(p.RequestID)),
+	}
+	if p.AutoTune {
+		params["auto-tune"] = "True"
+	}
+	if p.Trials != nil {
+		params["trials"] = strconv.Itoa(*p.Trials)
+	}
+	if p.CategoryID != nil {
+		params["category-id"] = strconv.Itoa(int(*p.CategoryID))
+	}
+
+	var result TrainResult
+	err := c.Service.Run(ctx, "train-model", params, &result, onProgress)
+	return result, err
+}
+
+func (c *Client) Login(ctx context.Context, uid int32) (LoginResult, error) {
+	var result LoginResult
+	err := c.Service.Run(ctx, "login", map[string]string{"uid": strconv.Itoa(int(uid))}, &result, nil)
+	return result, err
+}
+
+func (c *Client) JoinGroup(ctx context.Context, gid int32) error {
+	return c.Service.Run(ctx, "joingroup", map[string]string{"group_id": strconv.Itoa(int(gid))}, nil, nil)
+}
+
+// call sends task to the worker when there is one and to a new process
+// otherwise. Worker methods are named after the tasks they run.
+func (c *Client) call(ctx context.Context, task string, params map[string]string, result any) error {
+	if c.Worker != nil {
+		return c.Worker.Call(ctx, task, params, result)
+	}
+	return c.Service.Run(ctx, task, params, result, nil)
+}
+
+func joinIDs(ids []int32) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(int(id))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,41 @@
+package python
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestPredictParams tests the flags passed to the predict task
+func TestPredictParams(t *testing.T) {
+	params := predictParams(PredictParams{Targets: []int32{1, 2}, ModelName: "m/v1", CategoryID: 3, Rescore: true})
+	if params["targets"] != "1,2" || params["model-name"] != "m/v1" || params["category-id"] != "3" {
+		t.Errorf("Unexpected params: %v", params)
+	}
+	if params["rescore"] != "True" {
+		t.Error("Expected rescore to be passed")
+	}
+	if _, ok := params["shadow"]; ok {
+		t.Error("Expected shadow to be left out")
+	}
+}
+
+// TestByProfileID tests decoding explained predictions keyed by profile ID
+func TestByProfileID(t *testing.T) {
+	var raw map[string]*Prediction
+	err := json.Unmarshal([]byte(`{
+		"1": {"score": 0.8, "contributions": {"bias": 0.5, "embedding": 0.3}},
+		"2": null,
+		"x": {"score": 0.1}
+	}`), &raw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	predictions := byProfileID(raw)
+	if len(predictions) != 1 {
+		t.Fatalf("Expected 1 prediction, got %d", len(predictions))
+	}
+	if p := predictions[1]; p.Score != 0.8 || p.Contributions["embedding"] != 0.3 {
+		t.Errorf("Unexpected prediction: %+v", p)
+	}
+}
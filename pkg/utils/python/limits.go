@@ -0,0 +1,184 @@
+package python
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limits bound the python task processes. Zero values disable a limit.
+type Limits struct {
+	// MaxProcesses caps the task processes running at the same time, the
+	// worker excluded. Further tasks wait for a free slot.
+	MaxProcesses int
+	// MemoryMB caps the data segment of each process (RLIMIT_DATA).
+	MemoryMB int
+	// CPUSeconds caps the CPU time of each task process (RLIMIT_CPU). It is
+	// not applied to the worker, whose CPU time only grows.
+	CPUSeconds int
+	// Timeout is the deadline of tasks whose context has none.
+	Timeout time.Duration
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxProcesses: 2,
+		Timeout:      time.Hour,
+	}
+}
+
+// LimitsFromConfig reads PYTHON_MAX_PROCESSES, PYTHON_MEMORY_LIMIT_MB,
+// PYTHON_CPU_LIMIT_SEC and PYTHON_TASK_TIMEOUT_SEC, falling back to def for
+// missing or invalid values.
+func LimitsFromConfig(get func(key, defaultValue string) string, def Limits) Limits {
+	l := def
+	if v, err := strconv.Atoi(get("PYTHON_MAX_PROCESSES", strconv.Itoa(def.MaxProcesses))); err == nil && v >= 0 {
+		l.MaxProcesses = v
+	}
+	if v, err := strconv.Atoi(get("PYTHON_MEMORY_LIMIT_MB", strconv.Itoa(def.MemoryMB))); err == nil && v >= 0 {
+		l.MemoryMB = v
+	}
+	if v, err := strconv.Atoi(get("PYTHON_CPU_LIMIT_SEC", strconv.Itoa(def.CPUSeconds))); err == nil && v >= 0 {
+		l.CPUSeconds = v
+	}
+	if v, err := strconv.Atoi(get("PYTHON_TASK_TIMEOUT_SEC", strconv.Itoa(int(def.Timeout.Seconds())))); err == nil && v >= 0 {
+		l.Timeout = time.Duration(v) * time.Second
+	}
+	return l
+}
+
+// processes is shared by every PythonService so the cap is global.
+var processes = newLimiter(DefaultLimits())
+
+// SetLimits replaces the limits used by the next processes. Lowering
+// MaxProcesses does not stop running processes, new ones wait until enough
+// of them are done.
+func SetLimits(l Limits) {
+	processes.set(l)
+}
+
+func CurrentLimits() Limits {
+	processes.mu.Lock()
+	defer processes.mu.Unlock()
+	return processes.limits
+}
+
+// KillAll kills every running task process. It is meant for shutdown, since
+// tasks run in their own process group and do not get the server's signals.
+func KillAll() {
+	processes.mu.Lock()
+	defer processes.mu.Unlock()
+	for cmd := range processes.cmds {
+		killProcess(cmd)
+	}
+}
+
+type limiter struct {
+	mu      sync.Mutex
+	limits  Limits
+	running int
+	waiters []chan struct{}
+	cmds    map[*exec.Cmd]struct{}
+}
+
+func newLimiter(l Limits) *limiter {
+	return &limiter{limits: l, cmds: make(map[*exec.Cmd]struct{})}
+}
+
+func (l *limiter) set(limits Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+	l.grant()
+}
+
+// grant hands free slots to waiters in arrival order. l.mu must be held.
+func (l *limiter) grant() {
+	for len(l.waiters) > 0 && (l.limits.MaxProcesses <= 0 || l.running < l.limits.MaxProcesses) {
+		l.running++
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if len(l.waiters) == 0 && (l.limits.MaxProcesses <= 0 || l.running < l.limits.MaxProcesses) {
+		l.running++
+		l.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	l.waiters = append(l.waiters, ch)
+	l.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-ch:
+			// granted while giving up, hand the slot over
+			l.running--
+			l.grant()
+		default:
+			for i, w := range l.waiters {
+				if w == ch {
+					l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+					break
+				}
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running--
+	l.grant()
+}
+
+// start runs cmd in a slot once one is free, in its own process group and
+// under the resource limits. The returned func must be called after Wait.
+func (l *limiter) start(ctx context.Context, cmd *exec.Cmd, limits Limits) (func(), error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("no python process slot: %w", err)
+	}
+	configureProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		l.release()
+		return nil, err
+	}
+	if err := applyLimits(cmd.Process.Pid, limits); err != nil {
+		killProcess(cmd)
+		cmd.Wait()
+		l.release()
+		return nil, fmt.Errorf("failed to apply resource limits: %v", err)
+	}
+
+	l.mu.Lock()
+	l.cmds[cmd] = struct{}{}
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		delete(l.cmds, cmd)
+		l.mu.Unlock()
+		l.release()
+	}, nil
+}
+
+// withTimeout applies the default deadline when ctx has none.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
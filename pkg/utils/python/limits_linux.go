@@ -0,0 +1,23 @@
+package python
+
+import "golang.org/x/sys/unix"
+
+// applyLimits sets the rlimits of a started process. The process runs
+// unrestricted for the few microseconds before this, long before python has
+// imported anything.
+func applyLimits(pid int, l Limits) error {
+	if l.MemoryMB > 0 {
+		bytes := uint64(l.MemoryMB) << 20
+		if err := unix.Prlimit(pid, unix.RLIMIT_DATA, &unix.Rlimit{Cur: bytes, Max: bytes}, nil); err != nil {
+			return err
+		}
+	}
+	if l.CPUSeconds > 0 {
+		// SIGXCPU at the soft limit, SIGKILL a few seconds later
+		secs := uint64(l.CPUSeconds)
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: secs, Max: secs + 5}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
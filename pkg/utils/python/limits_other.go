@@ -0,0 +1,22 @@
+//go:build !linux
+
+package python
+
+import (
+	"sync"
+
+	lg "github.com/qxbao/asfpc/pkg/logger"
+)
+
+var warnLimits sync.Once
+
+// applyLimits is a no-op outside Linux, which is the only platform that can
+// set the rlimits of another process.
+func applyLimits(pid int, l Limits) error {
+	if l.MemoryMB > 0 || l.CPUSeconds > 0 {
+		warnLimits.Do(func() {
+			lg.GetLogger("Python").Warn("Memory and CPU limits are only supported on Linux, ignoring them")
+		})
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package python
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestLimiter tests the process cap, waiting and giving up
+func TestLimiter(t *testing.T) {
+	l := newLimiter(Limits{MaxProcesses: 1})
+	ctx := context.Background()
+
+	if err := l.acquire(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Gives up when context is done", func(t *testing.T) {
+		waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		if err := l.acquire(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+		if len(l.waiters) != 0 {
+			t.Errorf("Expected waiter to be removed, got %d", len(l.waiters))
+		}
+	})
+
+	t.Run("Waits for a free slot", func(t *testing.T) {
+		acquired := make(chan error)
+		go func() { acquired <- l.acquire(ctx) }()
+		select {
+		case <-acquired:
+			t.Fatal("Expected acquire to wait")
+		case <-time.After(20 * time.Millisecond):
+		}
+		l.release()
+		if err := <-acquired; err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Raising the cap wakes waiters", func(t *testing.T) {
+		acquired := make(chan error)
+		go func() { acquired <- l.acquire(ctx) }()
+		time.Sleep(20 * time.Millisecond)
+		l.set(Limits{MaxProcesses: 2})
+		select {
+		case err := <-acquired:
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected waiter to get the new slot")
+		}
+		if l.running != 2 {
+			t.Errorf("Expected 2 running, got %d", l.running)
+		}
+	})
+}
+
+// TestLimitsFromConfig tests reading limits and falling back on bad values
+func TestLimitsFromConfig(t *testing.T) {
+	config := map[string]string{
+		"PYTHON_MAX_PROCESSES":    "4",
+		"PYTHON_MEMORY_LIMIT_MB":  "2048",
+		"PYTHON_CPU_LIMIT_SEC":    "-1",
+		"PYTHON_TASK_TIMEOUT_SEC": "90",
+	}
+	get := func(key, defaultValue string) string {
+		if v, ok := config[key]; ok {
+			return v
+		}
+		return defaultValue
+	}
+	l := LimitsFromConfig(get, Limits{CPUSeconds: 600})
+	want := Limits{MaxProcesses: 4, MemoryMB: 2048, CPUSeconds: 600, Timeout: 90 * time.Second}
+	if l != want {
+		t.Errorf("Expected %+v, got %+v", want, l)
+	}
+}
+
+// TestRunScriptContext tests that a hung task is killed at its deadline
+func TestRunScriptContext(t *testing.T) {
+	dir := fakeInterpreter(t)
+	t.Setenv("ASFPC_FAKE_TASK", "1")
+	ps := NewPythonService("venv", false, true, &dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := ps.RunScriptContext(ctx, "--task=hang")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the task to be killed promptly, took %v", elapsed)
+	}
+}
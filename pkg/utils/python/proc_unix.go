@@ -0,0 +1,32 @@
+//go:build unix
+
+package python
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// configureProcess starts the task in a process group of its own, so that
+// cancelling it also kills what it spawned (browsers, dataloader workers).
+func configureProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return killProcess(cmd) }
+	cmd.WaitDelay = 5 * time.Second
+}
+
+func killProcess(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	if err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
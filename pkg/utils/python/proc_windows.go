@@ -0,0 +1,21 @@
+//go:build windows
+
+package python
+
+import (
+	"os/exec"
+	"time"
+)
+
+// configureProcess only bounds the wait for output pipes, Windows has no
+// process groups to kill.
+func configureProcess(cmd *exec.Cmd) {
+	cmd.WaitDelay = 5 * time.Second
+}
+
+func killProcess(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,143 @@
+package python
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ProtocolVersion is the envelope version spoken with `main.py --protocol`.
+// Python refuses to run a task when the versions differ.
+const ProtocolVersion = 1
+
+const (
+	EnvelopeProgress = "progress"
+	EnvelopeResult   = "result"
+
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+var ErrNoResult = errors.New("python task exited without a result")
+
+// Envelope is one JSON line written by a task on its protocol channel. A run
+// writes any number of progress envelopes followed by exactly one result.
+type Envelope struct {
+	Version  int             `json:"v"`
+	Type     string          `json:"type"`
+	Status   string          `json:"status,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    *TaskError      `json:"error,omitempty"`
+	Progress float64         `json:"progress,omitempty"`
+	Message  string          `json:"message,omitempty"`
+	Details  map[string]any  `json:"details,omitempty"`
+}
+
+// TaskError is the error reported by a task. Code is machine readable, e.g.
+// invalid_argument, unknown_task or internal.
+type TaskError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("python task failed (%s): %s", e.Code, e.Message)
+}
+
+// Progress is a progress envelope. Details carries task specific values, such
+// as the current optuna trial and best RMSE of a training.
+type Progress struct {
+	Progress float64        `json:"progress"`
+	Message  string         `json:"message"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+// readEnvelopes decodes the protocol channel until EOF, passing progress to
+// onProgress (which may be nil), and returns the result envelope.
+func readEnvelopes(r io.Reader, onProgress func(Progress), logger *zap.SugaredLogger) (*Envelope, error) {
+	var result *Envelope
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var env Envelope
+		if err := json.Unmarshal(scanner.Bytes(), &env); err != nil || env.Type == "" {
+			logger.Warnf("Ignoring unexpected output on protocol channel: %s", scanner.Text())
+			continue
+		}
+		if env.Version != ProtocolVersion {
+			return nil, fmt.Errorf("unsupported protocol version %d, expected %d", env.Version, ProtocolVersion)
+		}
+		switch env.Type {
+		case EnvelopeProgress:
+			if onProgress != nil {
+				onProgress(Progress{Progress: env.Progress, Message: env.Message, Details: env.Details})
+			}
+		case EnvelopeResult:
+			result = &env
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// decodeResult turns a result envelope into an error or decodes its payload.
+func decodeResult(env *Envelope, result any) error {
+	if env == nil {
+		return ErrNoResult
+	}
+	if env.Status != StatusOK {
+		if env.Error == nil {
+			return &TaskError{Code: "internal", Message: "task failed without an error"}
+		}
+		return env.Error
+	}
+	if result == nil || len(env.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Result, result); err != nil {
+		return fmt.Errorf("failed to decode task result: %v", err)
+	}
+	return nil
+}
+
+// logLine matches the level of python's "%(asctime)s [%(levelname)s] ..." format.
+var logLine = regexp.MustCompile(`\[(DEBUG|INFO|WARNING|ERROR|CRITICAL)\] (.*)$`)
+
+// logStderr forwards python's stderr to logger line by line. Lines without a
+// level, such as traceback lines, keep the level of the record before them.
+func logStderr(r io.Reader, logger *zap.SugaredLogger) {
+	level := zapcore.InfoLevel
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		if m := logLine.FindStringSubmatch(line); m != nil {
+			level = pythonLevel(m[1])
+			line = m[2]
+		}
+		logger.Logw(level, line)
+	}
+}
+
+func pythonLevel(name string) zapcore.Level {
+	switch name {
+	case "DEBUG":
+		return zapcore.DebugLevel
+	case "WARNING":
+		return zapcore.WarnLevel
+	case "ERROR", "CRITICAL":
+		return zapcore.ErrorLevel
+	}
+	return zapcore.InfoLevel
+}
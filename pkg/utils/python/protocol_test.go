@@ -0,0 +1,133 @@
+package python
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeTask writes envelopes the way main.py --protocol does
+func fakeTask() {
+	task := ""
+	for _, arg := range os.Args {
+		if v, ok := strings.CutPrefix(arg, "--task="); ok {
+			task = v
+		}
+	}
+	fmt.Fprintln(os.Stderr, "2025-10-24 12:00:00,000 [INFO] starting")
+	switch task {
+	case "fail":
+		fmt.Println(`{"v":1,"type":"result","status":"error","error":{"code":"invalid_argument","message":"--uid is required"}}`)
+		os.Exit(1)
+	case "crash":
+		os.Exit(2)
+	case "hang":
+		time.Sleep(time.Minute)
+	default:
+		fmt.Println(`{"v":1,"type":"progress","progress":0.5,"message":"halfway"}`)
+		fmt.Println(`{"v":1,"type":"result","status":"ok","result":{"task":"` + task + `"}}`)
+	}
+}
+
+// TestReadEnvelopes tests progress reporting, stray lines and version checks
+func TestReadEnvelopes(t *testing.T) {
+	logger := lg.GetLogger("Test")
+
+	var progress []Progress
+	input := strings.Join([]string{
+		`{"v":1,"type":"progress","progress":0.1,"message":"loading"}`,
+		`stray line`,
+		`{"v":1,"type":"progress","progress":0.9,"message":null,"details":{"trial":3,"best_rmse":0.12}}`,
+		`{"v":1,"type":"result","status":"ok","result":{"1":0.5}}`,
+	}, "\n")
+	env, err := readEnvelopes(strings.NewReader(input), func(p Progress) { progress = append(progress, p) }, logger)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(progress) != 2 || progress[0].Message != "loading" || progress[1].Progress != 0.9 {
+		t.Errorf("Unexpected progress events: %v", progress)
+	} else if progress[1].Details["trial"] != 3.0 || progress[1].Details["best_rmse"] != 0.12 {
+		t.Errorf("Expected progress details to be passed on, got %v", progress[1].Details)
+	}
+	var result map[string]float64
+	if err := decodeResult(env, &result); err != nil || result["1"] != 0.5 {
+		t.Errorf("Expected decoded result, got %v (err=%v)", result, err)
+	}
+
+	_, err = readEnvelopes(strings.NewReader(`{"v":2,"type":"result","status":"ok"}`), nil, logger)
+	if err == nil {
+		t.Error("Expected unknown protocol version to be rejected")
+	}
+}
+
+// TestDecodeResult tests error envelopes and missing results
+func TestDecodeResult(t *testing.T) {
+	var taskErr *TaskError
+	err := decodeResult(&Envelope{Status: StatusError, Error: &TaskError{Code: "not_found", Message: "missing"}}, nil)
+	if !errors.As(err, &taskErr) || taskErr.Code != "not_found" {
+		t.Errorf("Expected TaskError, got %v", err)
+	}
+	if err := decodeResult(nil, nil); !errors.Is(err, ErrNoResult) {
+		t.Errorf("Expected ErrNoResult, got %v", err)
+	}
+}
+
+// TestPythonLevel tests mapping python log levels to zap
+func TestPythonLevel(t *testing.T) {
+	tests := map[string]zapcore.Level{
+		"DEBUG":    zapcore.DebugLevel,
+		"INFO":     zapcore.InfoLevel,
+		"WARNING":  zapcore.WarnLevel,
+		"ERROR":    zapcore.ErrorLevel,
+		"CRITICAL": zapcore.ErrorLevel,
+	}
+	for name, want := range tests {
+		if got := pythonLevel(name); got != want {
+			t.Errorf("Level %s: expected %v, got %v", name, want, got)
+		}
+	}
+}
+
+// TestRun tests running a task process with the envelope protocol
+func TestRun(t *testing.T) {
+	dir := fakeInterpreter(t)
+	t.Setenv("ASFPC_FAKE_TASK", "1")
+	ps := NewPythonService("venv", true, true, &dir)
+
+	t.Run("Decodes result and progress", func(t *testing.T) {
+		var progress []Progress
+		var result struct {
+			Task string `json:"task"`
+		}
+		err := ps.Run(t.Context(), "predict", map[string]string{"targets": "1,2"}, &result, func(p Progress) {
+			progress = append(progress, p)
+		})
+		if err != nil || result.Task != "predict" {
+			t.Fatalf("Expected predict result, got %+v (err=%v)", result, err)
+		}
+		if len(progress) != 1 || progress[0].Progress != 0.5 || progress[0].Message != "halfway" {
+			t.Errorf("Unexpected progress events: %v", progress)
+		}
+	})
+
+	t.Run("Returns task error", func(t *testing.T) {
+		var taskErr *TaskError
+		err := ps.Run(t.Context(), "fail", nil, nil, nil)
+		if !errors.As(err, &taskErr) || taskErr.Code != "invalid_argument" {
+			t.Errorf("Expected invalid_argument, got %v", err)
+		}
+	})
+
+	t.Run("Reports missing result", func(t *testing.T) {
+		err := ps.Run(t.Context(), "crash", nil, nil, nil)
+		if !errors.Is(err, ErrNoResult) {
+			t.Errorf("Expected ErrNoResult, got %v", err)
+		}
+	})
+}
@@ -1,11 +1,18 @@
 package python
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+
+	lg "github.com/qxbao/asfpc/pkg/logger"
 )
 
 var exc, _ = os.Executable()
@@ -30,7 +37,12 @@ func NewPythonService(envName string, log, silent bool, pythonPath *string) *Pyt
 	}
 }
 
-func (ps PythonService) RunScript(args ...string) (string, error) {
+// Command builds the invocation of main.py inside the virtual environment.
+func (ps PythonService) Command(args ...string) *exec.Cmd {
+	return ps.command(context.Background(), args...)
+}
+
+func (ps PythonService) command(ctx context.Context, args ...string) *exec.Cmd {
 	var pythonExe string
 
 	if runtime.GOOS == "windows" {
@@ -49,13 +61,95 @@ func (ps PythonService) RunScript(args ...string) (string, error) {
 
 	cmdArgs := append([]string{"main.py"}, args...)
 
-	cmd := exec.Command(pythonExe, cmdArgs...)
+	cmd := exec.CommandContext(ctx, pythonExe, cmdArgs...)
 	cmd.Dir = ps.PythonPath
+	return cmd
+}
+
+func (ps PythonService) RunScript(args ...string) (string, error) {
+	return ps.RunScriptContext(context.Background(), args...)
+}
+
+// RunScriptContext runs main.py with args and returns its combined output. The
+// process group is killed when ctx is done, and the default task timeout
+// applies when ctx has no deadline. It waits for a free slot when
+// MaxProcesses are already running.
+func (ps PythonService) RunScriptContext(ctx context.Context, args ...string) (string, error) {
+	limits := CurrentLimits()
+	ctx, cancel := withTimeout(ctx, limits.Timeout)
+	defer cancel()
+
+	cmd := ps.command(ctx, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	done, err := processes.start(ctx, cmd, limits)
+	if err != nil {
+		return "", fmt.Errorf("python script failed: %w", err)
+	}
+	err = cmd.Wait()
+	done()
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return output.String(), fmt.Errorf("python script stopped: %w\nOutput: %s", ctxErr, output.String())
+	}
+	if err != nil {
+		return output.String(), fmt.Errorf("python script failed: %v\nOutput: %s", err, output.String())
+	}
+
+	return output.String(), nil
+}
 
-	output, err := cmd.CombinedOutput()
+// Run runs task with the envelope protocol, passing params as --key=value
+// flags. The result payload is decoded into result when it is not nil and
+// progress events are passed to onProgress. Logs always go to stderr, which is
+// forwarded to zap, so Silent is ignored.
+func (ps PythonService) Run(ctx context.Context, task string, params map[string]string, result any, onProgress func(Progress)) error {
+	logger := lg.GetLogger("Python").With("task", task)
+
+	args := []string{"--task=" + task, fmt.Sprintf("--protocol=%d", ProtocolVersion)}
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		args = append(args, fmt.Sprintf("--%s=%s", key, params[key]))
+	}
+	ps.Silent = false
+	limits := CurrentLimits()
+	ctx, cancel := withTimeout(ctx, limits.Timeout)
+	defer cancel()
+	cmd := ps.command(ctx, args...)
+
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return string(output), fmt.Errorf("python script failed: %v\nOutput: %s", err, string(output))
+		return err
 	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	done, err := processes.start(ctx, cmd, limits)
+	if err != nil {
+		return fmt.Errorf("failed to start python task %s: %w", task, err)
+	}
+
+	logged := make(chan struct{})
+	go func() {
+		logStderr(stderr, logger)
+		close(logged)
+	}()
+	env, readErr := readEnvelopes(stdout, onProgress, logger)
+	io.Copy(io.Discard, stdout)
+	<-logged
+	waitErr := cmd.Wait()
+	done()
 
-	return string(output), nil
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("python task %s stopped: %w", task, ctxErr)
+	}
+	if readErr != nil {
+		return fmt.Errorf("failed to read python task %s: %v", task, readErr)
+	}
+	if env == nil && waitErr != nil {
+		return fmt.Errorf("python task %s failed: %w: %v", task, ErrNoResult, waitErr)
+	}
+	return decodeResult(env, result)
 }
@@ -0,0 +1,571 @@
+package python
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/retry"
+)
+
+var (
+	ErrWorkerStopped = errors.New("python worker is stopped")
+	ErrWorkerExited  = errors.New("python worker exited before answering")
+)
+
+// RPCError is an error object returned by the worker.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("python worker error %d: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     *int64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+type WorkerOptions struct {
+	// Socket is the Unix socket the worker listens on. The worker speaks over
+	// its stdin and stdout when empty.
+	Socket         string
+	QueueSize      int
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration
+	// StartTimeout bounds how long the worker may take to answer its first
+	// health check, model imports included.
+	StartTimeout time.Duration
+	// Restart is the backoff between restarts. The attempt counter is reset
+	// once a worker has stayed up for StableAfter.
+	Restart     retry.Policy
+	StableAfter time.Duration
+}
+
+func DefaultWorkerOptions() WorkerOptions {
+	return WorkerOptions{
+		QueueSize:      32,
+		HealthInterval: 30 * time.Second,
+		HealthTimeout:  10 * time.Second,
+		StartTimeout:   2 * time.Minute,
+		Restart: retry.Policy{
+			BaseDelay: time.Second,
+			MaxDelay:  time.Minute,
+		},
+		StableAfter: time.Minute,
+	}
+}
+
+type WorkerStatus struct {
+	Running    bool       `json:"running"`
+	Healthy    bool       `json:"healthy"`
+	PID        int        `json:"pid"`
+	Transport  string     `json:"transport"`
+	Restarts   int64      `json:"restarts"`
+	Queued     int        `json:"queued"`
+	InFlight   int        `json:"in_flight"`
+	StartedAt  *time.Time `json:"started_at"`
+	LastHealth *time.Time `json:"last_health"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+type reply struct {
+	res rpcResponse
+	err error
+}
+
+type call struct {
+	ctx    context.Context
+	method string
+	params any
+	done   chan reply
+}
+
+// Worker keeps one python process running `main.py --task=worker` and sends it
+// JSON-RPC requests. Requests wait in a bounded queue while the process is
+// (re)starting; requests already sent when it dies fail with ErrWorkerExited.
+// The process is restarted with backoff until Stop is called.
+type Worker struct {
+	ps      *PythonService
+	opts    WorkerOptions
+	queue   chan *call
+	nextID  atomic.Int64
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	mu         sync.Mutex
+	session    *session
+	restarts   int64
+	lastHealth time.Time
+	lastErr    error
+}
+
+// session is one run of the python process.
+type session struct {
+	cmd       *exec.Cmd
+	w         io.Writer
+	closeW    func() error
+	writeMu   sync.Mutex
+	pending   map[int64]*call
+	pendingMu sync.Mutex
+	startedAt time.Time
+	healthy   atomic.Bool
+	exited    chan struct{}
+}
+
+func NewWorker(ps *PythonService, opts WorkerOptions) *Worker {
+	return &Worker{
+		ps:      ps,
+		opts:    opts,
+		queue:   make(chan *call, max(opts.QueueSize, 1)),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start launches the supervisor. It does not wait for the process to be ready,
+// calls made meanwhile are queued.
+func (w *Worker) Start() {
+	go w.supervise()
+}
+
+// Stop closes the worker's input so it can finish what it is doing, and kills
+// it when ctx is done first. Queued calls fail with ErrWorkerStopped.
+func (w *Worker) Stop(ctx context.Context) error {
+	w.once.Do(func() { close(w.stop) })
+	select {
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		w.mu.Lock()
+		if s := w.session; s != nil {
+			killProcess(s.cmd)
+		}
+		w.mu.Unlock()
+		<-w.stopped
+		return ctx.Err()
+	}
+}
+
+// Call sends method with params and decodes the result into result, which may
+// be nil. It blocks while the queue is full.
+func (w *Worker) Call(ctx context.Context, method string, params, result any) error {
+	c := &call{ctx: ctx, method: method, params: params, done: make(chan reply, 1)}
+	select {
+	case <-w.stop:
+		return ErrWorkerStopped
+	default:
+	}
+	select {
+	case w.queue <- c:
+	case <-w.stop:
+		return ErrWorkerStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var r reply
+	select {
+	case r = <-c.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if r.res.Error != nil {
+		return r.res.Error
+	}
+	if result == nil || len(r.res.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.res.Result, result)
+}
+
+// Healthy reports whether the current process answered its last health check.
+func (w *Worker) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.session != nil && w.session.healthy.Load()
+}
+
+func (w *Worker) Status() WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := WorkerStatus{
+		Transport: "stdio",
+		Restarts:  w.restarts,
+		Queued:    len(w.queue),
+	}
+	if w.opts.Socket != "" {
+		status.Transport = "unix"
+	}
+	if w.lastErr != nil {
+		status.LastError = w.lastErr.Error()
+	}
+	if !w.lastHealth.IsZero() {
+		lastHealth := w.lastHealth
+		status.LastHealth = &lastHealth
+	}
+	if s := w.session; s != nil {
+		status.Running = true
+		status.Healthy = s.healthy.Load()
+		status.PID = s.cmd.Process.Pid
+		startedAt := s.startedAt
+		status.StartedAt = &startedAt
+		s.pendingMu.Lock()
+		status.InFlight = len(s.pending)
+		s.pendingMu.Unlock()
+	}
+	return status
+}
+
+func (w *Worker) supervise() {
+	logger := lg.GetLogger("PythonWorker")
+	defer close(w.stopped)
+	defer w.drainQueue()
+
+	attempt := 0
+	for {
+		startedAt := time.Now()
+		err := w.runSession()
+
+		w.mu.Lock()
+		w.session = nil
+		w.lastErr = err
+		w.mu.Unlock()
+
+		select {
+		case <-w.stop:
+			logger.Info("Python worker stopped")
+			return
+		default:
+		}
+
+		if time.Since(startedAt) >= w.opts.StableAfter {
+			attempt = 0
+		}
+		delay := w.opts.Restart.Backoff(attempt)
+		attempt++
+		logger.Warnf("Python worker exited (%v), restarting in %v", err, delay)
+
+		select {
+		case <-w.stop:
+			logger.Info("Python worker stopped")
+			return
+		case <-time.After(delay):
+		}
+
+		w.mu.Lock()
+		w.restarts++
+		w.mu.Unlock()
+	}
+}
+
+// runSession starts the process and serves the queue until the process exits
+// or the worker is stopped.
+func (w *Worker) runSession() error {
+	logger := lg.GetLogger("PythonWorker")
+	s, r, err := w.startProcess()
+	if err != nil {
+		return err
+	}
+	logger.Infof("Python worker started (pid %d)", s.cmd.Process.Pid)
+
+	w.mu.Lock()
+	w.session = s
+	w.mu.Unlock()
+
+	waitErr := make(chan error, 1)
+	go func() {
+		w.readResponses(s, r)
+		waitErr <- s.cmd.Wait()
+		close(s.exited)
+	}()
+	go w.checkHealth(s)
+
+	stopping := false
+	for !stopping {
+		select {
+		case c := <-w.queue:
+			if c.ctx.Err() != nil {
+				continue
+			}
+			if err := w.send(s, c); err != nil {
+				killProcess(s.cmd)
+			}
+		case <-w.stop:
+			stopping = true
+		case <-s.exited:
+			stopping = true
+		}
+	}
+
+	s.closeW()
+	err = <-waitErr
+	s.failPending(ErrWorkerExited)
+	if err == nil {
+		err = errors.New("process exited")
+	}
+	return err
+}
+
+func (w *Worker) startProcess() (*session, io.Reader, error) {
+	args := []string{"--task=worker", fmt.Sprintf("--protocol=%d", ProtocolVersion)}
+	if w.opts.Socket != "" {
+		args = append(args, "--socket="+w.opts.Socket)
+	}
+	ps := *w.ps
+	ps.Silent = false
+	cmd := ps.Command(args...)
+	configureProcess(cmd)
+	logger := lg.GetLogger("PythonWorker")
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	go logStderr(stderr, logger)
+
+	s := &session{
+		cmd:       cmd,
+		pending:   make(map[int64]*call),
+		startedAt: time.Now(),
+		exited:    make(chan struct{}),
+	}
+
+	if w.opts.Socket == "" {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := w.start(cmd); err != nil {
+			return nil, nil, err
+		}
+		s.w, s.closeW = stdin, stdin.Close
+		return s, stdout, nil
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	go func() {
+		if env, _ := readEnvelopes(stdout, nil, logger); env != nil && env.Error != nil {
+			logger.Errorf("Python worker refused to start: %v", env.Error)
+		}
+		io.Copy(io.Discard, stdout)
+	}()
+	if err := w.start(cmd); err != nil {
+		return nil, nil, err
+	}
+	conn, err := dialSocket(w.opts.Socket, w.opts.StartTimeout)
+	if err != nil {
+		killProcess(cmd)
+		cmd.Wait()
+		return nil, nil, err
+	}
+	s.w, s.closeW = conn, conn.Close
+	return s, conn, nil
+}
+
+// start starts the process under the memory limit. CPU time is not limited
+// since it only grows over the life of the worker.
+func (w *Worker) start(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start python worker: %w", err)
+	}
+	if err := applyLimits(cmd.Process.Pid, Limits{MemoryMB: CurrentLimits().MemoryMB}); err != nil {
+		killProcess(cmd)
+		cmd.Wait()
+		return fmt.Errorf("failed to apply resource limits: %v", err)
+	}
+	return nil
+}
+
+// dialSocket waits for the worker to listen on path.
+func dialSocket(path string, timeout time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("python worker did not listen on %s: %w", path, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func (w *Worker) send(s *session, c *call) error {
+	id := w.nextID.Add(1)
+	line, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: c.method, Params: c.params})
+	if err != nil {
+		c.done <- reply{err: fmt.Errorf("failed to encode request: %w", err)}
+		return nil
+	}
+
+	s.pendingMu.Lock()
+	s.pending[id] = c
+	s.pendingMu.Unlock()
+
+	s.writeMu.Lock()
+	_, err = s.w.Write(append(line, '\n'))
+	s.writeMu.Unlock()
+	if err != nil {
+		s.take(id)
+		c.done <- reply{err: fmt.Errorf("failed to send request: %w", err)}
+	}
+	return err
+}
+
+func (w *Worker) readResponses(s *session, r io.Reader) {
+	logger := lg.GetLogger("PythonWorker")
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var res rpcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil || res.ID == nil {
+			var env Envelope
+			if json.Unmarshal(scanner.Bytes(), &env) == nil && env.Error != nil {
+				logger.Errorf("Python worker refused to start: %v", env.Error)
+			} else if env.Type != EnvelopeProgress {
+				logger.Warnf("Ignoring unexpected worker output: %s", scanner.Text())
+			}
+			continue
+		}
+		if c := s.take(*res.ID); c != nil {
+			c.done <- reply{res: res}
+		}
+	}
+}
+
+// checkHealth probes the process while it is idle; a busy process proves it
+// is alive by answering. The process is killed when a probe fails.
+func (w *Worker) checkHealth(s *session) {
+	logger := lg.GetLogger("PythonWorker")
+	timeout := w.opts.StartTimeout
+	for {
+		if !s.healthy.Load() || s.inFlight() == 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			c := &call{ctx: ctx, method: "health", done: make(chan reply, 1)}
+			err := w.send(s, c)
+			if err == nil {
+				select {
+				case r := <-c.done:
+					err = r.err
+					if r.res.Error != nil {
+						err = r.res.Error
+					}
+					if err == nil {
+						err = checkProtocol(r.res.Result)
+					}
+				case <-ctx.Done():
+					err = ctx.Err()
+				case <-s.exited:
+					cancel()
+					return
+				}
+			}
+			cancel()
+			if err != nil {
+				select {
+				case <-w.stop:
+					return
+				default:
+				}
+				logger.Errorf("Python worker failed its health check, killing it: %v", err)
+				s.healthy.Store(false)
+				killProcess(s.cmd)
+				return
+			}
+			if !s.healthy.Swap(true) {
+				logger.Info("Python worker is ready")
+			}
+			w.mu.Lock()
+			w.lastHealth = time.Now()
+			w.mu.Unlock()
+			timeout = w.opts.HealthTimeout
+		}
+
+		select {
+		case <-s.exited:
+			return
+		case <-time.After(w.opts.HealthInterval):
+		}
+	}
+}
+
+// checkProtocol compares the protocol version reported by a health check with
+// ours.
+func checkProtocol(result json.RawMessage) error {
+	var health struct {
+		Protocol int `json:"protocol"`
+	}
+	if err := json.Unmarshal(result, &health); err != nil {
+		return fmt.Errorf("invalid health response: %v", err)
+	}
+	if health.Protocol != ProtocolVersion {
+		return fmt.Errorf("worker speaks protocol %d, expected %d", health.Protocol, ProtocolVersion)
+	}
+	return nil
+}
+
+func (w *Worker) drainQueue() {
+	for {
+		select {
+		case c := <-w.queue:
+			c.done <- reply{err: ErrWorkerStopped}
+		default:
+			return
+		}
+	}
+}
+
+func (s *session) take(id int64) *call {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	c := s.pending[id]
+	delete(s.pending, id)
+	return c
+}
+
+func (s *session) inFlight() int {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	return len(s.pending)
+}
+
+func (s *session) failPending(err error) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	for id, c := range s.pending {
+		c.done <- reply{err: err}
+		delete(s.pending, id)
+	}
+}
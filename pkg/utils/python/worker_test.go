@@ -0,0 +1,147 @@
+package python
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/qxbao/asfpc/pkg/retry"
+)
+
+// TestMain turns the test binary into a fake worker when it is started by one
+// of the tests below in place of the venv interpreter.
+func TestMain(m *testing.M) {
+	if os.Getenv("ASFPC_FAKE_WORKER") == "1" {
+		fakeWorker()
+		return
+	}
+	if os.Getenv("ASFPC_FAKE_TASK") == "1" {
+		fakeTask()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeWorker answers health, echoes params, fails on "fail" and dies on "crash"
+func fakeWorker() {
+	scanner := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+		res := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "health":
+			res["result"] = map[string]any{"status": "ok", "protocol": ProtocolVersion}
+		case "echo":
+			res["result"] = req.Params
+		case "crash":
+			os.Exit(3)
+		default:
+			res["error"] = map[string]any{"code": -32000, "message": "boom"}
+		}
+		out.Encode(res)
+	}
+}
+
+// fakeInterpreter returns a python path whose venv interpreter is the test
+// binary.
+func fakeInterpreter(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("Fake interpreter relies on a symlink")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "venv", "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(exe, filepath.Join(dir, "venv", "bin", "python")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newFakeWorker(t *testing.T) *Worker {
+	dir := fakeInterpreter(t)
+	t.Setenv("ASFPC_FAKE_WORKER", "1")
+
+	opts := DefaultWorkerOptions()
+	opts.HealthInterval = 50 * time.Millisecond
+	opts.StartTimeout = 5 * time.Second
+	opts.Restart = retry.Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	w := NewWorker(NewPythonService("venv", false, true, &dir), opts)
+	w.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		w.Stop(ctx)
+	})
+	return w
+}
+
+// TestWorkerCall tests results and errors coming back from the worker
+func TestWorkerCall(t *testing.T) {
+	w := newFakeWorker(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got map[string]string
+	if err := w.Call(ctx, "echo", map[string]string{"targets": "1,2"}, &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got["targets"] != "1,2" {
+		t.Errorf("Expected params to be echoed, got %v", got)
+	}
+
+	var rpcErr *RPCError
+	if err := w.Call(ctx, "fail", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Message != "boom" {
+		t.Errorf("Expected worker error, got %v", err)
+	}
+}
+
+// TestWorkerRestart tests that a crashed worker is restarted
+func TestWorkerRestart(t *testing.T) {
+	w := newFakeWorker(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := w.Call(ctx, "crash", nil, nil); !errors.Is(err, ErrWorkerExited) {
+		t.Fatalf("Expected ErrWorkerExited, got %v", err)
+	}
+	if err := w.Call(ctx, "echo", map[string]int{"n": 1}, nil); err != nil {
+		t.Fatalf("Expected restarted worker to answer, got %v", err)
+	}
+	if restarts := w.Status().Restarts; restarts != 1 {
+		t.Errorf("Expected 1 restart, got %d", restarts)
+	}
+}
+
+// TestWorkerStop tests that calls fail once the worker is stopped
+func TestWorkerStop(t *testing.T) {
+	w := newFakeWorker(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := w.Stop(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := w.Call(ctx, "echo", nil, nil); !errors.Is(err, ErrWorkerStopped) {
+		t.Errorf("Expected ErrWorkerStopped, got %v", err)
+	}
+	if w.Status().Running {
+		t.Error("Expected no running process")
+	}
+}
@@ -0,0 +1,246 @@
+package training
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/registry"
+	"github.com/qxbao/asfpc/pkg/utils/python"
+)
+
+type TrainingService struct {
+	Server *infras.Server
+}
+
+var logger = lg.GetLogger("TrainingService")
+
+type Params struct {
+	ModelName  string
+	CategoryID *int32
+	AutoTune   bool
+	Trials     *int
+}
+
+// ModelsPath is the directory holding the model folders.
+func ModelsPath() (string, error) {
+	exc, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	return path.Join(path.Dir(exc), "python", "resources", "models"), nil
+}
+
+// ReadMetrics returns the metadata.json of a model version, or fallback
+// when it cannot be read.
+func ReadMetrics(versionPath string, fallback map[string]any) db.NullableJSON {
+	if base, err := ModelsPath(); err == nil {
+		data, err := os.ReadFile(path.Join(base, versionPath, "metadata.json"))
+		if err == nil && json.Valid(data) {
+			return db.NullableJSON(data)
+		}
+	}
+	if fallback == nil {
+		return db.NullableJSON("{}")
+	}
+	data, err := json.Marshal(fallback)
+	if err != nil {
+		return db.NullableJSON("{}")
+	}
+	return db.NullableJSON(data)
+}
+
+// Train trains a new version of the model and registers it. Versions are
+// never overwritten: each run trains into its own folder, which only becomes
+// a version once the version number is reserved.
+func (s *TrainingService) Train(ctx context.Context, r *jobs.Reporter, p Params) (db.ModelVersion, python.TrainResult, error) {
+	queries := s.Server.Queries
+	params := db.CreateModelVersionParams{
+		RequestID: sql.NullInt32{Int32: r.ID(), Valid: true},
+	}
+	if p.CategoryID != nil {
+		params.CategoryID = sql.NullInt32{Int32: *p.CategoryID, Valid: true}
+		if deployment, err := queries.GetModelDeployment(ctx, *p.CategoryID); err == nil {
+			params.ParentVersionID = deployment.ChampionVersionID
+		}
+		// the labels come from whichever prompt the gemini scoring uses
+		promptService := "gemini-preprocess-1"
+		if batchSize, _ := strconv.Atoi(s.Server.GetConfig(ctx, "GEMINI_SCORING_BATCH_SIZE", "1")); batchSize > 1 {
+			promptService = "gemini-batch-1"
+		}
+		if prompt, err := queries.GetPrompt(ctx, db.GetPromptParams{
+			ServiceName: promptService,
+			CategoryID:  *p.CategoryID,
+		}); err == nil {
+			params.PromptService = sql.NullString{String: prompt.ServiceName, Valid: true}
+			params.PromptVersion = sql.NullInt32{Int32: prompt.Version, Valid: true}
+		}
+	}
+
+	base, err := ModelsPath()
+	if err != nil {
+		return db.ModelVersion{}, python.TrainResult{}, err
+	}
+	// Train next to the models so the final move is a rename, the dot keeps
+	// the folder out of the sync
+	stagingPath := fmt.Sprintf(".train-%d", r.ID())
+	defer os.RemoveAll(path.Join(base, stagingPath))
+
+	result, err := s.Server.PythonClient(ctx).Train(ctx, python.TrainParams{
+		ModelName:  stagingPath,
+		RequestID:  r.ID(),
+		AutoTune:   p.AutoTune,
+		Trials:     p.Trials,
+		CategoryID: p.CategoryID,
+	}, func(p python.Progress) {
+		r.Progress(p.Progress, p.Message, p.Details)
+	})
+	if err != nil {
+		return db.ModelVersion{}, result, err
+	}
+
+	if result.Dataset.SnapshotID != "" {
+		params.DatasetSnapshotID = sql.NullString{String: result.Dataset.SnapshotID, Valid: true}
+		params.DatasetSize = sql.NullInt32{Int32: int32(result.Dataset.Size), Valid: true}
+	}
+	modelVersion, err := s.register(ctx, base, stagingPath, p.ModelName, params, result.TestResults)
+	if err != nil {
+		return db.ModelVersion{}, result, err
+	}
+	result.ModelName = modelVersion.Path
+	logger.Infof("Registered model version %s (ID: %d)", modelVersion.Path, modelVersion.ID)
+	return modelVersion, result, nil
+}
+
+// register moves a trained folder to the next version of the model and
+// records it. The model row is created with its first version.
+func (s *TrainingService) register(ctx context.Context, base string, stagingPath string, name string, params db.CreateModelVersionParams, testResults map[string]any) (db.ModelVersion, error) {
+	tx, err := s.Server.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return db.ModelVersion{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := s.Server.Queries.WithTx(tx)
+
+	if err := queries.LockModelVersions(ctx, name); err != nil {
+		return db.ModelVersion{}, fmt.Errorf("failed to lock versions of %s: %w", name, err)
+	}
+	model, err := queries.GetModelByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		description := fmt.Sprintf("Model trained on %s", time.Now().Format("2006-01-02 15:04:05"))
+		model, err = queries.CreateModel(ctx, db.CreateModelParams{
+			Name:        name,
+			Description: sql.NullString{String: description, Valid: true},
+			CategoryID:  sql.NullInt32{Valid: false},
+		})
+		if err != nil {
+			return db.ModelVersion{}, fmt.Errorf("failed to create model %s: %w", name, err)
+		}
+	} else if err != nil {
+		return db.ModelVersion{}, fmt.Errorf("failed to get model: %w", err)
+	}
+	version, err := queries.GetNextModelVersion(ctx, model.ID)
+	if err != nil {
+		return db.ModelVersion{}, fmt.Errorf("failed to get next version: %w", err)
+	}
+
+	versionPath := registry.VersionPath(name, version)
+	versionDir := path.Join(base, versionPath)
+	if err := os.MkdirAll(path.Dir(versionDir), 0755); err != nil {
+		return db.ModelVersion{}, fmt.Errorf("failed to create model folder: %w", err)
+	}
+	// Rename refuses a folder that is already there with files, so a version
+	// left on disk is never overwritten
+	if err := os.Rename(path.Join(base, stagingPath), versionDir); err != nil {
+		return db.ModelVersion{}, fmt.Errorf("failed to move model files to %s: %w", versionPath, err)
+	}
+	registered := false
+	defer func() {
+		if !registered {
+			os.RemoveAll(versionDir)
+		}
+	}()
+
+	params.ModelID = model.ID
+	params.Version = version
+	params.Path = versionPath
+	params.Metrics = ReadMetrics(versionPath, testResults)
+	modelVersion, err := queries.CreateModelVersion(ctx, params)
+	if err != nil {
+		return db.ModelVersion{}, fmt.Errorf("failed to register version %s: %w", versionPath, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return db.ModelVersion{}, err
+	}
+	registered = true
+	return modelVersion, nil
+}
+
+// Champion returns the folder and version of the category's champion along
+// with its deployment. Models assigned before versioning are used as they
+// are, without version.
+func (s *TrainingService) Champion(ctx context.Context, categoryID int32) (string, sql.NullInt32, db.ModelDeployment, error) {
+	queries := s.Server.Queries
+	deployment, err := queries.GetModelDeployment(ctx, categoryID)
+	if err == nil && deployment.ChampionVersionID.Valid {
+		version, err := queries.GetModelVersionByID(ctx, deployment.ChampionVersionID.Int32)
+		if err == nil {
+			return version.Path, deployment.ChampionVersionID, deployment, nil
+		}
+	}
+
+	model, err := queries.GetModelByCategory(ctx, sql.NullInt32{Int32: categoryID, Valid: true})
+	if err != nil {
+		return "", sql.NullInt32{}, deployment, err
+	}
+	return model.Name, sql.NullInt32{}, deployment, nil
+}
+
+// Promote makes version the champion of a category and assigns its model
+// to the category, in one transaction so both always point at the same model.
+func (s *TrainingService) Promote(ctx context.Context, version db.ModelVersion, categoryID int32) (db.ModelDeployment, error) {
+	tx, err := s.Server.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return db.ModelDeployment{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := s.Server.Queries.WithTx(tx)
+
+	deployment, err := queries.PromoteModelVersion(ctx, db.PromoteModelVersionParams{
+		CategoryID:        categoryID,
+		ChampionVersionID: sql.NullInt32{Int32: version.ID, Valid: true},
+	})
+	if err != nil {
+		return db.ModelDeployment{}, err
+	}
+	if err := queries.AssignModelCategory(ctx, db.AssignModelCategoryParams{
+		ID:         version.ModelID,
+		CategoryID: sql.NullInt32{Int32: categoryID, Valid: true},
+	}); err != nil {
+		return db.ModelDeployment{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return db.ModelDeployment{}, err
+	}
+	return deployment, nil
+}
+
+// Metrics parses the metrics of a version, reading its metadata.json when
+// the stored ones are incomplete, as for versions registered before the
+// registry kept them.
+func Metrics(version db.ModelVersion) (registry.Metadata, error) {
+	if m, err := registry.ParseMetadata(version.Metrics); err == nil {
+		return m, nil
+	}
+	return registry.ParseMetadata(ReadMetrics(version.Path, nil))
+}
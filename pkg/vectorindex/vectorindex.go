@@ -0,0 +1,168 @@
+// Package vectorindex builds the statements managing the pgvector indexes of
+// embedded_profile. Every category gets a partial index of its own, searches
+// always filter on one category.
+package vectorindex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Method string
+
+const (
+	HNSW    Method = "hnsw"
+	IVFFlat Method = "ivfflat"
+)
+
+// IndexPrefix starts the name of every per-category index, the category ID
+// follows it.
+const IndexPrefix = "idx_embedded_profile_embedding_cat_"
+
+// Bounds accepted by pgvector.
+const (
+	MinEfSearch = 1
+	MaxEfSearch = 1000
+)
+
+// Spec is how the indexes are built.
+type Spec struct {
+	Method Method
+	// M and EfConstruction tune HNSW indexes
+	M              int
+	EfConstruction int
+	// Lists tunes IVFFlat indexes
+	Lists int
+}
+
+func DefaultSpec() Spec {
+	return Spec{Method: HNSW, M: 16, EfConstruction: 64, Lists: 100}
+}
+
+func ParseMethod(s string) (Method, error) {
+	switch m := Method(strings.ToLower(strings.TrimSpace(s))); m {
+	case HNSW, IVFFlat:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown index method %q, expected hnsw or ivfflat", s)
+}
+
+// Validate checks the spec against the limits of pgvector.
+func (s Spec) Validate() error {
+	switch s.Method {
+	case HNSW:
+		if s.M < 2 || s.M > 100 {
+			return fmt.Errorf("m must be between 2 and 100, got %d", s.M)
+		}
+		if s.EfConstruction < 2*s.M || s.EfConstruction > 1000 {
+			return fmt.Errorf("ef_construction must be between 2*m and 1000, got %d", s.EfConstruction)
+		}
+	case IVFFlat:
+		if s.Lists < 1 || s.Lists > 32768 {
+			return fmt.Errorf("lists must be between 1 and 32768, got %d", s.Lists)
+		}
+	default:
+		return fmt.Errorf("unknown index method %q", s.Method)
+	}
+	return nil
+}
+
+func IndexName(categoryID int32) string {
+	return IndexPrefix + strconv.Itoa(int(categoryID))
+}
+
+// CategoryOf returns the category of a per-category index.
+func CategoryOf(name string) (int32, bool) {
+	rest, ok := strings.CutPrefix(name, IndexPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(rest, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return int32(id), true
+}
+
+// MethodOf reads the method of an index from its definition.
+func MethodOf(definition string) (Method, bool) {
+	lower := strings.ToLower(definition)
+	for _, m := range []Method{HNSW, IVFFlat} {
+		if strings.Contains(lower, "using "+string(m)+" ") {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+// Create builds the partial index of a category. Concurrent builds keep the
+// table writable but cannot run inside a transaction.
+func Create(spec Spec, categoryID int32, concurrently bool) (string, error) {
+	if err := spec.Validate(); err != nil {
+		return "", err
+	}
+	if categoryID <= 0 {
+		return "", fmt.Errorf("invalid category ID %d", categoryID)
+	}
+	var with string
+	switch spec.Method {
+	case HNSW:
+		with = fmt.Sprintf("m = %d, ef_construction = %d", spec.M, spec.EfConstruction)
+	case IVFFlat:
+		with = fmt.Sprintf("lists = %d", spec.Lists)
+	}
+	return fmt.Sprintf("CREATE INDEX %sIF NOT EXISTS %s ON public.embedded_profile USING %s (embedding vector_cosine_ops) WITH (%s) WHERE cid = %d",
+		concurrent(concurrently), IndexName(categoryID), spec.Method, with, categoryID), nil
+}
+
+func Drop(categoryID int32, concurrently bool) string {
+	return fmt.Sprintf("DROP INDEX %sIF EXISTS public.%s", concurrent(concurrently), IndexName(categoryID))
+}
+
+func Reindex(categoryID int32, concurrently bool) string {
+	return fmt.Sprintf("REINDEX INDEX %spublic.%s", concurrent(concurrently), IndexName(categoryID))
+}
+
+// SetEfSearch sets the size of the HNSW candidate list for the current
+// transaction. Larger values trade speed for recall.
+func SetEfSearch(efSearch int) (string, error) {
+	if efSearch < MinEfSearch || efSearch > MaxEfSearch {
+		return "", fmt.Errorf("ef_search must be between %d and %d, got %d", MinEfSearch, MaxEfSearch, efSearch)
+	}
+	return fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", efSearch), nil
+}
+
+// IterativeScan is how pgvector (0.8+) keeps scanning an HNSW index when
+// filters remove candidates. Without it a filtered search returns at most
+// ef_search rows before filtering, often fewer than asked for.
+type IterativeScan string
+
+const (
+	ScanOff     IterativeScan = "off"
+	ScanStrict  IterativeScan = "strict_order"
+	ScanRelaxed IterativeScan = "relaxed_order"
+)
+
+func ParseIterativeScan(s string) (IterativeScan, error) {
+	switch m := IterativeScan(strings.ToLower(strings.TrimSpace(s))); m {
+	case ScanOff, ScanStrict, ScanRelaxed:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown iterative scan %q, expected off, strict_order or relaxed_order", s)
+}
+
+// SetIterativeScan sets the iterative scan of the current transaction.
+func SetIterativeScan(mode IterativeScan) (string, error) {
+	if _, err := ParseIterativeScan(string(mode)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SET LOCAL hnsw.iterative_scan = %s", mode), nil
+}
+
+func concurrent(concurrently bool) string {
+	if concurrently {
+		return "CONCURRENTLY "
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package vectorindex
+
+import "testing"
+
+// TestCreate tests the statements of both methods
+func TestCreate(t *testing.T) {
+	stmt, err := Create(DefaultSpec(), 3, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_embedded_profile_embedding_cat_3 ON public.embedded_profile USING hnsw (embedding vector_cosine_ops) WITH (m = 16, ef_construction = 64) WHERE cid = 3"
+	if stmt != want {
+		t.Errorf("Expected %q, got %q", want, stmt)
+	}
+
+	stmt, err = Create(Spec{Method: IVFFlat, Lists: 50}, 7, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = "CREATE INDEX IF NOT EXISTS idx_embedded_profile_embedding_cat_7 ON public.embedded_profile USING ivfflat (embedding vector_cosine_ops) WITH (lists = 50) WHERE cid = 7"
+	if stmt != want {
+		t.Errorf("Expected %q, got %q", want, stmt)
+	}
+
+	if _, err := Create(DefaultSpec(), 0, false); err == nil {
+		t.Error("Expected an error for category 0")
+	}
+}
+
+// TestValidate tests the pgvector limits
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		spec Spec
+		ok   bool
+	}{
+		{DefaultSpec(), true},
+		{Spec{Method: HNSW, M: 1, EfConstruction: 64}, false},
+		{Spec{Method: HNSW, M: 40, EfConstruction: 64}, false},
+		{Spec{Method: IVFFlat, Lists: 0}, false},
+		{Spec{Method: "flat"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.spec.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%+v: expected ok=%v, got %v", tt.spec, tt.ok, err)
+		}
+	}
+}
+
+// TestNames tests reading back the category and method of an index
+func TestNames(t *testing.T) {
+	if id, ok := CategoryOf(IndexName(12)); !ok || id != 12 {
+		t.Errorf("Expected category 12, got %d (%v)", id, ok)
+	}
+	for _, name := range []string{"embedded_profile_embedding_idx", IndexPrefix + "x", IndexPrefix + "-1"} {
+		if _, ok := CategoryOf(name); ok {
+			t.Errorf("Expected %q not to be a category index", name)
+		}
+	}
+
+	m, ok := MethodOf("CREATE INDEX x ON public.embedded_profile USING hnsw (embedding vector_cosine_ops)")
+	if !ok || m != HNSW {
+		t.Errorf("Expected hnsw, got %q", m)
+	}
+	if _, err := ParseMethod("IVFFlat"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := ParseMethod("flat"); err == nil {
+		t.Error("Expected an error for an unknown method")
+	}
+}
+
+// TestSetEfSearch tests the bounds of ef_search
+func TestSetEfSearch(t *testing.T) {
+	if stmt, err := SetEfSearch(100); err != nil || stmt != "SET LOCAL hnsw.ef_search = 100" {
+		t.Errorf("Unexpected statement %q (%v)", stmt, err)
+	}
+	if _, err := SetEfSearch(0); err == nil {
+		t.Error("Expected an error for 0")
+	}
+	if _, err := SetEfSearch(MaxEfSearch + 1); err == nil {
+		t.Error("Expected an error above the maximum")
+	}
+}
+
+// TestSetIterativeScan tests the accepted modes
+func TestSetIterativeScan(t *testing.T) {
+	if stmt, err := SetIterativeScan(ScanStrict); err != nil || stmt != "SET LOCAL hnsw.iterative_scan = strict_order" {
+		t.Errorf("Unexpected statement %q (%v)", stmt, err)
+	}
+	if m, err := ParseIterativeScan(" Relaxed_Order "); err != nil || m != ScanRelaxed {
+		t.Errorf("Expected relaxed_order, got %q (%v)", m, err)
+	}
+	if _, err := SetIterativeScan("on; DROP TABLE x"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
@@ -1,6 +1,7 @@
 from datetime import datetime
 
 from sqlalchemy import DateTime, Float, Integer, SmallInteger, String, Text
+from sqlalchemy.dialects.postgresql import JSONB
 from sqlalchemy.orm import Mapped, mapped_column
 
 from .base import Base
@@ -12,9 +13,13 @@ class Request(Base):
   id: Mapped[int] = mapped_column(Integer, primary_key=True)
   progress: Mapped[float] = mapped_column(Float, nullable=False, default=0.0)
   status: Mapped[int] = mapped_column(SmallInteger, nullable=False, default=0)
-  description: Mapped[str | None] = mapped_column(String(50), nullable=True)
+  description: Mapped[str | None] = mapped_column(String(255), nullable=True)
   created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.now)
   updated_at: Mapped[datetime] = mapped_column(
     DateTime, default=datetime.now, onupdate=datetime.now
   )
   error_message: Mapped[str | None] = mapped_column(Text, nullable=True)
+  kind: Mapped[str] = mapped_column(String(32), nullable=False, default="unknown")
+  result: Mapped[dict | None] = mapped_column(JSONB, nullable=True)
+  started_at: Mapped[datetime | None] = mapped_column(DateTime, nullable=True)
+  finished_at: Mapped[datetime | None] = mapped_column(DateTime, nullable=True)
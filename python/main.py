@@ -6,16 +6,20 @@ Main module for the Python service.
 import argparse
 import asyncio
 import datetime
+import json
 import logging
 import os
 import sys
 from pathlib import Path
+from typing import Any
 
 import pandas as pd
 from dotenv import load_dotenv
 
 from database.database import Database
 from utils.navigator import TaskNavigator
+from utils.protocol import PROTOCOL_VERSION, TaskError, channel
+from utils.worker import WorkerServer
 
 load_dotenv(dotenv_path=Path(__file__).parent.parent / ".env")
 # Ensure logs directory exists
@@ -79,29 +83,69 @@ class MainProcess:
     )
     return main
 
-  async def run(self):
+  async def execute_task(self) -> Any:
     task_navigator = TaskNavigator(self.config)
     task = self.config.get("task")
     if task == "login":
-      await task_navigator.login()
-      sys.exit(0)
-    elif task == "joingroup":
-      await task_navigator.join_group()
-      sys.exit(0)
-    elif task == "train-model":
-      await task_navigator.train_model()
+      return await task_navigator.login()
+    if task == "joingroup":
+      return await task_navigator.join_group()
+    if task == "train-model":
+      return await task_navigator.train_model()
+    if task == "predict":
+      return await task_navigator.predict()
+    if task == "embed":
+      return await task_navigator.embed_profiles()
+    if task == "embed-text":
+      return await task_navigator.embed_text()
+    if task == "test":
+      return "Test task executed"
+    err_msg = f"Unknown task: {task}"
+    raise TaskError("unknown_task", err_msg)
+
+  async def run(self):
+    protocol = self.config.get("protocol")
+    if protocol is not None:
+      await self.run_protocol(str(protocol))
+      return
+
+    task = self.config.get("task")
+    if task == "worker":
+      await WorkerServer(self.config).serve()
       sys.exit(0)
-    elif task == "predict":
-      await task_navigator.predict()
+    try:
+      result = await self.execute_task()
+    except TaskError as err:
+      self.logger.error(err.message)  # noqa: TRY400
+      sys.exit(1)
+    if task == "predict":
+      print(json.dumps(result))  # noqa: T201
     elif task == "embed":
-      await task_navigator.embed_profiles()
-      sys.exit(0)
+      print(f"Embeddings generated for {result['success']}/{result['total']} profiles")  # noqa: T201
     elif task == "test":
-      print("Test task executed") # noqa: T201
+      print(result)  # noqa: T201
+    sys.exit(0)
+
+  async def run_protocol(self, version: str):
+    """Run the task and report its outcome as envelopes on the channel."""
+    channel.open()
+    if version != str(PROTOCOL_VERSION):
+      channel.error(TaskError(
+        "unsupported_protocol",
+        f"Protocol version {version} is not supported, expected {PROTOCOL_VERSION}",
+      ))
+      sys.exit(1)
+    if self.config.get("task") == "worker":
+      await WorkerServer(self.config).serve()
       sys.exit(0)
-    else:
-      self.logger.error("Unknown task: %s", task)
+    try:
+      result = await self.execute_task()
+    except Exception as err:
+      self.logger.exception("Task %s failed", self.config.get("task"))
+      channel.error(err)
       sys.exit(1)
+    channel.result(result)
+    sys.exit(0)
 
 
 async def execute():
@@ -315,12 +315,40 @@ class PotentialCustomerScoringModel:
     predictions = self.model.predict(dmatrix)
     return self.util.convert_numpy_types(predictions)
 
+  def explain(self, df: pd.DataFrame) -> list[dict[str, float]]:
+    """
+    Per-profile SHAP contributions of each input to the predicted score.
+
+    The embedding dimensions are summed into a single contribution to keep the
+    result compact. "bias" is the expected score the contributions add up from.
+    """
+    if self.model is None:
+      err_msg = "No model found"
+      raise ValueError(err_msg)
+    x, scaler, encoders = self.util.prepare_features(df, self.scaler, self.encoders)
+    self.scaler = scaler
+    self.encoders = encoders
+    dmatrix = xgb.DMatrix(x, missing=np.nan)
+    contribs = self.model.predict(dmatrix, pred_contribs=True)
+
+    # Columns follow prepare_features: embedding, gender, locale, relationship_status, age, then the bias
+    emb_dim = self.util.embedding_dimension
+    names = ["gender", "locale", "relationship_status", "age"]
+    result = []
+    for row in contribs:
+      contributions = {"embedding": round(float(row[:emb_dim].sum()), 6)}
+      for i, name in enumerate(names):
+        contributions[name] = round(float(row[emb_dim + i]), 6)
+      contributions["bias"] = round(float(row[-1]), 6)
+      result.append(contributions)
+    return result
+
   def save_model(self):
     if self.model is None:
       err_msg = "No model to save"
       raise ValueError(err_msg)
     model_dir = self.util.model_path
-    Path.mkdir(model_dir, exist_ok=True)
+    Path.mkdir(model_dir, parents=True, exist_ok=True)
 
     self.model.save_model(str(model_dir / "model.json"))
 
@@ -1,8 +1,6 @@
-import asyncio
 import datetime
 import gc
 import logging
-import threading
 from collections.abc import Sequence
 from pathlib import Path
 from typing import Any
@@ -18,8 +16,7 @@ from sklearn.calibration import LabelEncoder
 from sklearn.discriminant_analysis import StandardScaler
 from sklearn.metrics import r2_score, root_mean_squared_error
 
-from database.database import Database
-from database.services.request import RequestService
+from utils.protocol import channel
 
 
 class ModelUtility:
@@ -563,6 +560,8 @@ class ModelUtility:
       "max": float(y_pred.max()),
       "mean": float(y_pred.mean()),
       "std": float(y_pred.std()),
+      # Deciles, the reference distribution for drift monitoring
+      "quantiles": [float(q) for q in np.quantile(y_pred, np.linspace(0, 1, 11))],
     }
 
     # Residual analysis
@@ -585,6 +584,11 @@ class ModelUtility:
 
 
 class UpdateRequestCallback:
+  """
+  Reports each finished Optuna trial as a progress event. The Go side writes
+  it to the request row and pushes it to the request's stream.
+  """
+
   def __init__(
     self,
     request_id: int | None,
@@ -592,59 +596,30 @@ class UpdateRequestCallback:
     total_trials: int,
   ):
     self.request_id = request_id
-    self.rs = RequestService()
     self.trial_count = 0
     self.logger = logger
     self.total_trials = total_trials
 
-  def __call__(self, _: optuna.Study, __: optuna.trial.FrozenTrial):
+  def __call__(self, study: optuna.Study, _: optuna.trial.FrozenTrial):
     self.trial_count += 1
-    if self.request_id is not None:
-      progress = min(0.9, self.trial_count / self.total_trials * 0.8 + 0.1)
-      description = f"Optuna trial in progress: {self.trial_count}/{self.total_trials}"
-      self.logger.info(
-        "Updating request %s, Progress: %.2f%%", self.request_id, progress * 100
-      )
-    else:
+    if self.request_id is None:
       return
-    thread = threading.Thread(
-      target=self.isolated_update, args=(progress, description), daemon=True
-    )
-    thread.start()
-    self.logger.debug("Started isolated update thread for request %s", self.request_id)
-
-  def isolated_update(self, progress: float, description: str):
+    progress = min(0.9, self.trial_count / self.total_trials * 0.8 + 0.1)
     try:
-      loop = asyncio.new_event_loop()
-      asyncio.set_event_loop(loop)
-
-      try:
-
-        async def update_request_operation(
-          request_id: int, progress: float, description: str
-        ):
-          """Database operation to update request status"""
-          rs = RequestService()
-          db_session = Database.get_isolated_session()
-          return await rs.update_request(
-            request_id,
-            session=db_session,
-            progress=progress,
-            description=description,
-            status=1,
-          )
-
-        if self.request_id is not None:
-          success = loop.run_until_complete(
-            update_request_operation(self.request_id, progress, description)
-          )
-          if success:
-            self.logger.info("Successfully updated request %s", self.request_id)
-          else:
-            self.logger.warning("Request %s not found", self.request_id)
-        else:
-          self.logger.debug("No request_id provided, skipping update")
-      finally:
-        loop.close()
-    except RuntimeError:
-      self.logger.exception("Failed to update request status")
+      best_rmse = float(study.best_value)
+    except ValueError:
+      # every trial so far failed or was pruned
+      best_rmse = None
+    description = f"Optuna trial {self.trial_count}/{self.total_trials}"
+    if best_rmse is not None:
+      description += f", best RMSE {best_rmse:.4f}"
+    self.logger.info(
+      "Request %s, Progress: %.2f%% (%s)", self.request_id, progress * 100, description
+    )
+    channel.progress(
+      progress,
+      description,
+      trial=self.trial_count,
+      total_trials=self.total_trials,
+      best_rmse=best_rmse,
+    )
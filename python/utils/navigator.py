@@ -1,8 +1,7 @@
 import asyncio
-import json
+import hashlib
 import logging
 import re
-import sys
 from typing import Any
 
 import pandas as pd
@@ -14,10 +13,21 @@ from database.services import (
   GroupService,
   ProfileService,
   PromptService,
-  RequestService,
 )
 from ml import BGEM3EmbedModel, PotentialCustomerScoringModel
 from utils import DialogUtil
+from utils.protocol import TaskError, channel
+
+# Embedding models are expensive to load and never change on disk, so a long
+# lived worker keeps them around between requests. Scoring models are loaded
+# per call because retraining overwrites them in place.
+_embed_models: dict[str | None, BGEM3EmbedModel] = {}
+
+
+def get_embed_model(model_path: str | None) -> BGEM3EmbedModel:
+  if model_path not in _embed_models:
+    _embed_models[model_path] = BGEM3EmbedModel(model_path=model_path)
+  return _embed_models[model_path]
 
 
 class TaskNavigator:
@@ -25,7 +35,7 @@ class TaskNavigator:
     self.config = config
     self.logger = logging.getLogger("TaskNavigator")
 
-  async def login(self) -> None:
+  async def login(self) -> dict[str, bool]:
     user_id = self.config.get("uid", None)
     if not user_id:
       err_msg = "--uid is required for login task"
@@ -46,8 +56,9 @@ class TaskNavigator:
     )
     account.is_block = is_blocked
     await account_service.update_account(account)
+    return {"is_blocked": bool(is_blocked)}
 
-  async def join_group(self) -> None:
+  async def join_group(self) -> dict[str, bool]:
     group_id = self.config.get("group_id", None)
     if not group_id:
       err_msg = "--group_id is required for join_group task"
@@ -62,11 +73,11 @@ class TaskNavigator:
     group.is_joined = is_ok
     await gs.update_group(group)
     if not is_ok:
-      sys.exit(1)
-    else:
-      sys.exit(0)
+      err_msg = f"Failed to join group with id {group_id}"
+      raise TaskError("join_failed", err_msg)
+    return {"joined": True}
 
-  async def train_model(self) -> None:
+  async def train_model(self) -> dict[str, Any]:
     model_name = self.config.get("model-name", "ModelX")
     request_id = self.config.get("request-id", None)
     trials = self.config.get("trials", None)
@@ -94,6 +105,10 @@ class TaskNavigator:
       err_msg = f"No profiles available for training{f' in category {category_id}' if category_id else ''}"
       raise ValueError(err_msg)
     self.logger.info("Found %d profiles for training", len(profile_scores))
+    # Identifies the training set so versions trained on the same data can be told apart
+    snapshot = hashlib.sha256(
+      "\n".join(sorted(f"{profile.id}:{score}" for profile, score in profile_scores)).encode()
+    ).hexdigest()[:16]
     # Create DataFrame with profile data and gemini_score
     input_data = []
     for profile, gemini_score in profile_scores:
@@ -104,10 +119,7 @@ class TaskNavigator:
       profile_data["gemini_score"] = gemini_score
       input_data.append(profile_data)
     input_df = pd.DataFrame(input_data)
-    rs = RequestService()
-    await rs.update_request(
-      request_id, status=1, description="Preparing data for training...", progress=0.0
-    )
+    channel.progress(0.0, "Preparing data for training...")
     model = PotentialCustomerScoringModel(
       request_id=request_id,
       model_name=model_name
@@ -118,23 +130,35 @@ class TaskNavigator:
     auto_tune = self.config.get("auto-tune")
     auto_tune = not (not auto_tune or auto_tune == "False")
     model.load_data(input_df)
-    await rs.update_request(
-      request_id, status=1, progress=0.1, description="Training in progress..."
-    )
+    channel.progress(0.1, "Training in progress...")
     model.train(auto_tune=auto_tune)
-    await rs.update_request(
-      request_id, status=1, progress=0.95, description="Finalizing training..."
-    )
+    channel.progress(0.95, "Finalizing training...")
     self.logger.info("Model trained successfully")
     test_results = model.test()
+    test_results["feature_completeness"] = self._feature_completeness(input_data)
     self.logger.info("Test result: %s", test_results)
-    await rs.update_request(
-      request_id, status=1, progress=0.99, description="Saving model..."
-    )
+    channel.progress(0.99, "Saving model...")
     model.save_model()
     self.logger.info("Model saved as: %s", model_name)
-
-  async def predict(self) -> None:
+    return {
+      "model_name": model_name,
+      "test_results": test_results,
+      "dataset": {"snapshot_id": snapshot, "size": len(profile_scores)},
+    }
+
+  @staticmethod
+  def _feature_completeness(rows: list[dict]) -> dict[str, float]:
+    """Share of training rows with each input feature filled in"""
+    features = ("embedding", "gender", "relationship_status", "locale")
+    filled = dict.fromkeys(features, 0)
+    for row in rows:
+      for feature in features:
+        value = row[feature][0] if feature == "embedding" else row[feature]
+        if value not in (None, "", "NOT_SPECIFIED"):
+          filled[feature] += 1
+    return {feature: filled[feature] / len(rows) for feature in features}
+
+  async def predict(self) -> dict[str, float | dict | None]:
     model_name = self.config.get("model-name", None)
     if not model_name:
       err_msg = "Argument --model-name is required"
@@ -152,8 +176,14 @@ class TaskNavigator:
     id_list = list(id_set)
 
     category_id = self.config.get("category-id", None)
+    # Shadow runs score with the given model only, ignoring overrides and stored scores
+    shadow = self.config.get("shadow") == "True"
+    # Stale scores are recomputed instead of being returned as they are
+    rescore = self.config.get("rescore") == "True"
+    # Explained scores come with the SHAP contributions of each input
+    explain = self.config.get("explain") == "True"
     model_path_override = None
-    if category_id:
+    if category_id and not shadow:
       config_service = ConfigService()
       model_path_override = await config_service.get_ml_model_path(int(category_id))
       if model_path_override:
@@ -178,13 +208,15 @@ class TaskNavigator:
         return None
       profile, existing_score = result
 
-      if existing_score is not None:
+      if existing_score is not None and not (shadow or rescore or explain):
         return existing_score
 
       input_df = pd.DataFrame(profile.to_df(category_id=category_id_int))
       score = model.predict(input_df)
       if isinstance(score, list):
         score = score[0]
+      if explain:
+        return {"score": score, "contributions": model.explain(input_df)[0]}
       return score
 
     async def semaphore_proc(sem, i):
@@ -197,9 +229,26 @@ class TaskNavigator:
     res_obj = {}
     for i in range(len(id_list)):
       res_obj[str(id_list[i])] = result[i]
-    print(json.dumps(res_obj))  # noqa: T201
+    return res_obj
+
+  async def embed_text(self) -> dict[str, list[float]]:
+    """Embed a free-text query with the category's embedding model, for semantic search."""
+    text = self.config.get("text", None)
+    if not text or not str(text).strip():
+      err_msg = "Argument --text is required"
+      raise Exception(err_msg)
+    category_id = self.config.get("category-id", None)
+    if not category_id:
+      err_msg = "--category-id is required for embed-text task"
+      raise Exception(err_msg)
+
+    config_service = ConfigService()
+    embedding_model_path = await config_service.get_embedding_model_path(int(category_id))
+    model = get_embed_model(embedding_model_path)
+    embedding = model.embed(str(text).strip())
+    return {"embedding": embedding}  # type: ignore[dict-item]
 
-  async def embed_profiles(self) -> None:
+  async def embed_profiles(self) -> dict[str, int]:
     targets = self.config.get("targets", None)
     if not targets:
       err_msg = "Argument --targets is required"
@@ -225,7 +274,7 @@ class TaskNavigator:
     if embedding_model_path:
       self.logger.info("Using category-specific embedding model: %s", embedding_model_path)
 
-    model = BGEM3EmbedModel(model_path=embedding_model_path)
+    model = get_embed_model(embedding_model_path)
     profile_service = ProfileService()
     prompt_service = PromptService()
 
@@ -273,4 +322,4 @@ class TaskNavigator:
         continue
       if await profile_service.insert_profile_embedding(pid, category_id_int, emb):
         success_count += 1
-    print(f"Embeddings generated for {success_count}/{len(id_list)} profiles")  # noqa: T201
+    return {"success": success_count, "total": len(id_list)}
@@ -0,0 +1,85 @@
+import json
+import os
+import sys
+import threading
+from typing import Any, TextIO
+
+PROTOCOL_VERSION = 1
+
+
+class TaskError(Exception):
+  """Error with a machine readable code, reported as is to the Go side."""
+
+  def __init__(self, code: str, message: str):
+    super().__init__(message)
+    self.code = code
+    self.message = message
+
+
+def error_code(err: BaseException) -> str:
+  if isinstance(err, TaskError):
+    return err.code
+  if isinstance(err, ValueError):
+    return "invalid_argument"
+  return "internal"
+
+
+class Channel:
+  """
+  Dedicated channel for protocol envelopes.
+
+  Opening the channel moves the original stdout to a private descriptor and
+  points fd 1 at stderr, so output from tasks and native libraries can never
+  be mistaken for an envelope. Every envelope is one JSON line carrying the
+  protocol version in "v".
+  """
+
+  def __init__(self):
+    self.stream: TextIO | None = None
+    self.lock = threading.Lock()
+
+  def open(self) -> TextIO:
+    if self.stream is None:
+      sys.stdout.flush()
+      fd = os.dup(1)
+      os.dup2(2, 1)
+      sys.stdout = sys.stderr
+      self.stream = os.fdopen(fd, "w", buffering=1, encoding="utf-8")
+    return self.stream
+
+  def send(self, envelope: dict[str, Any]) -> None:
+    if self.stream is None:
+      return
+    line = json.dumps({"v": PROTOCOL_VERSION, **envelope}) + "\n"
+    with self.lock:
+      self.stream.write(line)
+      self.stream.flush()
+
+  def progress(
+    self, progress: float, message: str | None = None, **details: Any
+  ) -> None:
+    """
+    Report progress in [0, 1]. Keyword arguments are sent as "details", e.g.
+    the current trial and best score. Ignored when the channel is not open.
+    """
+    envelope: dict[str, Any] = {
+      "type": "progress",
+      "progress": progress,
+      "message": message,
+    }
+    if details:
+      envelope["details"] = details
+    self.send(envelope)
+
+  def result(self, result: Any) -> None:
+    self.send({"type": "result", "status": "ok", "result": result})
+
+  def error(self, err: BaseException) -> None:
+    self.send({
+      "type": "result",
+      "status": "error",
+      "error": {"code": error_code(err), "message": str(err)},
+    })
+
+
+channel = Channel()
@@ -0,0 +1,166 @@
+import asyncio
+import json
+import logging
+import os
+import sys
+import time
+from collections.abc import Awaitable, Callable
+from pathlib import Path
+from typing import Any
+
+from utils.navigator import TaskNavigator
+from utils.protocol import PROTOCOL_VERSION, channel
+
+PARSE_ERROR = -32700
+INVALID_REQUEST = -32600
+METHOD_NOT_FOUND = -32601
+TASK_ERROR = -32000
+
+Writer = Callable[[dict[str, Any]], Awaitable[None]]
+
+
+class WorkerServer:
+  """
+  Long lived worker speaking newline delimited JSON-RPC 2.0.
+
+  Requests are read from stdin (or from a Unix socket when --socket is given)
+  and answered as soon as they finish, so responses may come out of order and
+  callers must match them by id. Stdout is reserved for the protocol (see
+  utils.protocol.Channel); anything printed by a task is sent to stderr.
+  """
+
+  def __init__(self, config: dict[str, Any]):
+    self.config = config
+    self.logger = logging.getLogger("WorkerServer")
+    self.started_at = time.monotonic()
+    self.in_flight = 0
+    self.methods: dict[str, Callable[[dict[str, Any]], Awaitable[Any]]] = {
+      "health": self.health,
+      "predict": self.predict,
+      "embed": self.embed,
+      "embed-text": self.embed_text,
+    }
+
+  async def serve(self) -> None:
+    socket_path = self.config.get("socket")
+    if socket_path:
+      await self.serve_socket(str(socket_path))
+    else:
+      await self.serve_stdio()
+
+  async def serve_stdio(self) -> None:
+    out = channel.open()
+    lock = asyncio.Lock()
+
+    async def write(message: dict[str, Any]) -> None:
+      async with lock:
+        out.write(json.dumps(message) + "\n")
+        out.flush()
+
+    self.logger.info("Worker listening on stdio")
+    pending: set[asyncio.Task] = set()
+    while True:
+      line = await asyncio.to_thread(sys.stdin.buffer.readline)
+      if not line:
+        break
+      task = asyncio.create_task(self.handle(line, write))
+      pending.add(task)
+      task.add_done_callback(pending.discard)
+    if pending:
+      await asyncio.gather(*pending)
+    self.logger.info("Stdin closed, worker exiting")
+
+  async def serve_socket(self, socket_path: str) -> None:
+    """Serve a single connection and exit when it closes, like stdio does."""
+    channel.open()
+    if Path(socket_path).exists():
+      Path(socket_path).unlink()
+    finished = asyncio.Event()
+
+    async def on_connect(reader: asyncio.StreamReader, writer: asyncio.StreamWriter) -> None:
+      lock = asyncio.Lock()
+
+      async def write(message: dict[str, Any]) -> None:
+        async with lock:
+          writer.write(json.dumps(message).encode() + b"\n")
+          await writer.drain()
+
+      pending: set[asyncio.Task] = set()
+      try:
+        while line := await reader.readline():
+          task = asyncio.create_task(self.handle(line, write))
+          pending.add(task)
+          task.add_done_callback(pending.discard)
+        if pending:
+          await asyncio.gather(*pending)
+      finally:
+        writer.close()
+        finished.set()
+
+    server = await asyncio.start_unix_server(on_connect, path=socket_path, limit=64 * 1024 * 1024)
+    self.logger.info("Worker listening on %s", socket_path)
+    async with server:
+      await finished.wait()
+    Path(socket_path).unlink(missing_ok=True)
+    self.logger.info("Connection closed, worker exiting")
+
+  async def handle(self, line: bytes, write: Writer) -> None:
+    try:
+      request = json.loads(line)
+    except json.JSONDecodeError as err:
+      await write(self.error(None, PARSE_ERROR, f"Invalid JSON: {err}"))
+      return
+
+    request_id = request.get("id") if isinstance(request, dict) else None
+    if not isinstance(request, dict) or not isinstance(request.get("method"), str):
+      await write(self.error(request_id, INVALID_REQUEST, "Invalid request"))
+      return
+
+    method = self.methods.get(request["method"])
+    if method is None:
+      await write(self.error(request_id, METHOD_NOT_FOUND, f"Unknown method: {request['method']}"))
+      return
+
+    params = request.get("params") or {}
+    self.in_flight += 1
+    try:
+      result = await method(params)
+    except Exception as err:
+      self.logger.exception("Method %s failed", request["method"])
+      await write(self.error(request_id, TASK_ERROR, str(err)))
+      return
+    finally:
+      self.in_flight -= 1
+    await write({"jsonrpc": "2.0", "id": request_id, "result": result})
+
+  @staticmethod
+  def error(request_id: Any, code: int, message: str) -> dict[str, Any]:
+    return {
+      "jsonrpc": "2.0",
+      "id": request_id,
+      "error": {"code": code, "message": message},
+    }
+
+  async def health(self, _: dict[str, Any]) -> dict[str, Any]:
+    return {
+      "status": "ok",
+      "protocol": PROTOCOL_VERSION,
+      "pid": os.getpid(),
+      "uptime": time.monotonic() - self.started_at,
+      "in_flight": self.in_flight,
+    }
+
+  async def predict(self, params: dict[str, Any]) -> Any:
+    return await TaskNavigator(self.task_config(params)).predict()
+
+  async def embed(self, params: dict[str, Any]) -> Any:
+    return await TaskNavigator(self.task_config(params)).embed_profiles()
+
+  async def embed_text(self, params: dict[str, Any]) -> Any:
+    return await TaskNavigator(self.task_config(params)).embed_text()
+
+  def task_config(self, params: dict[str, Any]) -> dict[str, Any]:
+    """Params use the same keys as the command line flags of the task."""
+    config = dict(self.config)
+    config.update({k: str(v) for k, v in params.items()})
+    return config
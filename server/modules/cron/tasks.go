@@ -6,7 +6,9 @@ import (
 	"github.com/go-co-op/gocron/v2"
 	"github.com/qxbao/asfpc/infras"
 	analysis "github.com/qxbao/asfpc/server/modules/cron/tasks/analysis"
+	"github.com/qxbao/asfpc/server/modules/cron/tasks/lead"
 	"github.com/qxbao/asfpc/server/modules/cron/tasks/ml"
+	"github.com/qxbao/asfpc/server/modules/cron/tasks/request"
 	scan "github.com/qxbao/asfpc/server/modules/cron/tasks/scan"
 )
 
@@ -23,6 +25,11 @@ var TaskFuncs = map[string]func(s *infras.Server, name string) Task{
 	"Gemini Scoring": geminiScoring,
 	"Embed Profiles": embedProfiles,
 	"Score Profiles": scoreProfiles,
+	"Blend Scores": blendScores,
+	"Comment Intent": commentIntent,
+	"Purge Requests": purgeRequests,
+	"Retrain Models": retrainModels,
+	"Monitor Drift": monitorDrift,
 }
 
 func scanGroups(s *infras.Server, name string) Task {
@@ -99,3 +106,78 @@ func scoreProfiles(s *infras.Server, name string) Task {
 		}, s),
 	}
 }
+
+func blendScores(s *infras.Server, name string) Task {
+	return Task{
+		Name: name,
+		Def: gocron.DurationJob(
+			1 * time.Minute,
+		),
+		Fn: gocron.NewTask(func(server *infras.Server) {
+			leadService := &lead.LeadService{
+				Server: server,
+			}
+			leadService.BlendScoresCronjob()
+		}, s),
+	}
+}
+
+func commentIntent(s *infras.Server, name string) Task {
+	return Task{
+		Name: name,
+		Def: gocron.DurationJob(
+			5 * time.Minute,
+		),
+		Fn: gocron.NewTask(func(server *infras.Server) {
+			analysisService := &analysis.AnalysisService{
+				Server: server,
+			}
+			analysisService.CommentIntentCronjob()
+		}, s),
+	}
+}
+
+func purgeRequests(s *infras.Server, name string) Task {
+	return Task{
+		Name: name,
+		Def: gocron.DurationJob(
+			1 * time.Hour,
+		),
+		Fn: gocron.NewTask(func(server *infras.Server) {
+			requestService := &request.RequestService{
+				Server: server,
+			}
+			requestService.PurgeRequestsCronjob()
+		}, s),
+	}
+}
+
+func retrainModels(s *infras.Server, name string) Task {
+	return Task{
+		Name: name,
+		Def: gocron.DurationJob(
+			10 * time.Minute,
+		),
+		Fn: gocron.NewTask(func(server *infras.Server) {
+			mlService := &ml.MLService{
+				Server: server,
+			}
+			mlService.RetrainModelsCronjob()
+		}, s),
+	}
+}
+
+func monitorDrift(s *infras.Server, name string) Task {
+	return Task{
+		Name: name,
+		Def: gocron.DurationJob(
+			time.Hour,
+		),
+		Fn: gocron.NewTask(func(server *infras.Server) {
+			mlService := &ml.MLService{
+				Server: server,
+			}
+			mlService.DriftMonitorCronjob()
+		}, s),
+	}
+}
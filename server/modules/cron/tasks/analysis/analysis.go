@@ -4,9 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/qxbao/asfpc/db"
@@ -14,6 +14,7 @@ import (
 	"github.com/qxbao/asfpc/pkg/async"
 	"github.com/qxbao/asfpc/pkg/generative"
 	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/retry"
 	"github.com/qxbao/asfpc/pkg/utils/prompt"
 	"github.com/qxbao/asfpc/pkg/utils/python"
 )
@@ -24,6 +25,12 @@ type AnalysisService struct {
 
 var logger = lg.GetLogger("AnalysisService")
 
+// Jobs recorded in the LLM usage ledger
+const (
+	jobGeminiScoring = "gemini_scoring"
+	jobCommentIntent = "comment_intent"
+)
+
 func (as *AnalysisService) GeminiScoringCronjob() {
 	logger.Info("Starting Gemini scoring cronjob")
 	ctx := context.Background()
@@ -35,6 +42,12 @@ func (as *AnalysisService) GeminiScoringCronjob() {
 		return
 	}
 
+	guard, ok := as.loadBudgetGuard(ctx, jobGeminiScoring)
+	if !ok {
+		logger.Info("LLM budget exhausted. Exiting cronjob.")
+		return
+	}
+
 	geminiAPILimit := as.Server.GetConfig(ctx, "GEMINI_API_LIMIT", "15")
 	geminiAPILimitInt, err := strconv.ParseInt(geminiAPILimit, 10, 32)
 
@@ -43,6 +56,12 @@ func (as *AnalysisService) GeminiScoringCronjob() {
 		geminiAPILimitInt = 15
 	}
 
+	batchSize, err := strconv.Atoi(as.Server.GetConfig(ctx, "GEMINI_SCORING_BATCH_SIZE", "1"))
+	if err != nil || batchSize <= 0 {
+		logger.Warn("Invalid GEMINI_SCORING_BATCH_SIZE, using default 1")
+		batchSize = 1
+	}
+
 	// Get all categories
 	categories, err := as.Server.Queries.GetCategories(ctx)
 	if err != nil {
@@ -67,6 +86,9 @@ func (as *AnalysisService) GeminiScoringCronjob() {
 	// Collect all profiles across all categories
 	var profiles []db.GetProfilesAnalysisCronjobRow
 	for _, category := range categories {
+		if !as.allowCategory(ctx, guard, jobGeminiScoring, category.ID) {
+			continue
+		}
 		categoryProfiles, err := as.Server.Queries.GetProfilesAnalysisCronjob(ctx, db.GetProfilesAnalysisCronjobParams{
 			CategoryID: category.ID,
 			Limit:      int32(geminiAPILimitInt),
@@ -100,6 +122,7 @@ func (as *AnalysisService) GeminiScoringCronjob() {
 	}
 
 	generativeService := generative.GetGenerativeService(apiKey.ApiKey, "gemini-2.5-flash-lite")
+	generativeService.Retry = as.getRetryPolicy(ctx, "GEMINI")
 
 	err = generativeService.Init()
 
@@ -117,8 +140,10 @@ func (as *AnalysisService) GeminiScoringCronjob() {
 		return
 	}
 
+	cache := as.getResponseCache(ctx)
 	semaphore := async.GetSemaphore[infras.GeminiScoringTaskInput, bool](15)
 	promptService := prompt.PromptService{Server: as.Server}
+	batches := make(map[int32]*infras.GeminiBatchScoringTaskInput)
 
 	for _, profile := range profiles {
 		pr, err := promptService.GetPrompt(ctx, "gemini-preprocess-1", profile.CategoryID)
@@ -162,17 +187,63 @@ func (as *AnalysisService) GeminiScoringCronjob() {
 			profile.Gender.String,
 			profile.Birthday.String,
 		)
+		if batchSize > 1 {
+			batch, ok := batches[profile.CategoryID]
+			if !ok {
+				template, err := promptService.GetPrompt(ctx, "gemini-batch-1", profile.CategoryID)
+				if err != nil {
+					as.Server.Queries.LogAction(ctx, db.LogActionParams{
+						Action: "gemini_scoring_cronjob",
+						Description: sql.NullString{
+							String: fmt.Sprintf("Failed to get prompt (gemini-batch-1): %v", err.Error()),
+							Valid:  true,
+						},
+						TargetID:  sql.NullInt32{Int32: 0, Valid: false},
+						AccountID: sql.NullInt32{Int32: 0, Valid: false},
+					})
+					logger.Errorf("Failed to get profiles: %v", err.Error())
+					return
+				}
+				batch = &infras.GeminiBatchScoringTaskInput{
+					Ctx:                 ctx,
+					Gs:                  generativeService,
+					Cache:               cache,
+					Template:            template.Content,
+					BusinessDescription: businessDesc.Content,
+				}
+				batches[profile.CategoryID] = batch
+			}
+			batch.Items = append(batch.Items, infras.GeminiScoringItem{
+				Prompt:  profilePromptContent,
+				Profile: &profile,
+			})
+			continue
+		}
 		semaphore.Assign(as.geminiScoringTask, infras.GeminiScoringTaskInput{
 			Ctx:     ctx,
 			Gs:      generativeService,
+			Cache:   cache,
 			Prompt:  profilePromptContent,
 			Profile: &profile,
 		})
 	}
 
+	if batchSize > 1 {
+		count := as.runBatchScoring(ctx, batches, batchSize)
+		generativeService.SaveUsage(ctx, as.Server.Queries)
+		if err := cache.SaveStats(ctx); err != nil {
+			logger.Errorf("Failed to save LLM cache stats: %v", err)
+		}
+		logger.Infof("Gemini scoring cronjob completed: %d/%d profiles processed successfully", count, len(profiles))
+		return
+	}
+
 	_, errs := semaphore.Run()
 
 	generativeService.SaveUsage(ctx, as.Server.Queries)
+	if err := cache.SaveStats(ctx); err != nil {
+		logger.Errorf("Failed to save LLM cache stats: %v", err)
+	}
 	count := 0
 
 	for i, err := range errs {
@@ -195,33 +266,99 @@ func (as *AnalysisService) GeminiScoringCronjob() {
 	logger.Infof("Gemini scoring cronjob completed: %d/%d profiles processed successfully", count, len(profiles))
 }
 
+// getRetryPolicy reads the <prefix>_RETRY_* configs of an integration.
+func (as *AnalysisService) getRetryPolicy(ctx context.Context, prefix string) retry.Policy {
+	return retry.FromConfig(func(key, defaultValue string) string {
+		return as.Server.GetConfig(ctx, key, defaultValue)
+	}, prefix, retry.DefaultPolicy())
+}
+
+// getResponseCache returns the LLM response cache, or nil when caching is
+// disabled. Expired entries are purged on every call.
+func (as *AnalysisService) getResponseCache(ctx context.Context) *generative.ResponseCache {
+	enabled := as.Server.GetConfig(ctx, "LLM_CACHE_ENABLED_BOOL", "TRUE")
+	if strings.ToLower(enabled) != "true" {
+		return nil
+	}
+
+	ttlHours, err := strconv.ParseInt(as.Server.GetConfig(ctx, "LLM_CACHE_TTL_HOURS", "168"), 10, 32)
+	if err != nil || ttlHours <= 0 {
+		logger.Warn("Invalid LLM_CACHE_TTL_HOURS, using default 168")
+		ttlHours = 168
+	}
+
+	if _, err := as.Server.Queries.DeleteLlmCache(ctx, db.DeleteLlmCacheParams{ExpiredOnly: true}); err != nil {
+		logger.Warnf("Failed to purge expired LLM cache entries: %v", err)
+	}
+
+	return generative.NewResponseCache(as.Server.Queries, time.Duration(ttlHours)*time.Hour)
+}
+
 func (as *AnalysisService) geminiScoringTask(input infras.GeminiScoringTaskInput) bool {
-	response, err := input.Gs.GenerateText(input.Prompt)
-	if err != nil {
-		panic(fmt.Errorf("failed to generate text: %v", err))
+	score, ok := cachedScore(input.Ctx, input.Cache, input.Gs.Model, input.Prompt)
+	if !ok {
+		var usage generative.Usage
+		var err error
+		score, usage, err = requestScore(input.Ctx, input.Gs, input.Cache, input.Prompt)
+		input.Gs.Record(input.Profile.CategoryID, jobGeminiScoring, usage)
+		if err != nil {
+			panic(err)
+		}
 	}
 
-	score, err := strconv.ParseFloat(response, 64)
+	if err := as.saveGeminiScore(input.Ctx, input.Profile, score); err != nil {
+		panic(err)
+	}
 
+	return true
+}
+
+// cachedScore returns the cached score of a single-profile prompt.
+func cachedScore(ctx context.Context, cache *generative.ResponseCache, model, prompt string) (float64, bool) {
+	cached, ok := cache.Get(ctx, model, prompt)
+	if !ok {
+		return 0, false
+	}
+	score, err := strconv.ParseFloat(cached, 64)
+	return score, err == nil
+}
+
+// requestScore scores a single-profile prompt with its own request and caches
+// the parsed score. It returns the tokens counted for the request.
+func requestScore(ctx context.Context, gs *generative.GenerativeService, cache *generative.ResponseCache, prompt string) (float64, generative.Usage, error) {
+	response, usage, err := gs.GenerateTextWithUsage(prompt)
 	if err != nil {
-		panic(fmt.Errorf("failed to parse score: %v", err))
+		return 0, usage, fmt.Errorf("failed to generate text: %v", err)
 	}
 
-	err = as.Server.Queries.UpdateGeminiAnalysisProfile(input.Ctx, input.Profile.ID)
+	score, err := strconv.ParseFloat(strings.TrimSpace(response), 64)
 	if err != nil {
-		panic(fmt.Errorf("failed to update profile is_analyzed: %v", err))
+		return 0, usage, fmt.Errorf("failed to parse score: %v", err)
+	}
+
+	if err := cache.Set(ctx, gs.Model, prompt, strconv.FormatFloat(score, 'f', -1, 64)); err != nil {
+		logger.Warnf("Failed to cache score: %v", err)
 	}
 
-	err = as.Server.Queries.UpdateGeminiScore(input.Ctx, db.UpdateGeminiScoreParams{
-		UserProfileID: input.Profile.ID,
-		CategoryID:    input.Profile.CategoryID,
+	return score, usage, nil
+}
+
+func (as *AnalysisService) saveGeminiScore(ctx context.Context, profile *db.GetProfilesAnalysisCronjobRow, score float64) error {
+	err := as.Server.Queries.UpdateGeminiAnalysisProfile(ctx, profile.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update profile is_analyzed: %v", err)
+	}
+
+	err = as.Server.Queries.UpdateGeminiScore(ctx, db.UpdateGeminiScoreParams{
+		UserProfileID: profile.ID,
+		CategoryID:    profile.CategoryID,
 		GeminiScore:   sql.NullFloat64{Float64: score, Valid: true},
 	})
 	if err != nil {
-		panic(fmt.Errorf("failed to update gemini score: %v", err))
+		return fmt.Errorf("failed to update gemini score: %v", err)
 	}
 
-	return true
+	return nil
 }
 
 func (as *AnalysisService) GetGeminiKeys(c echo.Context) error {
@@ -300,28 +437,34 @@ func (as *AnalysisService) SelfEmbeddingCronjob() {
 			continue
 		}
 
-		pythonService := python.NewPythonService(os.Getenv("PYTHON_ENV_NAME"), false, true, nil)
-		idStrs := make([]string, 0, len(profiles))
-		for _, profileId := range profiles {
-			idStrs = append(idStrs, fmt.Sprintf("%d", profileId))
-		}
-		idStr := strings.Join(idStrs, ",")
-		output, err := pythonService.RunScript("--task=embed",
-			fmt.Sprintf("--targets=%s", idStr),
-			fmt.Sprintf("--embedding-model=%s", embeddingModel),
-			fmt.Sprintf("--category-id=%d", category.ID),
-		)
+		result, err := as.embedProfiles(ctx, python.EmbedParams{
+			Targets:        profiles,
+			CategoryID:     category.ID,
+			EmbeddingModel: embeddingModel,
+		})
 
 		if err != nil {
 			logger.Errorf("Failed to run embedding script for category %s: %v", category.Name, err)
 			continue
 		}
-		logger.Infof("Category %s embedding output: %s", category.Name, output)
+		logger.Infof("Category %s: embeddings generated for %d/%d profiles", category.Name, result.Success, result.Total)
 	}
 
 	logger.Info("Completed SelfEmbeddingCronjob for all categories")
 }
 
+// embedProfiles embeds the profiles on the python worker, or in a process of
+// its own when the worker is disabled.
+func (as *AnalysisService) embedProfiles(ctx context.Context, params python.EmbedParams) (python.EmbedResult, error) {
+	timeout, err := strconv.Atoi(as.Server.GetConfig(ctx, "PYTHON_WORKER_CALL_TIMEOUT_SEC", "1800"))
+	if err != nil || timeout <= 0 {
+		timeout = 1800
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	return as.Server.PythonClient(ctx).Embed(ctx, params)
+}
+
 func (as *AnalysisService) AddGeminiKey(c echo.Context) error {
 	queries := as.Server.Queries
 	dto := new(infras.AddGeminiKeyDTO)
@@ -0,0 +1,189 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/async"
+	"github.com/qxbao/asfpc/pkg/generative"
+	"github.com/qxbao/asfpc/pkg/utils/prompt"
+)
+
+type profilePayload struct {
+	ID                 int32  `json:"id"`
+	Name               string `json:"name"`
+	Location           string `json:"location"`
+	Work               string `json:"work"`
+	Bio                string `json:"bio"`
+	Education          string `json:"education"`
+	RelationshipStatus string `json:"relationship_status"`
+	Hometown           string `json:"hometown"`
+	Locale             string `json:"locale"`
+	Gender             string `json:"gender"`
+	Birthday           string `json:"birthday"`
+}
+
+type profileScoreResult struct {
+	ID    int32    `json:"id"`
+	Score *float64 `json:"score"`
+}
+
+// runBatchScoring splits the profiles of each category into requests of at
+// most batchSize profiles. It returns the number of profiles scored.
+func (as *AnalysisService) runBatchScoring(ctx context.Context, batches map[int32]*infras.GeminiBatchScoringTaskInput, batchSize int) int {
+	semaphore := async.GetSemaphore[infras.GeminiBatchScoringTaskInput, []infras.GeminiScoringResult](5)
+	for _, batch := range batches {
+		for start := 0; start < len(batch.Items); start += batchSize {
+			chunk := *batch
+			chunk.Items = batch.Items[start:min(start+batchSize, len(batch.Items))]
+			semaphore.Assign(as.geminiBatchScoringTask, chunk)
+		}
+	}
+
+	results, errs := semaphore.Run()
+	count := 0
+
+	for i, err := range errs {
+		if err != nil {
+			as.logScoringError(ctx, fmt.Sprintf("Failed to process profile batch: %v", err.Error()), sql.NullInt32{})
+			continue
+		}
+		for _, r := range results[i] {
+			if r.Err != nil {
+				as.logScoringError(ctx,
+					fmt.Sprintf("Failed to process profile %d: %v", r.ProfileID, r.Err.Error()),
+					sql.NullInt32{Int32: r.ProfileID, Valid: true},
+				)
+				continue
+			}
+			count++
+		}
+	}
+
+	return count
+}
+
+// geminiBatchScoringTask scores the uncached profiles of a batch with one
+// request. Profiles missing from the response, or all of them when the request
+// fails, are retried one by one.
+func (as *AnalysisService) geminiBatchScoringTask(input infras.GeminiBatchScoringTaskInput) []infras.GeminiScoringResult {
+	results := make([]infras.GeminiScoringResult, len(input.Items))
+	pending := make([]int, 0, len(input.Items))
+
+	for i, item := range input.Items {
+		results[i] = infras.GeminiScoringResult{ProfileID: item.Profile.ID}
+		if score, ok := cachedScore(input.Ctx, input.Cache, input.Gs.Model, item.Prompt); ok {
+			results[i].Err = as.saveGeminiScore(input.Ctx, item.Profile, score)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	if len(pending) == 0 {
+		return results
+	}
+
+	scores, usage, err := requestBatchScores(input, pending)
+	if err != nil {
+		logger.Warnf("Batch scoring of %d profiles failed, retrying one by one: %v", len(pending), err)
+	}
+
+	shares := usage.Split(len(pending))
+	for j, i := range pending {
+		item := input.Items[i]
+		input.Gs.Record(item.Profile.CategoryID, jobGeminiScoring, shares[j])
+
+		score, ok := scores[item.Profile.ID]
+		if ok {
+			err := input.Cache.Set(input.Ctx, input.Gs.Model, item.Prompt, strconv.FormatFloat(score, 'f', -1, 64))
+			if err != nil {
+				logger.Warnf("Failed to cache score for profile %d: %v", item.Profile.ID, err)
+			}
+		} else {
+			var used generative.Usage
+			score, used, err = requestScore(input.Ctx, input.Gs, input.Cache, item.Prompt)
+			input.Gs.Record(item.Profile.CategoryID, jobGeminiScoring, used)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+		}
+
+		results[i].Err = as.saveGeminiScore(input.Ctx, item.Profile, score)
+	}
+
+	return results
+}
+
+// requestBatchScores sends the pending profiles in one request and returns
+// the valid scores by profile id, with the tokens counted for the request.
+func requestBatchScores(input infras.GeminiBatchScoringTaskInput, pending []int) (map[int32]float64, generative.Usage, error) {
+	payload := make([]profilePayload, len(pending))
+	for j, i := range pending {
+		p := input.Items[i].Profile
+		payload[j] = profilePayload{
+			ID:                 p.ID,
+			Name:               p.Name.String,
+			Location:           p.Location.String,
+			Work:               p.Work.String,
+			Bio:                p.Bio.String,
+			Education:          p.Education.String,
+			RelationshipStatus: p.RelationshipStatus.String,
+			Hometown:           p.Hometown.String,
+			Locale:             p.Locale,
+			Gender:             p.Gender.String,
+			Birthday:           p.Birthday.String,
+		}
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, generative.Usage{}, fmt.Errorf("failed to encode profile batch: %v", err)
+	}
+
+	promptService := prompt.PromptService{}
+	batchPrompt := promptService.ReplacePrompt(input.Template, input.BusinessDescription, string(payloadJSON))
+
+	response, usage, err := input.Gs.GenerateTextWithUsage(batchPrompt)
+	if err != nil {
+		return nil, usage, fmt.Errorf("failed to generate text: %v", err)
+	}
+
+	var parsed []profileScoreResult
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &parsed); err != nil {
+		return nil, usage, fmt.Errorf("failed to parse batch response: %v", err)
+	}
+
+	sent := make(map[int32]bool, len(payload))
+	for _, p := range payload {
+		sent[p.ID] = true
+	}
+
+	scores := make(map[int32]float64, len(parsed))
+	for _, r := range parsed {
+		if !sent[r.ID] || r.Score == nil || *r.Score < 0 || *r.Score > 1 {
+			continue
+		}
+		scores[r.ID] = *r.Score
+	}
+
+	return scores, usage, nil
+}
+
+func (as *AnalysisService) logScoringError(ctx context.Context, msg string, targetID sql.NullInt32) {
+	as.Server.Queries.LogAction(ctx, db.LogActionParams{
+		Action: "gemini_scoring_cronjob",
+		Description: sql.NullString{
+			String: msg,
+			Valid:  true,
+		},
+		TargetID:  targetID,
+		AccountID: sql.NullInt32{Int32: 0, Valid: false},
+	})
+	logger.Error(msg)
+}
@@ -0,0 +1,33 @@
+package analysis
+
+import (
+	"context"
+
+	"github.com/qxbao/asfpc/pkg/budget"
+)
+
+// loadBudgetGuard loads the active LLM budgets with their current usage. The
+// second value is false when nothing may be dispatched, either because a
+// global budget is exhausted or because the budgets could not be checked.
+func (as *AnalysisService) loadBudgetGuard(ctx context.Context, job string) (budget.Guard, bool) {
+	guard, err := budget.Load(ctx, as.Server.Queries)
+	if err != nil {
+		logger.Error(err)
+		return budget.Guard{}, false
+	}
+	if b, exhausted := guard.Global(); exhausted {
+		budget.Report(ctx, as.Server.Queries, job, b)
+		return guard, false
+	}
+	return guard, true
+}
+
+// allowCategory reports whether the budgets of the category leave room for
+// more requests.
+func (as *AnalysisService) allowCategory(ctx context.Context, guard budget.Guard, job string, categoryID int32) bool {
+	b, exhausted := guard.Category(categoryID)
+	if exhausted {
+		budget.Report(ctx, as.Server.Queries, job, b)
+	}
+	return !exhausted
+}
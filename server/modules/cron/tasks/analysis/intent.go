@@ -0,0 +1,308 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/async"
+	"github.com/qxbao/asfpc/pkg/generative"
+	"github.com/qxbao/asfpc/pkg/utils/prompt"
+)
+
+// intentSignal is how much each intent label says about a buyer. The strongest
+// label of a comment, weighted by confidence, becomes the author's intent score.
+var intentSignal = map[string]float64{
+	"purchase_intent":        1.0,
+	"recommendation_request": 0.8,
+	"question":               0.5,
+	"complaint":              0.2,
+}
+
+type commentPayload struct {
+	ID      int32  `json:"id"`
+	Post    string `json:"post"`
+	Comment string `json:"comment"`
+}
+
+type commentIntentResult struct {
+	ID      int32              `json:"id"`
+	Intents map[string]float64 `json:"intents"`
+}
+
+func (as *AnalysisService) CommentIntentCronjob() {
+	logger.Info("Starting comment intent cronjob")
+	ctx := context.Background()
+
+	enableGemini := as.Server.GetConfig(ctx, "USE_GEMINI_ANALYSIS_BOOL", "TRUE")
+	if strings.ToLower(enableGemini) != "true" {
+		logger.Info("Gemini analysis is disabled. Exiting cronjob.")
+		return
+	}
+
+	guard, ok := as.loadBudgetGuard(ctx, jobCommentIntent)
+	if !ok {
+		logger.Info("LLM budget exhausted. Exiting cronjob.")
+		return
+	}
+
+	limit, err := strconv.ParseInt(as.Server.GetConfig(ctx, "COMMENT_INTENT_LIMIT", "50"), 10, 32)
+	if err != nil || limit <= 0 {
+		logger.Warn("Invalid COMMENT_INTENT_LIMIT, using default 50")
+		limit = 50
+	}
+
+	batchSize, err := strconv.Atoi(as.Server.GetConfig(ctx, "COMMENT_INTENT_BATCH_SIZE", "10"))
+	if err != nil || batchSize <= 0 {
+		logger.Warn("Invalid COMMENT_INTENT_BATCH_SIZE, using default 10")
+		batchSize = 10
+	}
+
+	maxAttempts, err := strconv.ParseInt(as.Server.GetConfig(ctx, "COMMENT_INTENT_MAX_ATTEMPTS", "3"), 10, 32)
+	if err != nil || maxAttempts <= 0 {
+		logger.Warn("Invalid COMMENT_INTENT_MAX_ATTEMPTS, using default 3")
+		maxAttempts = 3
+	}
+
+	categories, err := as.Server.Queries.GetCategories(ctx)
+	if err != nil {
+		logger.Errorf("Failed to get categories: %v", err)
+		return
+	}
+
+	if len(categories) == 0 {
+		logger.Info("No categories found. Skipping...")
+		return
+	}
+
+	apiKey, err := as.Server.Queries.GetGeminiKeyForUse(ctx)
+	if err != nil {
+		as.logIntentError(ctx, fmt.Sprintf("Failed to get gemini key: %v", err))
+		return
+	}
+
+	generativeService := generative.GetGenerativeService(apiKey.ApiKey, "gemini-2.5-flash-lite")
+	generativeService.Retry = as.getRetryPolicy(ctx, "GEMINI")
+	if err := generativeService.Init(); err != nil {
+		as.logIntentError(ctx, fmt.Sprintf("Failed to initialize generative service: %v", err))
+		return
+	}
+
+	promptService := prompt.PromptService{Server: as.Server}
+	semaphore := async.GetSemaphore[infras.CommentIntentTaskInput, bool](5)
+	batches := 0
+	var commentIDs []int32
+
+	for _, category := range categories {
+		if !as.allowCategory(ctx, guard, jobCommentIntent, category.ID) {
+			continue
+		}
+		comments, err := as.Server.Queries.GetCommentsForIntentAnalysis(ctx, db.GetCommentsForIntentAnalysisParams{
+			CategoryID:  category.ID,
+			MaxAttempts: int32(maxAttempts),
+			PageLimit:   int32(limit),
+		})
+		if err != nil {
+			logger.Errorf("Failed to get comments for category %s: %v", category.Name, err)
+			continue
+		}
+
+		if len(comments) == 0 {
+			continue
+		}
+
+		pr, err := promptService.GetPrompt(ctx, "comment-intent", category.ID)
+		if err != nil {
+			as.logIntentError(ctx, fmt.Sprintf("Failed to get prompt (comment-intent): %v", err))
+			continue
+		}
+		businessDesc, err := promptService.GetPrompt(ctx, "business-description", category.ID)
+		if err != nil {
+			as.logIntentError(ctx, fmt.Sprintf("Failed to get prompt (business-description): %v", err))
+			continue
+		}
+
+		for start := 0; start < len(comments); start += batchSize {
+			end := min(start+batchSize, len(comments))
+			batch := comments[start:end]
+
+			payload := make([]commentPayload, len(batch))
+			for i, c := range batch {
+				payload[i] = commentPayload{ID: c.ID, Post: c.PostContent, Comment: c.Content}
+			}
+			payloadJSON, err := json.Marshal(payload)
+			if err != nil {
+				logger.Errorf("Failed to encode comment batch: %v", err)
+				continue
+			}
+
+			semaphore.Assign(as.commentIntentTask, infras.CommentIntentTaskInput{
+				Ctx:        ctx,
+				Gs:         generativeService,
+				CategoryID: category.ID,
+				Prompt:     promptService.ReplacePrompt(pr.Content, businessDesc.Content, string(payloadJSON)),
+				Comments:   batch,
+			})
+			batches++
+			for _, c := range batch {
+				commentIDs = append(commentIDs, c.ID)
+			}
+		}
+	}
+
+	if batches == 0 {
+		logger.Info("No comments to analyze. Exiting cronjob.")
+		return
+	}
+
+	_, errs := semaphore.Run()
+	generativeService.SaveUsage(ctx, as.Server.Queries)
+
+	// synced once every batch is done, so comments classified for several
+	// categories in this run see all of their results
+	if err := as.Server.Queries.SyncCommentIntents(ctx, db.SyncCommentIntentsParams{
+		MaxAttempts: int32(maxAttempts),
+		CommentIds:  commentIDs,
+	}); err != nil {
+		as.logIntentError(ctx, fmt.Sprintf("Failed to update analyzed comments: %v", err))
+	}
+
+	count := 0
+	for _, err := range errs {
+		if err != nil {
+			as.logIntentError(ctx, fmt.Sprintf("Failed to classify comment batch: %v", err))
+		} else {
+			count++
+		}
+	}
+
+	logger.Infof("Comment intent cronjob completed: %d/%d batches processed successfully", count, batches)
+}
+
+// commentIntentTask classifies a batch of comments for one category. Every
+// comment of the batch is charged an attempt up front, so comments the model
+// leaves out or that are in a failed batch stop after COMMENT_INTENT_MAX_ATTEMPTS.
+func (as *AnalysisService) commentIntentTask(input infras.CommentIntentTaskInput) bool {
+	ids := make([]int32, len(input.Comments))
+	for i, comment := range input.Comments {
+		ids[i] = comment.ID
+	}
+	err := as.Server.Queries.AddCommentIntentAttempts(input.Ctx, db.AddCommentIntentAttemptsParams{
+		CommentIds: ids,
+		CategoryID: input.CategoryID,
+	})
+	if err != nil {
+		panic(fmt.Errorf("failed to count intent attempts: %v", err))
+	}
+
+	response, usage, err := input.Gs.GenerateTextWithUsage(input.Prompt)
+	input.Gs.Record(input.CategoryID, jobCommentIntent, usage)
+	if err != nil {
+		panic(fmt.Errorf("failed to generate text: %v", err))
+	}
+
+	results, err := parseIntentResponse(response)
+	if err != nil {
+		panic(err)
+	}
+
+	byID := make(map[int32]map[string]float64, len(results))
+	for _, r := range results {
+		byID[r.ID] = r.Intents
+	}
+
+	for _, comment := range input.Comments {
+		intents, ok := byID[comment.ID]
+		if !ok {
+			// Left unanalyzed, the next runs retry it until it runs out of attempts.
+			logger.Warnf("No intent returned for comment %d", comment.ID)
+			continue
+		}
+
+		label, confidence, signal := strongestIntent(intents)
+		intentsJSON, err := json.Marshal(intents)
+		if err != nil {
+			panic(fmt.Errorf("failed to encode intents: %v", err))
+		}
+
+		err = as.Server.Queries.UpdateCommentIntent(input.Ctx, db.UpdateCommentIntentParams{
+			CommentID:        comment.ID,
+			CategoryID:       input.CategoryID,
+			Intent:           sql.NullString{String: label, Valid: label != ""},
+			IntentConfidence: sql.NullFloat64{Float64: confidence, Valid: label != ""},
+			Intents:          intentsJSON,
+		})
+		if err != nil {
+			panic(fmt.Errorf("failed to update comment intent: %v", err))
+		}
+
+		if signal <= 0 {
+			continue
+		}
+
+		err = as.Server.Queries.UpdateIntentScore(input.Ctx, db.UpdateIntentScoreParams{
+			IntentScore:   signal,
+			UserProfileID: comment.AuthorID,
+			CategoryID:    input.CategoryID,
+		})
+		if err != nil {
+			panic(fmt.Errorf("failed to update intent score: %v", err))
+		}
+	}
+
+	return true
+}
+
+func (as *AnalysisService) logIntentError(ctx context.Context, msg string) {
+	as.Server.Queries.LogAction(ctx, db.LogActionParams{
+		Action: "comment_intent_cronjob",
+		Description: sql.NullString{
+			String: msg,
+			Valid:  true,
+		},
+		TargetID:  sql.NullInt32{Int32: 0, Valid: false},
+		AccountID: sql.NullInt32{Int32: 0, Valid: false},
+	})
+	logger.Error(msg)
+}
+
+// parseIntentResponse accepts the JSON array with or without a markdown fence.
+func parseIntentResponse(response string) ([]commentIntentResult, error) {
+	var results []commentIntentResult
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse intent response: %v", err)
+	}
+	return results, nil
+}
+
+// stripCodeFence removes the markdown fence models tend to wrap JSON in.
+func stripCodeFence(response string) string {
+	text := strings.TrimSpace(response)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
+
+// strongestIntent returns the most confident known label and the buyer signal
+// of the best label by signal weight times confidence.
+func strongestIntent(intents map[string]float64) (string, float64, float64) {
+	label, confidence, signal := "", 0.0, 0.0
+	for l, c := range intents {
+		weight, known := intentSignal[l]
+		if !known || c <= 0 {
+			continue
+		}
+		c = min(c, 1)
+		if c > confidence || (c == confidence && l < label) {
+			label, confidence = l, c
+		}
+		signal = max(signal, weight*c)
+	}
+	return label, confidence, signal
+}
@@ -0,0 +1,105 @@
+package lead
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/async"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/scoring"
+)
+
+type LeadService struct {
+	Server *infras.Server
+}
+
+var logger = lg.GetLogger("LeadCronService")
+
+func (s *LeadService) BlendScoresCronjob() {
+	logger.Info("Starting cron task [BlendScoresCronjob]...")
+	queries := s.Server.Queries
+	ctx := context.Background()
+	limit := s.Server.GetConfig(ctx, "LEAD_SCORING_LIMIT", "500")
+	limitInt, err := strconv.ParseInt(limit, 10, 32)
+	if err != nil {
+		logger.Errorf("invalid LEAD_SCORING_LIMIT: %v", err)
+		return
+	}
+
+	categories, err := queries.GetCategories(ctx)
+	if err != nil {
+		logger.Errorf("failed to get categories: %v", err)
+		return
+	}
+
+	for _, category := range categories {
+		policy, err := s.loadPolicy(ctx, category.ID)
+		if err != nil {
+			logger.Errorf("failed to load scoring policy for category %s: %v", category.Name, err)
+			continue
+		}
+
+		inputs, err := queries.GetLeadScoreInputs(ctx, db.GetLeadScoreInputsParams{
+			CategoryID: category.ID,
+			Limit:      int32(limitInt),
+		})
+		if err != nil {
+			logger.Errorf("failed to get lead score inputs (category %s): %v", category.Name, err)
+			continue
+		}
+
+		if len(inputs) == 0 {
+			continue
+		}
+
+		now := time.Now()
+		sem := async.GetSemaphore[db.UpdateFinalScoreParams, bool](5)
+		updateScore := func(params db.UpdateFinalScoreParams) bool {
+			err := queries.UpdateFinalScore(ctx, params)
+			if err != nil {
+				panic(err)
+			}
+			return true
+		}
+		for _, in := range inputs {
+			score, ok := policy.Final(scoring.NewInput(in.GeminiScore, in.ModelScore, in.IntentScore, in.NonNullCount, in.UpdatedAt), now)
+			sem.Assign(updateScore, db.UpdateFinalScoreParams{
+				UserProfileID: in.UserProfileID,
+				CategoryID:    in.CategoryID,
+				FinalScore: sql.NullFloat64{
+					Float64: score,
+					Valid:   ok,
+				},
+			})
+		}
+		_, errs := sem.Run()
+
+		successCount := 0
+		for _, e := range errs {
+			if e != nil {
+				logger.Errorf("failed to update final score: %v", e)
+			} else {
+				successCount++
+			}
+		}
+
+		logger.Infof("Category %s: Blended %d lead scores, %d successful", category.Name, len(inputs), successCount)
+	}
+
+	logger.Info("Completed BlendScoresCronjob for all categories")
+}
+
+func (s *LeadService) loadPolicy(ctx context.Context, categoryID int32) (scoring.Policy, error) {
+	row, err := s.Server.Queries.GetScoringPolicy(ctx, categoryID)
+	if err == sql.ErrNoRows {
+		return scoring.DefaultPolicy(), nil
+	}
+	if err != nil {
+		return scoring.Policy{}, err
+	}
+	return scoring.PolicyFromRow(row)
+}
@@ -0,0 +1,153 @@
+package ml
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/pkg/drift"
+	"github.com/qxbao/asfpc/pkg/utils/training"
+)
+
+// DriftMonitorCronjob compares the recent scores and inputs of every
+// deployed champion with its training-time distribution and stores the
+// result of the day, raising an alert when a threshold is exceeded.
+func (s *MLService) DriftMonitorCronjob() {
+	logger.Info("Starting cron task [DriftMonitorCronjob]...")
+	queries := s.Server.Queries
+	ctx := context.Background()
+
+	days, err := strconv.Atoi(s.Server.GetConfig(ctx, "DRIFT_WINDOW_DAYS", "7"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+	minSamples, err := strconv.Atoi(s.Server.GetConfig(ctx, "DRIFT_MIN_SAMPLES", "50"))
+	if err != nil || minSamples <= 0 {
+		minSamples = 50
+	}
+	thresholds := drift.Thresholds{
+		PSI:          s.Server.GetFloatConfig(ctx, "DRIFT_PSI_THRESHOLD", 0.2, 0, math.Inf(1)),
+		KS:           s.Server.GetFloatConfig(ctx, "DRIFT_KS_THRESHOLD", 0.15, 0, math.Inf(1)),
+		Completeness: s.Server.GetFloatConfig(ctx, "DRIFT_COMPLETENESS_THRESHOLD", 0.2, 0, math.Inf(1)),
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	categories, err := queries.GetCategories(ctx)
+	if err != nil {
+		logger.Errorf("failed to get categories: %v", err)
+		return
+	}
+
+	for _, category := range categories {
+		deployment, err := queries.GetModelDeployment(ctx, category.ID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger.Errorf("failed to get deployment of category %d: %v", category.ID, err)
+			}
+			continue
+		}
+		if !deployment.ChampionVersionID.Valid {
+			continue
+		}
+		if err := s.measureDrift(ctx, category.ID, deployment.ChampionVersionID.Int32, since, minSamples, thresholds); err != nil {
+			logger.Errorf("failed to measure drift of category %d: %v", category.ID, err)
+		}
+	}
+}
+
+func (s *MLService) measureDrift(ctx context.Context, categoryID, versionID int32, since time.Time, minSamples int, th drift.Thresholds) error {
+	queries := s.Server.Queries
+	version, err := queries.GetModelVersionByID(ctx, versionID)
+	if err != nil {
+		return err
+	}
+	ref, err := drift.ParseReference(version.Metrics)
+	if err != nil {
+		// versions registered before their metrics were stored
+		ref, err = drift.ParseReference(training.ReadMetrics(version.Path, nil))
+		if err != nil {
+			logger.Infof("[Category %d] %s has no reference distribution. Skipping...", categoryID, version.Path)
+			return nil
+		}
+	}
+
+	scores, err := queries.GetRecentModelScores(ctx, db.GetRecentModelScoresParams{
+		CategoryID: categoryID,
+		VersionID:  sql.NullInt32{Int32: versionID, Valid: true},
+		Since:      since,
+	})
+	if err != nil {
+		return err
+	}
+	if len(scores) < minSamples {
+		logger.Infof("[Category %d] only %d recent scores, need %d. Skipping...", categoryID, len(scores), minSamples)
+		return nil
+	}
+
+	counts, err := queries.GetRecentFeatureCompleteness(ctx, db.GetRecentFeatureCompletenessParams{
+		CategoryID: categoryID,
+		VersionID:  sql.NullInt32{Int32: versionID, Valid: true},
+		Since:      since,
+	})
+	if err != nil {
+		return err
+	}
+	completeness := map[string]float64{}
+	if counts.Total > 0 {
+		total := float64(counts.Total)
+		completeness["embedding"] = float64(counts.WithEmbedding) / total
+		completeness["gender"] = float64(counts.WithGender) / total
+		completeness["relationship_status"] = float64(counts.WithRelationshipStatus) / total
+		completeness["locale"] = float64(counts.WithLocale) / total
+	}
+
+	report := drift.Measure(ref, scores, completeness, th)
+	completenessJSON, err := json.Marshal(completeness)
+	if err != nil {
+		return err
+	}
+	alertsJSON, err := json.Marshal(report.Alerts)
+	if err != nil {
+		return err
+	}
+
+	_, err = queries.UpsertModelDrift(ctx, db.UpsertModelDriftParams{
+		CategoryID:        categoryID,
+		VersionID:         sql.NullInt32{Int32: version.ID, Valid: true},
+		SampleSize:        int32(report.SampleSize),
+		ScoreMean:         report.Mean,
+		ScoreStd:          report.Std,
+		Psi:               report.PSI,
+		Ks:                report.KS,
+		Completeness:      db.NullableJSON(completenessJSON),
+		CompletenessDrift: report.CompletenessDrift,
+		Alerts:            db.NullableJSON(alertsJSON),
+		IsAlert:           len(report.Alerts) > 0,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(report.Alerts) == 0 {
+		logger.Infof("[Category %d] %s: PSI %.3f, KS %.3f, no drift", categoryID, version.Path, report.PSI, report.KS)
+		return nil
+	}
+	description := fmt.Sprintf("%s drifted: %s", version.Path, strings.Join(report.Alerts, "; "))
+	logger.Warnf("[Category %d] %s", categoryID, description)
+	err = queries.LogAction(ctx, db.LogActionParams{
+		Action:      "model_drift_alert",
+		TargetID:    sql.NullInt32{Int32: categoryID, Valid: true},
+		Description: sql.NullString{String: description, Valid: true},
+	})
+	if err != nil {
+		logger.Errorf("failed to log drift alert of category %d: %v", categoryID, err)
+	}
+	return nil
+}
@@ -4,24 +4,23 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/qxbao/asfpc/db"
 	"github.com/qxbao/asfpc/infras"
 	"github.com/qxbao/asfpc/pkg/async"
 	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/registry"
 	"github.com/qxbao/asfpc/pkg/utils/python"
+	"github.com/qxbao/asfpc/pkg/utils/training"
 )
 
 type MLService struct {
 	Server *infras.Server
 }
 
-type ScoringResult map[string]float64
-
 var logger = lg.GetLogger("MachineLearningCronService")
 
 func (s *MLService) ScoreProfilesCronjob() {
@@ -34,6 +33,7 @@ func (s *MLService) ScoreProfilesCronjob() {
 		logger.Errorf("invalid ML_SCORING_PROFILE_LIMIT: %v", err)
 		return
 	}
+	explain := strings.ToLower(s.Server.GetConfig(ctx, "ML_SCORING_EXPLAIN_BOOL", "TRUE")) == "true"
 
 	// Get all categories
 	categories, err := queries.GetCategories(ctx)
@@ -51,59 +51,57 @@ func (s *MLService) ScoreProfilesCronjob() {
 	for _, category := range categories {
 		logger.Infof("Processing category: %s (ID: %d)", category.Name, category.ID)
 
-		// Get model for this category from model table
-		model, err := queries.GetModelByCategory(ctx, sql.NullInt32{Int32: category.ID, Valid: true})
+		// Get the champion of this category
+		trainer := training.TrainingService{Server: s.Server}
+		modelName, versionID, deployment, err := trainer.Champion(ctx, category.ID)
 		if err != nil {
 			logger.Infof("No model assigned to category %s. Skipping...", category.Name)
 			continue
 		}
 
-		modelName := model.Name
 		if modelName == "" {
 			logger.Infof("No model configured for category %s. Skipping...", category.Name)
 			continue
 		}
 
-		profiles, err := queries.GetProfilesForScoring(ctx, db.GetProfilesForScoringParams{
-			Cid:   category.ID,
-			Limit: int32(limitInt),
+		// Scores of another version or from outdated inputs are stale
+		candidates, err := queries.GetProfilesForScoring(ctx, db.GetProfilesForScoringParams{
+			CategoryID: category.ID,
+			VersionID:  versionID,
+			PageLimit:  int32(limitInt),
 		})
 		if err != nil {
 			logger.Errorf("failed to get profiles for scoring (category %s): %v", category.Name, err)
 			continue
 		}
 
-		if len(profiles) == 0 {
+		if len(candidates) == 0 {
 			logger.Infof("No profiles to score for category %s. Skipping...", category.Name)
 			continue
 		}
 
-		profileIDs := make([]string, len(profiles))
-		for i, p := range profiles {
-			profileIDs[i] = strconv.Itoa(int(p))
+		profiles := make([]int32, len(candidates))
+		inputHashes := make(map[int32]string, len(candidates))
+		for i, candidate := range candidates {
+			profiles[i] = candidate.ID
+			inputHashes[candidate.ID] = candidate.InputHash
 		}
-		profileIDsStr := strings.Join(profileIDs, ",")
 
 		logger.Infof("Scoring %d profiles for category %s using model %s", len(profiles), category.Name, modelName)
 
-		pythonService := python.NewPythonService(os.Getenv("PYTHON_ENV_NAME"), false, true, nil)
-
-		res, err := pythonService.RunScript("--task=predict",
-			fmt.Sprintf("--targets=%s", profileIDsStr),
-			fmt.Sprintf("--model-name=%s", modelName),
-			fmt.Sprintf("--category-id=%d", category.ID),
-		)
-
+		resData, contributions, err := s.score(ctx, python.PredictParams{
+			Targets:    profiles,
+			ModelName:  modelName,
+			CategoryID: category.ID,
+			Rescore:    true,
+		}, explain)
 		if err != nil {
-			logger.Errorf("failed to run python script for category %s: %v", category.Name, err)
+			logger.Errorf("failed to score profiles for category %s: %v", category.Name, err)
 			continue
 		}
 
-		var resData ScoringResult
-
-		if err := json.Unmarshal([]byte(res), &resData); err != nil {
-			logger.Errorf("failed to unmarshal scoring result for category %s: %v", category.Name, err)
-			continue
+		if deployment.ChallengerVersionID.Valid {
+			s.shadowScore(ctx, deployment, profiles, resData)
 		}
 
 		sem := async.GetSemaphore[db.UpdateModelScoreParams, bool](5)
@@ -115,18 +113,19 @@ func (s *MLService) ScoreProfilesCronjob() {
 			return true
 		}
 		for id, score := range resData {
-			strid, err := strconv.ParseInt(id, 10, 32)
-			if err != nil {
-				logger.Errorf("invalid profile ID from model: %v", err)
-				continue
-			}
 			sem.Assign(updateScore, db.UpdateModelScoreParams{
-				UserProfileID: int32(strid),
+				UserProfileID: id,
 				CategoryID:    category.ID,
 				ModelScore: sql.NullFloat64{
-					Float64: float64(score),
+					Float64: score,
 					Valid:   true,
 				},
+				ModelVersionID: versionID,
+				ModelInputHash: sql.NullString{
+					String: inputHashes[id],
+					Valid:  true,
+				},
+				ModelContributions: contributions[id],
 			})
 		}
 		_, errs := sem.Run()
@@ -145,3 +144,93 @@ func (s *MLService) ScoreProfilesCronjob() {
 
 	logger.Info("Completed ScoreProfilesCronjob for all categories")
 }
+
+// shadowScore scores a sample of the profiles with the challenger and keeps
+// the scores next to the champion's so both can be compared.
+func (s *MLService) shadowScore(ctx context.Context, deployment db.ModelDeployment, profiles []int32, champion map[int32]float64) {
+	challenger, err := s.Server.Queries.GetModelVersionByID(ctx, deployment.ChallengerVersionID.Int32)
+	if err != nil {
+		logger.Warnf("failed to get challenger of category %d: %v", deployment.CategoryID, err)
+		return
+	}
+
+	sample := make([]int32, 0)
+	for _, id := range profiles {
+		if _, ok := champion[id]; ok && registry.Sampled(challenger.ID, id, deployment.ChallengerSampleRate) {
+			sample = append(sample, id)
+		}
+	}
+	if len(sample) == 0 {
+		return
+	}
+
+	scores, err := s.predict(ctx, python.PredictParams{
+		Targets:    sample,
+		ModelName:  challenger.Path,
+		CategoryID: deployment.CategoryID,
+		Shadow:     true,
+	})
+	if err != nil {
+		logger.Errorf("challenger %s failed to score profiles: %v", challenger.Path, err)
+		return
+	}
+
+	for id, score := range scores {
+		err := s.Server.Queries.UpsertShadowScore(ctx, db.UpsertShadowScoreParams{
+			VersionID:     challenger.ID,
+			UserProfileID: id,
+			CategoryID:    deployment.CategoryID,
+			Score:         score,
+			ChampionScore: sql.NullFloat64{Float64: champion[id], Valid: true},
+		})
+		if err != nil {
+			logger.Errorf("failed to save shadow score of profile %d: %v", id, err)
+		}
+	}
+	logger.Infof("Challenger %s shadow-scored %d profiles", challenger.Path, len(scores))
+}
+
+// score scores the profiles with the champion. Explained scores come with
+// the contributions of each input, kept next to the score.
+func (s *MLService) score(ctx context.Context, params python.PredictParams, explain bool) (map[int32]float64, map[int32]db.NullableJSON, error) {
+	if !explain {
+		scores, err := s.predict(ctx, params)
+		return scores, nil, err
+	}
+
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+	predictions, err := s.Server.PythonClient(ctx).Explain(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scores := make(map[int32]float64, len(predictions))
+	contributions := make(map[int32]db.NullableJSON, len(predictions))
+	for id, prediction := range predictions {
+		scores[id] = prediction.Score
+		data, err := json.Marshal(prediction.Contributions)
+		if err != nil {
+			logger.Warnf("failed to encode contributions of profile %d: %v", id, err)
+			continue
+		}
+		contributions[id] = data
+	}
+	return scores, contributions, nil
+}
+
+// predict scores the profiles on the python worker, or in a process of its
+// own when the worker is disabled.
+func (s *MLService) predict(ctx context.Context, params python.PredictParams) (map[int32]float64, error) {
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+	return s.Server.PythonClient(ctx).Predict(ctx, params)
+}
+
+func (s *MLService) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout, err := strconv.Atoi(s.Server.GetConfig(ctx, "PYTHON_WORKER_CALL_TIMEOUT_SEC", "1800"))
+	if err != nil || timeout <= 0 {
+		timeout = 1800
+	}
+	return context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+}
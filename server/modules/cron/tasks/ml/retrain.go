@@ -0,0 +1,173 @@
+package ml
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/registry"
+	"github.com/qxbao/asfpc/pkg/utils/training"
+)
+
+// RetrainModelsCronjob starts a retraining job for every category whose
+// policy is due. Whether the new version is promoted is decided once it is
+// trained.
+func (s *MLService) RetrainModelsCronjob() {
+	logger.Info("Starting cron task [RetrainModelsCronjob]...")
+	queries := s.Server.Queries
+	ctx := context.Background()
+
+	policies, err := queries.GetRetrainPolicies(ctx)
+	if err != nil {
+		logger.Errorf("failed to get retrain policies: %v", err)
+		return
+	}
+
+	timeout, err := strconv.Atoi(s.Server.GetConfig(ctx, "PYTHON_TRAIN_TIMEOUT_SEC", "21600"))
+	if err != nil || timeout <= 0 {
+		timeout = 21600
+	}
+
+	for _, policy := range policies {
+		if !policy.IsEnabled {
+			continue
+		}
+
+		if policy.LastRequestID.Valid {
+			request, err := queries.GetRequestById(ctx, policy.LastRequestID.Int32)
+			if err == nil && !jobs.Status(request.Status).Final() {
+				logger.Infof("Retraining of category %d is still running (request %d). Skipping...", policy.CategoryID, request.ID)
+				continue
+			}
+		}
+
+		count, err := queries.CountLabeledProfiles(ctx, policy.CategoryID)
+		if err != nil {
+			logger.Errorf("failed to count labelled profiles of category %d: %v", policy.CategoryID, err)
+			continue
+		}
+
+		var lastRun time.Time
+		if policy.LastRunAt.Valid {
+			lastRun = policy.LastRunAt.Time
+		}
+		schedule := registry.Schedule{
+			Interval:       time.Duration(policy.IntervalHours) * time.Hour,
+			MinNewProfiles: int64(policy.MinNewProfiles),
+		}
+		reason := schedule.Due(lastRun, count-int64(policy.ProfilesAtLastRun), time.Now())
+		if reason == "" {
+			continue
+		}
+
+		id, err := s.Server.Jobs.Submit(ctx, jobs.Job{
+			Kind:        jobs.KindRetrain,
+			Description: fmt.Sprintf("Retraining %s for category %d...", policy.ModelName, policy.CategoryID),
+			Timeout:     time.Duration(timeout) * time.Second,
+			Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+				return s.retrain(ctx, r, policy)
+			},
+		})
+		if err != nil {
+			s.logRetrain(ctx, policy.CategoryID, fmt.Sprintf("Failed to start retraining %s: %v", policy.ModelName, err))
+			continue
+		}
+
+		err = queries.MarkRetrainPolicyRun(ctx, db.MarkRetrainPolicyRunParams{
+			CategoryID:        policy.CategoryID,
+			ProfilesAtLastRun: int32(count),
+			LastRequestID:     sql.NullInt32{Int32: id, Valid: true},
+		})
+		if err != nil {
+			logger.Errorf("failed to record retraining of category %d: %v", policy.CategoryID, err)
+		}
+		s.logRetrain(ctx, policy.CategoryID, fmt.Sprintf("Retraining %s (request %d): %s", policy.ModelName, id, reason))
+	}
+}
+
+// retrain trains a new version and promotes it when it passes the policy's
+// gate against the champion.
+func (s *MLService) retrain(ctx context.Context, r *jobs.Reporter, policy db.RetrainPolicy) (any, error) {
+	categoryID := policy.CategoryID
+	var trials *int
+	if policy.Trials.Valid {
+		t := int(policy.Trials.Int32)
+		trials = &t
+	}
+
+	trainer := training.TrainingService{Server: s.Server}
+	version, _, err := trainer.Train(ctx, r, training.Params{
+		ModelName:  policy.ModelName,
+		CategoryID: &categoryID,
+		AutoTune:   policy.AutoTune,
+		Trials:     trials,
+	})
+	if err != nil {
+		s.logRetrain(ctx, categoryID, fmt.Sprintf("Retraining %s failed: %v", policy.ModelName, err))
+		return nil, err
+	}
+
+	decision, err := s.gate(ctx, policy, version)
+	if err != nil {
+		s.logRetrain(ctx, categoryID, fmt.Sprintf("%s: failed to compare with the champion: %v", version.Path, err))
+		return nil, err
+	}
+	if decision.Promote {
+		if _, err := trainer.Promote(ctx, version, categoryID); err != nil {
+			s.logRetrain(ctx, categoryID, fmt.Sprintf("%s: failed to promote: %v", version.Path, err))
+			return nil, err
+		}
+	}
+	s.logRetrain(ctx, categoryID, fmt.Sprintf("%s %s", version.Path, decision.Reason))
+
+	return map[string]any{
+		"version":  version,
+		"decision": decision,
+	}, nil
+}
+
+func (s *MLService) gate(ctx context.Context, policy db.RetrainPolicy, version db.ModelVersion) (registry.Decision, error) {
+	queries := s.Server.Queries
+	candidate, err := training.Metrics(version)
+	if err != nil {
+		return registry.Decision{Reason: "kept champion: no usable metrics, " + err.Error()}, nil
+	}
+
+	var champion *registry.Metadata
+	deployment, err := queries.GetModelDeployment(ctx, policy.CategoryID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return registry.Decision{}, err
+	}
+	if err == nil && deployment.ChampionVersionID.Valid {
+		current, err := queries.GetModelVersionByID(ctx, deployment.ChampionVersionID.Int32)
+		if err != nil {
+			return registry.Decision{}, err
+		}
+		metrics, err := training.Metrics(current)
+		if err != nil {
+			return registry.Decision{Reason: "kept champion: its metrics are unknown, promote manually"}, nil
+		}
+		champion = &metrics
+	}
+
+	gate := registry.Gate{RMSEMargin: policy.RmseMargin, R2Margin: policy.R2Margin}
+	return gate.Evaluate(candidate, champion), nil
+}
+
+// logRetrain records a retraining decision in the action log.
+func (s *MLService) logRetrain(ctx context.Context, categoryID int32, description string) {
+	logger.Infof("[Category %d] %s", categoryID, description)
+	err := s.Server.Queries.LogAction(ctx, db.LogActionParams{
+		Action:      "model_retraining",
+		TargetID:    sql.NullInt32{Int32: categoryID, Valid: true},
+		Description: sql.NullString{String: description, Valid: true},
+	})
+	if err != nil {
+		logger.Errorf("failed to log retraining of category %d: %v", categoryID, err)
+	}
+}
@@ -0,0 +1,39 @@
+package request
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/qxbao/asfpc/infras"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+)
+
+type RequestService struct {
+	Server *infras.Server
+}
+
+var logger = lg.GetLogger("RequestCronService")
+
+// PurgeRequestsCronjob deletes the requests finished more than
+// REQUEST_RETENTION_DAYS ago, with the files they left behind.
+func (s *RequestService) PurgeRequestsCronjob() {
+	logger.Info("Starting cron task [PurgeRequestsCronjob]...")
+	if s.Server.Jobs == nil {
+		logger.Warn("Job runner is not started, skipping")
+		return
+	}
+	ctx := context.Background()
+	days, err := strconv.Atoi(s.Server.GetConfig(ctx, "REQUEST_RETENTION_DAYS", "30"))
+	if err != nil || days <= 0 {
+		logger.Errorf("invalid REQUEST_RETENTION_DAYS: %v", err)
+		return
+	}
+
+	n, err := s.Server.Jobs.Purge(ctx, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		logger.Errorf("failed to purge requests: %v", err)
+		return
+	}
+	logger.Infof("Purged %d requests older than %d days", n, days)
+}
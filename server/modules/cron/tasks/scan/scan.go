@@ -3,6 +3,7 @@ package analysis
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -12,14 +13,18 @@ import (
 	"github.com/qxbao/asfpc/infras"
 	"github.com/qxbao/asfpc/pkg/async"
 	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/retry"
+	"github.com/qxbao/asfpc/pkg/trigger"
 	db_utils "github.com/qxbao/asfpc/pkg/utils/db"
 	"github.com/qxbao/asfpc/pkg/utils/client"
 	"github.com/qxbao/asfpc/pkg/utils/facebook"
+	"resty.dev/v3"
 )
 
 
 type ScanService struct {
 	Server infras.Server
+	Rules  trigger.RuleSet
 }
 
 type GroupScanError struct {
@@ -57,6 +62,7 @@ type processCommentInput struct {
 	ScraperId int32
 	Comment   infras.PostComment
 	PostID    int32
+	GroupID   int32
 }
 
 type GroupScanSuccess struct {
@@ -80,6 +86,8 @@ func (s ScanService) ScanAllGroups() {
 		return
 	}
 
+	s.loadTriggerRules(ctx)
+
 	groupLimit, _ := strconv.ParseInt(s.Server.GetConfig(ctx, "FACEBOOK_GROUP_LIMIT", "5"), 10, 32)
 	mainConcurrency, _ := strconv.ParseInt(s.Server.GetConfig(ctx, "SCAN_MAIN_CONCURRENCY", "2"), 10, 32)
 
@@ -172,7 +180,7 @@ func (s ScanService) processPosts(input processPostsInput) PostScanResult {
 	posts, err := fg.GetGroupFeed(&input.Group.GroupID, &map[string]string{
 		"limit": fmt.Sprintf("%d", feedLimit),
 		"order": "chronological",
-	}, client.NewRestyClient)
+	}, s.graphClient(input.Context))
 
 	if err != nil {
 		panic(fmt.Errorf("failed to fetch group feed: %s", err.Error()))
@@ -245,6 +253,19 @@ func (s ScanService) processPost(input processPostInput) bool {
 		GroupID:   input.GroupID,
 	})
 
+	if hits := s.Rules.Evaluate(content); len(hits) > 0 && p.ID != 0 && input.Post.From != nil && input.Post.From.ID != nil {
+		authorID, err := s.createProfile(input.Context, db.CreateProfileParams{
+			FacebookID:  input.Post.From.ID.String(),
+			Name:        db_utils.ToNullString(input.Post.From.Name),
+			ScrapedByID: input.ScraperId,
+		})
+		if err != nil {
+			logger.Errorf("Failed to create profile for post author %s: %v", input.Post.From.ID.String(), err)
+		} else {
+			s.applyTriggerHits(input.Context, authorID, "post", p.ID, hits)
+		}
+	}
+
 	if input.Post.Comments != nil && input.Post.Comments.Data != nil {
 		commentConcurrency, _ := strconv.ParseInt(s.Server.GetConfig(input.Context, "SCAN_COMMENT_CONCURRENCY", "5"), 10, 32)
 		semaphore := async.GetSemaphore[processCommentInput, bool](int(commentConcurrency))
@@ -261,6 +282,7 @@ func (s ScanService) processPost(input processPostInput) bool {
 				Context:   input.Context,
 				Comment:   comment,
 				PostID:    p.ID,
+				GroupID:   input.GroupID,
 			})
 		}
 		_, errs := semaphore.Run()
@@ -289,7 +311,7 @@ func (s ScanService) processComment(input processCommentInput) bool {
 		panic(fmt.Errorf("anonymous comment or invalid comment ID: %v", input.Comment.ID))
 	}
 
-	profile, err := s.Server.Queries.CreateProfile(input.Context, db.CreateProfileParams{
+	profileID, err := s.createProfile(input.Context, db.CreateProfileParams{
 		FacebookID:  input.Comment.From.ID.String(),
 		Name:        db_utils.ToNullString(input.Comment.From.Name),
 		ScrapedByID: input.ScraperId,
@@ -298,6 +320,14 @@ func (s ScanService) processComment(input processCommentInput) bool {
 		panic(fmt.Errorf("failed to create profile for comment author %s: %v", input.Comment.From.ID.String(), err))
 	}
 
+	err = s.Server.Queries.AddProfileToGroupCategories(input.Context, db.AddProfileToGroupCategoriesParams{
+		UserProfileID: profileID,
+		GroupID:       input.GroupID,
+	})
+	if err != nil {
+		panic(fmt.Errorf("failed to attach comment author %d to group categories: %v", profileID, err))
+	}
+
 	parsedTime, err := time.Parse("2006-01-02T15:04:05-0700", *input.Comment.CreatedTime)
 	if err != nil {
 		panic(fmt.Errorf("failed to parse CreatedTime for comment %s: %v", *input.Comment.ID, err))
@@ -308,19 +338,79 @@ func (s ScanService) processComment(input processCommentInput) bool {
 		panic(fmt.Errorf("invalid comment ID format: %s", *input.Comment.ID))
 	}
 
-	_, err = s.Server.Queries.CreateComment(input.Context, db.CreateCommentParams{
+	comment, err := s.Server.Queries.CreateComment(input.Context, db.CreateCommentParams{
 		CommentID: commentID[len(commentID)-1],
 		PostID:    input.PostID,
 		Content:   db_utils.GetStringOrDefault(input.Comment.Message, ""),
-		AuthorID:  profile.ID,
+		AuthorID:  profileID,
 		CreatedAt: parsedTime,
 	})
 	if err != nil {
 		panic(fmt.Errorf("failed to create comment %s: %v", *input.Comment.ID, err))
 	}
+
+	s.applyTriggerHits(input.Context, profileID, "comment", comment.ID, s.Rules.Evaluate(comment.Content))
 	return true
 }
 
+// createProfile returns the ID of the profile of a facebook user, creating it
+// if needed. Users whose profile was merged away resolve to the survivor, so
+// scans don't bring the merged profile back.
+func (s ScanService) createProfile(ctx context.Context, params db.CreateProfileParams) (int32, error) {
+	survivorID, err := s.Server.Queries.GetMergedProfileSurvivor(ctx, params.FacebookID)
+	if err == nil && survivorID.Valid {
+		return survivorID.Int32, nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to resolve merged profile %s: %w", params.FacebookID, err)
+	}
+	profile, err := s.Server.Queries.CreateProfile(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	return profile.ID, nil
+}
+
+func (s *ScanService) loadTriggerRules(ctx context.Context) {
+	rows, err := s.Server.Queries.GetActiveTriggerRules(ctx)
+	if err != nil {
+		logger.Errorf("Failed to load trigger rules: %v", err)
+		return
+	}
+	rules, errs := trigger.Load(rows)
+	for _, err := range errs {
+		logger.Warnf("Skipping trigger rule: %v", err)
+	}
+	s.Rules = rules
+	logger.Infof("Loaded %d trigger rules", len(rules))
+}
+
+// applyTriggerHits attaches the author to every category whose rules matched
+// and records which rule hit.
+func (s ScanService) applyTriggerHits(ctx context.Context, profileID int32, sourceType string, sourceID int32, hits []trigger.Hit) {
+	for _, hit := range hits {
+		err := s.Server.Queries.AddUserProfileCategory(ctx, db.AddUserProfileCategoryParams{
+			UserProfileID: profileID,
+			CategoryID:    hit.CategoryID,
+		})
+		if err != nil {
+			logger.Errorf("Failed to attach profile %d to category %d: %v", profileID, hit.CategoryID, err)
+			continue
+		}
+		_, err = s.Server.Queries.CreateTriggerRuleHit(ctx, db.CreateTriggerRuleHitParams{
+			RuleID:        hit.RuleID,
+			CategoryID:    hit.CategoryID,
+			UserProfileID: profileID,
+			SourceType:    sourceType,
+			SourceID:      sourceID,
+			Matched:       hit.Matched,
+		})
+		if err != nil {
+			logger.Errorf("Failed to record trigger rule hit for profile %d: %v", profileID, err)
+		}
+	}
+}
+
 type processProfileInput struct {
 	Context context.Context
 	Profile db.GetProfilesToScanRow
@@ -377,6 +467,13 @@ func (s ScanService) ScanAllProfiles() {
 	logger.Infof("ScanAllProfiles task completed: %d/%d", successCount, len(profiles))
 }
 
+// graphClient returns a Graph API client factory using the GRAPH_RETRY_* configs.
+func (s ScanService) graphClient(ctx context.Context) func() *resty.Client {
+	return client.WithPolicy(retry.FromConfig(func(key, defaultValue string) string {
+		return s.Server.GetConfig(ctx, key, defaultValue)
+	}, "GRAPH", retry.DefaultPolicy()))
+}
+
 func (s ScanService) processProfileWithSemaphore(input processProfileInput) bool {
 	err := s.processProfile(input.Context, input.Profile)
 	if err != nil {
@@ -389,7 +486,7 @@ func (s ScanService) processProfile(ctx context.Context, profile db.GetProfilesT
 	fg := facebook.FacebookGraph{
 		AccessToken: profile.AccessToken.String,
 	}
-	fetchedProfile, err := fg.GetUserDetails(profile.FacebookID, &map[string]string{}, client.NewRestyClient)
+	fetchedProfile, err := fg.GetUserDetails(profile.FacebookID, &map[string]string{}, s.graphClient(ctx))
 
 	if err != nil {
 		s.Server.Queries.UpdateProfileScanStatus(ctx, profile.ID)
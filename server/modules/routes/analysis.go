@@ -12,12 +12,31 @@ func InitAnalysisRoutes(s *infras.Server) {
 	e.GET("/analysis/profile/list", service.GetProfiles)
 	e.GET("/analysis/profile/stats", service.GetProfileStats)
 	e.GET("/analysis/key/list", service.GetGeminiKeys)
+	e.GET("/analysis/usage", service.GetLLMUsage)
+	e.GET("/analysis/price/list", service.GetLLMPrices)
+	e.GET("/analysis/budget/list", service.GetLLMBudgets)
 	e.GET("/analysis/profile/export", service.ExportProfiles)
 	e.GET("/analysis/profile/similar", service.FindSimilarProfiles)
+	e.GET("/analysis/profile/search", service.SearchProfiles)
+	e.GET("/analysis/profile/:id/explain", service.ExplainProfile)
+	e.GET("/analysis/embedding/index", service.GetEmbeddingIndexes)
+	e.GET("/analysis/persona/list", service.GetPersonas)
+	e.GET("/analysis/profile/duplicate/list", service.GetProfileDuplicates)
+	e.GET("/analysis/profile/merge/list", service.GetProfileMerges)
 	e.POST("/analysis/profile/import", service.ImportProfiles)
 	e.POST("/analysis/profile/category/bulk", service.AddAllProfilesToCategory)
+	e.POST("/analysis/profile/category/backfill", service.BackfillProfileCategories)
+	e.POST("/analysis/embedding/index/rebuild", service.RebuildEmbeddingIndexes)
+	e.POST("/analysis/persona/cluster", service.ClusterPersonas)
+	e.POST("/analysis/profile/dedup", service.DetectDuplicates)
+	e.POST("/analysis/profile/duplicate/:id/dismiss", service.DismissProfileDuplicate)
+	e.POST("/analysis/profile/merge", service.MergeProfiles)
 	e.POST("/analysis/key/add", service.AddGeminiKey)
+	e.PUT("/analysis/price", service.UpsertLLMPrice)
+	e.PUT("/analysis/budget", service.UpsertLLMBudget)
 	e.DELETE("/analysis/key/delete", service.DeleteGeminiKey)
 	e.DELETE("/analysis/profile/delete_scores", service.ResetProfilesModelScore)
 	e.DELETE("/analysis/profile/delete_junk", service.DeleteJunkProfiles)
+	e.DELETE("/analysis/cache", service.ClearLLMCache)
+	e.DELETE("/analysis/budget/:id", service.DeleteLLMBudget)
 }
\ No newline at end of file
@@ -13,8 +13,13 @@ func InitCategoryRoutes(s *infras.Server) {
 	
 	e.GET("/category/list", services.GetCategories)
 	e.GET("/category/group/:id", services.GetGroupCategories)
+	e.GET("/category/rule/:id", services.GetTriggerRules)
+	e.GET("/category/rule/hits", services.GetTriggerRuleHits)
 	e.POST("/category/add", services.AddCategory)
 	e.POST("/category/group", services.AddGroupCategory)
+	e.POST("/category/rule", services.AddTriggerRule)
+	e.PUT("/category/rule/active", services.SetTriggerRuleActive)
+	e.DELETE("/category/rule/:id", services.DeleteTriggerRule)
 	e.DELETE("/category/group", services.DeleteGroupCategory)
 	e.DELETE("/category/delete/:id", services.DeleteCategory)
 	e.PUT("/category/assign", services.UpdateCategory)
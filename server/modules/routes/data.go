@@ -13,6 +13,9 @@ func InitDataRoutes(s *infras.Server) {
 	e.GET("/data/prompt/list", services.GetAllPrompts)
 	e.GET("/data/log/list", services.GetLogs)
 	e.GET("/data/request/:request_id", services.TraceRequest)
+	e.GET("/data/request/:request_id/stream", services.StreamRequest)
+	e.GET("/data/request/:request_id/download", services.DownloadRequest)
+	e.POST("/data/request/:request_id/cancel", services.CancelRequest)
 	e.POST("/data/prompt/add", services.CreatePrompt)
 	e.POST("/data/prompt/rollback", services.RollbackPrompt)
 	e.DELETE("/data/prompt/delete", services.DeletePrompt)
@@ -21,4 +24,5 @@ func InitDataRoutes(s *infras.Server) {
 	e.GET("/data/chart/dashboard", services.GetDashboardStats)
 	e.GET("/data/chart/timeseries", services.GetTimeSeriesData)
 	e.GET("/data/chart/scores", services.GetScoreDistribution)
+	e.GET("/data/chart/spend", services.GetDailySpend)
 }
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/server/modules/routes/services/lead"
+)
+
+func InitLeadRoutes(s *infras.Server) {
+	e := s.Echo
+	services := lead.LeadRoutingService{
+		Server: s,
+	}
+
+	e.GET("/lead/list", services.GetLeads)
+	e.GET("/lead/export", services.ExportLeads)
+	e.GET("/lead/policy/:category_id", services.GetScoringPolicy)
+	e.PUT("/lead/policy", services.UpsertScoringPolicy)
+	e.POST("/lead/calibrate", services.CalibrateLeads)
+	e.POST("/lead/label", services.LabelLead)
+	e.GET("/lead/lookalike", services.GetLookalikes)
+	e.GET("/lead/lookalike/export", services.ExportLookalikes)
+	e.GET("/lead/lookalike/seed/list", services.GetLookalikeSeeds)
+	e.POST("/lead/lookalike/seed", services.UploadLookalikeSeed)
+	e.DELETE("/lead/lookalike/seed/:id", services.DeleteLookalikeSeed)
+}
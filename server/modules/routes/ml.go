@@ -11,7 +11,10 @@ func InitMLRoutes(s *infras.Server) {
 
 	e.GET("/ml/list", service.ListModels)
 	e.GET("/ml/export", service.ExportModel)
+	e.POST("/ml/import", service.ImportModel)
 	e.POST("/ml/train", service.Train)
+	e.POST("/ml/train/cancel", service.CancelTraining)
 	e.DELETE("/ml/delete", service.DeleteModel)
 	e.POST("/ml/sync", service.SyncModels)
+	e.GET("/ml/worker", service.GetWorkerStatus)
 }
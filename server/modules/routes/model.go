@@ -16,4 +16,14 @@ func InitModelRoutes(s *infras.Server) {
 	e.PUT("/model/update", services.UpdateModel)
 	e.DELETE("/model/delete/:id", services.DeleteModel)
 	e.POST("/model/assign", services.AssignModelToCategory)
+	e.GET("/model/version/list/:id", services.GetModelVersions)
+	e.GET("/model/version/:id/lineage", services.GetModelVersionLineage)
+	e.GET("/model/deployment/:category_id", services.GetModelDeployment)
+	e.POST("/model/deployment/promote", services.PromoteModelVersion)
+	e.PUT("/model/deployment/challenger", services.SetModelChallenger)
+	e.GET("/model/retrain/list", services.GetRetrainPolicies)
+	e.PUT("/model/retrain", services.UpsertRetrainPolicy)
+	e.DELETE("/model/retrain/:category_id", services.DeleteRetrainPolicy)
+	e.GET("/model/drift/alerts", services.GetModelDriftAlerts)
+	e.GET("/model/drift/:category_id", services.GetModelDrift)
 }
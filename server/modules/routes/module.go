@@ -19,6 +19,7 @@ var RoutesModule = fx.Module(
 		InitCronRoutes,
 		InitCategoryRoutes,
 		InitModelRoutes,
+		InitLeadRoutes,
 		SyncModelsOnStartup,
 	),
 )
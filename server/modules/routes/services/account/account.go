@@ -1,22 +1,22 @@
 package account
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/qxbao/asfpc/db"
 	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
 	"github.com/qxbao/asfpc/pkg/logger"
 	"github.com/qxbao/asfpc/pkg/utils/client"
 	db_util "github.com/qxbao/asfpc/pkg/utils/db"
 	"github.com/qxbao/asfpc/pkg/utils/facebook"
-	"github.com/qxbao/asfpc/pkg/utils/python"
 )
 
 type AccountRoutingService infras.RoutingService
@@ -199,25 +199,61 @@ func (s *AccountRoutingService) GenAccountsAT(c echo.Context) error {
 			"error": "Invalid request body",
 		})
 	}
-	queries := s.Server.Queries
 	if len(dto.IDs) == 0 {
 		return c.JSON(http.StatusBadRequest, map[string]any{
 			"error": "No account IDs provided",
 		})
 	}
 
+	ids := dto.IDs
+	id, err := s.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindAccountToken,
+		Description: fmt.Sprintf("Generating access tokens for %d accounts...", len(ids)),
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			return s.genAccountsAT(ctx, r, ids)
+		},
+	})
+	if err != nil {
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "Failed to start token generation: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"request_id": id,
+		"message":    "Token generation started",
+	})
+}
+
+func (s *AccountRoutingService) genAccountsAT(ctx context.Context, r *jobs.Reporter, ids []int32) (any, error) {
+	queries := s.Server.Queries
+
 	var wg sync.WaitGroup
-	successCount := make(chan int32, len(dto.IDs))
-	errorIds := make(chan int32, len(dto.IDs))
-	errChan := make(chan error, len(dto.IDs))
-	for _, id := range dto.IDs {
+	var mu sync.Mutex
+	processed, done := 0, 0
+	failures := make([]string, 0)
+	eIds := make([]int32, 0)
+	fail := func(accountId int32, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, err.Error())
+		eIds = append(eIds, accountId)
+	}
+
+	for _, id := range ids {
 		wg.Add(1)
 		go func(accountId int32) {
 			defer wg.Done()
-			account, err := queries.GetAccountById(c.Request().Context(), accountId)
+			defer func() {
+				mu.Lock()
+				done++
+				progress := float64(done) / float64(len(ids))
+				mu.Unlock()
+				r.Progress(progress, "", nil)
+			}()
+			account, err := queries.GetAccountById(ctx, accountId)
 			if err != nil {
-				errChan <- err
-				errorIds <- accountId
+				fail(accountId, err)
 				return
 			}
 			fg := facebook.FacebookGraph{}
@@ -229,46 +265,27 @@ func (s *AccountRoutingService) GenAccountsAT(c echo.Context) error {
 			if err != nil {
 				at, err = fg.GenerateFBAccessTokenIOS(username, account.Password, client.NewRestyClient)
 				if err != nil {
-					errChan <- fmt.Errorf("account ID %d: failed to generate access token: %w", accountId, err)
-					errorIds <- accountId
+					fail(accountId, fmt.Errorf("account ID %d: failed to generate access token: %w", accountId, err))
 					return
 				}
 			}
-			queries.UpdateAccountAccessToken(c.Request().Context(), db.UpdateAccountAccessTokenParams{
+			queries.UpdateAccountAccessToken(ctx, db.UpdateAccountAccessTokenParams{
 				ID:          account.ID,
 				AccessToken: sql.NullString{String: *at, Valid: true},
 			})
-			successCount <- 1
+			mu.Lock()
+			processed++
+			mu.Unlock()
 		}(id)
 	}
 
 	wg.Wait()
-	close(errChan)
-	close(errorIds)
-	close(successCount)
-
-	processed := 0
-	for range successCount {
-		processed++
-	}
 
-	var errors []string
-	for err := range errChan {
-		errors = append(errors, err.Error())
-	}
-
-	var eIds []int32
-	for id := range errorIds {
-		eIds = append(eIds, id)
-	}
-
-	return c.JSON(http.StatusOK, map[string]any{
-		"data": map[string]any{
-			"success_count":  processed,
-			"error_accounts": eIds,
-			"errors":         errors,
-		},
-	})
+	return map[string]any{
+		"success_count":  processed,
+		"error_accounts": eIds,
+		"errors":         failures,
+	}, nil
 }
 
 func (s *AccountRoutingService) LoginAccount(c echo.Context) error {
@@ -278,8 +295,8 @@ func (s *AccountRoutingService) LoginAccount(c echo.Context) error {
 			"error": "Invalid request body",
 		})
 	}
-	pythonService := python.NewPythonService(os.Getenv("PYTHON_ENV_NAME"), false, true, nil)
-	res, err := pythonService.RunScript("--task=login", fmt.Sprintf("--uid=%d", dto.UID))
+	ctx := c.Request().Context()
+	res, err := s.Server.PythonClient(ctx).Login(ctx, dto.UID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{
 			"error": "Failed to execute login script: " + err.Error(),
@@ -297,15 +314,15 @@ func (s *AccountRoutingService) JoinGroup(c echo.Context) error {
 			"error": "Invalid request body",
 		})
 	}
-	pythonService := python.NewPythonService(os.Getenv("PYTHON_ENV_NAME"), false, false, nil)
-	res, err := pythonService.RunScript("--task=joingroup", fmt.Sprintf("--group_id=%d", dto.GID))
+	ctx := c.Request().Context()
+	err := s.Server.PythonClient(ctx).JoinGroup(ctx, dto.GID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]any{
 			"error": "Failed to execute login script: " + err.Error(),
 		})
 	}
 	return c.JSON(http.StatusOK, map[string]any{
-		"data": res,
+		"data": "success",
 	})
 }
 
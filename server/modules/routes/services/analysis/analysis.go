@@ -1,14 +1,18 @@
 package analysis
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
-	"net/http"
+	"fmt"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/qxbao/asfpc/db"
 	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
 	"github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/utils/embedding"
 )
 
 type AnalysisRoutingService infras.RoutingService
@@ -153,8 +157,29 @@ func (as *AnalysisRoutingService) DeleteJunkProfiles(c echo.Context) error {
 	})
 }
 
+// ResetProfilesModelScore clears model scores so they are computed again.
+// With a category_id only that category is reset, stale scores are already
+// picked up by the scoring task on their own.
 func (as *AnalysisRoutingService) ResetProfilesModelScore(c echo.Context) error {
 	queries := as.Server.Queries
+	if categoryIDStr := c.QueryParam("category_id"); categoryIDStr != "" {
+		categoryID, err := strconv.ParseInt(categoryIDStr, 10, 32)
+		if err != nil {
+			return c.JSON(400, map[string]any{
+				"error": "invalid category_id",
+			})
+		}
+		count, err := queries.ResetCategoryModelScores(c.Request().Context(), int32(categoryID))
+		if err != nil {
+			return c.JSON(500, map[string]any{
+				"error": "failed to reset category model scores: " + err.Error(),
+			})
+		}
+		return c.JSON(200, map[string]any{
+			"data": count,
+		})
+	}
+
 	err := queries.ResetProfilesModelScore(c.Request().Context())
 	if err != nil {
 		return c.JSON(500, map[string]any{
@@ -184,25 +209,36 @@ func (as *AnalysisRoutingService) ExportProfiles(c echo.Context) error {
 		})
 	}
 	
-	profiles, err := queries.GetProfilesForExport(c.Request().Context(), int32(categoryID))
+	id, err := as.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindProfileExport,
+		Description: fmt.Sprintf("Exporting profiles of category %d...", categoryID),
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			profiles, err := queries.GetProfilesForExport(ctx, int32(categoryID))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get profiles: %w", err)
+			}
+			if profiles == nil {
+				profiles = make([]db.GetProfilesForExportRow, 0)
+			}
+			r.Progress(0.5, fmt.Sprintf("Writing %d profiles...", len(profiles)), nil)
+
+			name := fmt.Sprintf("profiles_%d.json", categoryID)
+			if err := r.WriteJSON(name, profiles); err != nil {
+				return nil, err
+			}
+			return map[string]any{"file": name, "count": len(profiles)}, nil
+		},
+	})
 	if err != nil {
-		return c.JSON(500, map[string]any{
-			"error": "failed to get profiles: " + err.Error(),
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "failed to start export: " + err.Error(),
 		})
 	}
-	if profiles == nil {
-		profiles = make([]db.GetProfilesForExportRow, 0)
-	}
-
-	c.Response().Header().Set(echo.HeaderContentType, "application/json")
-	c.Response().Header().Set(
-		echo.HeaderContentDisposition,
-		"attachment; filename=data.json",
-	)
 
-	enc := json.NewEncoder(c.Response().Writer)
-	c.Response().WriteHeader(http.StatusOK)
-	return enc.Encode(profiles)
+	return c.JSON(200, map[string]any{
+		"request_id": id,
+		"message":    "Export started",
+	})
 }
 
 func (as *AnalysisRoutingService) GetGeminiKeys(c echo.Context) error {
@@ -253,9 +289,53 @@ func (as *AnalysisRoutingService) ImportProfiles(c echo.Context) error {
 			"error": "Failed to parse JSON: " + err.Error(),
 		})
 	}
-	successCount := 0
-	for _, profile := range profiles {
-		p, err := as.Server.Queries.ImportProfile(c.Request().Context(), db.ImportProfileParams{
+	id, err := as.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindProfileImport,
+		Description: fmt.Sprintf("Importing %d profiles...", len(profiles)),
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			return as.importProfiles(ctx, r, profiles)
+		},
+	})
+	if err != nil {
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "failed to start import: " + err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"request_id": id,
+		"message":    "Import started",
+	})
+}
+
+func (as *AnalysisRoutingService) importProfiles(ctx context.Context, r *jobs.Reporter, profiles []db.GetProfilesForExportRow) (any, error) {
+	// Profiles merged into another one would come back as a new row
+	facebookIDs := make([]string, len(profiles))
+	for i, profile := range profiles {
+		facebookIDs[i] = profile.FacebookID
+	}
+	survivors, err := as.Server.Queries.GetMergedProfileSurvivors(ctx, facebookIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merged profiles: %w", err)
+	}
+	merged := make(map[string]bool, len(survivors))
+	for _, survivor := range survivors {
+		merged[survivor.MergedFacebookID] = true
+	}
+
+	successCount, mergedCount := 0, 0
+	for i, profile := range profiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i%100 == 0 {
+			r.Progress(float64(i)/float64(len(profiles)), fmt.Sprintf("Imported %d/%d profiles", successCount, len(profiles)), nil)
+		}
+		if merged[profile.FacebookID] {
+			mergedCount++
+			continue
+		}
+		p, err := as.Server.Queries.ImportProfile(ctx, db.ImportProfileParams{
 			FacebookID:         profile.FacebookID,
 			Name:               profile.Name,
 			Bio:                profile.Bio,
@@ -278,7 +358,7 @@ func (as *AnalysisRoutingService) ImportProfiles(c echo.Context) error {
 		if err != nil {
 			logger.GetLogger("ARS").Errorf("Failed to import profile for Facebook ID %s: %v", profile.FacebookID, err)
 		}
-		err = as.Server.Queries.UpsertEmbeddedProfiles(c.Request().Context(), db.UpsertEmbeddedProfilesParams{
+		err = as.Server.Queries.UpsertEmbeddedProfiles(ctx, db.UpsertEmbeddedProfilesParams{
 			Pid:       p.ID,
 			Cid:       profile.CategoryID,
 			Embedding: profile.Embedding,
@@ -289,9 +369,7 @@ func (as *AnalysisRoutingService) ImportProfiles(c echo.Context) error {
 		}
 		successCount++
 	}
-	return c.JSON(200, map[string]any{
-		"data": successCount,
-	})
+	return map[string]any{"imported": successCount, "merged": mergedCount, "total": len(profiles)}, nil
 }
 
 func (as *AnalysisRoutingService) FindSimilarProfiles(c echo.Context) error {
@@ -319,10 +397,23 @@ func (as *AnalysisRoutingService) FindSimilarProfiles(c echo.Context) error {
 		})
 	}
 
-	similarProfiles, err := as.Server.Queries.FindSimilarProfiles(c.Request().Context(), db.FindSimilarProfilesParams{
-		Pid:   *dto.ProfileID,
-		Limit: *dto.TopK,
-		Cid:   *dto.CategoryID,
+	ctx := c.Request().Context()
+	indexService := embedding.IndexService{Server: as.Server}
+	ef, err := indexService.ResolveEfSearch(ctx, dto.EfSearch, *dto.TopK)
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	var similarProfiles []db.FindSimilarProfilesRow
+	err = indexService.WithEfSearch(ctx, ef, func(q *db.Queries) error {
+		similarProfiles, err = q.FindSimilarProfiles(ctx, db.FindSimilarProfilesParams{
+			Pid:   *dto.ProfileID,
+			Limit: *dto.TopK,
+			Cid:   *dto.CategoryID,
+		})
+		return err
 	})
 
 	if err != nil {
@@ -364,16 +455,128 @@ func (as *AnalysisRoutingService) AddAllProfilesToCategory(c echo.Context) error
 		})
 	}
 
-	// Add all profiles to the category
-	rowsAffected, err := as.Server.Queries.AddAllProfilesToCategory(c.Request().Context(), *dto.CategoryID)
+	categoryID := *dto.CategoryID
+	id, err := as.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindCategoryBulk,
+		Description: fmt.Sprintf("Adding all profiles to category %d...", categoryID),
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			rowsAffected, err := as.Server.Queries.AddAllProfilesToCategory(ctx, categoryID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add profiles to category: %w", err)
+			}
+			log.Infof("Successfully added %d profiles to category ID %d", rowsAffected, categoryID)
+			return map[string]any{"rows_affected": rowsAffected}, nil
+		},
+	})
 	if err != nil {
-		log.Errorf("Failed to add profiles to category: %v", err)
-		return c.JSON(500, map[string]any{
+		log.Errorf("Failed to start adding profiles to category: %v", err)
+		return c.JSON(jobs.StatusCode(err), map[string]any{
 			"error": "Failed to add profiles to category: " + err.Error(),
 		})
 	}
 
-	log.Infof("Successfully added %d profiles to category ID %d", rowsAffected, *dto.CategoryID)
+	return c.JSON(200, map[string]any{
+		"request_id": id,
+		"message":    "Adding profiles to category",
+	})
+}
+
+// BackfillProfileCategories attaches historical comment authors to the
+// categories of the groups they commented in.
+func (as *AnalysisRoutingService) BackfillProfileCategories(c echo.Context) error {
+	log := logger.GetLogger("BackfillProfileCategories")
+
+	dto := new(infras.BackfillProfileCategoriesDTO)
+	if err := c.Bind(dto); err != nil {
+		log.Errorf("Failed to bind request: %v", err)
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	categoryID := sql.NullInt32{}
+	if dto.CategoryID != nil {
+		if _, err := as.Server.Queries.GetCategoryByID(c.Request().Context(), *dto.CategoryID); err != nil {
+			log.Errorf("Category not found: %v", err)
+			return c.JSON(404, map[string]any{
+				"error": "Category not found",
+			})
+		}
+		categoryID = sql.NullInt32{Int32: *dto.CategoryID, Valid: true}
+	}
+
+	id, err := as.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindCategoryBackfill,
+		Description: "Backfilling profile categories...",
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			rowsAffected, err := as.Server.Queries.BackfillProfileCategoriesFromComments(ctx, categoryID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to backfill profile categories: %w", err)
+			}
+
+			as.Server.Queries.LogAction(ctx, db.LogActionParams{
+				Action: "backfill_profile_categories",
+				Description: sql.NullString{
+					String: fmt.Sprintf("Attached %d comment authors to their group categories", rowsAffected),
+					Valid:  true,
+				},
+				TargetID:  categoryID,
+				AccountID: sql.NullInt32{Valid: false},
+			})
+			log.Infof("Backfilled %d profile categories", rowsAffected)
+			return map[string]any{"rows_affected": rowsAffected}, nil
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to start backfilling profile categories: %v", err)
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "Failed to backfill profile categories: " + err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"request_id": id,
+		"message":    "Backfill started",
+	})
+}
+
+func (as *AnalysisRoutingService) ClearLLMCache(c echo.Context) error {
+	log := logger.GetLogger("ClearLLMCache")
+
+	dto := new(infras.ClearLLMCacheDTO)
+	if err := c.Bind(dto); err != nil {
+		log.Errorf("Failed to bind request: %v", err)
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	model := sql.NullString{}
+	if dto.Model != nil && *dto.Model != "" {
+		model = sql.NullString{String: *dto.Model, Valid: true}
+	}
+
+	rowsAffected, err := as.Server.Queries.DeleteLlmCache(c.Request().Context(), db.DeleteLlmCacheParams{
+		Model:       model,
+		ExpiredOnly: dto.ExpiredOnly,
+	})
+	if err != nil {
+		log.Errorf("Failed to clear LLM cache: %v", err)
+		return c.JSON(500, map[string]any{
+			"error": "Failed to clear LLM cache: " + err.Error(),
+		})
+	}
+
+	as.Server.Queries.LogAction(c.Request().Context(), db.LogActionParams{
+		Action: "clear_llm_cache",
+		Description: sql.NullString{
+			String: fmt.Sprintf("Removed %d cached LLM responses", rowsAffected),
+			Valid:  true,
+		},
+		TargetID:  sql.NullInt32{Valid: false},
+		AccountID: sql.NullInt32{Valid: false},
+	})
+	log.Infof("Removed %d cached LLM responses", rowsAffected)
 
 	return c.JSON(200, map[string]any{
 		"data": rowsAffected,
@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/utils/dedup"
+)
+
+// DetectDuplicates looks for profiles of the same person in the background
+// and records them as merge candidates.
+func (as *AnalysisRoutingService) DetectDuplicates(c echo.Context) error {
+	log := logger.GetLogger("DetectDuplicates")
+
+	dto := new(infras.DetectDuplicatesDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	params := dedup.Params{CategoryIDs: dto.CategoryIDs}
+	id, err := as.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindProfileDedup,
+		Description: "Detecting duplicate profiles...",
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			dedupService := dedup.DedupService{Server: as.Server}
+			return dedupService.Detect(ctx, params, r)
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to start duplicate detection: %v", err)
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "Failed to detect duplicates: " + err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"request_id": id,
+		"message":    "Duplicate detection started",
+	})
+}
+
+func (as *AnalysisRoutingService) GetProfileDuplicates(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	dto := new(infras.GetProfileDuplicatesDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request",
+		})
+	}
+	if dto.Page == nil {
+		dto.Page = new(int32)
+		*dto.Page = 0
+	}
+	if dto.Limit == nil {
+		dto.Limit = new(int32)
+		*dto.Limit = 10
+	}
+	if dto.Status == "" {
+		dto.Status = dedup.StatusPending
+	}
+	if dto.Status != dedup.StatusPending && dto.Status != dedup.StatusDismissed {
+		return c.JSON(400, map[string]any{
+			"error": "status must be pending or dismissed",
+		})
+	}
+	minConfidence := 0.0
+	if dto.MinConfidence != nil {
+		minConfidence = *dto.MinConfidence
+	}
+
+	duplicates, err := as.Server.Queries.GetProfileDuplicates(ctx, db.GetProfileDuplicatesParams{
+		Status:        dto.Status,
+		MinConfidence: minConfidence,
+		PageLimit:     *dto.Limit,
+		PageOffset:    *dto.Page * *dto.Limit,
+	})
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to get duplicates: " + err.Error(),
+		})
+	}
+	count, err := as.Server.Queries.CountProfileDuplicates(ctx, db.CountProfileDuplicatesParams{
+		Status:        dto.Status,
+		MinConfidence: minConfidence,
+	})
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to count duplicates: " + err.Error(),
+		})
+	}
+	if duplicates == nil {
+		duplicates = make([]db.GetProfileDuplicatesRow, 0)
+	}
+
+	return c.JSON(200, map[string]any{
+		"total": count,
+		"data":  duplicates,
+	})
+}
+
+// DismissProfileDuplicate marks a merge candidate as not the same person, so
+// later detections leave it alone.
+func (as *AnalysisRoutingService) DismissProfileDuplicate(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid id: " + err.Error(),
+		})
+	}
+
+	affected, err := as.Server.Queries.UpdateProfileDuplicateStatus(c.Request().Context(), db.UpdateProfileDuplicateStatusParams{
+		ID:     int32(id),
+		Status: dedup.StatusDismissed,
+	})
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to dismiss duplicate: " + err.Error(),
+		})
+	}
+	if affected == 0 {
+		return c.JSON(404, map[string]any{
+			"error": "Duplicate not found",
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": "success",
+	})
+}
+
+// MergeProfiles merges one profile into another, either from a merge
+// candidate or from two profile IDs.
+func (as *AnalysisRoutingService) MergeProfiles(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	dto := new(infras.MergeProfilesDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	params := dedup.MergeParams{CandidateID: dto.CandidateID}
+	switch {
+	case dto.SurvivorID != nil && dto.MergedID != nil:
+		params.SurvivorID, params.MergedID = *dto.SurvivorID, *dto.MergedID
+	case dto.CandidateID != nil && dto.SurvivorID == nil && dto.MergedID == nil:
+		candidate, err := as.Server.Queries.GetProfileDuplicate(ctx, *dto.CandidateID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]any{
+					"error": "Duplicate not found",
+				})
+			}
+			return c.JSON(500, map[string]any{
+				"error": "Failed to get duplicate: " + err.Error(),
+			})
+		}
+		params.SurvivorID, params.MergedID = candidate.ProfileID, candidate.DuplicateID
+	default:
+		return c.JSON(400, map[string]any{
+			"error": "candidate_id or both survivor_id and merged_id are required",
+		})
+	}
+
+	dedupService := dedup.DedupService{Server: as.Server}
+	merge, err := dedupService.Merge(ctx, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, dedup.ErrProfileNotFound), errors.Is(err, dedup.ErrCandidateNotFound):
+			return c.JSON(404, map[string]any{
+				"error": err.Error(),
+			})
+		case errors.Is(err, dedup.ErrCandidateMismatch), errors.Is(err, dedup.ErrCandidateClosed), errors.Is(err, dedup.ErrSameProfile):
+			return c.JSON(400, map[string]any{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(500, map[string]any{
+			"error": fmt.Sprintf("Failed to merge profile %d into %d: %v", params.MergedID, params.SurvivorID, err),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": merge,
+	})
+}
+
+// GetProfileMerges lists past merges, newest first.
+func (as *AnalysisRoutingService) GetProfileMerges(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	dto := new(infras.QueryWithPageDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request",
+		})
+	}
+	if dto.Page == nil {
+		dto.Page = new(int32)
+		*dto.Page = 0
+	}
+	if dto.Limit == nil {
+		dto.Limit = new(int32)
+		*dto.Limit = 10
+	}
+
+	merges, err := as.Server.Queries.GetProfileMerges(ctx, db.GetProfileMergesParams{
+		Limit:  *dto.Limit,
+		Offset: *dto.Page * *dto.Limit,
+	})
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to get merges: " + err.Error(),
+		})
+	}
+	count, err := as.Server.Queries.CountProfileMerges(ctx)
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to count merges: " + err.Error(),
+		})
+	}
+	if merges == nil {
+		merges = make([]db.ProfileMerge, 0)
+	}
+
+	return c.JSON(200, map[string]any{
+		"total": count,
+		"data":  merges,
+	})
+}
@@ -0,0 +1,101 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/utils/embedding"
+)
+
+func (as *AnalysisRoutingService) GetEmbeddingIndexes(c echo.Context) error {
+	indexService := embedding.IndexService{Server: as.Server}
+	indexes, err := indexService.List(c.Request().Context())
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": indexes,
+	})
+}
+
+// RebuildEmbeddingIndexes reindexes or rebuilds the vector indexes in the
+// background, rebuild is needed to apply a new VECTOR_INDEX_* config.
+func (as *AnalysisRoutingService) RebuildEmbeddingIndexes(c echo.Context) error {
+	log := logger.GetLogger("RebuildEmbeddingIndexes")
+	ctx := c.Request().Context()
+
+	dto := new(infras.RebuildEmbeddingIndexDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	mode := embedding.ModeReindex
+	if dto.Mode != "" {
+		mode = embedding.Mode(strings.ToLower(dto.Mode))
+	}
+	if mode != embedding.ModeReindex && mode != embedding.ModeRebuild {
+		return c.JSON(400, map[string]any{
+			"error": "mode must be reindex or rebuild",
+		})
+	}
+
+	indexService := embedding.IndexService{Server: as.Server}
+	if _, err := indexService.Spec(ctx); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid vector index config: " + err.Error(),
+		})
+	}
+	for _, categoryID := range dto.CategoryIDs {
+		if _, err := as.Server.Queries.GetCategoryByID(ctx, categoryID); err != nil {
+			return c.JSON(404, map[string]any{
+				"error": fmt.Sprintf("Category %d not found", categoryID),
+			})
+		}
+	}
+
+	id, err := as.Server.Jobs.Submit(ctx, jobs.Job{
+		Kind:        jobs.KindEmbeddingIndex,
+		Description: "Rebuilding embedding indexes...",
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			report, err := indexService.Rebuild(ctx, mode, dto.CategoryIDs, r)
+			if err != nil {
+				return report, err
+			}
+
+			as.Server.Queries.LogAction(ctx, db.LogActionParams{
+				Action: "rebuild_embedding_indexes",
+				Description: sql.NullString{
+					String: fmt.Sprintf("%s: %d created, %d reindexed, %d dropped",
+						mode, len(report.Created), len(report.Reindexed), len(report.Dropped)),
+					Valid: true,
+				},
+				TargetID:  sql.NullInt32{Valid: false},
+				AccountID: sql.NullInt32{Valid: false},
+			})
+			return report, nil
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to start rebuilding embedding indexes: %v", err)
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "Failed to rebuild embedding indexes: " + err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"request_id": id,
+		"message":    "Index rebuild started",
+	})
+}
@@ -0,0 +1,264 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/budget"
+	"github.com/qxbao/asfpc/pkg/explain"
+	"github.com/qxbao/asfpc/pkg/generative"
+	"github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/retry"
+	"github.com/qxbao/asfpc/pkg/utils/prompt"
+	"github.com/qxbao/asfpc/pkg/utils/python"
+	"github.com/qxbao/asfpc/pkg/utils/training"
+)
+
+const (
+	jobScoreRationale = "score_rationale"
+	rationaleModel    = "gemini-2.5-flash-lite"
+)
+
+// ExplainProfile breaks the model score of a profile down into the
+// contributions of its inputs and asks Gemini for the reasoning behind it.
+// Contributions stored with the score are used when present, otherwise they
+// are computed with the category's champion.
+func (as *AnalysisRoutingService) ExplainProfile(c echo.Context) error {
+	log := logger.GetLogger("ExplainProfile")
+	ctx := c.Request().Context()
+
+	profileID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid profile ID",
+		})
+	}
+
+	dto := new(infras.ExplainProfileDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request",
+		})
+	}
+	if dto.CategoryID == nil {
+		return c.JSON(400, map[string]any{
+			"error": "category_id is required",
+		})
+	}
+
+	profile, err := as.Server.Queries.GetProfileById(ctx, int32(profileID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(404, map[string]any{
+				"error": "Profile not found",
+			})
+		}
+		return c.JSON(500, map[string]any{
+			"error": "Failed to get profile: " + err.Error(),
+		})
+	}
+
+	score, err := as.Server.Queries.GetProfileCategory(ctx, db.GetProfileCategoryParams{
+		UserProfileID: profile.ID,
+		CategoryID:    *dto.CategoryID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(404, map[string]any{
+				"error": "Profile is not in this category",
+			})
+		}
+		return c.JSON(500, map[string]any{
+			"error": "Failed to get profile scores: " + err.Error(),
+		})
+	}
+
+	result := infras.ProfileExplanation{
+		ProfileID:   profile.ID,
+		CategoryID:  score.CategoryID,
+		ModelScore:  nullFloat(score.ModelScore),
+		GeminiScore: nullFloat(score.GeminiScore),
+		FinalScore:  nullFloat(score.FinalScore),
+	}
+	if score.ModelVersionID.Valid {
+		result.ModelVersionID = &score.ModelVersionID.Int32
+	}
+
+	if len(score.ModelContributions) > 0 {
+		contributions, err := explain.Parse(score.ModelContributions)
+		if err != nil {
+			log.Warnf("Profile %d has invalid stored contributions: %v", profile.ID, err)
+		} else {
+			explanation := explain.Rank(contributions)
+			result.Explanation, result.ExplanationSource = &explanation, "stored"
+		}
+	}
+	if result.Explanation == nil {
+		explanation, err := as.explainLive(ctx, profile.ID, score.CategoryID)
+		if err != nil {
+			log.Warnf("Failed to explain profile %d: %v", profile.ID, err)
+		} else if explanation != nil {
+			result.Explanation, result.ExplanationSource = explanation, "live"
+		}
+	}
+
+	if dto.Rationale == nil || *dto.Rationale {
+		rationale, err := as.scoreRationale(ctx, &profile, &result)
+		if err != nil {
+			log.Warnf("Failed to get the rationale of profile %d: %v", profile.ID, err)
+			result.RationaleError = err.Error()
+		} else {
+			result.Rationale = &rationale
+		}
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": result,
+	})
+}
+
+// explainLive computes the contributions with the category's champion. It
+// returns nil when the category has no model.
+func (as *AnalysisRoutingService) explainLive(ctx context.Context, profileID, categoryID int32) (*explain.Explanation, error) {
+	trainer := training.TrainingService{Server: as.Server}
+	modelName, _, _, err := trainer.Champion(ctx, categoryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ctx, cancel := as.pythonContext(ctx)
+	defer cancel()
+
+	predictions, err := as.Server.PythonClient(ctx).Explain(ctx, python.PredictParams{
+		Targets:    []int32{profileID},
+		ModelName:  modelName,
+		CategoryID: categoryID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	prediction, ok := predictions[profileID]
+	if !ok {
+		return nil, fmt.Errorf("model %s returned no score", modelName)
+	}
+	explanation := explain.Rank(prediction.Contributions)
+	return &explanation, nil
+}
+
+// scoreRationale asks Gemini why the profile got its scores. Responses are
+// cached, the same profile and scores are only explained once.
+func (as *AnalysisRoutingService) scoreRationale(ctx context.Context, profile *db.GetProfileByIdRow, result *infras.ProfileExplanation) (string, error) {
+	promptService := prompt.PromptService{Server: as.Server}
+	template, err := promptService.GetPrompt(ctx, "gemini-explain-1", result.CategoryID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get prompt (gemini-explain-1): %v", err)
+	}
+	businessDesc, err := promptService.GetPrompt(ctx, "business-description", result.CategoryID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get prompt (business-description): %v", err)
+	}
+
+	contributions := "N/A"
+	if result.Explanation != nil {
+		contributions = result.Explanation.Describe()
+	}
+	content := promptService.ReplacePrompt(template.Content,
+		businessDesc.Content,
+		profile.Name.String,
+		profile.Location.String,
+		profile.Work.String,
+		profile.Bio.String,
+		profile.Education.String,
+		profile.RelationshipStatus.String,
+		profile.Hometown.String,
+		profile.Locale,
+		profile.Gender.String,
+		profile.Birthday.String,
+		formatScore(result.GeminiScore),
+		formatScore(result.ModelScore),
+		contributions,
+	)
+
+	ttlHours, err := strconv.ParseInt(as.Server.GetConfig(ctx, "LLM_CACHE_TTL_HOURS", "168"), 10, 32)
+	if err != nil || ttlHours <= 0 {
+		ttlHours = 168
+	}
+	var cache *generative.ResponseCache
+	if strings.ToLower(as.Server.GetConfig(ctx, "LLM_CACHE_ENABLED_BOOL", "TRUE")) == "true" {
+		cache = generative.NewResponseCache(as.Server.Queries, time.Duration(ttlHours)*time.Hour)
+	}
+	log := logger.GetLogger("ExplainProfile")
+	defer func() {
+		if err := cache.SaveStats(ctx); err != nil {
+			log.Warnf("Failed to save LLM cache stats: %v", err)
+		}
+	}()
+
+	if rationale, ok := cache.Get(ctx, rationaleModel, content); ok {
+		return rationale, nil
+	}
+	if err := budget.Check(ctx, as.Server.Queries, jobScoreRationale, result.CategoryID); err != nil {
+		return "", err
+	}
+
+	apiKey, err := as.Server.Queries.GetGeminiKeyForUse(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gemini key: %v", err)
+	}
+	gs := generative.GetGenerativeService(apiKey.ApiKey, rationaleModel)
+	gs.Retry = retry.FromConfig(func(key, defaultValue string) string {
+		return as.Server.GetConfig(ctx, key, defaultValue)
+	}, "GEMINI", retry.DefaultPolicy())
+	if err := gs.Init(); err != nil {
+		return "", fmt.Errorf("failed to initialize generative service: %v", err)
+	}
+
+	response, usage, err := gs.GenerateTextWithUsage(content)
+	gs.Record(result.CategoryID, jobScoreRationale, usage)
+	if saveErr := gs.SaveUsage(ctx, as.Server.Queries); saveErr != nil {
+		log.Warnf("Failed to save LLM usage: %v", saveErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate text: %v", err)
+	}
+
+	rationale := strings.TrimSpace(response)
+	if err := cache.Set(ctx, gs.Model, content, rationale); err != nil {
+		log.Warnf("Failed to cache rationale: %v", err)
+	}
+	return rationale, nil
+}
+
+// pythonContext bounds a call to the python worker by its call timeout.
+func (as *AnalysisRoutingService) pythonContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout, err := strconv.Atoi(as.Server.GetConfig(ctx, "PYTHON_WORKER_CALL_TIMEOUT_SEC", "1800"))
+	if err != nil || timeout <= 0 {
+		timeout = 1800
+	}
+	return context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+}
+
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func formatScore(v *float64) string {
+	if v == nil {
+		return "N/A"
+	}
+	return strconv.FormatFloat(*v, 'f', 3, 64)
+}
@@ -0,0 +1,140 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/utils/persona"
+)
+
+const (
+	maxPersonas       = 50
+	maxPersonaMembers = 50
+)
+
+// ClusterPersonas groups the profiles of a category into personas in the
+// background, replacing the previous ones.
+func (as *AnalysisRoutingService) ClusterPersonas(c echo.Context) error {
+	log := logger.GetLogger("ClusterPersonas")
+	ctx := c.Request().Context()
+
+	dto := new(infras.ClusterPersonasDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+	if dto.CategoryID == nil {
+		return c.JSON(400, map[string]any{
+			"error": "category_id is required",
+		})
+	}
+	if _, err := as.Server.Queries.GetCategoryByID(ctx, *dto.CategoryID); err != nil {
+		return c.JSON(404, map[string]any{
+			"error": "Category not found",
+		})
+	}
+
+	k, err := strconv.Atoi(as.Server.GetConfig(ctx, "PERSONA_CLUSTERS", "8"))
+	if err != nil {
+		k = 8
+	}
+	if dto.K != nil {
+		k = *dto.K
+	}
+	if k < 2 || k > maxPersonas {
+		return c.JSON(400, map[string]any{
+			"error": fmt.Sprintf("k must be between 2 and %d", maxPersonas),
+		})
+	}
+
+	params := persona.Params{
+		CategoryID: *dto.CategoryID,
+		K:          k,
+		Label:      dto.Label == nil || *dto.Label,
+	}
+	id, err := as.Server.Jobs.Submit(ctx, jobs.Job{
+		Kind:        jobs.KindPersonaCluster,
+		Description: fmt.Sprintf("Clustering profiles of category %d into %d personas...", params.CategoryID, k),
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			personaService := persona.PersonaService{Server: as.Server}
+			return personaService.Cluster(ctx, params, r)
+		},
+	})
+	if err != nil {
+		log.Errorf("Failed to start clustering personas: %v", err)
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "Failed to cluster personas: " + err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"request_id": id,
+		"message":    "Clustering started",
+	})
+}
+
+// GetPersonas lists the personas of a category with their size, average
+// scores and the members closest to their centroid.
+func (as *AnalysisRoutingService) GetPersonas(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	dto := new(infras.GetPersonasDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request",
+		})
+	}
+	if dto.CategoryID == nil {
+		return c.JSON(400, map[string]any{
+			"error": "category_id is required",
+		})
+	}
+
+	members := int32(5)
+	if dto.Members != nil {
+		if *dto.Members < 0 || *dto.Members > maxPersonaMembers {
+			return c.JSON(400, map[string]any{
+				"error": fmt.Sprintf("members must be between 0 and %d", maxPersonaMembers),
+			})
+		}
+		members = *dto.Members
+	}
+
+	personas, err := as.Server.Queries.GetPersonas(ctx, *dto.CategoryID)
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to get personas: " + err.Error(),
+		})
+	}
+
+	summaries := make([]infras.PersonaSummary, len(personas))
+	for i, p := range personas {
+		summaries[i] = infras.PersonaSummary{GetPersonasRow: p, Members: make([]db.GetPersonaMembersRow, 0)}
+		if members == 0 {
+			continue
+		}
+		rows, err := as.Server.Queries.GetPersonaMembers(ctx, db.GetPersonaMembersParams{
+			PersonaID: p.ID,
+			PageLimit: members,
+		})
+		if err != nil {
+			return c.JSON(500, map[string]any{
+				"error": "Failed to get persona members: " + err.Error(),
+			})
+		}
+		if rows != nil {
+			summaries[i].Members = rows
+		}
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": summaries,
+	})
+}
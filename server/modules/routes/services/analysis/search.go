@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/utils/embedding"
+	"github.com/qxbao/asfpc/pkg/utils/python"
+)
+
+const maxSearchResults = 100
+
+// SearchProfiles finds the profiles of a category closest to a free-text
+// query. The query is embedded with the category's embedding model and
+// compared with the profile embeddings.
+func (as *AnalysisRoutingService) SearchProfiles(c echo.Context) error {
+	log := logger.GetLogger("SearchProfiles")
+	ctx := c.Request().Context()
+
+	dto := new(infras.SearchProfilesDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request",
+		})
+	}
+
+	query := strings.TrimSpace(dto.Query)
+	if query == "" {
+		return c.JSON(400, map[string]any{
+			"error": "q is required",
+		})
+	}
+	if dto.CategoryID == nil {
+		return c.JSON(400, map[string]any{
+			"error": "category_id is required",
+		})
+	}
+
+	limit := int32(20)
+	if dto.TopK != nil {
+		if *dto.TopK < 1 || *dto.TopK > maxSearchResults {
+			return c.JSON(400, map[string]any{
+				"error": fmt.Sprintf("top_k must be between 1 and %d", maxSearchResults),
+			})
+		}
+		limit = *dto.TopK
+	}
+
+	params := db.SearchProfilesByEmbeddingParams{
+		CategoryID: *dto.CategoryID,
+		PageLimit:  limit,
+	}
+	if dto.Location != nil && strings.TrimSpace(*dto.Location) != "" {
+		params.Location = sql.NullString{String: strings.TrimSpace(*dto.Location), Valid: true}
+	}
+	if dto.MinScore != nil {
+		params.MinScore = sql.NullFloat64{Float64: *dto.MinScore, Valid: true}
+	}
+	var err error
+	if params.ScannedAfter, err = parseSearchDate(dto.ScannedAfter); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid scanned_after: " + err.Error(),
+		})
+	}
+	if params.ScannedBefore, err = parseSearchDate(dto.ScannedBefore); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid scanned_before: " + err.Error(),
+		})
+	}
+
+	indexService := embedding.IndexService{Server: as.Server}
+	ef, err := indexService.ResolveEfSearch(ctx, dto.EfSearch, limit)
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	vector, err := as.embedQuery(ctx, query, *dto.CategoryID)
+	if err != nil {
+		log.Errorf("Failed to embed query: %v", err)
+		return c.JSON(500, map[string]any{
+			"error": "Failed to embed query: " + err.Error(),
+		})
+	}
+	params.Query = db.Vector(vector)
+
+	var profiles []db.SearchProfilesByEmbeddingRow
+	err = indexService.WithEfSearch(ctx, ef, func(q *db.Queries) error {
+		profiles, err = q.SearchProfilesByEmbedding(ctx, params)
+		return err
+	})
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to search profiles: " + err.Error(),
+		})
+	}
+
+	if profiles == nil {
+		profiles = make([]db.SearchProfilesByEmbeddingRow, 0)
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": profiles,
+	})
+}
+
+func (as *AnalysisRoutingService) embedQuery(ctx context.Context, query string, categoryID int32) ([]float32, error) {
+	ctx, cancel := as.pythonContext(ctx)
+	defer cancel()
+
+	return as.Server.PythonClient(ctx).EmbedText(ctx, python.EmbedTextParams{
+		Text:       query,
+		CategoryID: categoryID,
+	})
+}
+
+// parseSearchDate reads a date or a RFC 3339 timestamp, empty means no
+// filter.
+func parseSearchDate(v *string) (sql.NullTime, error) {
+	if v == nil || *v == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, *v); err == nil {
+			return sql.NullTime{Time: t, Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339, got %q", *v)
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/budget"
+)
+
+func (as *AnalysisRoutingService) GetLLMUsage(c echo.Context) error {
+	dto := new(infras.GetLLMUsageDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	days := int32(30)
+	if dto.Days != nil {
+		if *dto.Days <= 0 {
+			return c.JSON(400, map[string]any{
+				"error": "days must be positive",
+			})
+		}
+		days = *dto.Days
+	}
+
+	usage, err := as.Server.Queries.GetLlmUsageSummary(c.Request().Context(), days)
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to get llm usage: " + err.Error(),
+		})
+	}
+
+	if usage == nil {
+		usage = make([]db.GetLlmUsageSummaryRow, 0)
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": usage,
+	})
+}
+
+func (as *AnalysisRoutingService) GetLLMPrices(c echo.Context) error {
+	prices, err := as.Server.Queries.GetLlmPrices(c.Request().Context())
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to get llm prices: " + err.Error(),
+		})
+	}
+
+	if prices == nil {
+		prices = make([]db.LlmPrice, 0)
+	}
+
+	return c.JSON(200, map[string]any{
+		"data":  prices,
+		"total": len(prices),
+	})
+}
+
+func (as *AnalysisRoutingService) UpsertLLMPrice(c echo.Context) error {
+	dto := new(infras.UpsertLLMPriceDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if dto.Model == "" {
+		return c.JSON(400, map[string]any{
+			"error": "model is required",
+		})
+	}
+
+	if dto.InputPrice < 0 || dto.OutputPrice < 0 {
+		return c.JSON(400, map[string]any{
+			"error": "prices must not be negative",
+		})
+	}
+
+	if dto.Provider == "" {
+		dto.Provider = "gemini"
+	}
+
+	price, err := as.Server.Queries.UpsertLlmPrice(c.Request().Context(), db.UpsertLlmPriceParams{
+		Model:       dto.Model,
+		Provider:    dto.Provider,
+		InputPrice:  dto.InputPrice,
+		OutputPrice: dto.OutputPrice,
+	})
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to save llm price: " + err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": price,
+	})
+}
+
+// GetLLMBudgets lists the budgets with the usage of their current window
+func (as *AnalysisRoutingService) GetLLMBudgets(c echo.Context) error {
+	budgets, err := as.Server.Queries.GetLlmBudgetUsage(c.Request().Context())
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to get llm budgets: " + err.Error(),
+		})
+	}
+
+	if budgets == nil {
+		budgets = make([]db.GetLlmBudgetUsageRow, 0)
+	}
+
+	return c.JSON(200, map[string]any{
+		"data":  budgets,
+		"total": len(budgets),
+	})
+}
+
+func (as *AnalysisRoutingService) UpsertLLMBudget(c echo.Context) error {
+	dto := new(infras.UpsertLLMBudgetDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !budget.ValidPeriod(dto.Period) {
+		return c.JSON(400, map[string]any{
+			"error": "period must be daily or monthly",
+		})
+	}
+
+	if dto.MaxTokens == nil && dto.MaxCost == nil {
+		return c.JSON(400, map[string]any{
+			"error": "at least one of max_tokens or max_cost is required",
+		})
+	}
+
+	if (dto.MaxTokens != nil && *dto.MaxTokens < 0) || (dto.MaxCost != nil && *dto.MaxCost < 0) {
+		return c.JSON(400, map[string]any{
+			"error": "limits must not be negative",
+		})
+	}
+
+	params := db.UpsertLlmBudgetParams{
+		Period:   dto.Period,
+		IsActive: dto.IsActive == nil || *dto.IsActive,
+	}
+	if dto.CategoryID != nil {
+		params.CategoryID = sql.NullInt32{Int32: *dto.CategoryID, Valid: true}
+	}
+	if dto.MaxTokens != nil {
+		params.MaxTokens = sql.NullInt64{Int64: *dto.MaxTokens, Valid: true}
+	}
+	if dto.MaxCost != nil {
+		params.MaxCost = sql.NullFloat64{Float64: *dto.MaxCost, Valid: true}
+	}
+
+	b, err := as.Server.Queries.UpsertLlmBudget(c.Request().Context(), params)
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to save llm budget: " + err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": b,
+	})
+}
+
+func (as *AnalysisRoutingService) DeleteLLMBudget(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "invalid id: " + err.Error(),
+		})
+	}
+
+	affected, err := as.Server.Queries.DeleteLlmBudget(c.Request().Context(), int32(id))
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to delete llm budget: " + err.Error(),
+		})
+	}
+
+	if affected == 0 {
+		return c.JSON(404, map[string]any{
+			"error": "budget not found",
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": "success",
+	})
+}
@@ -1,13 +1,18 @@
 package category
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/qxbao/asfpc/db"
 	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/utils/embedding"
 )
 
 type CategoryRoutingService infras.RoutingService
@@ -49,6 +54,11 @@ func (s *CategoryRoutingService) AddCategory(c echo.Context) error {
 		})
 	}
 	
+	s.submitIndexJob(c.Request().Context(), fmt.Sprintf("Indexing embeddings of category %d...", category.ID),
+		func(ctx context.Context, is *embedding.IndexService) error {
+			return is.Ensure(ctx, category.ID)
+		})
+
 	return c.JSON(http.StatusOK, map[string]any{
 		"data": category,
 	})
@@ -77,11 +87,31 @@ func (s *CategoryRoutingService) DeleteCategory(c echo.Context) error {
 		})
 	}
 
+	s.submitIndexJob(c.Request().Context(), fmt.Sprintf("Dropping embedding index of category %d...", categoryIdInt),
+		func(ctx context.Context, is *embedding.IndexService) error {
+			return is.Drop(ctx, int32(categoryIdInt))
+		})
+
 	return c.JSON(http.StatusOK, map[string]any{
 		"message": "Category deleted successfully",
 	})
 }
 
+// submitIndexJob keeps the vector index of a category in sync with it. The
+// category change stands if the job fails, the index admin endpoint fixes it.
+func (s *CategoryRoutingService) submitIndexJob(ctx context.Context, description string, run func(ctx context.Context, is *embedding.IndexService) error) {
+	_, err := s.Server.Jobs.Submit(ctx, jobs.Job{
+		Kind:        jobs.KindEmbeddingIndex,
+		Description: description,
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			return nil, run(ctx, &embedding.IndexService{Server: s.Server})
+		},
+	})
+	if err != nil {
+		logger.GetLogger("CategoryRoutingService").Warnf("Failed to submit index job: %v", err)
+	}
+}
+
 func (s *CategoryRoutingService) UpdateCategory(c echo.Context) error {
 	dto := new(infras.UpdateCategoryRequest)
 	if err := c.Bind(dto); err != nil {
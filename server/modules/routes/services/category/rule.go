@@ -0,0 +1,195 @@
+package category
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/trigger"
+)
+
+func (s *CategoryRoutingService) GetTriggerRules(c echo.Context) error {
+	categoryID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid category ID",
+		})
+	}
+
+	rules, err := s.Server.Queries.GetTriggerRulesByCategory(c.Request().Context(), int32(categoryID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to get trigger rules: " + err.Error(),
+		})
+	}
+
+	if rules == nil {
+		rules = make([]db.TriggerRule, 0)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": rules,
+	})
+}
+
+func (s *CategoryRoutingService) AddTriggerRule(c echo.Context) error {
+	dto := new(infras.AddTriggerRuleRequest)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if dto.Language != "" && dto.Language != trigger.LanguageVietnamese && dto.Language != trigger.LanguageEnglish {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "language must be vi, en or empty",
+		})
+	}
+
+	params := db.CreateTriggerRuleParams{
+		CategoryID:       dto.CategoryID,
+		Name:             dto.Name,
+		Keywords:         nonNil(dto.Keywords),
+		Patterns:         nonNil(dto.Patterns),
+		NegativeKeywords: nonNil(dto.NegativeKeywords),
+		Language: sql.NullString{
+			String: dto.Language,
+			Valid:  dto.Language != "",
+		},
+		IsActive: true,
+	}
+
+	// Compile before saving so broken regexes never reach the scanner
+	r, err := trigger.Compile(db.TriggerRule{
+		Keywords:         params.Keywords,
+		Patterns:         params.Patterns,
+		NegativeKeywords: params.NegativeKeywords,
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	if len(r.Keywords) == 0 && len(r.Patterns) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "at least one keyword or pattern is required",
+		})
+	}
+
+	rule, err := s.Server.Queries.CreateTriggerRule(c.Request().Context(), params)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to create trigger rule: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": rule,
+	})
+}
+
+func (s *CategoryRoutingService) SetTriggerRuleActive(c echo.Context) error {
+	dto := new(infras.SetTriggerRuleActiveRequest)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	err := s.Server.Queries.SetTriggerRuleActive(c.Request().Context(), db.SetTriggerRuleActiveParams{
+		ID:       dto.ID,
+		IsActive: dto.IsActive,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to update trigger rule: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": "Trigger rule updated successfully",
+	})
+}
+
+func (s *CategoryRoutingService) DeleteTriggerRule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid trigger rule ID",
+		})
+	}
+
+	err = s.Server.Queries.DeleteTriggerRule(c.Request().Context(), int32(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to delete trigger rule: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": "Trigger rule deleted successfully",
+	})
+}
+
+func (s *CategoryRoutingService) GetTriggerRuleHits(c echo.Context) error {
+	dto := new(infras.QueryWithPageDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if dto.CategoryID == nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "category_id is required",
+		})
+	}
+
+	if dto.Page == nil {
+		dto.Page = new(int32)
+		*dto.Page = 0
+	}
+
+	if dto.Limit == nil {
+		dto.Limit = new(int32)
+		*dto.Limit = 10
+	}
+
+	hits, err := s.Server.Queries.GetTriggerRuleHits(c.Request().Context(), db.GetTriggerRuleHitsParams{
+		CategoryID: *dto.CategoryID,
+		Limit:      *dto.Limit,
+		Offset:     *dto.Page * *dto.Limit,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to get trigger rule hits: " + err.Error(),
+		})
+	}
+
+	if hits == nil {
+		hits = make([]db.GetTriggerRuleHitsRow, 0)
+	}
+
+	count, err := s.Server.Queries.CountTriggerRuleHits(c.Request().Context(), *dto.CategoryID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to count trigger rule hits: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data":  hits,
+		"total": count,
+	})
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -1,11 +1,18 @@
 package data
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"path/filepath"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/qxbao/asfpc/db"
 	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/progress"
 )
 
 type DataRoutingService infras.RoutingService
@@ -46,6 +53,143 @@ func (ds *DataRoutingService) TraceRequest(c echo.Context) error {
 	})
 }
 
+// StreamRequest pushes a request's updates as Server-Sent Events. The current
+// row is sent first, the stream ends once the request is done or failed.
+func (ds *DataRoutingService) StreamRequest(c echo.Context) error {
+	dto := new(infras.TraceRequestDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Subscribe before reading the row so no update falls in between
+	events, cancel := ds.Server.Progress.Subscribe(dto.RequestID)
+	defer cancel()
+
+	trace, err := ds.Server.Queries.GetRequestById(c.Request().Context(), dto.RequestID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "request not found",
+		})
+	} else if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to trace request: " + err.Error(),
+		})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	current := progress.Event{
+		RequestID:    trace.ID,
+		Status:       trace.Status,
+		Progress:     trace.Progress,
+		Description:  trace.Description.String,
+		ErrorMessage: trace.ErrorMessage.String,
+		UpdatedAt:    trace.UpdatedAt.Time,
+		Final:        jobs.Status(trace.Status).Final(),
+	}
+	if err := writeEvent(res, current); err != nil || current.Final {
+		return nil
+	}
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(res, e); err != nil || e.Final {
+				return nil
+			}
+		}
+	}
+}
+
+func writeEvent(res *echo.Response, e progress.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(res, "event: progress\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+func (ds *DataRoutingService) CancelRequest(c echo.Context) error {
+	dto := new(infras.TraceRequestDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !ds.Server.Jobs.Cancel(dto.RequestID) {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "request is not queued or running",
+		})
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": "success",
+	})
+}
+
+// DownloadRequest serves the file written by a finished export request.
+func (ds *DataRoutingService) DownloadRequest(c echo.Context) error {
+	dto := new(infras.TraceRequestDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	trace, err := ds.Server.Queries.GetRequestById(c.Request().Context(), dto.RequestID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "request not found",
+		})
+	} else if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to trace request: " + err.Error(),
+		})
+	}
+
+	if jobs.Status(trace.Status) != jobs.StatusDone {
+		return c.JSON(http.StatusConflict, map[string]any{
+			"error": "request is not done",
+		})
+	}
+
+	var result struct {
+		File string `json:"file"`
+	}
+	if err := json.Unmarshal(trace.Result, &result); err != nil || result.File == "" {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "request has no file",
+		})
+	}
+
+	name := filepath.Base(result.File)
+	return c.Attachment(filepath.Join(ds.Server.Jobs.Path(trace.ID), name), name)
+}
+
 func (ds *DataRoutingService) GetAllPrompts(c echo.Context) error {
 	queries := ds.Server.Queries
 	dto := new(infras.QueryWithPageDTO)
@@ -254,6 +398,33 @@ func (ds *DataRoutingService) GetTimeSeriesData(c echo.Context) error {
 	})
 }
 
+// GetDailySpend returns the LLM tokens and estimated cost per day for a category
+func (ds *DataRoutingService) GetDailySpend(c echo.Context) error {
+	queries := ds.Server.Queries
+	dto := new(infras.ChartRequestDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	data, err := queries.GetDailySpend(c.Request().Context(), sql.NullInt32{Int32: dto.CategoryID, Valid: true})
+
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "failed to get daily spend: " + err.Error(),
+		})
+	}
+
+	if data == nil {
+		data = make([]db.GetDailySpendRow, 0)
+	}
+
+	return c.JSON(200, map[string]any{
+		"data": data,
+	})
+}
+
 type Data struct {
 	Range       string  `json:"range"`
 	GeminiScore float64 `json:"gemini_score"`
@@ -0,0 +1,353 @@
+package lead
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/scoring"
+)
+
+type LeadRoutingService infras.RoutingService
+
+func (s *LeadRoutingService) GetLeads(c echo.Context) error {
+	dto := new(infras.GetLeadsDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if dto.CategoryID == nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "category_id is required",
+		})
+	}
+
+	if dto.Page == nil {
+		dto.Page = new(int32)
+		*dto.Page = 0
+	}
+
+	if dto.Limit == nil {
+		dto.Limit = new(int32)
+		*dto.Limit = 10
+	}
+
+	minScore := 0.0
+	if dto.MinScore != nil {
+		minScore = *dto.MinScore
+	}
+
+	queries := s.Server.Queries
+	leads, err := queries.GetLeads(c.Request().Context(), db.GetLeadsParams{
+		CategoryID: *dto.CategoryID,
+		MinScore:   minScore,
+		Ascending:  dto.Order == "asc",
+		PageLimit:  *dto.Limit,
+		PageOffset: *dto.Page * *dto.Limit,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to get leads: " + err.Error(),
+		})
+	}
+
+	if leads == nil {
+		leads = make([]db.GetLeadsRow, 0)
+	}
+
+	count, err := queries.CountLeads(c.Request().Context(), db.CountLeadsParams{
+		CategoryID: *dto.CategoryID,
+		MinScore:   minScore,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to count leads: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data":  leads,
+		"total": count,
+	})
+}
+
+func (s *LeadRoutingService) ExportLeads(c echo.Context) error {
+	dto := new(infras.ExportLeadsDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if dto.CategoryID == nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "category_id is required",
+		})
+	}
+
+	minScore := 0.0
+	if dto.MinScore != nil {
+		minScore = *dto.MinScore
+	}
+
+	categoryID := *dto.CategoryID
+	queries := s.Server.Queries
+	id, err := s.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindLeadExport,
+		Description: fmt.Sprintf("Exporting leads of category %d...", categoryID),
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			count, err := queries.CountLeads(ctx, db.CountLeadsParams{
+				CategoryID: categoryID,
+				MinScore:   minScore,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to count leads: %w", err)
+			}
+
+			leads, err := queries.GetLeads(ctx, db.GetLeadsParams{
+				CategoryID: categoryID,
+				MinScore:   minScore,
+				Ascending:  false,
+				PageLimit:  int32(count),
+				PageOffset: 0,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get leads: %w", err)
+			}
+
+			if leads == nil {
+				leads = make([]db.GetLeadsRow, 0)
+			}
+			r.Progress(0.5, fmt.Sprintf("Writing %d leads...", len(leads)), nil)
+
+			name := fmt.Sprintf("leads_%d.json", categoryID)
+			if err := r.WriteJSON(name, leads); err != nil {
+				return nil, err
+			}
+			return map[string]any{"file": name, "count": len(leads)}, nil
+		},
+	})
+	if err != nil {
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "failed to start export: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"request_id": id,
+		"message":    "Export started",
+	})
+}
+
+func (s *LeadRoutingService) GetScoringPolicy(c echo.Context) error {
+	categoryID, err := strconv.ParseInt(c.Param("category_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "invalid category_id: " + err.Error(),
+		})
+	}
+
+	policy, err := s.Server.Queries.GetScoringPolicy(c.Request().Context(), int32(categoryID))
+	if err == sql.ErrNoRows {
+		def := scoring.DefaultPolicy()
+		return c.JSON(http.StatusOK, map[string]any{
+			"data": db.ScoringPolicy{
+				CategoryID:         int32(categoryID),
+				GeminiWeight:       def.GeminiWeight,
+				ModelWeight:        def.ModelWeight,
+				CompletenessWeight: def.CompletenessWeight,
+				IntentWeight:       def.IntentWeight,
+				MinCompleteness:    def.MinCompleteness,
+				DecayHalfLifeDays:  def.DecayHalfLifeDays,
+				CalibrationMethod:  scoring.CalibrationNone,
+				Calibration:        db.NullableJSON("{}"),
+				UpdatedAt:          time.Now(),
+			},
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to get scoring policy: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": policy,
+	})
+}
+
+func (s *LeadRoutingService) UpsertScoringPolicy(c echo.Context) error {
+	dto := new(infras.UpsertScoringPolicyDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if dto.GeminiWeight < 0 || dto.ModelWeight < 0 || dto.CompletenessWeight < 0 || dto.IntentWeight < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "weights must not be negative",
+		})
+	}
+
+	if dto.GeminiWeight+dto.ModelWeight+dto.IntentWeight == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "at least one of gemini_weight, model_weight or intent_weight must be positive",
+		})
+	}
+
+	if dto.MinCompleteness < 0 || dto.MinCompleteness > scoring.MaxCompleteness {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": fmt.Sprintf("min_completeness must be between 0 and %d", scoring.MaxCompleteness),
+		})
+	}
+
+	if dto.DecayHalfLifeDays < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "decay_half_life_days must not be negative",
+		})
+	}
+
+	policy, err := s.Server.Queries.UpsertScoringPolicy(c.Request().Context(), db.UpsertScoringPolicyParams{
+		CategoryID:         dto.CategoryID,
+		GeminiWeight:       dto.GeminiWeight,
+		ModelWeight:        dto.ModelWeight,
+		CompletenessWeight: dto.CompletenessWeight,
+		MinCompleteness:    dto.MinCompleteness,
+		DecayHalfLifeDays:  dto.DecayHalfLifeDays,
+		IntentWeight:       dto.IntentWeight,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to save scoring policy: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": policy,
+	})
+}
+
+// CalibrateLeads fits the requested calibration on labeled profiles of the
+// category. Scores are refreshed by the Blend Scores cron job afterwards.
+func (s *LeadRoutingService) CalibrateLeads(c echo.Context) error {
+	dto := new(infras.CalibrateLeadsDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	ctx := c.Request().Context()
+	queries := s.Server.Queries
+
+	calibration := db.NullableJSON("{}")
+	method := dto.Method
+	if method != scoring.CalibrationNone {
+		policy := scoring.DefaultPolicy()
+		row, err := queries.GetScoringPolicy(ctx, dto.CategoryID)
+		if err != nil && err != sql.ErrNoRows {
+			return c.JSON(http.StatusInternalServerError, map[string]any{
+				"error": "failed to get scoring policy: " + err.Error(),
+			})
+		}
+		if err == nil {
+			policy, _ = scoring.PolicyFromRow(row)
+		}
+
+		samples, err := queries.GetCalibrationSamples(ctx, dto.CategoryID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]any{
+				"error": "failed to get labeled profiles: " + err.Error(),
+			})
+		}
+
+		scores := make([]float64, 0, len(samples))
+		labels := make([]bool, 0, len(samples))
+		for _, sample := range samples {
+			raw, ok := policy.Raw(scoring.NewInput(sample.GeminiScore, sample.ModelScore, sample.IntentScore, sample.NonNullCount, sample.UpdatedAt))
+			if !ok {
+				continue
+			}
+			scores = append(scores, raw)
+			labels = append(labels, sample.Label.Int16 > 0)
+		}
+
+		fitted, err := scoring.Fit(method, scores, labels)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"error": "failed to fit calibration: " + err.Error(),
+			})
+		}
+
+		calibration, err = json.Marshal(fitted)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]any{
+				"error": "failed to encode calibration: " + err.Error(),
+			})
+		}
+	}
+
+	policy, err := queries.UpdateScoringCalibration(ctx, db.UpdateScoringCalibrationParams{
+		CategoryID:        dto.CategoryID,
+		CalibrationMethod: method,
+		Calibration:       calibration,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to save calibration: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": policy,
+	})
+}
+
+func (s *LeadRoutingService) LabelLead(c echo.Context) error {
+	dto := new(infras.LabelLeadDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	label := sql.NullInt16{}
+	if dto.Label != nil {
+		label.Valid = true
+		if *dto.Label {
+			label.Int16 = 1
+		}
+	}
+
+	affected, err := s.Server.Queries.UpdateProfileLabel(c.Request().Context(), db.UpdateProfileLabelParams{
+		UserProfileID: dto.ProfileID,
+		CategoryID:    dto.CategoryID,
+		Label:         label,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to label lead: " + err.Error(),
+		})
+	}
+
+	if affected == 0 {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "profile is not in this category",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": "success",
+	})
+}
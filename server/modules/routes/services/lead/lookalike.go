@@ -0,0 +1,317 @@
+package lead
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	"github.com/qxbao/asfpc/pkg/lookalike"
+	"github.com/qxbao/asfpc/pkg/utils/embedding"
+	"github.com/qxbao/asfpc/pkg/vectorindex"
+)
+
+const (
+	maxSeedSize       = 10000
+	defaultLookalikes = 50
+)
+
+// UploadLookalikeSeed creates or extends a seed list of converted customers.
+// Uploaded ids without a profile are reported back, they are not stored.
+func (s *LeadRoutingService) UploadLookalikeSeed(c echo.Context) error {
+	dto := new(infras.UploadLookalikeSeedDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "name is required",
+		})
+	}
+	facebookIDs := make([]string, 0, len(dto.FacebookIDs))
+	for _, id := range dto.FacebookIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			facebookIDs = append(facebookIDs, id)
+		}
+	}
+	size := len(dto.ProfileIDs) + len(facebookIDs)
+	if size == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "profile_ids or facebook_ids is required",
+		})
+	}
+	if size > maxSeedSize {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": fmt.Sprintf("at most %d ids can be uploaded at once", maxSeedSize),
+		})
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.Server.Queries.GetCategoryByID(ctx, dto.CategoryID); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "category not found",
+		})
+	}
+
+	profiles, err := s.Server.Queries.ResolveSeedProfiles(ctx, db.ResolveSeedProfilesParams{
+		ProfileIds:  dto.ProfileIDs,
+		FacebookIds: facebookIDs,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to resolve profiles: " + err.Error(),
+		})
+	}
+
+	foundIDs := make(map[int32]bool, len(profiles))
+	foundFacebookIDs := make(map[string]bool, len(profiles))
+	profileIDs := make([]int32, 0, len(profiles))
+	for _, p := range profiles {
+		foundIDs[p.ID] = true
+		foundFacebookIDs[p.FacebookID] = true
+		profileIDs = append(profileIDs, p.ID)
+	}
+	unmatched := make([]string, 0)
+	for _, id := range dto.ProfileIDs {
+		if !foundIDs[id] {
+			unmatched = append(unmatched, strconv.Itoa(int(id)))
+		}
+	}
+	for _, id := range facebookIDs {
+		if !foundFacebookIDs[id] {
+			unmatched = append(unmatched, id)
+		}
+	}
+
+	result := infras.LookalikeSeedUpload{Matched: len(profileIDs), Unmatched: unmatched}
+	tx, err := s.Server.Database.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to begin transaction: " + err.Error(),
+		})
+	}
+	defer tx.Rollback()
+	queries := s.Server.Queries.WithTx(tx)
+
+	result.Seed, err = queries.UpsertLookalikeSeed(ctx, db.UpsertLookalikeSeedParams{
+		CategoryID: dto.CategoryID,
+		Name:       name,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to save seed: " + err.Error(),
+		})
+	}
+	if dto.Replace {
+		if err := queries.ClearLookalikeSeedProfiles(ctx, result.Seed.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]any{
+				"error": "failed to clear seed: " + err.Error(),
+			})
+		}
+	}
+	result.Added, err = queries.AddLookalikeSeedProfiles(ctx, db.AddLookalikeSeedProfilesParams{
+		SeedID:     result.Seed.ID,
+		ProfileIds: profileIDs,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to add seed profiles: " + err.Error(),
+		})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to save seed: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": result,
+	})
+}
+
+func (s *LeadRoutingService) GetLookalikeSeeds(c echo.Context) error {
+	categoryID, err := strconv.ParseInt(c.QueryParam("category_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "invalid category_id",
+		})
+	}
+
+	seeds, err := s.Server.Queries.GetLookalikeSeeds(c.Request().Context(), int32(categoryID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to get seeds: " + err.Error(),
+		})
+	}
+
+	if seeds == nil {
+		seeds = make([]db.GetLookalikeSeedsRow, 0)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": seeds,
+	})
+}
+
+func (s *LeadRoutingService) DeleteLookalikeSeed(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "invalid seed id",
+		})
+	}
+
+	affected, err := s.Server.Queries.DeleteLookalikeSeed(c.Request().Context(), int32(id))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to delete seed: " + err.Error(),
+		})
+	}
+	if affected == 0 {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "seed not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"message": "Seed deleted successfully",
+	})
+}
+
+// GetLookalikes returns the profiles of the seed's category closest to the
+// seed, seed profiles excluded.
+func (s *LeadRoutingService) GetLookalikes(c echo.Context) error {
+	params, code, err := s.lookalikeParams(c)
+	if err != nil {
+		return c.JSON(code, map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	lookalikeService := embedding.LookalikeService{Server: s.Server}
+	lookalikes, err := lookalikeService.Find(c.Request().Context(), params)
+	if err != nil {
+		if errors.Is(err, lookalike.ErrEmptySeed) {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "failed to find lookalikes: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": lookalikes,
+	})
+}
+
+// ExportLookalikes writes the lookalikes of a seed to a segment file in the
+// background.
+func (s *LeadRoutingService) ExportLookalikes(c echo.Context) error {
+	params, code, err := s.lookalikeParams(c)
+	if err != nil {
+		return c.JSON(code, map[string]any{
+			"error": err.Error(),
+		})
+	}
+
+	id, err := s.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindLookalikeExport,
+		Description: fmt.Sprintf("Exporting lookalikes of seed %s...", params.Seed.Name),
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			lookalikeService := embedding.LookalikeService{Server: s.Server}
+			lookalikes, err := lookalikeService.Find(ctx, params)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find lookalikes: %w", err)
+			}
+			r.Progress(0.5, fmt.Sprintf("Writing %d lookalikes...", len(lookalikes)), nil)
+
+			name := fmt.Sprintf("lookalike_%d.json", params.Seed.ID)
+			path, err := r.File(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create export file: %w", err)
+			}
+			f, err := os.Create(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create export file: %w", err)
+			}
+			if err := json.NewEncoder(f).Encode(embedding.Segment{
+				SeedID:      params.Seed.ID,
+				SeedName:    params.Seed.Name,
+				CategoryID:  params.Seed.CategoryID,
+				Mode:        params.Mode,
+				GeneratedAt: time.Now(),
+				Profiles:    lookalikes,
+			}); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to write export file: %w", err)
+			}
+			if err := f.Close(); err != nil {
+				return nil, err
+			}
+			return map[string]any{"file": name, "count": len(lookalikes)}, nil
+		},
+	})
+	if err != nil {
+		return c.JSON(jobs.StatusCode(err), map[string]any{
+			"error": "failed to start export: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"request_id": id,
+		"message":    "Export started",
+	})
+}
+
+// lookalikeParams reads the query of the lookalike endpoints. The status code
+// goes with the error.
+func (s *LeadRoutingService) lookalikeParams(c echo.Context) (embedding.LookalikeParams, int, error) {
+	var params embedding.LookalikeParams
+	dto := new(infras.GetLookalikesDTO)
+	if err := c.Bind(dto); err != nil {
+		return params, http.StatusBadRequest, errors.New("invalid request")
+	}
+	if dto.SeedID == nil {
+		return params, http.StatusBadRequest, errors.New("seed_id is required")
+	}
+
+	mode, err := lookalike.ParseMode(dto.Mode)
+	if err != nil {
+		return params, http.StatusBadRequest, err
+	}
+	params.Mode = mode
+
+	params.TopK = defaultLookalikes
+	if dto.TopK != nil {
+		if *dto.TopK < 1 || *dto.TopK > vectorindex.MaxEfSearch {
+			return params, http.StatusBadRequest, fmt.Errorf("top_k must be between 1 and %d", vectorindex.MaxEfSearch)
+		}
+		params.TopK = *dto.TopK
+	}
+	params.EfSearch = dto.EfSearch
+
+	params.Seed, err = s.Server.Queries.GetLookalikeSeed(c.Request().Context(), *dto.SeedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return params, http.StatusNotFound, errors.New("seed not found")
+		}
+		return params, http.StatusInternalServerError, fmt.Errorf("failed to get seed: %w", err)
+	}
+	return params, http.StatusOK, nil
+}
@@ -0,0 +1,168 @@
+package ml
+
+import (
+	"archive/zip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/pkg/registry"
+	"github.com/qxbao/asfpc/pkg/utils/training"
+)
+
+// ImportModel takes a zip made by ExportModel and registers it as version 1
+// of a new model. The model is only added to the database, and so becomes
+// assignable, once its folder passes ValidateModel.
+func (s *MLRoutingService) ImportModel(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid file upload: " + err.Error(),
+		})
+	}
+
+	name := c.FormValue("model_name")
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(file.Filename), ".zip")
+	}
+	if !registry.ValidName(name) {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid model name",
+		})
+	}
+
+	ctx := c.Request().Context()
+	maxMB, err := strconv.ParseInt(s.Server.GetConfig(ctx, "ML_IMPORT_MAX_MB", "500"), 10, 64)
+	if err != nil || maxMB <= 0 {
+		maxMB = 500
+	}
+	maxBytes := maxMB << 20
+	if file.Size > maxBytes {
+		return c.JSON(400, map[string]any{
+			"error": fmt.Sprintf("Archive is larger than %d MB", maxMB),
+		})
+	}
+
+	if _, err := s.Server.Queries.GetModelByName(ctx, name); err == nil {
+		return c.JSON(409, map[string]any{
+			"error": "Model " + name + " already exists",
+		})
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to check model name: " + err.Error(),
+		})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Failed to open file: " + err.Error(),
+		})
+	}
+	defer src.Close()
+	zr, err := zip.NewReader(src, file.Size)
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid zip archive: " + err.Error(),
+		})
+	}
+
+	base, err := training.ModelsPath()
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": err.Error(),
+		})
+	}
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to create models directory: " + err.Error(),
+		})
+	}
+
+	// Extract next to the models so the final move is a rename, the dot
+	// keeps the folder out of the sync
+	tmp, err := os.MkdirTemp(base, ".import-")
+	if err != nil {
+		return c.JSON(500, map[string]any{
+			"error": "Failed to create import directory: " + err.Error(),
+		})
+	}
+	defer os.RemoveAll(tmp)
+
+	metadata, err := registry.ExtractArchive(zr, tmp, maxBytes)
+	if err != nil {
+		return c.JSON(400, map[string]any{
+			"error": "Invalid model archive: " + err.Error(),
+		})
+	}
+
+	// Mkdir fails when the folder exists, which also covers models that are
+	// on disk but not synced yet
+	modelDir := path.Join(base, name)
+	if err := os.Mkdir(modelDir, 0755); err != nil {
+		if os.IsExist(err) {
+			return c.JSON(409, map[string]any{
+				"error": "Model folder " + name + " already exists",
+			})
+		}
+		return c.JSON(500, map[string]any{
+			"error": "Failed to create model folder: " + err.Error(),
+		})
+	}
+	versionPath := registry.VersionPath(name, 1)
+	if err := os.Rename(tmp, path.Join(base, versionPath)); err != nil {
+		os.RemoveAll(modelDir)
+		return c.JSON(500, map[string]any{
+			"error": "Failed to move model files: " + err.Error(),
+		})
+	}
+
+	validation, err := s.ValidateModel(versionPath)
+	if err != nil || !validation.IsValid {
+		os.RemoveAll(modelDir)
+		return c.JSON(400, map[string]any{
+			"error": "Imported model failed validation",
+		})
+	}
+
+	description := fmt.Sprintf("Imported from %s on %s", path.Base(file.Filename), time.Now().Format("2006-01-02 15:04:05"))
+	model, err := s.Server.Queries.CreateModel(ctx, db.CreateModelParams{
+		Name:        name,
+		Description: sql.NullString{String: description, Valid: true},
+		CategoryID:  sql.NullInt32{Valid: false},
+	})
+	if err != nil {
+		os.RemoveAll(modelDir)
+		return c.JSON(500, map[string]any{
+			"error": "Failed to create model: " + err.Error(),
+		})
+	}
+	version, err := s.Server.Queries.CreateModelVersion(ctx, db.CreateModelVersionParams{
+		ModelID: model.ID,
+		Version: 1,
+		Path:    versionPath,
+		Metrics: training.ReadMetrics(versionPath, nil),
+	})
+	if err != nil {
+		s.Server.Queries.DeleteModel(ctx, model.ID)
+		os.RemoveAll(modelDir)
+		return c.JSON(500, map[string]any{
+			"error": "Failed to register model version: " + err.Error(),
+		})
+	}
+	logger.Infof("Imported model %s (rmse %.4f) as %s", name, metadata.RMSE, versionPath)
+
+	return c.JSON(200, map[string]any{
+		"data": map[string]any{
+			"model":   model,
+			"version": version,
+		},
+	})
+}
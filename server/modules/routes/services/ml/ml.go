@@ -10,14 +10,18 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/qxbao/asfpc/db"
 	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
 	lg "github.com/qxbao/asfpc/pkg/logger"
-	"github.com/qxbao/asfpc/pkg/utils/python"
+	"github.com/qxbao/asfpc/pkg/registry"
+	"github.com/qxbao/asfpc/pkg/utils/training"
 )
 
 type MLRoutingService struct {
@@ -36,86 +40,66 @@ func (s *MLRoutingService) Train(c echo.Context) error {
 		name := "Model_" + time.Now().Format("20060102150405")
 		dto.ModelName = &name
 	}
+	if !registry.ValidName(*dto.ModelName) {
+		return c.JSON(400, map[string]any{"error": "Invalid model name"})
+	}
 
-	id, err := s.Server.Queries.CreateRequest(c.Request().Context(), sql.NullString{
-		String: fmt.Sprintf("Queueing model %s's training task...", *dto.ModelName),
-		Valid:  true,
-	})
+	timeout, err := strconv.Atoi(s.Server.GetConfig(c.Request().Context(), "PYTHON_TRAIN_TIMEOUT_SEC", "21600"))
+	if err != nil || timeout <= 0 {
+		timeout = 21600
+	}
 
+	id, err := s.Server.Jobs.Submit(c.Request().Context(), jobs.Job{
+		Kind:        jobs.KindTrain,
+		Description: fmt.Sprintf("Queueing model %s's training task...", *dto.ModelName),
+		Timeout:     time.Duration(timeout) * time.Second,
+		Run: func(ctx context.Context, r *jobs.Reporter) (any, error) {
+			return s.trainingTask(ctx, r, dto)
+		},
+	})
 	if err != nil {
-		return c.JSON(500, map[string]any{"error": "Cannot create request"})
+		return c.JSON(jobs.StatusCode(err), map[string]any{"error": "Cannot create request: " + err.Error()})
 	}
 
-	go s.trainingTask(id, dto)
-
 	return c.JSON(200, map[string]any{
 		"request_id": id,
 		"message":    "Training started",
 	})
 }
 
-func (s *MLRoutingService) trainingTask(requestId int32, dto *infras.MLTrainDTO) {
-	pythonService := python.NewPythonService(os.Getenv("PYTHON_ENV_NAME"), true, true, nil)
-	autoTune := "False"
-
-	if *dto.AutoTune {
-		autoTune = "True"
-	}
-
-	args := []string{
-		"--task=train-model",
-		fmt.Sprintf("--model-name=%s", *dto.ModelName),
-		fmt.Sprintf("--auto-tune=%s", autoTune),
-		fmt.Sprintf("--request-id=%d", requestId),
-	}
-
-	if dto.Trials != nil {
-		args = append(args, fmt.Sprintf("--trials=%d", *dto.Trials))
+// CancelTraining is kept for older clients, /data/request/:request_id/cancel
+// cancels any request.
+func (s *MLRoutingService) CancelTraining(c echo.Context) error {
+	dto := new(infras.CancelTrainingDTO)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(400, map[string]any{"error": "Invalid request body"})
 	}
 
-	if dto.CategoryID != nil {
-		args = append(args, fmt.Sprintf("--category-id=%d", *dto.CategoryID))
+	if !s.Server.Jobs.Cancel(dto.RequestID) {
+		return c.JSON(404, map[string]any{"error": "Training is not running"})
 	}
 
-	_, err := pythonService.RunScript(
-		args...,
-	)
-
-	if err != nil {
-		err := s.Server.Queries.UpdateRequestStatus(context.Background(), db.UpdateRequestStatusParams{
-			ID:           requestId,
-			Status:       3,
-			ErrorMessage: sql.NullString{String: err.Error(), Valid: true},
-			Description:  sql.NullString{String: "Training failed.", Valid: true},
-		})
-		if err != nil {
-			logger.Errorf("Failed to update request status for request %d: %v", requestId, err)
-		}
-		return
-	}
-	err = s.Server.Queries.UpdateRequestStatus(context.Background(), db.UpdateRequestStatusParams{
-		ID:          requestId,
-		Status:      2,
-		Progress:    1.0,
-		Description: sql.NullString{String: "Training completed.", Valid: true},
+	return c.JSON(200, map[string]any{
+		"data": "success",
 	})
-	if err != nil {
-		logger.Errorf("Failed to update request status for request %d: %v", requestId, err)
-	}
-
-	ctx := context.Background()
+}
 
-	description := fmt.Sprintf("Model trained on %s", time.Now().Format("2006-01-02 15:04:05"))
-	_, err = s.Server.Queries.CreateModel(ctx, db.CreateModelParams{
-		Name:        *dto.ModelName,
-		Description: sql.NullString{String: description, Valid: true},
-		CategoryID:  sql.NullInt32{Valid: false},
+func (s *MLRoutingService) trainingTask(ctx context.Context, r *jobs.Reporter, dto *infras.MLTrainDTO) (any, error) {
+	trainer := training.TrainingService{Server: s.Server}
+	version, result, err := trainer.Train(ctx, r, training.Params{
+		ModelName:  *dto.ModelName,
+		CategoryID: dto.CategoryID,
+		AutoTune:   *dto.AutoTune,
+		Trials:     dto.Trials,
 	})
 	if err != nil {
-		logger.Errorf("Failed to create model row in database for %s: %v", *dto.ModelName, err)
-	} else {
-		logger.Infof("Model row created in database for %s", *dto.ModelName)
+		return nil, err
 	}
+	return map[string]any{
+		"model_name":   result.ModelName,
+		"version":      version,
+		"test_results": result.TestResults,
+	}, nil
 }
 
 type PredictionStats struct {
@@ -123,6 +107,9 @@ type PredictionStats struct {
 	Max  float64 `json:"max"`
 	Mean float64 `json:"mean"`
 	Std  float64 `json:"std"`
+	// Quantiles are the deciles of the test predictions, the reference of
+	// the drift monitor.
+	Quantiles []float64 `json:"quantiles,omitempty"`
 }
 
 type ResidualStats struct {
@@ -148,6 +135,9 @@ type ModelMetadata struct {
 	SavedAt         string          `json:"saved_at"`
 	IsGPU           bool            `json:"is_gpu"`
 	TrainParams     TrainParams     `json:"train_params"`
+	// FeatureCompleteness is the share of training profiles with each
+	// feature filled in.
+	FeatureCompleteness map[string]float64 `json:"feature_completeness,omitempty"`
 }
 
 type ModelInfo struct {
@@ -158,20 +148,19 @@ type ModelInfo struct {
 	CreatedAt   time.Time          `json:"created_at,omitempty"`
 	Metadata    *ModelMetadata     `json:"metadata,omitempty"`
 	Validation  *ModelValidation   `json:"validation,omitempty"`
+	Versions    []int32            `json:"versions,omitempty"`
 }
 
-// SyncModelsWithDatabase synchronizes models between filesystem and database
+// SyncModelsWithDatabase synchronizes models and their versions between
+// filesystem and database
 // Called during server startup to ensure consistency
 func (s *MLRoutingService) SyncModelsWithDatabase(ctx context.Context) error {
 	logger.Info("Starting model sync between filesystem and database...")
-	
-	modelsDir := path.Join("python", "resources", "models")
-	exc, err := os.Executable()
+
+	modelsPath, err := training.ModelsPath()
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		return err
 	}
-
-	modelsPath := path.Join(path.Dir(exc), modelsDir)
 	if err := os.MkdirAll(modelsPath, 0755); err != nil {
 		return fmt.Errorf("failed to create models directory: %w", err)
 	}
@@ -181,12 +170,20 @@ func (s *MLRoutingService) SyncModelsWithDatabase(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get models from database: %w", err)
 	}
+	dbVersions, err := s.Server.Queries.GetAllModelVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get model versions from database: %w", err)
+	}
 
 	// Create maps for easy lookup
 	dbModelMap := make(map[string]db.Model)
 	for _, model := range dbModels {
 		dbModelMap[model.Name] = model
 	}
+	dbVersionMap := make(map[string]db.ModelVersion)
+	for _, version := range dbVersions {
+		dbVersionMap[version.Path] = version
+	}
 
 	// Get all folders in models directory
 	folders, err := os.ReadDir(modelsPath)
@@ -195,44 +192,74 @@ func (s *MLRoutingService) SyncModelsWithDatabase(ctx context.Context) error {
 	}
 
 	folderMap := make(map[string]bool)
-	addedCount := 0
-	
-	// Check folders and add missing models to database
+	versionMap := make(map[string]bool)
+	addedCount, addedVersions := 0, 0
+
+	// Check folders and add missing models and versions to database
 	for _, folder := range folders {
 		if !folder.IsDir() {
 			continue
 		}
-		
+
 		folderName := folder.Name()
-		folderMap[folderName] = true
-		
-		// Validate model folder
-		validation, err := s.ValidateModel(folderName)
+		// leftovers of imports and other folders that cannot be models
+		if !registry.ValidName(folderName) {
+			continue
+		}
+		versions, err := s.versionFolders(folderName)
 		if err != nil {
 			logger.Warnf("Failed to validate model %s: %v", folderName, err)
 			continue
 		}
-		
-		// Only sync valid models
-		if !validation.IsValid {
+
+		// Only sync models with a valid version
+		if len(versions) == 0 {
 			logger.Infof("Skipping invalid model folder: %s", folderName)
 			continue
 		}
-		
+		folderMap[folderName] = true
+
 		// Check if model exists in database
-		if _, exists := dbModelMap[folderName]; !exists {
+		model, exists := dbModelMap[folderName]
+		if !exists {
 			// Create model in database
 			description := fmt.Sprintf("Auto-synced model from filesystem on %s", time.Now().Format("2006-01-02 15:04:05"))
-			_, err := s.Server.Queries.CreateModel(ctx, db.CreateModelParams{
+			model, err = s.Server.Queries.CreateModel(ctx, db.CreateModelParams{
 				Name:        folderName,
 				Description: sql.NullString{String: description, Valid: true},
 				CategoryID:  sql.NullInt32{Valid: false},
 			})
 			if err != nil {
 				logger.Errorf("Failed to create model %s in database: %v", folderName, err)
+				continue
+			}
+			logger.Infof("✓ Added model to database: %s", folderName)
+			addedCount++
+		}
+
+		for _, v := range versions {
+			versionMap[v.Path] = true
+			if _, exists := dbVersionMap[v.Path]; exists {
+				continue
+			}
+			number := v.Version
+			if number == 0 {
+				if number, err = s.Server.Queries.GetNextModelVersion(ctx, model.ID); err != nil {
+					logger.Errorf("Failed to get next version of model %s: %v", folderName, err)
+					continue
+				}
+			}
+			_, err := s.Server.Queries.CreateModelVersion(ctx, db.CreateModelVersionParams{
+				ModelID: model.ID,
+				Version: number,
+				Path:    v.Path,
+				Metrics: training.ReadMetrics(v.Path, nil),
+			})
+			if err != nil {
+				logger.Errorf("Failed to create model version %s in database: %v", v.Path, err)
 			} else {
-				logger.Infof("✓ Added model to database: %s", folderName)
-				addedCount++
+				logger.Infof("✓ Added model version to database: %s", v.Path)
+				addedVersions++
 			}
 		}
 	}
@@ -251,7 +278,27 @@ func (s *MLRoutingService) SyncModelsWithDatabase(ctx context.Context) error {
 		}
 	}
 
-	logger.Infof("Model sync completed: %d added, %d removed", addedCount, deletedCount)
+	// Delete versions whose folder is gone, those of removed models went
+	// with them
+	modelNames := make(map[int32]string, len(dbModels))
+	for _, dbModel := range dbModels {
+		modelNames[dbModel.ID] = dbModel.Name
+	}
+	deletedVersions := 0
+	for _, version := range dbVersions {
+		name, ok := modelNames[version.ModelID]
+		if versionMap[version.Path] || (ok && !folderMap[name]) {
+			continue
+		}
+		if err := s.Server.Queries.DeleteModelVersion(ctx, version.ID); err != nil {
+			logger.Errorf("Failed to delete orphaned model version %s from database: %v", version.Path, err)
+		} else {
+			deletedVersions++
+		}
+	}
+
+	logger.Infof("Model sync completed: %d added, %d removed, %d versions added, %d versions removed",
+		addedCount, deletedCount, addedVersions, deletedVersions)
 	return nil
 }
 
@@ -311,7 +358,7 @@ func (s *MLRoutingService) ListModels(c echo.Context) error {
 
 	models := make([]ModelInfo, 0)
 	for _, folder := range folders {
-		if !folder.IsDir() {
+		if !folder.IsDir() || !registry.ValidName(folder.Name()) {
 			continue
 		}
 		
@@ -327,21 +374,27 @@ func (s *MLRoutingService) ListModels(c echo.Context) error {
 			modelInfo.CreatedAt = dbModel.CreatedAt
 		}
 		
-		validation, err := s.ValidateModel(folder.Name())
+		versions, err := s.versionFolders(folder.Name())
 		if err != nil {
 			return c.JSON(500, map[string]any{
 				"error": "Failed to validate model " + folder.Name() + ": " + err.Error(),
 			})
 		}
+		validation := ModelValidation{IsExists: true, IsValid: len(versions) > 0}
 		modelInfo.Validation = &validation
 		
 		if !validation.IsValid {
 			models = append(models, modelInfo)
 			continue
 		}
+		for _, v := range versions {
+			if v.Version > 0 {
+				modelInfo.Versions = append(modelInfo.Versions, v.Version)
+			}
+		}
 		
-		// Read metadata.json from folder
-		metadataPath := path.Join(path.Dir(exc), modelsDir, folder.Name(), "metadata.json")
+		// Read metadata.json from the latest version
+		metadataPath := path.Join(path.Dir(exc), modelsDir, versions[0].Path, "metadata.json")
 		if _, err := os.Stat(metadataPath); err == nil {
 			data, err := os.ReadFile(metadataPath)
 			var metadata ModelMetadata
@@ -409,6 +462,59 @@ func (s *MLRoutingService) ValidateModel(modelName string) (ModelValidation, err
 	return ModelValidation{IsExists: true, IsValid: validCount == len(requiredFiles)}, nil
 }
 
+type versionFolder struct {
+	// Version is 0 for a model trained before versioning, which keeps its
+	// files at the top of the model folder.
+	Version int32
+	Path    string
+}
+
+// versionFolders returns the valid versions of a model folder, newest first.
+func (s *MLRoutingService) versionFolders(modelName string) ([]versionFolder, error) {
+	base, err := training.ModelsPath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path.Join(base, modelName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	versions := make([]versionFolder, 0)
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "v") {
+			continue
+		}
+		n, err := strconv.ParseInt(e.Name()[1:], 10, 32)
+		if err != nil || n <= 0 {
+			continue
+		}
+		versionPath := registry.VersionPath(modelName, int32(n))
+		validation, err := s.ValidateModel(versionPath)
+		if err != nil {
+			return nil, err
+		}
+		if validation.IsValid {
+			versions = append(versions, versionFolder{Version: int32(n), Path: versionPath})
+		}
+	}
+	slices.SortFunc(versions, func(a, b versionFolder) int {
+		return int(b.Version - a.Version)
+	})
+
+	validation, err := s.ValidateModel(modelName)
+	if err != nil {
+		return nil, err
+	}
+	if validation.IsValid {
+		versions = append(versions, versionFolder{Path: modelName})
+	}
+	return versions, nil
+}
+
 func (s *MLRoutingService) DeleteModel(c echo.Context) error {
 	dto := new(infras.WithModelNameDTO)
 	if err := c.Bind(dto); err != nil {
@@ -519,6 +625,10 @@ func (s *MLRoutingService) ExportModel(c echo.Context) error {
 
 	modelsDir := path.Join("python", "resources", "models")
 	modelPath := path.Join(path.Dir(exc), modelsDir, dto.ModelName)
+	// versioned models export their latest version
+	if versions, err := s.versionFolders(dto.ModelName); err == nil && len(versions) > 0 {
+		modelPath = path.Join(path.Dir(exc), modelsDir, versions[0].Path)
+	}
 	buf := new(bytes.Buffer)
 	zipWriter := zip.NewWriter(buf)
 	dirs, err := os.ReadDir(modelPath)
@@ -566,3 +676,14 @@ func (s *MLRoutingService) ExportModel(c echo.Context) error {
 	_, err = c.Response().Write(buf.Bytes())
 	return err
 }
+
+func (s *MLRoutingService) GetWorkerStatus(c echo.Context) error {
+	if s.Server.Python == nil {
+		return c.JSON(200, map[string]any{
+			"data": map[string]any{"enabled": false},
+		})
+	}
+	return c.JSON(200, map[string]any{
+		"data": s.Server.Python.Status(),
+	})
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+)
+
+func (s *ModelRoutingService) GetModelDrift(c echo.Context) error {
+	categoryID, err := strconv.ParseInt(c.Param("category_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid category ID",
+		})
+	}
+
+	limit := int32(30)
+	if v := c.QueryParam("limit"); v != "" {
+		l, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || l <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"error": "Invalid limit",
+			})
+		}
+		limit = int32(l)
+	}
+
+	drift, err := s.Server.Queries.GetModelDrift(c.Request().Context(), db.GetModelDriftParams{
+		CategoryID: int32(categoryID),
+		Limit:      limit,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to retrieve model drift: " + err.Error(),
+		})
+	}
+
+	if drift == nil {
+		drift = make([]db.ModelDrift, 0)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": drift,
+	})
+}
+
+func (s *ModelRoutingService) GetModelDriftAlerts(c echo.Context) error {
+	days := 7
+	if v := c.QueryParam("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"error": "Invalid days",
+			})
+		}
+		days = d
+	}
+
+	alerts, err := s.Server.Queries.GetModelDriftAlerts(c.Request().Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to retrieve drift alerts: " + err.Error(),
+		})
+	}
+
+	if alerts == nil {
+		alerts = make([]db.ModelDrift, 0)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": alerts,
+	})
+}
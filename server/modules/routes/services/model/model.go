@@ -187,6 +187,19 @@ func (s *ModelRoutingService) AssignModelToCategory(c echo.Context) error {
 		})
 	}
 
+	// The latest version of the model becomes the category's champion
+	if version, err := s.Server.Queries.GetLatestModelVersion(ctx, dto.ModelID); err == nil {
+		_, err := s.Server.Queries.PromoteModelVersion(ctx, db.PromoteModelVersionParams{
+			CategoryID:        dto.CategoryID,
+			ChampionVersionID: sql.NullInt32{Int32: version.ID, Valid: true},
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]any{
+				"error": "Failed to promote model version: " + err.Error(),
+			})
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]any{
 		"data": model,
 	})
@@ -0,0 +1,234 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/registry"
+	"github.com/qxbao/asfpc/pkg/utils/training"
+)
+
+func (s *ModelRoutingService) GetModelVersions(c echo.Context) error {
+	modelID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid model ID",
+		})
+	}
+
+	versions, err := s.Server.Queries.GetModelVersions(c.Request().Context(), int32(modelID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to retrieve model versions: " + err.Error(),
+		})
+	}
+
+	if versions == nil {
+		versions = make([]db.ModelVersion, 0)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": versions,
+	})
+}
+
+// GetModelVersionLineage returns the version followed by the champions it
+// was trained to replace, oldest last.
+func (s *ModelRoutingService) GetModelVersionLineage(c echo.Context) error {
+	versionID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid version ID",
+		})
+	}
+
+	lineage, err := s.Server.Queries.GetModelVersionLineage(c.Request().Context(), int32(versionID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to retrieve lineage: " + err.Error(),
+		})
+	}
+
+	if len(lineage) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "Model version not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": lineage,
+	})
+}
+
+// GetModelDeployment returns the champion and challenger of a category with
+// a comparison of the challenger's shadow scores.
+func (s *ModelRoutingService) GetModelDeployment(c echo.Context) error {
+	categoryID, err := strconv.ParseInt(c.Param("category_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid category ID",
+		})
+	}
+
+	ctx := c.Request().Context()
+	deployment, err := s.Server.Queries.GetModelDeployment(ctx, int32(categoryID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "No model deployed for this category",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to retrieve deployment: " + err.Error(),
+		})
+	}
+
+	data := map[string]any{
+		"deployment": deployment,
+	}
+	if deployment.ChampionVersionID.Valid {
+		if champion, err := s.Server.Queries.GetModelVersionByID(ctx, deployment.ChampionVersionID.Int32); err == nil {
+			data["champion"] = champion
+		}
+	}
+	if deployment.ChallengerVersionID.Valid {
+		if challenger, err := s.Server.Queries.GetModelVersionByID(ctx, deployment.ChallengerVersionID.Int32); err == nil {
+			data["challenger"] = challenger
+		}
+		comparison, err := s.compareChallenger(ctx, deployment.ChallengerVersionID.Int32, deployment.CategoryID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]any{
+				"error": "Failed to compare challenger: " + err.Error(),
+			})
+		}
+		data["comparison"] = comparison
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": data,
+	})
+}
+
+func (s *ModelRoutingService) compareChallenger(ctx context.Context, versionID, categoryID int32) (registry.Comparison, error) {
+	rows, err := s.Server.Queries.GetShadowScores(ctx, db.GetShadowScoresParams{
+		VersionID:  versionID,
+		CategoryID: categoryID,
+	})
+	if err != nil {
+		return registry.Comparison{}, err
+	}
+
+	samples := make([]registry.Sample, 0, len(rows))
+	for _, row := range rows {
+		sample := registry.Sample{
+			Champion:   row.ChampionScore.Float64,
+			Challenger: row.Score,
+		}
+		if row.GeminiScore.Valid {
+			label := row.GeminiScore.Float64
+			sample.Label = &label
+		}
+		samples = append(samples, sample)
+	}
+	return registry.Compare(samples), nil
+}
+
+// PromoteModelVersion makes a version the champion of a category. The
+// version's model is assigned to the category as well.
+func (s *ModelRoutingService) PromoteModelVersion(c echo.Context) error {
+	dto := new(infras.PromoteModelVersionRequest)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	ctx := c.Request().Context()
+	version, err := s.Server.Queries.GetModelVersionByID(ctx, dto.VersionID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]any{
+			"error": "Model version not found",
+		})
+	}
+
+	categoryID := version.CategoryID
+	if dto.CategoryID != nil {
+		categoryID = sql.NullInt32{Int32: *dto.CategoryID, Valid: true}
+	}
+	if !categoryID.Valid {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "category_id is required for versions trained without a category",
+		})
+	}
+
+	trainer := training.TrainingService{Server: s.Server}
+	deployment, err := trainer.Promote(ctx, version, categoryID.Int32)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to promote model version: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": deployment,
+	})
+}
+
+// SetModelChallenger sets the version shadow-scoring a sample of the
+// category's profiles next to the champion.
+func (s *ModelRoutingService) SetModelChallenger(c echo.Context) error {
+	dto := new(infras.SetModelChallengerRequest)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	sampleRate := registry.DefaultSampleRate
+	if dto.SampleRate != nil {
+		if *dto.SampleRate < 0 || *dto.SampleRate > 1 {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"error": "sample_rate must be between 0 and 1",
+			})
+		}
+		sampleRate = *dto.SampleRate
+	}
+
+	ctx := c.Request().Context()
+	var versionID sql.NullInt32
+	if dto.VersionID != nil {
+		if _, err := s.Server.Queries.GetModelVersionByID(ctx, *dto.VersionID); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]any{
+				"error": "Model version not found",
+			})
+		}
+		deployment, err := s.Server.Queries.GetModelDeployment(ctx, dto.CategoryID)
+		if err == nil && deployment.ChampionVersionID.Valid && deployment.ChampionVersionID.Int32 == *dto.VersionID {
+			return c.JSON(http.StatusBadRequest, map[string]any{
+				"error": "The champion cannot be its own challenger",
+			})
+		}
+		versionID = sql.NullInt32{Int32: *dto.VersionID, Valid: true}
+	}
+
+	deployment, err := s.Server.Queries.SetModelChallenger(ctx, db.SetModelChallengerParams{
+		CategoryID:           dto.CategoryID,
+		ChallengerVersionID:  versionID,
+		ChallengerSampleRate: sampleRate,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to set challenger: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": deployment,
+	})
+}
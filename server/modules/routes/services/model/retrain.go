@@ -0,0 +1,107 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/qxbao/asfpc/db"
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/registry"
+)
+
+func (s *ModelRoutingService) GetRetrainPolicies(c echo.Context) error {
+	policies, err := s.Server.Queries.GetRetrainPolicies(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to retrieve retrain policies: " + err.Error(),
+		})
+	}
+
+	if policies == nil {
+		policies = make([]db.RetrainPolicy, 0)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": policies,
+	})
+}
+
+func (s *ModelRoutingService) UpsertRetrainPolicy(c echo.Context) error {
+	dto := new(infras.UpsertRetrainPolicyRequest)
+	if err := c.Bind(dto); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid request body",
+		})
+	}
+
+	if dto.IntervalHours < 0 || dto.MinNewProfiles < 0 || dto.RMSEMargin < 0 || dto.R2Margin < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "interval_hours, min_new_profiles and margins cannot be negative",
+		})
+	}
+	if dto.Trials != nil && *dto.Trials < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "trials must be at least 1",
+		})
+	}
+	if dto.ModelName == "" {
+		dto.ModelName = fmt.Sprintf("Category_%d", dto.CategoryID)
+	}
+	if !registry.ValidName(dto.ModelName) {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid model name",
+		})
+	}
+
+	isEnabled := true
+	if dto.IsEnabled != nil {
+		isEnabled = *dto.IsEnabled
+	}
+	var trials sql.NullInt32
+	if dto.Trials != nil {
+		trials = sql.NullInt32{Int32: *dto.Trials, Valid: true}
+	}
+
+	policy, err := s.Server.Queries.UpsertRetrainPolicy(c.Request().Context(), db.UpsertRetrainPolicyParams{
+		CategoryID:     dto.CategoryID,
+		ModelName:      dto.ModelName,
+		IsEnabled:      isEnabled,
+		IntervalHours:  dto.IntervalHours,
+		MinNewProfiles: dto.MinNewProfiles,
+		RmseMargin:     dto.RMSEMargin,
+		R2Margin:       dto.R2Margin,
+		AutoTune:       dto.AutoTune,
+		Trials:         trials,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to save retrain policy: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"data": policy,
+	})
+}
+
+func (s *ModelRoutingService) DeleteRetrainPolicy(c echo.Context) error {
+	categoryID, err := strconv.ParseInt(c.Param("category_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "Invalid category ID",
+		})
+	}
+
+	if err := s.Server.Queries.DeleteRetrainPolicy(c.Request().Context(), int32(categoryID)); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]any{
+			"error": "Failed to delete retrain policy: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"message": "Retrain policy deleted successfully",
+	})
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/jobs"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"go.uber.org/fx"
+)
+
+// runnerOptions reads the job runner settings from the config table.
+func runnerOptions(ctx context.Context, s *infras.Server) jobs.Options {
+	opts := jobs.DefaultOptions()
+	if v, err := strconv.Atoi(s.GetConfig(ctx, "JOB_WORKERS", "4")); err == nil && v > 0 {
+		opts.Workers = v
+	}
+	if v, err := strconv.Atoi(s.GetConfig(ctx, "JOB_QUEUE_SIZE", "100")); err == nil && v > 0 {
+		opts.QueueSize = v
+	}
+	if exc, err := os.Executable(); err == nil {
+		opts.Dir = filepath.Join(filepath.Dir(exc), "resources", "requests")
+	}
+	return opts
+}
+
+func RegisterHooks(s *infras.Server, lc fx.Lifecycle) {
+	logger := lg.GetLogger("JobRunnerModule")
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			opts := runnerOptions(ctx, s)
+			runner := jobs.NewRunner(s, opts)
+			if err := runner.Start(ctx); err != nil {
+				return err
+			}
+			s.Jobs = runner
+			logger.Infof("Job runner started with %d workers", opts.Workers)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if s.Jobs == nil {
+				return nil
+			}
+			logger.Info("Stopping job runner...")
+			if err := s.Jobs.Stop(ctx); err != nil {
+				logger.Errorf("Job runner did not stop in time: %v", err)
+			}
+			return nil
+		},
+	})
+}
+
+var RunnerModule = fx.Module("JobRunner",
+	fx.Invoke(RegisterHooks),
+)
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qxbao/asfpc/infras"
+	lg "github.com/qxbao/asfpc/pkg/logger"
+	"github.com/qxbao/asfpc/pkg/utils/python"
+	"go.uber.org/fx"
+)
+
+// workerOptions reads the worker settings from the config table.
+func workerOptions(ctx context.Context, s *infras.Server) python.WorkerOptions {
+	opts := python.DefaultWorkerOptions()
+	if strings.ToLower(s.GetConfig(ctx, "PYTHON_WORKER_TRANSPORT", "stdio")) == "unix" {
+		opts.Socket = filepath.Join(os.TempDir(), fmt.Sprintf("asfpc-worker-%d.sock", os.Getpid()))
+	}
+	if v, err := strconv.Atoi(s.GetConfig(ctx, "PYTHON_WORKER_QUEUE_SIZE", "32")); err == nil && v > 0 {
+		opts.QueueSize = v
+	}
+	if v, err := strconv.Atoi(s.GetConfig(ctx, "PYTHON_WORKER_HEALTH_INTERVAL_SEC", "30")); err == nil && v > 0 {
+		opts.HealthInterval = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(s.GetConfig(ctx, "PYTHON_WORKER_HEALTH_TIMEOUT_SEC", "10")); err == nil && v > 0 {
+		opts.HealthTimeout = time.Duration(v) * time.Second
+	}
+	return opts
+}
+
+func RegisterHooks(s *infras.Server, lc fx.Lifecycle) {
+	logger := lg.GetLogger("PythonWorkerModule")
+	var worker *python.Worker
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			python.SetLimits(python.LimitsFromConfig(func(key, defaultValue string) string {
+				return s.GetConfig(ctx, key, defaultValue)
+			}, python.DefaultLimits()))
+			if strings.ToUpper(s.GetConfig(ctx, "PYTHON_WORKER_ENABLED_BOOL", "TRUE")) != "TRUE" {
+				logger.Info("Python worker is disabled, tasks will spawn a process each")
+				return nil
+			}
+			opts := workerOptions(ctx, s)
+			worker = python.NewWorker(python.NewPythonService(os.Getenv("PYTHON_ENV_NAME"), true, true, nil), opts)
+			worker.Start()
+			s.Python = worker
+			logger.Infof("Python worker starting (queue size %d)", opts.QueueSize)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// Tasks run in their own process group and would outlive us
+			python.KillAll()
+			if worker == nil {
+				return nil
+			}
+			logger.Info("Stopping python worker...")
+			if err := worker.Stop(ctx); err != nil {
+				logger.Errorf("Python worker did not stop in time: %v", err)
+			}
+			return nil
+		},
+	})
+}
+
+var WorkerModule = fx.Module("PythonWorker",
+	fx.Invoke(RegisterHooks),
+)
@@ -9,6 +9,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/qxbao/asfpc/db"
 	"github.com/qxbao/asfpc/infras"
+	"github.com/qxbao/asfpc/pkg/progress"
 	"go.uber.org/fx"
 )
 
@@ -25,6 +26,7 @@ func NewServer(database *sql.DB, queries *db.Queries) *infras.Server {
 		Database: database,
 		Queries:  queries,
 		Echo:     e, // Echo is now available immediately
+		Progress: progress.NewBroker(64),
 	}
 
 	// Set environment variables with defaults